@@ -0,0 +1,235 @@
+package audit
+
+import (
+	// Go Internal Packages
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	// Local Packages
+	events "learn-go/events"
+	models "learn-go/models"
+	utils "learn-go/utils"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// Anonymous is the actor of the changes made without one, see WithRequest
+const Anonymous = "anonymous"
+
+// Request identifies who made the changes of a context
+type Request struct {
+	Actor     string
+	RequestID string
+}
+
+type requestKey struct{}
+
+// Actor returns who makes the changes of a connection, the common name of its verified
+// client certificate. The actor a client names in actorHeader is only taken when its
+// certificate is one of trustedClients, e.g. the proxy that authenticates the users;
+// the header of any other client, or of one without a certificate, is ignored, since
+// nothing but mTLS tells the proxy apart from a client that sets the header itself.
+func Actor(state *tls.ConnectionState, actorHeader string, trustedClients []string) string {
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return ""
+	}
+	client := state.VerifiedChains[0][0].Subject.CommonName
+	if actorHeader != "" && slices.Contains(trustedClients, client) {
+		return actorHeader
+	}
+	return client
+}
+
+// WithRequest returns the context of the changes made for req
+func WithRequest(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+// RequestFrom returns the request of the context, its actor is Anonymous when unknown
+func RequestFrom(ctx context.Context) Request {
+	req, _ := ctx.Value(requestKey{}).(Request)
+	if req.Actor == "" {
+		req.Actor = Anonymous
+	}
+	return req
+}
+
+// Repository appends to the audit log and reads it back, appending an entry whose id is
+// in the log already is a no-op
+type Repository interface {
+	Append(ctx context.Context, entries ...models.AuditEntry) error
+	List(ctx context.Context, entity, entityID string, limit, offset int) ([]models.AuditEntry, error)
+}
+
+// Change is a change to an entity, Before is nil on creation and After on deletion.
+// Action is derived from them unless it is set, e.g. to models.AuditRestore.
+type Change struct {
+	Entity   string
+	EntityID string
+	Action   string
+	Before   any
+	After    any
+}
+
+// Recorder keeps the audit log of the changes the services make. The services hand the
+// events of Events to the repository together with the change, so an entry is written
+// if and only if its change is. As an events.EventHandler the Recorder then appends the
+// entries to the audit log. A nil Recorder records nothing.
+type Recorder struct {
+	logger     *zap.Logger
+	repository Repository
+}
+
+func NewRecorder(repository Repository, logger *zap.Logger) *Recorder {
+	return &Recorder{repository: repository, logger: logger}
+}
+
+// Enabled reports whether Events returns any events
+func (r *Recorder) Enabled() bool {
+	return r != nil
+}
+
+// Events returns an events.AuditRecorded event for every change, its entry stamped with
+// the request of the context
+func (r *Recorder) Events(ctx context.Context, changes ...Change) ([]events.Event, error) {
+	if !r.Enabled() || len(changes) == 0 {
+		return nil, nil
+	}
+
+	req := RequestFrom(ctx)
+	at := time.Now().UTC()
+	evts := make([]events.Event, 0, len(changes))
+	for _, change := range changes {
+		diff, err := Diff(change.Before, change.After)
+		if err != nil {
+			return nil, err
+		}
+		entry := models.AuditEntry{
+			ID:        utils.GenerateRandomID(),
+			Entity:    change.Entity,
+			EntityID:  change.EntityID,
+			Action:    actionOf(change),
+			Actor:     req.Actor,
+			RequestID: req.RequestID,
+			At:        at,
+			Changes:   diff,
+		}
+		evt, err := events.New(events.AuditRecorded, change.EntityID, entry)
+		if err != nil {
+			return nil, err
+		}
+		evts = append(evts, evt)
+	}
+	return evts, nil
+}
+
+func (r *Recorder) Name() string {
+	return "audit"
+}
+
+// Handle appends the entry of an events.AuditRecorded event to the audit log, other
+// events are ignored. Appending an entry again is a no-op, so redeliveries are safe.
+func (r *Recorder) Handle(ctx context.Context, evt events.Event) error {
+	if evt.Type != events.AuditRecorded {
+		return nil
+	}
+	var entry models.AuditEntry
+	if err := json.Unmarshal(evt.Payload, &entry); err != nil {
+		r.logger.Error("dropping undecodable audit entry", zap.String("eventId", evt.ID), zap.Error(err))
+		return nil
+	}
+	return r.repository.Append(ctx, entry)
+}
+
+func actionOf(change Change) string {
+	switch {
+	case change.Action != "":
+		return change.Action
+	case change.Before == nil:
+		return models.AuditCreate
+	case change.After == nil:
+		return models.AuditDelete
+	default:
+		return models.AuditUpdate
+	}
+}
+
+// Diff returns the fields whose JSON values differ between before and after, sorted by
+// name. Objects are compared field by field, any other value as a whole.
+func Diff(before, after any) ([]models.AuditChange, error) {
+	beforeFields, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []models.AuditChange{}
+	for field, value := range beforeFields {
+		if afterValue, ok := afterFields[field]; !ok || !reflect.DeepEqual(value, afterValue) {
+			changes = append(changes, models.AuditChange{Field: field, Before: value, After: afterFields[field]})
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes = append(changes, models.AuditChange{Field: field, After: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// flatten returns the leaves of the JSON object of v by their dotted paths
+func flatten(v any) (map[string]any, error) {
+	fields := map[string]any{}
+	if v == nil {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audited value: %w", err)
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode audited value: %w", err)
+	}
+
+	var walk func(prefix string, node any)
+	walk = func(prefix string, node any) {
+		object, ok := node.(map[string]any)
+		if !ok || len(object) == 0 {
+			fields[prefix] = node
+			return
+		}
+		for key, child := range object {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			walk(key, child)
+		}
+	}
+	if object, ok := tree.(map[string]any); ok && len(object) == 0 {
+		return fields, nil
+	}
+	walk("", tree)
+	return fields, nil
+}
+
+// List returns the window of the audit log of an entity, or of one of them when entityID
+// is not empty, newest first
+func (r *Recorder) List(ctx context.Context, entity, entityID string, limit, offset int) ([]models.AuditEntry, error) {
+	entries, err := r.repository.List(ctx, entity, entityID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries due to :: %w", err)
+	}
+	return entries, nil
+}
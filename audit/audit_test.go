@@ -0,0 +1,205 @@
+package audit
+
+import (
+	// Go Internal Packages
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+
+	// Local Packages
+	events "learn-go/events"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// memLog is an in-memory Repository
+type memLog struct {
+	mu      sync.Mutex
+	entries []models.AuditEntry
+}
+
+func (m *memLog) Append(_ context.Context, entries ...models.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entry := range entries {
+		seen := false
+		for _, existing := range m.entries {
+			seen = seen || existing.ID == entry.ID
+		}
+		if !seen {
+			m.entries = append(m.entries, entry)
+		}
+	}
+	return nil
+}
+
+func (m *memLog) List(_ context.Context, entity, entityID string, limit, offset int) ([]models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.AuditEntry{}, m.entries...), nil
+}
+
+func TestRecorderEvents(t *testing.T) {
+	recorder := NewRecorder(&memLog{}, zap.NewNop())
+	ctx := WithRequest(context.Background(), Request{Actor: "ann", RequestID: "req-1"})
+
+	evts, err := recorder.Events(ctx,
+		Change{Entity: models.AuditStudent, EntityID: "1", After: map[string]string{"name": "Ann"}},
+		Change{Entity: models.AuditStudent, EntityID: "1", Before: map[string]string{"name": "Ann"}},
+	)
+	if err != nil || len(evts) != 2 {
+		t.Fatalf("Events() = %d events, %v, want 2", len(evts), err)
+	}
+	for i, wantAction := range []string{models.AuditCreate, models.AuditDelete} {
+		if evts[i].Type != events.AuditRecorded || evts[i].AggregateID != "1" {
+			t.Fatalf("event %d = %+v, want an audit.recorded event of 1", i, evts[i])
+		}
+		var entry models.AuditEntry
+		if err := json.Unmarshal(evts[i].Payload, &entry); err != nil {
+			t.Fatal(err)
+		}
+		if entry.ID == "" || entry.Action != wantAction || entry.Actor != "ann" || entry.RequestID != "req-1" {
+			t.Fatalf("entry %d = %+v, want action %s by ann", i, entry, wantAction)
+		}
+	}
+
+	var disabled *Recorder
+	if evts, err := disabled.Events(ctx, Change{EntityID: "1"}); err != nil || evts != nil {
+		t.Fatalf("Events() of a nil Recorder = %v, %v, want none", evts, err)
+	}
+	if evts, _ := recorder.Events(context.Background(), Change{EntityID: "1", After: 1}); len(evts) != 1 {
+		t.Fatalf("Events() = %d events, want 1", len(evts))
+	} else if entry := decode(t, evts[0]); entry.Actor != Anonymous {
+		t.Fatalf("actor of a context without a request = %q, want %q", entry.Actor, Anonymous)
+	}
+}
+
+func TestRecorderHandle(t *testing.T) {
+	log := &memLog{}
+	recorder := NewRecorder(log, zap.NewNop())
+	ctx := context.Background()
+
+	evts, _ := recorder.Events(ctx, Change{Entity: models.AuditOrder, EntityID: "o1", After: map[string]int{"total": 1}})
+	other, _ := events.New(events.OrderCreated, "o1", nil)
+	undecodable := events.Event{ID: "bad", Type: events.AuditRecorded, Payload: json.RawMessage(`"entry"`)}
+
+	// Redeliveries of an entry append it once, other events are ignored
+	for _, evt := range []events.Event{evts[0], evts[0], other, undecodable} {
+		if err := recorder.Handle(ctx, evt); err != nil {
+			t.Fatalf("Handle(%s) error = %v", evt.Type, err)
+		}
+	}
+	entries, _ := recorder.List(ctx, models.AuditOrder, "o1", 10, 0)
+	if len(entries) != 1 || !reflect.DeepEqual(entries[0], decode(t, evts[0])) {
+		t.Fatalf("audit log = %+v, want the one entry", entries)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type student struct {
+		Name    string   `json:"name"`
+		Address address  `json:"address"`
+		Tags    []string `json:"tags,omitempty"`
+	}
+
+	tests := []struct {
+		name   string
+		before any
+		after  any
+		want   []models.AuditChange
+	}{
+		{
+			name:  "creation",
+			after: student{Name: "Ann", Address: address{City: "Pune"}},
+			want: []models.AuditChange{
+				{Field: "address.city", After: "Pune"},
+				{Field: "name", After: "Ann"},
+			},
+		},
+		{
+			name:   "nested field",
+			before: student{Name: "Ann", Address: address{City: "Pune"}},
+			after:  student{Name: "Ann", Address: address{City: "Goa"}},
+			want:   []models.AuditChange{{Field: "address.city", Before: "Pune", After: "Goa"}},
+		},
+		{
+			name:   "arrays as a whole",
+			before: student{Name: "Ann", Tags: []string{"a"}},
+			after:  student{Name: "Ann", Tags: []string{"a", "b"}},
+			want:   []models.AuditChange{{Field: "tags", Before: []any{"a"}, After: []any{"a", "b"}}},
+		},
+		{
+			name:   "deletion",
+			before: student{Name: "Ann"},
+			want: []models.AuditChange{
+				{Field: "address.city", Before: ""},
+				{Field: "name", Before: "Ann"},
+			},
+		},
+		{
+			name:   "no change",
+			before: student{Name: "Ann"},
+			after:  student{Name: "Ann"},
+			want:   []models.AuditChange{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("Diff() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActor(t *testing.T) {
+	verified := func(commonName string) *tls.ConnectionState {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+	trusted := []string{"proxy"}
+
+	tests := []struct {
+		name   string
+		state  *tls.ConnectionState
+		header string
+		want   string
+	}{
+		{name: "no tls ignores the header", header: "mallory"},
+		{name: "unverified certificate ignores the header", state: &tls.ConnectionState{}, header: "mallory"},
+		{name: "client certificate", state: verified("svc-a"), want: "svc-a"},
+		{name: "untrusted client ignores the header", state: verified("svc-a"), header: "mallory", want: "svc-a"},
+		{name: "trusted client names the actor", state: verified("proxy"), header: "ann", want: "ann"},
+		{name: "trusted client without the header", state: verified("proxy"), want: "proxy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Actor(tt.state, tt.header, trusted); got != tt.want {
+				t.Fatalf("Actor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func decode(t *testing.T, evt events.Event) models.AuditEntry {
+	t.Helper()
+	var entry models.AuditEntry
+	if err := json.Unmarshal(evt.Payload, &entry); err != nil {
+		t.Fatalf("undecodable audit entry: %v", err)
+	}
+	return entry
+}
@@ -0,0 +1,251 @@
+// Package client is a typed Go client for the learn-go HTTP API.
+//
+//	c := client.New("http://localhost:8888/learn-go/v1", client.WithBearerToken(token))
+//	student, err := c.GetStudent(ctx, "21")
+//	if errors.IsKind(err, errors.NotFound) { ... }
+package client
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	patch "learn-go/patch"
+)
+
+// Client calls the API mounted at its base url, it is safe for concurrent use
+type Client struct {
+	baseURL     string
+	headers     http.Header
+	httpClient  *http.Client
+	maxRetries  int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	pageSize    int
+	bearerToken string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the http.Client used for the requests, http.DefaultClient by default
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithBearerToken sends the token in the Authorization header of every request
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.bearerToken = token }
+}
+
+// WithHeader sends the header with every request, e.g. an API key
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.headers.Add(key, value) }
+}
+
+// WithRetries sets how many times a request failing with a 5xx or 429 is retried and
+// the backoff before the first retry, it doubles up to 30s. Defaults to 3 and 200ms.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) { c.maxRetries, c.backoff = maxRetries, backoff }
+}
+
+// WithPageSize sets the number of items fetched per request by the iterators, 100 by default
+func WithPageSize(size int) Option {
+	return func(c *Client) { c.pageSize = size }
+}
+
+// New returns a client for the API at baseURL, e.g. http://localhost:8888/learn-go/v1
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		headers:    http.Header{},
+		httpClient: http.DefaultClient,
+		maxRetries: 3,
+		backoff:    200 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		pageSize:   100,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// typedBody is a request body sent with its own media type instead of application/json
+type typedBody struct {
+	contentType string
+	value       any
+}
+
+// do sends the request and decodes a successful response into out when it is not nil.
+// Error responses are returned as *errors.Error with the kind of the status code.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	contentType := "application/json"
+	if typed, ok := body.(typedBody); ok {
+		contentType, body = typed.contentType, typed.value
+	}
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := c.send(ctx, method, target, contentType, payload)
+		if err != nil {
+			return err
+		}
+
+		if c.retryable(method, res.StatusCode) && attempt < c.maxRetries {
+			wait := c.wait(attempt, res)
+			drain(res)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		return decode(res, out)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, target, contentType string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	for key, values := range c.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s %s: %w", method, target, err)
+	}
+	return res, nil
+}
+
+// retryable reports whether the request may be sent again. A 429 was not processed
+// so any method is retried, a 5xx only for idempotent methods as a POST or a PATCH
+// may have been applied before the server failed.
+func (c *Client) retryable(method string, status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return status >= 500 && method != http.MethodPost && method != http.MethodPatch
+}
+
+// patchBody sends a patch.JSONPatch as a JSON Patch and any other value as a merge patch
+func patchBody(p any) typedBody {
+	switch p.(type) {
+	case patch.JSONPatch, []patch.Operation:
+		return typedBody{contentType: patch.JSONPatchType, value: p}
+	default:
+		return typedBody{contentType: patch.MergePatchType, value: p}
+	}
+}
+
+// wait honours Retry-After, otherwise backs off exponentially with jitter
+func (c *Client) wait(attempt int, res *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	wait := min(c.backoff<<attempt, c.maxBackoff)
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// errorBody is the body written by response.RespondError
+type errorBody struct {
+	Message          string                  `json:"message"`
+	ValidationErrors errors.ValidationErrors `json:"validation_errors"`
+}
+
+func decode(res *http.Response, out any) error {
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		if out == nil {
+			drain(res)
+			return nil
+		}
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	}
+
+	var body errorBody
+	data, _ := io.ReadAll(res.Body)
+	if err := json.Unmarshal(data, &body); err != nil || body.Message == "" {
+		body.Message = strings.TrimSpace(string(data))
+		if body.Message == "" {
+			body.Message = http.StatusText(res.StatusCode)
+		}
+	}
+
+	args := []any{kindOf(res.StatusCode), body.Message}
+	if len(body.ValidationErrors) > 0 {
+		args = append(args, body.ValidationErrors)
+	}
+	return errors.E(args...)
+}
+
+// kindOf maps the status codes of response.RespondError back to error kinds
+func kindOf(status int) errors.Kind {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return errors.Invalid
+	case http.StatusRequestEntityTooLarge:
+		return errors.TooLarge
+	case http.StatusNotFound:
+		return errors.NotFound
+	case http.StatusUnauthorized:
+		return errors.Unauthorized
+	case http.StatusForbidden:
+		return errors.Forbidden
+	case http.StatusConflict:
+		return errors.Conflict
+	case http.StatusUnsupportedMediaType:
+		return errors.Unsupported
+	case http.StatusInternalServerError:
+		return errors.Internal
+	default:
+		return errors.Other
+	}
+}
+
+func drain(res *http.Response) {
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+}
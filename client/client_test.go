@@ -0,0 +1,379 @@
+package client_test
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// Local Packages
+	client "learn-go/client"
+	errors "learn-go/errors"
+	events "learn-go/events"
+	xhttp "learn-go/http"
+	handlers "learn-go/http/handlers"
+	models "learn-go/models"
+	patch "learn-go/patch"
+	sqldb "learn-go/repositories/sqldb"
+	health "learn-go/services/health"
+	orders "learn-go/services/orders"
+	students "learn-go/services/students"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// newTestAPI serves the real router over services backed by an in-memory SQLite
+// database and returns a client of it
+func newTestAPI(t *testing.T, opts ...client.Option) *client.Client {
+	t.Helper()
+	ctx := context.Background()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := sqldb.Connect(ctx, sqldb.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := sqldb.Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	logger := zap.NewNop()
+	emitter := events.NewEmitter(false)
+	studentsSvc := students.NewService(sqldb.NewStudentsRepository(db), emitter, nil)
+	ordersSvc := orders.NewService(sqldb.NewOrdersRepository(db), emitter, nil)
+	server := xhttp.NewServer("/learn-go", logger,
+		handlers.NewStudentsHandler(studentsSvc, nil), handlers.NewOrdersHandler(ordersSvc),
+		health.NewService(logger, nil, nil, db), nil, nil, nil, nil, nil, nil,
+		xhttp.Options{BodyLimit: 1 << 20})
+	router, err := server.Router(ctx)
+	if err != nil {
+		t.Fatalf("Router() error = %v", err)
+	}
+
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+	return client.New(ts.URL+"/learn-go/v1", opts...)
+}
+
+func newStudent(rollNo string) models.StudentModel {
+	return models.StudentModel{RollNo: rollNo, Name: "Student " + rollNo, Gender: "female", MailID: rollNo + "@example.com"}
+}
+
+func newOrder(userID string) models.Order {
+	return models.Order{
+		UserID:      userID,
+		OrderStatus: "placed",
+		LineItems:   []models.LineItem{{ItemID: "book", Quantity: 1, Price: 9.5}},
+	}
+}
+
+func TestStudentsAgainstRouter(t *testing.T) {
+	c := newTestAPI(t)
+	ctx := context.Background()
+
+	if _, err := c.CreateStudent(ctx, newStudent("21")); err != nil {
+		t.Fatalf("CreateStudent() error = %v", err)
+	}
+	got, err := c.GetStudent(ctx, "21")
+	if err != nil || got.Name != "Student 21" {
+		t.Fatalf("GetStudent() = %+v, %v", got, err)
+	}
+
+	patched, err := c.PatchStudent(ctx, "21", map[string]any{"name": "Ann"})
+	if err != nil || patched.Name != "Ann" {
+		t.Fatalf("PatchStudent() with a merge patch = %+v, %v", patched, err)
+	}
+	ops := patch.JSONPatch{{Op: "replace", Path: "/gender", Value: []byte(`"male"`)}}
+	patched, err = c.PatchStudent(ctx, "21", ops)
+	if err != nil || patched.Gender != "male" || patched.Name != "Ann" {
+		t.Fatalf("PatchStudent() with a JSON Patch = %+v, %v", patched, err)
+	}
+
+	if err := c.DeleteStudent(ctx, "21"); err != nil {
+		t.Fatalf("DeleteStudent() error = %v", err)
+	}
+	if _, err := c.CreateStudent(ctx, newStudent("21")); !errors.IsKind(err, errors.Conflict) {
+		t.Fatalf("CreateStudent() of a deleted roll number error = %v, want Conflict", err)
+	}
+	restored, err := c.RestoreStudent(ctx, "21")
+	if err != nil || restored.Name != "Ann" {
+		t.Fatalf("RestoreStudent() = %+v, %v", restored, err)
+	}
+}
+
+func TestErrorKindsAgainstRouter(t *testing.T) {
+	c := newTestAPI(t)
+	ctx := context.Background()
+	if _, err := c.CreateStudent(ctx, newStudent("21")); err != nil {
+		t.Fatalf("CreateStudent() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		kind errors.Kind
+	}{
+		{name: "missing student", kind: errors.NotFound, call: func() error {
+			_, err := c.GetStudent(ctx, "404")
+			return err
+		}},
+		{name: "taken roll number", kind: errors.Conflict, call: func() error {
+			_, err := c.CreateStudent(ctx, newStudent("21"))
+			return err
+		}},
+		{name: "invalid student", kind: errors.Invalid, call: func() error {
+			_, err := c.CreateStudent(ctx, models.StudentModel{RollNo: "22"})
+			return err
+		}},
+		{name: "restore a student that is not deleted", kind: errors.Conflict, call: func() error {
+			_, err := c.RestoreStudent(ctx, "21")
+			return err
+		}},
+		{name: "missing order", kind: errors.NotFound, call: func() error {
+			_, err := c.GetOrder(ctx, "404")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			var e *errors.Error
+			if !errors.As(err, &e) || e.Kind != tt.kind {
+				t.Fatalf("error = %v, want kind %s", err, tt.kind)
+			}
+			if e.Message == "" {
+				t.Fatalf("error has no message")
+			}
+		})
+	}
+}
+
+func TestValidationErrorsAreDecoded(t *testing.T) {
+	c := newTestAPI(t)
+	_, err := c.CreateStudent(context.Background(), models.StudentModel{RollNo: "22", Name: "Ann"})
+
+	var ve errors.ValidationErrors
+	if !errors.As(err, &ve) || len(ve) == 0 {
+		t.Fatalf("CreateStudent() error = %v, want validation errors", err)
+	}
+}
+
+func TestStudentsIteratorPages(t *testing.T) {
+	c := newTestAPI(t, client.WithPageSize(2))
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		if _, err := c.CreateStudent(ctx, newStudent(fmt.Sprintf("%02d", i))); err != nil {
+			t.Fatalf("CreateStudent() error = %v", err)
+		}
+	}
+
+	var rollNos []string
+	for student, err := range c.Students(ctx) {
+		if err != nil {
+			t.Fatalf("Students() error = %v", err)
+		}
+		rollNos = append(rollNos, student.RollNo)
+	}
+	if got := strings.Join(rollNos, ","); got != "01,02,03,04,05" {
+		t.Fatalf("Students() = %s, want 01,02,03,04,05", got)
+	}
+}
+
+func TestOrdersIteratorPagesThroughConcurrentWrites(t *testing.T) {
+	c := newTestAPI(t, client.WithPageSize(3))
+	ctx := context.Background()
+	before := map[string]bool{}
+	for i := 0; i < 8; i++ {
+		id, err := c.CreateOrder(ctx, newOrder("21"))
+		if err != nil {
+			t.Fatalf("CreateOrder() error = %v", err)
+		}
+		before[id] = true
+	}
+
+	seen := map[string]bool{}
+	last := ""
+	for order, err := range c.Orders(ctx, "21") {
+		if err != nil {
+			t.Fatalf("Orders() error = %v", err)
+		}
+		if seen[order.ID] {
+			t.Fatalf("Orders() repeated order %s", order.ID)
+		}
+		if order.ID <= last {
+			t.Fatalf("Orders() yielded %s after %s, want order id order", order.ID, last)
+		}
+		seen[order.ID], last = true, order.ID
+
+		// Writes between the pages must neither skip nor repeat the other orders
+		if len(seen) == 3 {
+			if _, err := c.CreateOrder(ctx, newOrder("21")); err != nil {
+				t.Fatalf("CreateOrder() error = %v", err)
+			}
+		}
+	}
+
+	for id := range before {
+		if !seen[id] {
+			t.Fatalf("Orders() skipped order %s", id)
+		}
+	}
+}
+
+func TestIteratorYieldsError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"forbidden"}`))
+	}))
+	defer ts.Close()
+	c := client.New(ts.URL)
+
+	count := 0
+	for _, err := range c.Orders(context.Background(), "") {
+		count++
+		if !errors.IsKind(err, errors.Forbidden) {
+			t.Fatalf("Orders() error = %v, want Forbidden", err)
+		}
+	}
+	if count != 1 {
+		t.Fatalf("Orders() yielded %d times, want once", count)
+	}
+}
+
+// flakyServer answers the first failures requests with status, then with a student
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"message":"try again"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"roll_no":"21","name":"Ann","gender":"female","mail_id":"a@example.com"}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &calls
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    http.Header
+		call      func(c *client.Client) error
+		wantCalls int32
+		wantKind  errors.Kind
+		wantErr   bool
+	}{
+		{
+			name:   "GET retried on 5xx",
+			status: http.StatusServiceUnavailable,
+			call: func(c *client.Client) error {
+				_, err := c.GetStudent(context.Background(), "21")
+				return err
+			},
+			wantCalls: 3,
+		},
+		{
+			name:   "POST not retried on 5xx",
+			status: http.StatusBadGateway,
+			call: func(c *client.Client) error {
+				_, err := c.CreateStudent(context.Background(), newStudent("21"))
+				return err
+			},
+			wantCalls: 1, wantErr: true, wantKind: errors.Other,
+		},
+		{
+			name:   "PATCH not retried on 5xx",
+			status: http.StatusInternalServerError,
+			call: func(c *client.Client) error {
+				_, err := c.PatchStudent(context.Background(), "21", map[string]any{"name": "Ann"})
+				return err
+			},
+			wantCalls: 1, wantErr: true, wantKind: errors.Internal,
+		},
+		{
+			name:   "POST retried on 429 after Retry-After",
+			status: http.StatusTooManyRequests,
+			header: http.Header{"Retry-After": {"0"}},
+			call: func(c *client.Client) error {
+				_, err := c.CreateStudent(context.Background(), newStudent("21"))
+				return err
+			},
+			wantCalls: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, calls := flakyServer(t, 2, tt.status, tt.header)
+			c := client.New(ts.URL, client.WithRetries(3, time.Millisecond))
+
+			err := tt.call(c)
+			if tt.wantErr {
+				if !errors.IsKind(err, tt.wantKind) {
+					t.Fatalf("error = %v, want kind %s", err, tt.wantKind)
+				}
+			} else if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("server called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	ts, calls := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+	c := client.New(ts.URL, client.WithRetries(2, time.Millisecond))
+
+	_, err := c.GetStudent(context.Background(), "21")
+	if !errors.IsKind(err, errors.Other) || !strings.Contains(err.Error(), "try again") {
+		t.Fatalf("GetStudent() error = %v, want the last response", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("server called %d times, want 3", got)
+	}
+}
+
+func TestContextCancelledDuringBackoff(t *testing.T) {
+	ts, _ := flakyServer(t, 100, http.StatusServiceUnavailable, nil)
+	c := client.New(ts.URL, client.WithRetries(5, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.GetStudent(ctx, "21")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetStudent() error = %v, want context.DeadlineExceeded", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("GetStudent() kept backing off after the context was done")
+	}
+}
+
+func TestContextCancelledDuringRequest(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	c := client.New(ts.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetStudent(ctx, "21"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetStudent() error = %v, want context.DeadlineExceeded", err)
+	}
+}
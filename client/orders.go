@@ -0,0 +1,89 @@
+package client
+
+import (
+	// Go Internal Packages
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	// Local Packages
+	models "learn-go/models"
+)
+
+// Orders iterates over the orders in order id order, only the orders of userID when
+// it is not empty, fetching them a page at a time. Every page starts after the last
+// order of the previous one, so orders written meanwhile are neither skipped nor
+// repeated. The iteration stops at the first error, which is yielded with a zero order.
+func (c *Client) Orders(ctx context.Context, userID string) iter.Seq2[models.Order, error] {
+	return func(yield func(models.Order, error) bool) {
+		after := ""
+		for {
+			query := url.Values{"limit": {strconv.Itoa(c.pageSize)}}
+			if userID != "" {
+				query.Set("user_id", userID)
+			}
+			if after != "" {
+				query.Set("after", after)
+			}
+			var orders []models.Order
+			if err := c.do(ctx, http.MethodGet, "/orders/", query, nil, &orders); err != nil {
+				yield(models.Order{}, err)
+				return
+			}
+			for _, order := range orders {
+				if !yield(order, nil) {
+					return
+				}
+			}
+			if len(orders) < c.pageSize {
+				return
+			}
+			after = orders[len(orders)-1].ID
+		}
+	}
+}
+
+func (c *Client) GetOrder(ctx context.Context, orderID string) (models.Order, error) {
+	var order models.Order
+	err := c.do(ctx, http.MethodGet, "/orders/"+url.PathEscape(orderID), nil, nil, &order)
+	return order, err
+}
+
+// CreateOrder places the order and returns the id assigned by the server
+func (c *Client) CreateOrder(ctx context.Context, order models.Order) (string, error) {
+	var res message
+	if err := c.do(ctx, http.MethodPost, "/orders/", nil, order, &res); err != nil {
+		return "", err
+	}
+	return res.suffix(), nil
+}
+
+// UpdateOrder replaces the order with the id of order
+func (c *Client) UpdateOrder(ctx context.Context, order models.Order) error {
+	return c.do(ctx, http.MethodPut, "/orders/"+url.PathEscape(order.ID), nil, order, nil)
+}
+
+// PatchOrder applies p to the order and returns the patched order, see PatchStudent
+func (c *Client) PatchOrder(ctx context.Context, orderID string, p any) (models.Order, error) {
+	var patched models.Order
+	err := c.do(ctx, http.MethodPatch, "/orders/"+url.PathEscape(orderID), nil, patchBody(p), &patched)
+	return patched, err
+}
+
+func (c *Client) DeleteOrder(ctx context.Context, orderID string) error {
+	return c.do(ctx, http.MethodDelete, "/orders/"+url.PathEscape(orderID), nil, nil, nil)
+}
+
+// message is the body of the order endpoints that answer with a message,
+// e.g. "sucessfully created order : <id>"
+type message struct {
+	Message string `json:"message"`
+}
+
+func (m message) suffix() string {
+	_, after, _ := strings.Cut(m.Message, " : ")
+	return after
+}
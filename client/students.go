@@ -0,0 +1,91 @@
+package client
+
+import (
+	// Go Internal Packages
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	// Local Packages
+	models "learn-go/models"
+)
+
+// ListStudents returns every student
+func (c *Client) ListStudents(ctx context.Context) ([]models.StudentModel, error) {
+	var students []models.StudentModel
+	err := c.do(ctx, http.MethodGet, "/students/", nil, nil, &students)
+	return students, err
+}
+
+// Students iterates over every student, fetching them a page at a time. The
+// iteration stops at the first error, which is yielded with a zero student.
+func (c *Client) Students(ctx context.Context) iter.Seq2[models.StudentModel, error] {
+	return paginate(c.pageSize, func(limit, offset int) ([]models.StudentModel, error) {
+		query := url.Values{"limit": {strconv.Itoa(limit)}, "offset": {strconv.Itoa(offset)}}
+		var students []models.StudentModel
+		err := c.do(ctx, http.MethodGet, "/students/", query, nil, &students)
+		return students, err
+	})
+}
+
+func (c *Client) GetStudent(ctx context.Context, rollNo string) (models.StudentModel, error) {
+	var student models.StudentModel
+	err := c.do(ctx, http.MethodGet, "/students/"+url.PathEscape(rollNo), nil, nil, &student)
+	return student, err
+}
+
+func (c *Client) CreateStudent(ctx context.Context, student models.StudentModel) (models.StudentModel, error) {
+	var created models.StudentModel
+	err := c.do(ctx, http.MethodPost, "/students/", nil, student, &created)
+	return created, err
+}
+
+func (c *Client) UpdateStudent(ctx context.Context, rollNo string, student models.StudentModel) (models.StudentModel, error) {
+	var updated models.StudentModel
+	err := c.do(ctx, http.MethodPut, "/students/"+url.PathEscape(rollNo), nil, student, &updated)
+	return updated, err
+}
+
+// PatchStudent applies p to the student and returns the patched student. A patch.JSONPatch
+// is sent as a JSON Patch, any other value, e.g. map[string]any{"name": "Ann"}, as a
+// merge patch.
+func (c *Client) PatchStudent(ctx context.Context, rollNo string, p any) (models.StudentModel, error) {
+	var patched models.StudentModel
+	err := c.do(ctx, http.MethodPatch, "/students/"+url.PathEscape(rollNo), nil, patchBody(p), &patched)
+	return patched, err
+}
+
+func (c *Client) DeleteStudent(ctx context.Context, rollNo string) error {
+	return c.do(ctx, http.MethodDelete, "/students/"+url.PathEscape(rollNo), nil, nil, nil)
+}
+
+// RestoreStudent undoes the deletion of a student that was not purged yet
+func (c *Client) RestoreStudent(ctx context.Context, rollNo string) (models.StudentModel, error) {
+	var restored models.StudentModel
+	err := c.do(ctx, http.MethodPost, "/students/"+url.PathEscape(rollNo)+"/restore", nil, nil, &restored)
+	return restored, err
+}
+
+// paginate yields the items of consecutive pages until a page is not full
+func paginate[T any](pageSize int, fetch func(limit, offset int) ([]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for offset := 0; ; offset += pageSize {
+			items, err := fetch(pageSize, offset)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(items) < pageSize {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	// Local Packages
+	config "learn-go/config"
+	errors "learn-go/errors"
+	models "learn-go/models"
+	jobs "learn-go/services/jobs"
+	orders "learn-go/services/orders"
+	students "learn-go/services/students"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// Background job types
+const (
+	jobImportStudents     = "students.import"
+	jobPurgeStudents      = "students.purge"
+	jobRebuildOrdersCache = "orders.rebuild_cache"
+)
+
+// importStudentsParams are the params of a students.import job
+type importStudentsParams struct {
+	DryRun   bool                  `json:"dry_run"`
+	Students []models.StudentModel `json:"students"`
+}
+
+// RegisterJobs registers the job types available with the configured backends
+func RegisterJobs(k config.Config, jobsSvc *jobs.JobsService, studentsSvc *students.StudentsService,
+	ordersSvc *orders.OrdersService) {
+	jobsSvc.Register(jobImportStudents, importStudentsJob(studentsSvc), validateImportStudents)
+	jobsSvc.Register(jobPurgeStudents, purgeStudentsJob(studentsSvc, k.Students.DeletedRetention), nil)
+	if k.PersistsOrders() {
+		jobsSvc.Register(jobRebuildOrdersCache, rebuildOrdersCacheJob(ordersSvc), nil)
+	}
+}
+
+func decodeImportStudents(params json.RawMessage) (importStudentsParams, error) {
+	var p importStudentsParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return p, errors.InvalidParamsErr(err)
+	}
+	return p, nil
+}
+
+func validateImportStudents(params json.RawMessage) error {
+	p, err := decodeImportStudents(params)
+	if err != nil {
+		return err
+	}
+	if len(p.Students) == 0 {
+		ve := errors.ValidationErrs()
+		ve.Add("/params/students", "cannot be empty")
+		return errors.ValidationFailedErr(ve.Err())
+	}
+	return nil
+}
+
+// importStudentsJob imports the students of the params like POST /students/import,
+// the report is the result of the job
+func importStudentsJob(studentsSvc *students.StudentsService) jobs.Handler {
+	return func(ctx context.Context, params json.RawMessage, progress jobs.ProgressFunc) (any, error) {
+		p, err := decodeImportStudents(params)
+		if err != nil {
+			return nil, err
+		}
+
+		total := int64(len(p.Students))
+		records := func(yield func(models.ImportRecord, error) bool) {
+			for i, student := range p.Students {
+				if err := ctx.Err(); err != nil {
+					yield(models.ImportRecord{}, err)
+					return
+				}
+				progress(int64(i), total)
+				if !yield(models.ImportRecord{Line: i + 1, Student: student}, nil) {
+					return
+				}
+			}
+		}
+
+		report, err := studentsSvc.ImportStudents(ctx, records, p.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		progress(total, total)
+		return report, nil
+	}
+}
+
+// purgeStudentsJob removes the students deleted more than retention ago for good
+func purgeStudentsJob(studentsSvc *students.StudentsService, retention time.Duration) jobs.Handler {
+	return func(ctx context.Context, params json.RawMessage, progress jobs.ProgressFunc) (any, error) {
+		purged, err := studentsSvc.PurgeStudents(ctx, retention)
+		if err != nil {
+			return nil, err
+		}
+		progress(int64(purged), int64(purged))
+		return map[string]int{"purged": purged}, nil
+	}
+}
+
+// SchedulePurge queues a students.purge job every interval until the context is done.
+// Every replica queues its own, purging again finds nothing left to remove.
+func SchedulePurge(jobsSvc *jobs.JobsService, interval time.Duration, logger *zap.Logger) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				job, err := jobsSvc.Enqueue(ctx, models.JobRequest{Type: jobPurgeStudents})
+				if err != nil {
+					logger.Error("failed to queue students purge", zap.Error(err))
+					continue
+				}
+				logger.Info("queued students purge", zap.String("jobId", job.ID))
+			}
+		}
+	}
+}
+
+// rebuildOrdersCacheJob repopulates the orders cache like the rebuild-orders command
+func rebuildOrdersCacheJob(ordersSvc *orders.OrdersService) jobs.Handler {
+	return func(ctx context.Context, params json.RawMessage, progress jobs.ProgressFunc) (any, error) {
+		restored, err := ordersSvc.RebuildCache(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild orders cache due to :: %w", err)
+		}
+		progress(int64(restored), int64(restored))
+		return map[string]int{"restored": restored}, nil
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	// Go Internal Packages
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	// Local Packages
+	models "learn-go/models"
+	jobs "learn-go/services/jobs"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// queuedJobs is a JobsRepository that only keeps the queued jobs
+type queuedJobs struct {
+	jobs.JobsRepository
+	mu     sync.Mutex
+	queued []models.Job
+}
+
+func (q *queuedJobs) Enqueue(_ context.Context, job models.Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queued = append(q.queued, job)
+	return nil
+}
+
+func (q *queuedJobs) count() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+func TestSchedulePurge(t *testing.T) {
+	repo := &queuedJobs{}
+	jobsSvc := jobs.NewService(repo, jobs.Options{MaxAttempts: 1}, zap.NewNop())
+	jobsSvc.Register(jobPurgeStudents, purgeStudentsJob(nil, time.Hour), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SchedulePurge(jobsSvc, 10*time.Millisecond, zap.NewNop())(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.count() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if repo.count() < 2 {
+		t.Fatalf("SchedulePurge() queued %d jobs, want one every interval", repo.count())
+	}
+	for _, job := range repo.queued {
+		if job.Type != jobPurgeStudents || job.Status != models.JobQueued {
+			t.Fatalf("queued %+v, want a queued %s job", job, jobPurgeStudents)
+		}
+	}
+}
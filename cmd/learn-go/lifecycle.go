@@ -0,0 +1,154 @@
+package main
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"time"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// Readiness is flipped to failing first on shutdown, see health.HealthCheckerService
+type Readiness interface {
+	SetReady(ready bool)
+}
+
+// Lifecycle runs the listeners and the background workers of the server and stops them
+// in phases once the context is done or a listener fails:
+//
+//  1. readiness is flipped to failing so that load balancers stop routing here,
+//  2. the pre-stop delay gives them the time to notice, requests are still served,
+//  3. the listeners drain their in-flight requests,
+//  4. the workers are stopped one by one, the last registered first,
+//  5. the datastore clients are closed in the order they were registered.
+//
+// Every phase is logged with its duration.
+type Lifecycle struct {
+	closeTimeout time.Duration
+	logger       *zap.Logger
+	preStopDelay time.Duration
+	readiness    Readiness
+
+	closers   []closer
+	listeners []listener
+	workers   []worker
+}
+
+type listener struct {
+	name   string
+	listen func(ctx context.Context) error
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+func NewLifecycle(readiness Readiness, preStopDelay, closeTimeout time.Duration, logger *zap.Logger) *Lifecycle {
+	return &Lifecycle{
+		closeTimeout: closeTimeout,
+		logger:       logger,
+		preStopDelay: preStopDelay,
+		readiness:    readiness,
+	}
+}
+
+// Listen registers a listener, listen serves until its context is done and then drains
+func (l *Lifecycle) Listen(name string, listen func(ctx context.Context) error) {
+	l.listeners = append(l.listeners, listener{name: name, listen: listen})
+}
+
+// Work registers a background worker, run returns once it stopped after its context is done.
+// Workers that produce the work of others are registered after them so they stop first.
+func (l *Lifecycle) Work(name string, run func(ctx context.Context)) {
+	l.workers = append(l.workers, worker{name: name, run: run})
+}
+
+// OnClose registers the close of a client, the clients are closed once every worker stopped
+func (l *Lifecycle) OnClose(name string, close func(ctx context.Context) error) {
+	l.closers = append(l.closers, closer{name: name, close: close})
+}
+
+// Run starts the workers and the listeners and blocks until everything stopped. It returns
+// the error of the listener that failed, if any.
+func (l *Lifecycle) Run(ctx context.Context) error {
+	workCtxs := make([]context.CancelFunc, len(l.workers))
+	workDone := make([]chan struct{}, len(l.workers))
+	for i, w := range l.workers {
+		workCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		workCtxs[i], workDone[i] = cancel, make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			w.run(workCtx)
+		}(workDone[i])
+	}
+
+	listenCtx, stopListeners := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopListeners()
+	errs := make(chan error, len(l.listeners))
+	for _, lis := range l.listeners {
+		go func() {
+			if err := lis.listen(listenCtx); err != nil {
+				errs <- fmt.Errorf("%s: %w", lis.name, err)
+				return
+			}
+			errs <- nil
+		}()
+	}
+
+	// A listener returning before the shutdown failed to serve
+	running := len(l.listeners)
+	var err error
+	select {
+	case <-ctx.Done():
+		l.logger.Info("Shutting down")
+	case err = <-errs:
+		running--
+		l.logger.Error("Shutting down after a listener failed", zap.Error(err))
+	}
+	start := time.Now()
+
+	l.phase("readiness", func() { l.readiness.SetReady(false) })
+	if err == nil && l.preStopDelay > 0 {
+		l.phase("pre-stop delay", func() { time.Sleep(l.preStopDelay) })
+	}
+	l.phase("drain listeners", func() {
+		stopListeners()
+		for ; running > 0; running-- {
+			if listenErr := <-errs; listenErr != nil {
+				l.logger.Error("listener failed to drain", zap.Error(listenErr))
+			}
+		}
+	})
+	for i := len(l.workers) - 1; i >= 0; i-- {
+		l.phase("stop "+l.workers[i].name, func() {
+			workCtxs[i]()
+			<-workDone[i]
+		})
+	}
+	for _, c := range l.closers {
+		l.phase("close "+c.name, func() {
+			closeCtx, cancel := context.WithTimeout(context.Background(), l.closeTimeout)
+			defer cancel()
+			if closeErr := c.close(closeCtx); closeErr != nil {
+				l.logger.Error("failed to close", zap.String("client", c.name), zap.Error(closeErr))
+			}
+		})
+	}
+
+	l.logger.Info("Shut down", zap.Duration("duration", time.Since(start)))
+	return err
+}
+
+func (l *Lifecycle) phase(name string, fn func()) {
+	start := time.Now()
+	fn()
+	l.logger.Info("Shutdown phase done", zap.String("phase", name), zap.Duration("duration", time.Since(start)))
+}
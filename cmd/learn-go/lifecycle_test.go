@@ -0,0 +1,161 @@
+package main
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// steps records what the parts of a lifecycle did, in order
+type steps struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (s *steps) add(step string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.steps = append(s.steps, step)
+}
+
+func (s *steps) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.steps...)
+}
+
+func (s *steps) SetReady(ready bool) {
+	s.add(fmt.Sprintf("ready %v", ready))
+}
+
+// listen serves until its context is done, then drains
+func (s *steps) listen(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		s.add(name + " drained")
+		return nil
+	}
+}
+
+// work runs until its context is done
+func (s *steps) work(name string) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		<-ctx.Done()
+		s.add(name + " stopped")
+	}
+}
+
+func (s *steps) close(name string, err error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			s.add(name + " closed without a timeout")
+		}
+		s.add(name + " closed")
+		return err
+	}
+}
+
+func TestLifecycleShutdownOrder(t *testing.T) {
+	const preStopDelay = 50 * time.Millisecond
+	s := &steps{}
+	l := NewLifecycle(s, preStopDelay, time.Second, zap.NewNop())
+	l.OnClose("mongo", s.close("mongo", nil))
+	l.OnClose("redis", s.close("redis", fmt.Errorf("already closed")))
+	// The relay feeds the bus, it is registered after it to stop first
+	l.Work("events bus", s.work("events bus"))
+	l.Work("outbox relay", s.work("outbox relay"))
+
+	var drainedAfter time.Duration
+	var shutdownAt time.Time
+	l.Listen("http", func(ctx context.Context) error {
+		<-ctx.Done()
+		drainedAfter = time.Since(shutdownAt)
+		s.add("http drained")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Run(ctx) }()
+	shutdownAt = time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run() did not return after the shutdown, steps %v", s.get())
+	}
+	want := []string{
+		"ready false",
+		"http drained",
+		"outbox relay stopped",
+		"events bus stopped",
+		"mongo closed",
+		"redis closed",
+	}
+	if got := s.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps = %v, want %v", got, want)
+	}
+	// Requests are served through the pre-stop delay
+	if drainedAfter < preStopDelay {
+		t.Fatalf("listeners drained %v after the shutdown, want after the %v pre-stop delay", drainedAfter, preStopDelay)
+	}
+}
+
+func TestLifecycleShutsDownWhenAListenerFails(t *testing.T) {
+	s := &steps{}
+	// A failed listener skips the pre-stop delay, which would block the test
+	l := NewLifecycle(s, time.Hour, time.Second, zap.NewNop())
+	l.OnClose("sql", s.close("sql", nil))
+	l.Work("job workers", s.work("job workers"))
+	l.Listen("http", s.listen("http"))
+	l.Listen("grpc", func(ctx context.Context) error { return fmt.Errorf("address in use") })
+
+	done := make(chan error)
+	go func() { done <- l.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "grpc: address in use" {
+			t.Fatalf("Run() error = %v, want the failure of grpc", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run() did not return after a listener failed, steps %v", s.get())
+	}
+	want := []string{"ready false", "http drained", "job workers stopped", "sql closed"}
+	if got := s.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps = %v, want %v", got, want)
+	}
+}
+
+func TestLifecycleWaitsForEveryWorker(t *testing.T) {
+	s := &steps{}
+	l := NewLifecycle(s, 0, time.Second, zap.NewNop())
+	l.OnClose("redis", s.close("redis", nil))
+	l.Work("job workers", func(ctx context.Context) {
+		<-ctx.Done()
+		// Drains the running jobs before it returns
+		time.Sleep(20 * time.Millisecond)
+		s.add("job workers stopped")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	want := []string{"ready false", "job workers stopped", "redis closed"}
+	if got := s.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps = %v, want %v", got, want)
+	}
+}
@@ -3,80 +3,381 @@ package main
 import (
 	// Go Internal Packages
 	"context"
+	"database/sql"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	// Local Packages
+	audit "learn-go/audit"
 	config "learn-go/config"
+	errors "learn-go/errors"
+	events "learn-go/events"
+	xgraphql "learn-go/graphql"
+	xgrpc "learn-go/grpc"
 	xhttp "learn-go/http"
 	handlers "learn-go/http/handlers"
+	smiddlewares "learn-go/http/middlewares"
 	mongodb "learn-go/repositories/mongodb"
 	redis "learn-go/repositories/redis"
+	sqldb "learn-go/repositories/sqldb"
 	health "learn-go/services/health"
+	jobs "learn-go/services/jobs"
 	orders "learn-go/services/orders"
 	students "learn-go/services/students"
+	webhooks "learn-go/services/webhooks"
 
 	// External Packages
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-chi/cors"
 	_ "github.com/jsternberg/zap-logfmt"
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/rawbytes"
+	goredis "github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 )
 
-// InitializeServer sets up an HTTP server with defined handlers. Repositories are initialized,
-//
-//	create the services, and subsequently construct handlers for the services
-func InitializeServer(ctx context.Context, k config.Config, logger *zap.Logger) (*xhttp.Server, error) {
+// Connections holds the datastore clients, a client is nil when no entity uses its store
+type Connections struct {
+	Mongo *mongo.Client
+	Redis *goredis.Client
+	SQL   *sql.DB
+}
+
+// Connect opens the connections required by the configured storage backends
+func Connect(ctx context.Context, k config.Config) (*Connections, error) {
+	conns := &Connections{}
+	var err error
+
 	// Mongo Connection
-	mongoClient, err := mongodb.Connect(ctx, k.Mongo.URI)
-	if err != nil {
-		return nil, err
+	if k.UsesMongo() {
+		conns.Mongo, err = mongodb.Connect(ctx, k.Mongo.URI)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Redis Connection
-	redisClient, err := redis.Connect(ctx, k.Redis.URI, k.Redis.Password)
+	if k.UsesRedis() {
+		conns.Redis, err = redis.Connect(ctx, k.Redis.URI, k.Redis.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// SQL Connection && Migrations
+	if k.UsesSQL() {
+		conns.SQL, err = sqldb.Connect(ctx, k.SQL.Driver, k.SQL.DSN)
+		if err != nil {
+			return nil, err
+		}
+		if err = sqldb.Migrate(ctx, conns.SQL); err != nil {
+			return nil, err
+		}
+	}
+
+	return conns, nil
+}
+
+// NewOrdersService builds the orders service for the configured backend and persistence mode
+func NewOrdersService(
+	k config.Config,
+	conns *Connections,
+	emitter *events.Emitter,
+	recorder *audit.Recorder,
+	logger *zap.Logger,
+) *orders.OrdersService {
+	if k.Storage.Orders == config.StorageSQL {
+		return orders.NewService(sqldb.NewOrdersRepository(conns.SQL), emitter, recorder)
+	}
+
+	ordersRepo := redis.NewOrdersRepository(conns.Redis)
+	if !k.PersistsOrders() {
+		return orders.NewService(ordersRepo, emitter, recorder)
+	}
+	return orders.NewDurableService(ordersRepo, mongodb.NewOrdersRepository(conns.Mongo),
+		orders.Persistence(k.Orders.Persistence), k.Orders.WriteBehindQueueSize, emitter, recorder, logger)
+}
+
+// NewRelay builds the outbox relay over every outbox the configured backends write to
+func NewRelay(k config.Config, conns *Connections, publisher events.Publisher, logger *zap.Logger) *events.Relay {
+	var outboxes []events.Outbox
+	if k.Storage.Orders == config.StorageRedis {
+		outboxes = append(outboxes, redis.NewOutbox(conns.Redis, logger))
+	}
+	if conns.Mongo != nil {
+		outboxes = append(outboxes, mongodb.NewOutbox(conns.Mongo))
+	}
+	if conns.SQL != nil {
+		outboxes = append(outboxes, sqldb.NewOutbox(conns.SQL))
+	}
+
+	return events.NewRelay(publisher, k.Events.RelayInterval, k.Events.RelayBatchSize, logger, outboxes...)
+}
+
+// InitializeServer sets up an HTTP server with defined handlers. Repositories are initialized,
+//
+//	create the services, and subsequently construct handlers for the services.
+//	The listeners, the background workers and the datastore clients are registered
+//	with the returned lifecycle, which runs them and shuts them down in order.
+func InitializeServer(
+	ctx context.Context,
+	k config.Config,
+	logger *zap.Logger,
+) (*Lifecycle, error) {
+	conns, err := Connect(ctx, k)
 	if err != nil {
 		return nil, err
 	}
 
+	healthSvc := health.NewService(logger, conns.Mongo, conns.Redis, conns.SQL)
+	lifecycle := NewLifecycle(healthSvc, k.Shutdown.PreStopDelay, k.Shutdown.CloseTimeout, logger)
+	// The clients are closed in this order once every worker stopped
+	if conns.Mongo != nil {
+		lifecycle.OnClose("mongo", conns.Mongo.Disconnect)
+	}
+	if conns.Redis != nil {
+		lifecycle.OnClose("redis", func(context.Context) error { return conns.Redis.Close() })
+	}
+	if conns.SQL != nil {
+		lifecycle.OnClose("sql", func(context.Context) error { return conns.SQL.Close() })
+	}
+
 	// Init repos, services && handlers
-	studentsRepo := mongodb.NewStudentsRepository(mongoClient)
-	ordersRepo := redis.NewOrdersRepository(redisClient)
+	var studentsRepo students.StudentsRepository
+	switch k.Storage.Students {
+	case config.StorageSQL:
+		studentsRepo = sqldb.NewStudentsRepository(conns.SQL)
+	default:
+		mongoStudents := mongodb.NewStudentsRepository(conns.Mongo)
+		if err := mongoStudents.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		studentsRepo = mongoStudents
+	}
+
+	var studentsCache xhttp.CacheStatsProvider
+	if k.Cache.Students.Enabled {
+		cacheRepo := redis.NewStudentsCacheRepository(conns.Redis, studentsRepo,
+			k.Cache.Students.TTL, k.Cache.Students.NegativeTTL, logger)
+		studentsRepo, studentsCache = cacheRepo, cacheRepo
+	}
 
-	healthSvc := health.NewService(logger, mongoClient, redisClient)
-	studentsSvc := students.NewService(studentsRepo)
-	ordersSvc := orders.NewService(ordersRepo)
+	eventHandlers := []events.EventHandler{events.NewLogHandler(logger)}
+	var webhooksHandler *handlers.WebhooksHandler
+	if k.Webhooks.Enabled {
+		webhooksRepo := mongodb.NewWebhooksRepository(conns.Mongo)
+		if err := webhooksRepo.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		webhooksHandler = handlers.NewWebhooksHandler(webhooks.NewService(webhooksRepo, k.Webhooks.AllowPrivateNetworks))
+		deliverer := webhooks.NewDeliverer(webhooksRepo,
+			webhooks.NewHTTPClient(k.Webhooks.Timeout, k.Webhooks.AllowPrivateNetworks),
+			k.Webhooks.MaxAttempts, k.Webhooks.RetryBackoff, logger)
+		eventHandlers = append(eventHandlers, deliverer)
+		lifecycle.Work("webhook retries", deliverer.Run)
+	}
+
+	var orderChangesHandler *handlers.OrderChangesHandler
+	if k.Orders.Changes.Enabled {
+		changesRepo := redis.NewOrderChangesRepository(conns.Redis, k.Orders.Changes.History, logger)
+		feed := orders.NewChangeFeed(changesRepo, logger)
+		orderChangesHandler = handlers.NewOrderChangesHandler(feed, k.Orders.Changes.Heartbeat, handlers.LiveOptions{
+			MaxSubscriptions: k.Orders.Changes.Live.MaxSubscriptions,
+			PingInterval:     k.Orders.Changes.Live.PingInterval,
+			OriginPatterns:   k.Orders.Changes.Live.OriginPatterns,
+		})
+		eventHandlers = append(eventHandlers, feed)
+		lifecycle.Work("order changes feed", feed.Run)
+	}
+
+	// The audit entries travel through the outbox and the bus to the recorder
+	var recorder *audit.Recorder
+	var auditHandler *handlers.AuditHandler
+	if k.Audit.Enabled {
+		auditRepo := mongodb.NewAuditRepository(conns.Mongo)
+		if err := auditRepo.EnsureIndexes(ctx); err != nil {
+			return nil, err
+		}
+		recorder = audit.NewRecorder(auditRepo, logger)
+		auditHandler = handlers.NewAuditHandler(recorder)
+		eventHandlers = append(eventHandlers, recorder)
+	}
 
-	studentsHandler := handlers.NewStudentsHandler(studentsSvc)
+	emitter := events.NewEmitter(k.Events.Enabled)
+	if k.Events.Enabled {
+		var publisher events.Publisher = events.NewLogPublisher(logger)
+		if k.UsesEventBus() {
+			bus := redis.NewEventBus(conns.Redis, redis.EventBusOptions{
+				Stream:        k.Events.Bus.Stream,
+				MaxLen:        k.Events.Bus.MaxLen,
+				MaxDeliveries: k.Events.Bus.MaxDeliveries,
+				RetryBackoff:  k.Events.Bus.RetryBackoff,
+			}, logger)
+			for _, handler := range eventHandlers {
+				if err := bus.Subscribe(ctx, handler); err != nil {
+					return nil, err
+				}
+			}
+			publisher = bus
+			lifecycle.Work("events bus", bus.Run)
+		}
+		lifecycle.Work("outbox relay", NewRelay(k, conns, publisher, logger).Run)
+	}
+
+	studentsSvc := students.NewService(studentsRepo, emitter, recorder)
+	ordersSvc := NewOrdersService(k, conns, emitter, recorder, logger)
+	lifecycle.Work("orders write-behind", ordersSvc.Run)
+
+	studentsHandler := handlers.NewStudentsHandler(studentsSvc, k.Students.IncludeDeletedActors)
 	ordersHandler := handlers.NewOrdersHandler(ordersSvc)
 
-	server := xhttp.NewServer(k.Prefix, logger, studentsHandler, ordersHandler, healthSvc)
-	return server, nil
+	var graphqlHandler http.Handler
+	if k.GraphQL.Enabled {
+		graphqlHandler, err = xgraphql.NewHandler(studentsSvc, ordersSvc,
+			xgraphql.Limits{MaxDepth: k.GraphQL.MaxDepth, MaxComplexity: k.GraphQL.MaxComplexity}, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var jobsSvc *jobs.JobsService
+	var jobsHandler *handlers.JobsHandler
+	if k.Jobs.Enabled {
+		jobsSvc = jobs.NewService(redis.NewJobsRepository(conns.Redis), jobs.Options{
+			Workers:      k.Jobs.Workers,
+			MaxAttempts:  k.Jobs.MaxAttempts,
+			RetryBackoff: k.Jobs.RetryBackoff,
+			DrainTimeout: k.Jobs.DrainTimeout,
+			ResultTTL:    k.Jobs.ResultTTL,
+		}, logger)
+		RegisterJobs(k, jobsSvc, studentsSvc, ordersSvc)
+		jobsHandler = handlers.NewJobsHandler(jobsSvc)
+		// Registered last to stop first, jobs write through every other worker
+		lifecycle.Work("job workers", jobsSvc.Run)
+		if k.Students.PurgeInterval > 0 {
+			lifecycle.Work("students purge schedule", SchedulePurge(jobsSvc, k.Students.PurgeInterval, logger))
+		}
+	}
+
+	server := xhttp.NewServer(k.Prefix, logger, studentsHandler, ordersHandler, healthSvc, studentsCache,
+		webhooksHandler, orderChangesHandler, graphqlHandler, jobsHandler, auditHandler, ServerOptions(k))
+	lifecycle.Listen("http", func(ctx context.Context) error { return server.Listen(ctx, k.Listen) })
+
+	if k.GRPC.Enabled {
+		grpcServer := xgrpc.NewServer(logger, studentsSvc, ordersSvc, k.HTTP.ShutdownTimeout,
+			k.Audit.ActorHeader, k.Audit.TrustedClients)
+		lifecycle.Listen("grpc", func(ctx context.Context) error { return grpcServer.Listen(ctx, k.GRPC.Listen) })
+	}
+	return lifecycle, nil
 }
 
+// ServerOptions maps the http and audit config to the options of the HTTP server
+func ServerOptions(k config.Config) xhttp.Options {
+	c := k.HTTP
+	options := xhttp.Options{
+		BodyLimit:           c.BodyLimit,
+		BodyLimits:          c.BodyLimits,
+		Compress:            c.Compression.Enabled,
+		CompressMinSize:     c.Compression.MinSize,
+		CompressEncodings:   c.Compression.Encodings,
+		ReadHeaderTimeout:   c.ReadHeaderTimeout,
+		ReadTimeout:         c.ReadTimeout,
+		WriteTimeout:        c.WriteTimeout,
+		IdleTimeout:         c.IdleTimeout,
+		ShutdownTimeout:     c.ShutdownTimeout,
+		HandlerTimeout:      c.HandlerTimeout,
+		HandlerTimeouts:     c.HandlerTimeouts,
+		StreamingRoutes:     c.StreamingRoutes,
+		H2C:                 c.H2C,
+		AuditActorHeader:    k.Audit.ActorHeader,
+		AuditTrustedClients: k.Audit.TrustedClients,
+	}
+	if c.TLS.Enabled {
+		options.TLS = &xhttp.TLSOptions{
+			CertFile:          c.TLS.CertFile,
+			KeyFile:           c.TLS.KeyFile,
+			ClientCAFile:      c.TLS.ClientCAFile,
+			RequireClientCert: c.TLS.ClientAuth == "require",
+			ReloadInterval:    c.TLS.ReloadInterval,
+		}
+	}
+	if c.CORS.Enabled {
+		options.CORS = &cors.Options{
+			AllowedOrigins:   c.CORS.AllowedOrigins,
+			AllowedMethods:   c.CORS.AllowedMethods,
+			AllowedHeaders:   c.CORS.AllowedHeaders,
+			ExposedHeaders:   c.CORS.ExposedHeaders,
+			AllowCredentials: c.CORS.AllowCredentials,
+			MaxAge:           int(c.CORS.MaxAge / time.Second),
+		}
+	}
+	if c.SecurityHeaders.Enabled {
+		options.SecurityHeaders = &smiddlewares.SecurityOptions{
+			HSTSMaxAge:            c.SecurityHeaders.HSTSMaxAge,
+			HSTSIncludeSubdomains: c.SecurityHeaders.HSTSIncludeSubdomains,
+			HSTSPreload:           c.SecurityHeaders.HSTSPreload,
+			TrustForwardedProto:   c.SecurityHeaders.TrustForwardedProto,
+			FrameOptions:          c.SecurityHeaders.FrameOptions,
+			ReferrerPolicy:        c.SecurityHeaders.ReferrerPolicy,
+			ContentSecurityPolicy: c.SecurityHeaders.ContentSecurityPolicy,
+		}
+	}
+	return options
+}
+
+// RebuildOrders repopulates the redis orders cache from the mongo system of record
+func RebuildOrders(ctx context.Context, k config.Config, logger *zap.Logger) error {
+	if !k.PersistsOrders() {
+		return errors.NewError("orders.persistence must be enabled to rebuild the orders cache")
+	}
+
+	conns, err := Connect(ctx, k)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	restored, err := NewOrdersService(k, conns, nil, nil, logger).RebuildCache(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Info("Rebuilt orders cache", zap.Int("orders", restored), zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// Commands supported by the binary, serve is the default
+const (
+	serveCmd         = "serve"
+	rebuildOrdersCmd = "rebuild-orders"
+)
+
 // LoadConfig loads the default configuration and overrides it with the config file
-// specified by the path defined in the config flag
-func LoadConfig() *koanf.Koanf {
+// specified by the path defined in the config flag. It also returns the selected command.
+func LoadConfig() (*koanf.Koanf, string) {
 	configPathMsg := "path to the application config file"
 	configPath := kingpin.Flag("config", configPathMsg).Short('c').Default("config.yml").String()
+	kingpin.Command(serveCmd, "start the http server").Default()
+	kingpin.Command(rebuildOrdersCmd, "repopulate the redis orders cache from mongo")
 
-	kingpin.Parse()
+	command := kingpin.Parse()
 	k := koanf.New(".")
 	_ = k.Load(rawbytes.Provider(config.DefaultConfig), yaml.Parser())
 	if *configPath != "" {
 		_ = k.Load(file.Provider(*configPath), yaml.Parser())
 	}
 
-	return k
+	return k, command
 }
 
 func main() {
-	k := LoadConfig()
+	k, command := LoadConfig()
 
 	// Unmarshalling config into struct
 	appKonf := config.Config{}
@@ -109,12 +410,20 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	srv, err := InitializeServer(ctx, appKonf, logger)
+	if command == rebuildOrdersCmd {
+		if err = RebuildOrders(ctx, appKonf, logger); err != nil {
+			logger.Fatal("cannot rebuild orders cache", zap.Error(err))
+		}
+		return
+	}
+
+	lifecycle, err := InitializeServer(ctx, appKonf, logger)
 	if err != nil {
 		logger.Fatal("cannot initialize server", zap.Error(err))
 	}
 
-	if err = srv.Listen(ctx, appKonf.Listen); err != nil {
+	// When a listener fails the rest is shut down gracefully before the process exits
+	if err = lifecycle.Run(ctx); err != nil {
 		logger.Fatal("cannot listen", zap.Error(err))
 	}
 }
@@ -1,6 +1,10 @@
 package config
 
 import (
+	// Go Internal Packages
+	"strings"
+	"time"
+
 	// Local Packages
 	"learn-go/errors"
 )
@@ -15,30 +19,290 @@ listen: ":8888"
 
 prefix: "/learn-go"
 
+# request bodies above body_limit bytes are answered with a 413, body_limits overrides
+# the limit of single routes by "METHOD /pattern" under /v1
+http:
+  body_limit: 1048576
+  body_limits:
+    "POST /students/import": 67108864
+  # responses of at least min_size bytes are compressed with the first of encodings
+  # the client accepts
+  compression:
+    enabled: true
+    min_size: 1024
+    encodings: ["zstd", "br", "gzip"]
+  # server timeouts, zero disables one
+  read_header_timeout: "5s"
+  read_timeout: "30s"
+  write_timeout: "60s"
+  idle_timeout: "120s"
+  shutdown_timeout: "5s"
+  # handlers run with handler_timeout on the request context, handler_timeouts overrides
+  # it by "METHOD /pattern" and zero disables it
+  handler_timeout: "30s"
+  handler_timeouts: {}
+  # routes streaming their bodies run without the read, write and handler timeouts
+  streaming_routes:
+    - "POST /students/import"
+    - "GET /students/export"
+    - "GET /orders/export"
+    - "GET /orders/stream"
+    - "GET /orders/live"
+    - "GET /orders/{orderId}/stream"
+  # certificates are reloaded when the files change, client_ca_file enables mTLS with
+  # client_auth: require | verify_if_given
+  tls:
+    enabled: false
+    cert_file: ""
+    key_file: ""
+    client_ca_file: ""
+    client_auth: "require"
+    reload_interval: "1m"
+  # HTTP/2 without TLS, for traffic behind a proxy or inside the cluster
+  h2c: false
+  # cross origin requests of browser apps, an origin may hold one wildcard, e.g.
+  # https://*.example.com, and preflight responses are cached for max_age
+  cors:
+    enabled: false
+    allowed_origins: []
+    allowed_methods: ["GET", "POST", "PUT", "PATCH", "DELETE"]
+    allowed_headers: ["Accept", "Authorization", "Content-Type", "If-Match", "Last-Event-ID", "X-Request-Id"]
+    exposed_headers: ["Content-Disposition", "Location", "X-Request-Id"]
+    allow_credentials: false
+    max_age: "10m"
+  # headers hardening every response, hsts is only sent over HTTPS and zero hsts_max_age
+  # disables it, the docs UI gets a content security policy of its own. Behind a TLS
+  # terminating proxy that sets X-Forwarded-Proto, trust_forwarded_proto sends hsts to
+  # the requests it forwarded over HTTPS, only enable it when clients cannot reach the
+  # server but through the proxy.
+  security_headers:
+    enabled: true
+    hsts_max_age: "0s"
+    hsts_include_subdomains: false
+    hsts_preload: false
+    trust_forwarded_proto: false
+    frame_options: "DENY"
+    referrer_policy: "no-referrer"
+    content_security_policy: "default-src 'none'; frame-ancestors 'none'"
+
+# graphql endpoint over students and orders, queries deeper or costlier than the limits are rejected
+graphql:
+  enabled: false
+  max_depth: 6
+  max_complexity: 2000
+
+# second listener serving the students and orders services over gRPC
+grpc:
+  enabled: false
+  listen: ":9090"
+
 is_prod_mode: false
 
+# on SIGTERM the health endpoint fails first, pre_stop_delay later the listeners drain
+# within http.shutdown_timeout, the workers stop and the datastore clients are closed
+# within close_timeout each
+shutdown:
+  pre_stop_delay: "5s"
+  close_timeout: "5s"
+
 mongo:
   uri: "mongodb://localhost:27017"
 
 redis:
   uri: "localhost:6379"
   password: ""
+
+# backend per entity, students: mongo | sql, orders: redis | sql
+storage:
+  students: "mongo"
+  orders: "redis"
+
+# deleted students can be restored for deleted_retention, then the students.purge job
+# removes them for good. With jobs enabled it is queued every purge_interval, 0 leaves it
+# to POST /jobs {"type": "students.purge"}. Only the include_deleted_actors (see audit
+# for how the actor is known) may read deleted students with ?include_deleted=true.
+students:
+  deleted_retention: "720h"
+  purge_interval: "24h"
+  include_deleted_actors: []
+
+# persistence of redis orders to the mongo orders collection: none | write_through | write_behind
+orders:
+  persistence: "none"
+  write_behind_queue_size: 1024
+  # server-sent events of order changes, requires the events bus
+  changes:
+    enabled: false
+    history: 10000
+    heartbeat: "15s"
+    # websocket endpoint, origin_patterns lists the cross-origin hosts allowed to connect
+    live:
+      max_subscriptions: 50
+      ping_interval: "30s"
+      origin_patterns: []
+
+# cache-aside layer in redis for student reads, works with any students backend
+cache:
+  students:
+    enabled: false
+    ttl: "5m"
+    negative_ttl: "30s"
+
+# domain events are written to an outbox atomically with every change and
+# relayed to the publisher, students in mongo then require a replica set
+events:
+  enabled: false
+  relay_interval: "1s"
+  relay_batch_size: 100
+  # redis streams bus the relay publishes to, consumed in-process through consumer groups
+  bus:
+    enabled: false
+    stream: "EVENTS"
+    max_len: 100000
+    max_deliveries: 5
+    retry_backoff: "1s"
+
+# outgoing webhooks for domain events, requires the events bus and mongo. A failed
+# delivery is retried in the background, retry_backoff doubles after every attempt
+webhooks:
+  enabled: false
+  timeout: "10s"
+  max_attempts: 5
+  retry_backoff: "1s"
+  # lets subscriptions target loopback, link-local and private addresses, for local development only
+  allow_private_networks: false
+
+# append-only log in mongo of every change to students and orders, requires the events
+# bus: the entries are written to the outbox with the change they record. The actor of a
+# change is the common name of the verified client certificate (see http.tls). The
+# actor_header is only taken from the clients whose certificate common name is one of
+# trusted_clients, e.g. the proxy in front of the server; without mTLS every change is
+# anonymous, as any client could set the header. gRPC has no TLS, its calls are anonymous.
+audit:
+  enabled: false
+  actor_header: "X-Actor"
+  trusted_clients: []
+
+# background jobs queued in redis and run by a pool of workers on every replica, on shutdown
+# the running jobs get drain_timeout to finish before they are handed back to the queue
+jobs:
+  enabled: false
+  workers: 4
+  max_attempts: 3
+  retry_backoff: "5s"
+  drain_timeout: "30s"
+  result_ttl: "168h"
+
+# driver: sqlite | postgres
+sql:
+  driver: "sqlite"
+  dsn: "file:learn-go.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
 `)
 
+// Supported storage backends
+const (
+	StorageMongo = "mongo"
+	StorageRedis = "redis"
+	StorageSQL   = "sql"
+)
+
 type Config struct {
-	Application string `koanf:"application"`
-	Logger      Logger `koanf:"logger"`
-	Listen      string `koanf:"listen"`
-	Prefix      string `koanf:"prefix"`
-	IsProdMode  bool   `koanf:"is_prod_mode"`
-	Mongo       Mongo  `koanf:"mongo"`
-	Redis       Redis  `koanf:"redis"`
+	Application string   `koanf:"application"`
+	Logger      Logger   `koanf:"logger"`
+	Listen      string   `koanf:"listen"`
+	Prefix      string   `koanf:"prefix"`
+	HTTP        HTTP     `koanf:"http"`
+	GraphQL     GraphQL  `koanf:"graphql"`
+	GRPC        GRPC     `koanf:"grpc"`
+	IsProdMode  bool     `koanf:"is_prod_mode"`
+	Shutdown    Shutdown `koanf:"shutdown"`
+	Mongo       Mongo    `koanf:"mongo"`
+	Redis       Redis    `koanf:"redis"`
+	Storage     Storage  `koanf:"storage"`
+	SQL         SQL      `koanf:"sql"`
+	Cache       Cache    `koanf:"cache"`
+	Students    Students `koanf:"students"`
+	Orders      Orders   `koanf:"orders"`
+	Events      Events   `koanf:"events"`
+	Webhooks    Webhooks `koanf:"webhooks"`
+	Audit       Audit    `koanf:"audit"`
+	Jobs        Jobs     `koanf:"jobs"`
 }
 
 type Logger struct {
 	Level string `koanf:"level"`
 }
 
+type HTTP struct {
+	BodyLimit         int64                    `koanf:"body_limit"`
+	BodyLimits        map[string]int64         `koanf:"body_limits"`
+	Compression       Compression              `koanf:"compression"`
+	ReadHeaderTimeout time.Duration            `koanf:"read_header_timeout"`
+	ReadTimeout       time.Duration            `koanf:"read_timeout"`
+	WriteTimeout      time.Duration            `koanf:"write_timeout"`
+	IdleTimeout       time.Duration            `koanf:"idle_timeout"`
+	ShutdownTimeout   time.Duration            `koanf:"shutdown_timeout"`
+	HandlerTimeout    time.Duration            `koanf:"handler_timeout"`
+	HandlerTimeouts   map[string]time.Duration `koanf:"handler_timeouts"`
+	StreamingRoutes   []string                 `koanf:"streaming_routes"`
+	TLS               TLS                      `koanf:"tls"`
+	H2C               bool                     `koanf:"h2c"`
+	CORS              CORS                     `koanf:"cors"`
+	SecurityHeaders   SecurityHeaders          `koanf:"security_headers"`
+}
+
+type TLS struct {
+	Enabled        bool          `koanf:"enabled"`
+	CertFile       string        `koanf:"cert_file"`
+	KeyFile        string        `koanf:"key_file"`
+	ClientCAFile   string        `koanf:"client_ca_file"`
+	ClientAuth     string        `koanf:"client_auth"`
+	ReloadInterval time.Duration `koanf:"reload_interval"`
+}
+
+type CORS struct {
+	Enabled          bool          `koanf:"enabled"`
+	AllowedOrigins   []string      `koanf:"allowed_origins"`
+	AllowedMethods   []string      `koanf:"allowed_methods"`
+	AllowedHeaders   []string      `koanf:"allowed_headers"`
+	ExposedHeaders   []string      `koanf:"exposed_headers"`
+	AllowCredentials bool          `koanf:"allow_credentials"`
+	MaxAge           time.Duration `koanf:"max_age"`
+}
+
+type SecurityHeaders struct {
+	Enabled               bool          `koanf:"enabled"`
+	HSTSMaxAge            time.Duration `koanf:"hsts_max_age"`
+	HSTSIncludeSubdomains bool          `koanf:"hsts_include_subdomains"`
+	HSTSPreload           bool          `koanf:"hsts_preload"`
+	TrustForwardedProto   bool          `koanf:"trust_forwarded_proto"`
+	FrameOptions          string        `koanf:"frame_options"`
+	ReferrerPolicy        string        `koanf:"referrer_policy"`
+	ContentSecurityPolicy string        `koanf:"content_security_policy"`
+}
+
+type Compression struct {
+	Enabled   bool     `koanf:"enabled"`
+	MinSize   int      `koanf:"min_size"`
+	Encodings []string `koanf:"encodings"`
+}
+
+type GraphQL struct {
+	Enabled       bool `koanf:"enabled"`
+	MaxDepth      int  `koanf:"max_depth"`
+	MaxComplexity int  `koanf:"max_complexity"`
+}
+
+type GRPC struct {
+	Enabled bool   `koanf:"enabled"`
+	Listen  string `koanf:"listen"`
+}
+
+type Shutdown struct {
+	PreStopDelay time.Duration `koanf:"pre_stop_delay"`
+	CloseTimeout time.Duration `koanf:"close_timeout"`
+}
+
 type Mongo struct {
 	URI string `koanf:"uri"`
 }
@@ -48,6 +312,122 @@ type Redis struct {
 	Password string `koanf:"password"`
 }
 
+type Storage struct {
+	Students string `koanf:"students"`
+	Orders   string `koanf:"orders"`
+}
+
+type Students struct {
+	DeletedRetention     time.Duration `koanf:"deleted_retention"`
+	PurgeInterval        time.Duration `koanf:"purge_interval"`
+	IncludeDeletedActors []string      `koanf:"include_deleted_actors"`
+}
+
+type SQL struct {
+	Driver string `koanf:"driver"`
+	DSN    string `koanf:"dsn"`
+}
+
+type Orders struct {
+	Persistence          string       `koanf:"persistence"`
+	WriteBehindQueueSize int          `koanf:"write_behind_queue_size"`
+	Changes              OrderChanges `koanf:"changes"`
+}
+
+type OrderChanges struct {
+	Enabled   bool          `koanf:"enabled"`
+	History   int64         `koanf:"history"`
+	Heartbeat time.Duration `koanf:"heartbeat"`
+	Live      LiveOrders    `koanf:"live"`
+}
+
+type LiveOrders struct {
+	MaxSubscriptions int           `koanf:"max_subscriptions"`
+	PingInterval     time.Duration `koanf:"ping_interval"`
+	OriginPatterns   []string      `koanf:"origin_patterns"`
+}
+
+// PersistsOrders reports whether redis orders are persisted to mongo
+func (c *Config) PersistsOrders() bool {
+	return c.Storage.Orders == StorageRedis && c.Orders.Persistence != "none"
+}
+
+type Events struct {
+	Enabled        bool          `koanf:"enabled"`
+	RelayInterval  time.Duration `koanf:"relay_interval"`
+	RelayBatchSize int           `koanf:"relay_batch_size"`
+	Bus            EventBus      `koanf:"bus"`
+}
+
+type EventBus struct {
+	Enabled       bool          `koanf:"enabled"`
+	Stream        string        `koanf:"stream"`
+	MaxLen        int64         `koanf:"max_len"`
+	MaxDeliveries int64         `koanf:"max_deliveries"`
+	RetryBackoff  time.Duration `koanf:"retry_backoff"`
+}
+
+// UsesEventBus reports whether events are published to the redis streams bus
+func (c *Config) UsesEventBus() bool {
+	return c.Events.Enabled && c.Events.Bus.Enabled
+}
+
+type Webhooks struct {
+	Enabled      bool          `koanf:"enabled"`
+	Timeout      time.Duration `koanf:"timeout"`
+	MaxAttempts  int           `koanf:"max_attempts"`
+	RetryBackoff time.Duration `koanf:"retry_backoff"`
+	// AllowPrivateNetworks turns off the check that webhooks only reach public addresses
+	AllowPrivateNetworks bool `koanf:"allow_private_networks"`
+}
+
+type Audit struct {
+	Enabled     bool   `koanf:"enabled"`
+	ActorHeader string `koanf:"actor_header"`
+	// TrustedClients are the common names of the client certificates whose actor_header is taken
+	TrustedClients []string `koanf:"trusted_clients"`
+}
+
+type Jobs struct {
+	Enabled      bool          `koanf:"enabled"`
+	Workers      int           `koanf:"workers"`
+	MaxAttempts  int           `koanf:"max_attempts"`
+	RetryBackoff time.Duration `koanf:"retry_backoff"`
+	DrainTimeout time.Duration `koanf:"drain_timeout"`
+	ResultTTL    time.Duration `koanf:"result_ttl"`
+}
+
+type Cache struct {
+	Students StudentsCache `koanf:"students"`
+}
+
+type StudentsCache struct {
+	Enabled     bool          `koanf:"enabled"`
+	TTL         time.Duration `koanf:"ttl"`
+	NegativeTTL time.Duration `koanf:"negative_ttl"`
+}
+
+// UsesMongo reports whether any entity or feature is backed by MongoDB
+func (c *Config) UsesMongo() bool {
+	return c.Storage.Students == StorageMongo || c.PersistsOrders() || c.Webhooks.Enabled || c.Audit.Enabled
+}
+
+// UsesRedis reports whether any entity is stored or cached in Redis, or jobs are queued there
+func (c *Config) UsesRedis() bool {
+	return c.Storage.Orders == StorageRedis || c.Cache.Students.Enabled || c.UsesEventBus() || c.Jobs.Enabled
+}
+
+// UsesSQL reports whether any entity is stored in the SQL database
+func (c *Config) UsesSQL() bool {
+	return c.Storage.Students == StorageSQL || c.Storage.Orders == StorageSQL
+}
+
+// isRoute reports whether the key of a per route setting is a method and a pattern
+func isRoute(route string) bool {
+	method, pattern, ok := strings.Cut(route, " ")
+	return ok && method != "" && strings.HasPrefix(pattern, "/")
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	ve := errors.ValidationErrs()
@@ -58,15 +438,243 @@ func (c *Config) Validate() error {
 	if c.Listen == "" {
 		ve.Add("listen", "cannot be empty")
 	}
+	if c.HTTP.BodyLimit <= 0 {
+		ve.Add("http.body_limit", "must be greater than zero")
+	}
+	for route, limit := range c.HTTP.BodyLimits {
+		if !isRoute(route) {
+			ve.Add("http.body_limits", route+" must be a method and a pattern, e.g. POST /students/import")
+		}
+		if limit <= 0 {
+			ve.Add("http.body_limits", route+" must be greater than zero")
+		}
+	}
+	for name, timeout := range map[string]time.Duration{
+		"http.read_header_timeout": c.HTTP.ReadHeaderTimeout,
+		"http.read_timeout":        c.HTTP.ReadTimeout,
+		"http.write_timeout":       c.HTTP.WriteTimeout,
+		"http.idle_timeout":        c.HTTP.IdleTimeout,
+		"http.handler_timeout":     c.HTTP.HandlerTimeout,
+	} {
+		if timeout < 0 {
+			ve.Add(name, "cannot be negative")
+		}
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		ve.Add("http.shutdown_timeout", "must be greater than zero")
+	}
+	for route, timeout := range c.HTTP.HandlerTimeouts {
+		if !isRoute(route) {
+			ve.Add("http.handler_timeouts", route+" must be a method and a pattern, e.g. GET /students/")
+		}
+		if timeout < 0 {
+			ve.Add("http.handler_timeouts", route+" cannot be negative")
+		}
+	}
+	for _, route := range c.HTTP.StreamingRoutes {
+		if !isRoute(route) {
+			ve.Add("http.streaming_routes", route+" must be a method and a pattern, e.g. GET /orders/stream")
+		}
+	}
+	if c.HTTP.TLS.Enabled {
+		if c.HTTP.TLS.CertFile == "" {
+			ve.Add("http.tls.cert_file", "cannot be empty")
+		}
+		if c.HTTP.TLS.KeyFile == "" {
+			ve.Add("http.tls.key_file", "cannot be empty")
+		}
+		if c.HTTP.TLS.ClientAuth != "require" && c.HTTP.TLS.ClientAuth != "verify_if_given" {
+			ve.Add("http.tls.client_auth", "must be one of require, verify_if_given")
+		}
+		if c.HTTP.TLS.ReloadInterval <= 0 {
+			ve.Add("http.tls.reload_interval", "must be greater than zero")
+		}
+		if c.HTTP.H2C {
+			ve.Add("http.h2c", "cannot be enabled with http.tls, HTTP/2 is negotiated over TLS")
+		}
+	}
+	if c.HTTP.Compression.Enabled {
+		if c.HTTP.Compression.MinSize < 0 {
+			ve.Add("http.compression.min_size", "cannot be negative")
+		}
+		if len(c.HTTP.Compression.Encodings) == 0 {
+			ve.Add("http.compression.encodings", "cannot be empty")
+		}
+		for _, encoding := range c.HTTP.Compression.Encodings {
+			if encoding != "zstd" && encoding != "br" && encoding != "gzip" {
+				ve.Add("http.compression.encodings", "must be some of zstd, br, gzip")
+				break
+			}
+		}
+	}
+	if c.HTTP.CORS.Enabled {
+		if len(c.HTTP.CORS.AllowedOrigins) == 0 {
+			ve.Add("http.cors.allowed_origins", "cannot be empty")
+		}
+		for _, origin := range c.HTTP.CORS.AllowedOrigins {
+			if strings.Count(origin, "*") > 1 {
+				ve.Add("http.cors.allowed_origins", origin+" can hold a single wildcard")
+			}
+			if origin == "*" && c.HTTP.CORS.AllowCredentials {
+				ve.Add("http.cors.allowed_origins", "cannot allow every origin with http.cors.allow_credentials")
+			}
+		}
+		if len(c.HTTP.CORS.AllowedMethods) == 0 {
+			ve.Add("http.cors.allowed_methods", "cannot be empty")
+		}
+		if c.HTTP.CORS.MaxAge < 0 {
+			ve.Add("http.cors.max_age", "cannot be negative")
+		}
+	}
+	if c.HTTP.SecurityHeaders.Enabled {
+		if c.HTTP.SecurityHeaders.HSTSMaxAge < 0 {
+			ve.Add("http.security_headers.hsts_max_age", "cannot be negative")
+		}
+		switch c.HTTP.SecurityHeaders.FrameOptions {
+		case "", "DENY", "SAMEORIGIN":
+		default:
+			ve.Add("http.security_headers.frame_options", "must be one of DENY, SAMEORIGIN or empty")
+		}
+	}
+	if c.GraphQL.Enabled && c.GraphQL.MaxDepth <= 0 {
+		ve.Add("graphql.max_depth", "must be greater than zero")
+	}
+	if c.GraphQL.Enabled && c.GraphQL.MaxComplexity <= 0 {
+		ve.Add("graphql.max_complexity", "must be greater than zero")
+	}
+	if c.GRPC.Enabled && c.GRPC.Listen == "" {
+		ve.Add("grpc.listen", "cannot be empty")
+	}
+	if c.Shutdown.PreStopDelay < 0 {
+		ve.Add("shutdown.pre_stop_delay", "cannot be negative")
+	}
+	if c.Shutdown.CloseTimeout <= 0 {
+		ve.Add("shutdown.close_timeout", "must be greater than zero")
+	}
 	if c.Logger.Level == "" {
 		ve.Add("logger.level", "cannot be empty")
 	}
-	if c.Mongo.URI == "" {
+	if c.Storage.Students != StorageMongo && c.Storage.Students != StorageSQL {
+		ve.Add("storage.students", "must be one of mongo, sql")
+	}
+	if c.Storage.Orders != StorageRedis && c.Storage.Orders != StorageSQL {
+		ve.Add("storage.orders", "must be one of redis, sql")
+	}
+	if c.Students.DeletedRetention <= 0 {
+		ve.Add("students.deleted_retention", "must be greater than zero")
+	}
+	if c.Students.PurgeInterval < 0 {
+		ve.Add("students.purge_interval", "cannot be negative")
+	}
+	switch c.Orders.Persistence {
+	case "none", "write_through":
+	case "write_behind":
+		if c.Orders.WriteBehindQueueSize <= 0 {
+			ve.Add("orders.write_behind_queue_size", "must be greater than zero")
+		}
+	default:
+		ve.Add("orders.persistence", "must be one of none, write_through, write_behind")
+	}
+	if c.Orders.Persistence != "none" && c.Storage.Orders != StorageRedis {
+		ve.Add("orders.persistence", "requires storage.orders to be redis")
+	}
+	if c.Webhooks.Enabled {
+		if !c.UsesEventBus() {
+			ve.Add("webhooks.enabled", "requires events.enabled and events.bus.enabled")
+		}
+		if c.Webhooks.Timeout <= 0 {
+			ve.Add("webhooks.timeout", "must be greater than zero")
+		}
+		if c.Webhooks.MaxAttempts <= 0 {
+			ve.Add("webhooks.max_attempts", "must be greater than zero")
+		}
+		if c.Webhooks.RetryBackoff <= 0 {
+			ve.Add("webhooks.retry_backoff", "must be greater than zero")
+		}
+	}
+	if c.Audit.Enabled && !c.UsesEventBus() {
+		ve.Add("audit.enabled", "requires events.enabled and events.bus.enabled")
+	}
+	if c.Orders.Changes.Enabled {
+		if !c.UsesEventBus() {
+			ve.Add("orders.changes.enabled", "requires events.enabled and events.bus.enabled")
+		}
+		if c.Orders.Changes.History <= 0 {
+			ve.Add("orders.changes.history", "must be greater than zero")
+		}
+		if c.Orders.Changes.Heartbeat <= 0 {
+			ve.Add("orders.changes.heartbeat", "must be greater than zero")
+		}
+		if c.Orders.Changes.Live.MaxSubscriptions <= 0 {
+			ve.Add("orders.changes.live.max_subscriptions", "must be greater than zero")
+		}
+		if c.Orders.Changes.Live.PingInterval <= 0 {
+			ve.Add("orders.changes.live.ping_interval", "must be greater than zero")
+		}
+	}
+	if c.Jobs.Enabled {
+		if c.Jobs.Workers <= 0 {
+			ve.Add("jobs.workers", "must be greater than zero")
+		}
+		if c.Jobs.MaxAttempts <= 0 {
+			ve.Add("jobs.max_attempts", "must be greater than zero")
+		}
+		if c.Jobs.RetryBackoff <= 0 {
+			ve.Add("jobs.retry_backoff", "must be greater than zero")
+		}
+		if c.Jobs.DrainTimeout <= 0 {
+			ve.Add("jobs.drain_timeout", "must be greater than zero")
+		}
+		if c.Jobs.ResultTTL <= 0 {
+			ve.Add("jobs.result_ttl", "must be greater than zero")
+		}
+	}
+	if c.UsesMongo() && c.Mongo.URI == "" {
 		ve.Add("mongo.uri", "cannot be empty")
 	}
-	if c.Redis.URI == "" {
+	if c.UsesRedis() && c.Redis.URI == "" {
 		ve.Add("redis.uri", "cannot be empty")
 	}
+	if c.UsesSQL() {
+		if c.SQL.Driver != "sqlite" && c.SQL.Driver != "postgres" {
+			ve.Add("sql.driver", "must be one of sqlite, postgres")
+		}
+		if c.SQL.DSN == "" {
+			ve.Add("sql.dsn", "cannot be empty")
+		}
+	}
+
+	if c.Cache.Students.Enabled {
+		if c.Cache.Students.TTL <= 0 {
+			ve.Add("cache.students.ttl", "must be greater than zero")
+		}
+		if c.Cache.Students.NegativeTTL <= 0 {
+			ve.Add("cache.students.negative_ttl", "must be greater than zero")
+		}
+	}
+
+	if c.Events.Enabled {
+		if c.Events.RelayInterval <= 0 {
+			ve.Add("events.relay_interval", "must be greater than zero")
+		}
+		if c.Events.RelayBatchSize <= 0 {
+			ve.Add("events.relay_batch_size", "must be greater than zero")
+		}
+	}
+	if c.UsesEventBus() {
+		if c.Events.Bus.Stream == "" {
+			ve.Add("events.bus.stream", "cannot be empty")
+		}
+		if c.Events.Bus.MaxLen <= 0 {
+			ve.Add("events.bus.max_len", "must be greater than zero")
+		}
+		if c.Events.Bus.MaxDeliveries <= 0 {
+			ve.Add("events.bus.max_deliveries", "must be greater than zero")
+		}
+		if c.Events.Bus.RetryBackoff <= 0 {
+			ve.Add("events.bus.retry_backoff", "must be greater than zero")
+		}
+	}
 
 	return ve.Err()
 }
@@ -1,13 +1,29 @@
 package errors
 
+import (
+	// Go Internal Packages
+	"fmt"
+	"net/http"
+)
+
 func InvalidParamsErr(err error) error {
 	return E(Invalid, "invalid params", err)
 }
 
+// InvalidBodyErr reports a body that cannot be read or decoded, a body cut off by
+// http.MaxBytesReader is reported as TooLarge
 func InvalidBodyErr(err error) error {
+	var tooLarge *http.MaxBytesError
+	if As(err, &tooLarge) {
+		return BodyTooLargeErr(tooLarge.Limit)
+	}
 	return E(Invalid, "invalid request body", err)
 }
 
+func BodyTooLargeErr(limit int64) error {
+	return E(TooLarge, fmt.Sprintf("request body exceeds the limit of %d bytes", limit))
+}
+
 func ValidationFailedErr(err error) error {
 	return E(Invalid, "validation failed", err)
 }
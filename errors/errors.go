@@ -48,6 +48,8 @@ const (
 	NotFound                 // Entity does not exist
 	Unauthorized             // Unauthorized access
 	Forbidden                // Forbidden access
+	Unsupported              // Unsupported media type of a request body
+	TooLarge                 // Request body above its size limit
 )
 
 func (k Kind) String() string {
@@ -56,10 +58,16 @@ func (k Kind) String() string {
 		return "unclassified error"
 	case Internal:
 		return "internal error"
+	case Conflict:
+		return "conflict"
 	case Invalid:
 		return "invalid input"
 	case NotFound:
 		return "entity not found"
+	case Unsupported:
+		return "unsupported media type"
+	case TooLarge:
+		return "request entity too large"
 	default:
 		return "unknown error kind"
 	}
@@ -86,6 +94,12 @@ func E(args ...interface{}) error {
 	return e
 }
 
+// IsKind reports whether err wraps an *Error of the given kind
+func IsKind(err error, kind Kind) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == kind
+}
+
 var (
 	As = errors.As
 	Is = errors.Is
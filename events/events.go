@@ -0,0 +1,114 @@
+package events
+
+import (
+	// Go Internal Packages
+	"encoding/json"
+	"fmt"
+	"time"
+
+	// Local Packages
+	utils "learn-go/utils"
+)
+
+// Type names a domain event
+type Type string
+
+const (
+	OrderCreated       Type = "order.created"
+	OrderUpdated       Type = "order.updated"
+	OrderStatusChanged Type = "order.status_changed"
+	OrderDeleted       Type = "order.deleted"
+	StudentEnrolled    Type = "student.enrolled"
+	StudentUpdated     Type = "student.updated"
+	StudentDeleted     Type = "student.deleted"
+	StudentRestored    Type = "student.restored"
+
+	// AuditRecorded carries a models.AuditEntry, it is written with the change it
+	// records. It is internal to the audit log and not one of Types.
+	AuditRecorded Type = "audit.recorded"
+)
+
+// Event is a fact about a change to an order or a student. It is written to an
+// outbox together with the change and published afterwards by the Relay.
+type Event struct {
+	ID          string          `json:"id" bson:"_id"`
+	Type        Type            `json:"type" bson:"type"`
+	AggregateID string          `json:"aggregate_id" bson:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at" bson:"occurred_at"`
+	Payload     json.RawMessage `json:"payload" bson:"payload"`
+
+	// Position of the event in the outbox it was read from, only set by Outbox.Pending
+	Position string `json:"-" bson:"-"`
+}
+
+// StatusChange is the payload of OrderStatusChanged
+type StatusChange struct {
+	OrderID string `json:"order_id"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// Deletion is the payload of StudentDeleted
+type Deletion struct {
+	ID string `json:"id"`
+}
+
+// New creates an event with a fresh id for the given aggregate
+func New(eventType Type, aggregateID string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to encode %s payload: %w", eventType, err)
+	}
+	return Event{
+		ID:          utils.GenerateRandomID(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		OccurredAt:  time.Now().UTC(),
+		Payload:     data,
+	}, nil
+}
+
+// Emitter creates the events the services hand to their repositories. A disabled
+// Emitter returns no events, so the repositories skip the outbox write entirely.
+type Emitter struct {
+	enabled bool
+}
+
+func NewEmitter(enabled bool) *Emitter {
+	return &Emitter{enabled: enabled}
+}
+
+// Enabled reports whether Emit creates events
+func (e *Emitter) Enabled() bool {
+	return e != nil && e.enabled
+}
+
+// Emit returns the event for the change as a slice ready to be passed to a repository
+func (e *Emitter) Emit(eventType Type, aggregateID string, payload any) ([]Event, error) {
+	if !e.Enabled() {
+		return nil, nil
+	}
+	evt, err := New(eventType, aggregateID, payload)
+	if err != nil {
+		return nil, err
+	}
+	return []Event{evt}, nil
+}
+
+// Types returns every event type the services emit
+func Types() []Type {
+	return []Type{
+		OrderCreated, OrderUpdated, OrderStatusChanged, OrderDeleted,
+		StudentEnrolled, StudentUpdated, StudentDeleted, StudentRestored,
+	}
+}
+
+// IsValid reports whether t is an event type the services emit
+func (t Type) IsValid() bool {
+	for _, known := range Types() {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package events
+
+import (
+	// Go Internal Packages
+	"context"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// EventHandler processes the events delivered by the event bus. Every handler gets
+// its own consumer group named after it, so each handler sees every event once and
+// handlers fail and retry independently. Returning an error makes the bus redeliver
+// the event, handlers must therefore be idempotent.
+type EventHandler interface {
+	Name() string
+	Handle(ctx context.Context, evt Event) error
+}
+
+// LogHandler logs every event it receives at debug level
+type LogHandler struct {
+	logger *zap.Logger
+}
+
+func NewLogHandler(logger *zap.Logger) *LogHandler {
+	return &LogHandler{logger: logger}
+}
+
+func (h *LogHandler) Name() string {
+	return "log"
+}
+
+func (h *LogHandler) Handle(_ context.Context, evt Event) error {
+	h.logger.Debug("Event received", zap.String("id", evt.ID), zap.String("type", string(evt.Type)),
+		zap.String("aggregateId", evt.AggregateID))
+	return nil
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	// Go Internal Packages
+	"context"
+	"time"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// Outbox is the table, collection or stream events are written to atomically with the data change
+type Outbox interface {
+	// Name identifies the outbox in logs
+	Name() string
+	// Pending returns up to limit unpublished events in the order they were written
+	Pending(ctx context.Context, limit int) ([]Event, error)
+	// MarkPublished records that the given events, as returned by Pending, were published
+	MarkPublished(ctx context.Context, evts []Event) error
+}
+
+// Publisher delivers an event to its consumers
+type Publisher interface {
+	Publish(ctx context.Context, evt Event) error
+}
+
+// LogPublisher only logs the events, it is used when no event bus is configured
+type LogPublisher struct {
+	logger *zap.Logger
+}
+
+func NewLogPublisher(logger *zap.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, evt Event) error {
+	p.logger.Info("Event published", zap.String("id", evt.ID), zap.String("type", string(evt.Type)),
+		zap.String("aggregateId", evt.AggregateID))
+	return nil
+}
+
+// Relay moves events from the outboxes to the publisher with at-least-once delivery:
+// an event is only marked published after Publish succeeded, so a crash in between
+// publishes it again on the next run.
+type Relay struct {
+	batchSize int
+	interval  time.Duration
+	logger    *zap.Logger
+	outboxes  []Outbox
+	publisher Publisher
+}
+
+func NewRelay(publisher Publisher, interval time.Duration, batchSize int, logger *zap.Logger, outboxes ...Outbox) *Relay {
+	return &Relay{
+		batchSize: batchSize,
+		interval:  interval,
+		logger:    logger,
+		outboxes:  outboxes,
+		publisher: publisher,
+	}
+}
+
+// Run polls the outboxes every interval until ctx is cancelled. An outbox that
+// returned a full batch is polled again right away.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, outbox := range r.outboxes {
+			for n := r.batchSize; n == r.batchSize; {
+				n = r.relay(ctx, outbox)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay publishes one batch of the outbox and returns how many events were published
+func (r *Relay) relay(ctx context.Context, outbox Outbox) int {
+	logger := r.logger.With(zap.String("outbox", outbox.Name()))
+
+	pending, err := outbox.Pending(ctx, r.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("failed to read outbox", zap.Error(err))
+		}
+		return 0
+	}
+
+	published := make([]Event, 0, len(pending))
+	for _, evt := range pending {
+		if err := r.publisher.Publish(ctx, evt); err != nil {
+			// Stop at the first failure to keep the order, the rest is retried next time
+			logger.Error("failed to publish event", zap.String("id", evt.ID), zap.Error(err))
+			break
+		}
+		published = append(published, evt)
+	}
+	if len(published) == 0 {
+		return 0
+	}
+
+	if err := outbox.MarkPublished(ctx, published); err != nil {
+		logger.Error("failed to record outbox progress", zap.Error(err))
+		return 0
+	}
+	return len(published)
+}
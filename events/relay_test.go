@@ -0,0 +1,154 @@
+package events
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// memOutbox is an Outbox over a list of events, the published ones are dropped from it
+type memOutbox struct {
+	mu      sync.Mutex
+	pending []Event
+	markErr error
+	reads   int
+}
+
+func newMemOutbox(ids ...string) *memOutbox {
+	o := &memOutbox{}
+	for _, id := range ids {
+		o.pending = append(o.pending, Event{ID: id})
+	}
+	return o
+}
+
+func (o *memOutbox) Name() string {
+	return "memory"
+}
+
+func (o *memOutbox) Pending(_ context.Context, limit int) ([]Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.reads++
+	return append([]Event{}, o.pending[:min(limit, len(o.pending))]...), nil
+}
+
+func (o *memOutbox) MarkPublished(_ context.Context, evts []Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.markErr != nil {
+		return o.markErr
+	}
+	o.pending = o.pending[len(evts):]
+	return nil
+}
+
+func (o *memOutbox) left() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return eventIDs(o.pending)
+}
+
+// memPublisher records the published events and fails the one with the id failOn
+type memPublisher struct {
+	mu        sync.Mutex
+	failOn    string
+	published []Event
+}
+
+func (p *memPublisher) Publish(_ context.Context, evt Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if evt.ID == p.failOn {
+		return fmt.Errorf("bus unavailable")
+	}
+	p.published = append(p.published, evt)
+	return nil
+}
+
+func (p *memPublisher) ids() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return eventIDs(p.published)
+}
+
+func eventIDs(evts []Event) []string {
+	ids := []string{}
+	for _, evt := range evts {
+		ids = append(ids, evt.ID)
+	}
+	return ids
+}
+
+func TestRelayBatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		failOn        string
+		markErr       error
+		want          int
+		wantPublished []string
+		wantLeft      []string
+	}{
+		{name: "every event", want: 3, wantPublished: []string{"e1", "e2", "e3"}, wantLeft: []string{}},
+		{name: "stops at the first failure", failOn: "e2", want: 1, wantPublished: []string{"e1"},
+			wantLeft: []string{"e2", "e3"}},
+		{name: "failing first", failOn: "e1", want: 0, wantPublished: []string{}, wantLeft: []string{"e1", "e2", "e3"}},
+		// The events are published again by the next batch
+		{name: "progress not recorded", markErr: fmt.Errorf("outbox unavailable"), want: 0,
+			wantPublished: []string{"e1", "e2", "e3"}, wantLeft: []string{"e1", "e2", "e3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := newMemOutbox("e1", "e2", "e3")
+			outbox.markErr = tt.markErr
+			publisher := &memPublisher{failOn: tt.failOn}
+			r := NewRelay(publisher, time.Hour, 10, zap.NewNop(), outbox)
+
+			if got := r.relay(context.Background(), outbox); got != tt.want {
+				t.Fatalf("relay() = %d, want %d", got, tt.want)
+			}
+			if got := publisher.ids(); !reflect.DeepEqual(got, tt.wantPublished) {
+				t.Fatalf("published %v, want %v", got, tt.wantPublished)
+			}
+			if got := outbox.left(); !reflect.DeepEqual(got, tt.wantLeft) {
+				t.Fatalf("outbox holds %v, want %v", got, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestRelayDrainsFullBatches(t *testing.T) {
+	outbox := newMemOutbox("e1", "e2", "e3", "e4", "e5")
+	publisher := &memPublisher{}
+	// The interval is too long for a second poll, the outbox is drained by the first
+	r := NewRelay(publisher, time.Hour, 2, zap.NewNop(), outbox)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(outbox.left()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got, want := publisher.ids(), []string{"e1", "e2", "e3", "e4", "e5"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("published %v, want %v", got, want)
+	}
+	// Two full batches and the last one
+	if outbox.reads != 3 {
+		t.Fatalf("the outbox was read %d times, want 3", outbox.reads)
+	}
+}
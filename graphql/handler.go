@@ -0,0 +1,174 @@
+package graphql
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"net/http"
+
+	// Local Packages
+	errors "learn-go/errors"
+	resp "learn-go/http/response"
+	models "learn-go/models"
+
+	// External Packages
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.uber.org/zap"
+)
+
+type StudentsService interface {
+	GetOneStudent(context.Context, string, bool) (*models.StudentModel, error)
+	GetAllStudents(context.Context, bool) (*[]models.StudentModel, error)
+	InsertStudent(context.Context, models.StudentModel) error
+	UpdateStudent(context.Context, string, models.StudentModel) error
+	DeleteStudent(context.Context, string) error
+}
+
+type OrdersService interface {
+	Insert(ctx context.Context, order models.Order) (string, error)
+	GetOne(ctx context.Context, orderID string) (models.Order, error)
+	List(ctx context.Context, userID string, fn func(order models.Order) error) error
+	Update(ctx context.Context, order models.Order) error
+	Delete(ctx context.Context, orderID string) error
+}
+
+// Handler serves GraphQL queries and mutations over the students and orders services
+type Handler struct {
+	limits   Limits
+	logger   *zap.Logger
+	orders   OrdersService
+	schema   graphql.Schema
+	students StudentsService
+}
+
+func NewHandler(
+	studentsSvc StudentsService,
+	ordersSvc OrdersService,
+	limits Limits,
+	logger *zap.Logger,
+) (*Handler, error) {
+	h := &Handler{students: studentsSvc, orders: ordersSvc, limits: limits, logger: logger}
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// request is the body of a GraphQL POST, GET requests carry the same fields as query
+// params. Extensions, e.g. persisted query hashes, are accepted and ignored.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+// ServeHTTP executes the request, errors of the query itself are reported in the errors
+// of the result with a 200 as GraphQL clients expect
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				resp.RespondMessage(w, http.StatusBadRequest, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := resp.JSON.Decode(r.Body, &req); err != nil {
+			resp.RespondError(w, errors.InvalidBodyErr(err).(*errors.Error))
+			return
+		}
+	default:
+		resp.RespondMessage(w, http.StatusMethodNotAllowed, "only GET and POST are supported")
+		return
+	}
+
+	// Mutations change state and are only accepted over POST
+	result := h.execute(r.Context(), req, r.Method == http.MethodPost)
+	resp.RespondJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) execute(ctx context.Context, req request, allowMutations bool) *graphql.Result {
+	src := source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})
+	doc, err := parser.Parse(parser.ParseParams{Source: src})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	validation := graphql.ValidateDocument(&h.schema, doc, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+	if err := h.limits.check(doc, req.Variables); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if !allowMutations && hasMutation(doc) {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(errors.NewError("mutations must be sent with POST"))}
+	}
+
+	ctx = context.WithValue(ctx, loadersKey{}, h.newLoaders())
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
+
+// gqlError is returned by the resolvers, the kind and validation errors of the
+// application error are reported in the extensions of the GraphQL error
+type gqlError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+func (e *gqlError) Error() string {
+	return e.message
+}
+
+func (e *gqlError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+// toGraphQLError converts a service error, the codes follow the HTTP status classes
+// of response.RespondError
+func (h *Handler) toGraphQLError(err error) error {
+	e, ok := err.(*errors.Error)
+	if !ok {
+		h.logger.Error("internal error", zap.Error(err))
+		return &gqlError{message: "internal error", extensions: map[string]interface{}{"code": "INTERNAL"}}
+	}
+
+	gerr := &gqlError{message: e.Message, extensions: map[string]interface{}{}}
+	switch e.Kind {
+	case errors.NotFound:
+		gerr.extensions["code"] = "NOT_FOUND"
+	case errors.Invalid:
+		gerr.extensions["code"] = "INVALID"
+		var ve errors.ValidationErrors
+		if errors.As(e, &ve) {
+			gerr.extensions["validation_errors"] = ve
+		} else if e.WrappedErr != nil {
+			gerr.message = e.WrappedErr.Error()
+		}
+	case errors.Conflict:
+		gerr.extensions["code"] = "CONFLICT"
+	case errors.Unauthorized:
+		gerr.extensions["code"] = "UNAUTHORIZED"
+	case errors.Forbidden:
+		gerr.extensions["code"] = "FORBIDDEN"
+	default:
+		gerr.extensions["code"] = "INTERNAL"
+	}
+	return gerr
+}
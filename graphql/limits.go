@@ -0,0 +1,129 @@
+package graphql
+
+import (
+	// Go Internal Packages
+	"fmt"
+	"strconv"
+
+	// External Packages
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound the cost of a query before it is executed. The depth is the deepest
+// nesting of fields, the complexity counts every selected field once per element of
+// the lists above it, lists are sized by their limit argument clamped to [0, maxLimit]
+// or defaultLimit.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// measure walks the operations of the document, inlining fragments. The document
+// must be validated first, validation rejects fragment cycles.
+type measure struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+func (l Limits) check(doc *ast.Document, variables map[string]interface{}) error {
+	m := measure{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			m.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if depth := m.depth(op.SelectionSet); depth > l.MaxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, l.MaxDepth)
+		}
+		if complexity := m.complexity(op.SelectionSet); complexity > l.MaxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", complexity, l.MaxComplexity)
+		}
+	}
+	return nil
+}
+
+func (m measure) depth(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+
+	deepest := 0
+	for _, sel := range set.Selections {
+		var depth int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			depth = 1 + m.depth(sel.SelectionSet)
+		case *ast.InlineFragment:
+			depth = m.depth(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[sel.Name.Value]; ok {
+				depth = m.depth(fragment.SelectionSet)
+			}
+		}
+		deepest = max(deepest, depth)
+	}
+	return deepest
+}
+
+func (m measure) complexity(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+
+	total := 0
+	for _, sel := range set.Selections {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			total += 1 + m.size(sel)*m.complexity(sel.SelectionSet)
+		case *ast.InlineFragment:
+			total += m.complexity(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[sel.Name.Value]; ok {
+				total += m.complexity(fragment.SelectionSet)
+			}
+		}
+	}
+	return total
+}
+
+// size is the number of elements a field may return, 1 for fields that are not lists.
+// A limit out of range fails in page, it is clamped so that a negative one cannot
+// offset the complexity of the other fields.
+func (m measure) size(field *ast.Field) int {
+	if _, ok := listFields[field.Name.Value]; !ok {
+		return 1
+	}
+
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				return min(max(n, 0), maxLimit)
+			}
+			return maxLimit
+		case *ast.Variable:
+			if n, ok := m.variables[value.Name.Value].(float64); ok {
+				return int(min(max(n, 0), maxLimit))
+			}
+		}
+	}
+	return defaultLimit
+}
+
+func hasMutation(doc *ast.Document) bool {
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok && op.Operation == ast.OperationTypeMutation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package graphql
+
+import (
+	// Go Internal Packages
+	"strings"
+	"testing"
+
+	// External Packages
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	limits := Limits{MaxDepth: 4, MaxComplexity: 1000}
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		wantErr   string
+	}{
+		{
+			name:  "flat list within the limits",
+			query: `{ students { name } }`,
+		},
+		{
+			name:    "too deep",
+			query:   `{ students { orders { student { orders { order_id } } } } }`,
+			wantErr: "query depth 5 exceeds the maximum of 4",
+		},
+		{
+			name:    "nested lists multiply",
+			query:   `{ students(limit: 100) { orders(limit: 100) { student { name } } } }`,
+			wantErr: "query complexity 20101 exceeds the maximum of 1000",
+		},
+		{
+			name:    "negative limit cannot offset the complexity",
+			query:   `{ b: students(limit: 100) { orders(limit: 100) { student { name } } } a: students(limit: -100000) { name } }`,
+			wantErr: "query complexity 20102 exceeds the maximum of 1000",
+		},
+		{
+			name:    "limit above the maximum counts as the maximum",
+			query:   `{ students(limit: 100000) { orders { order_id } } }`,
+			wantErr: "query complexity 2101 exceeds the maximum of 1000",
+		},
+		{
+			name:      "limit from a variable",
+			query:     `query ($n: Int) { students(limit: $n) { orders(limit: $n) { order_id } } }`,
+			variables: map[string]interface{}{"n": float64(50)},
+			wantErr:   "query complexity 2551 exceeds the maximum of 1000",
+		},
+		{
+			name:      "negative limit from a variable",
+			query:     `query ($n: Int) { a: students(limit: $n) { name } b: students { orders { order_id } } }`,
+			variables: map[string]interface{}{"n": float64(-100000)},
+		},
+		{
+			name:    "fragments are inlined",
+			query:   `{ students(limit: 100) { ...withOrders } } fragment withOrders on Student { orders(limit: 100) { order_id } }`,
+			wantErr: "query complexity 10101 exceeds the maximum of 1000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(tt.query)})})
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			err = limits.check(doc, tt.variables)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("check() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
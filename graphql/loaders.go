@@ -0,0 +1,132 @@
+package graphql
+
+import (
+	// Go Internal Packages
+	"context"
+	"sync"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+)
+
+// loader batches the keys requested by the resolvers of one level of the query into a
+// single fetch. Load registers the key and returns a thunk, graphql-go runs the thunks
+// only after every field of the level resolved, so the first thunk fetches all keys.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]struct{}
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:  fetch,
+		queued: map[K]struct{}{},
+		values: map[K]V{},
+		errs:   map[K]error{},
+	}
+}
+
+func (l *loader[K, V]) Load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if _, ok := l.queued[key]; !ok {
+		l.queued[key] = struct{}{}
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+			values, err := l.fetch(ctx, keys)
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+					continue
+				}
+				l.values[k] = values[k]
+			}
+		}
+		return l.values[key], l.errs[key]
+	}
+}
+
+// loaders are created per request so that batches and results are never shared between requests
+type loaders struct {
+	orders   *loader[string, []models.Order]
+	students *loader[string, *models.StudentModel]
+}
+
+type loadersKey struct{}
+
+func (h *Handler) newLoaders() *loaders {
+	return &loaders{
+		orders:   newLoader(h.fetchOrdersByUser),
+		students: newLoader(h.fetchStudents),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// fetchOrdersByUser lists the orders once and groups them by the requested users
+func (h *Handler) fetchOrdersByUser(ctx context.Context, userIDs []string) (map[string][]models.Order, error) {
+	byUser := make(map[string][]models.Order, len(userIDs))
+	for _, userID := range userIDs {
+		byUser[userID] = []models.Order{}
+	}
+
+	// A single user is filtered by the service, several users share one listing
+	filter := ""
+	if len(userIDs) == 1 {
+		filter = userIDs[0]
+	}
+	err := h.orders.List(ctx, filter, func(order models.Order) error {
+		if orders, ok := byUser[order.UserID]; ok {
+			byUser[order.UserID] = append(orders, order)
+		}
+		return nil
+	})
+	return byUser, err
+}
+
+// fetchStudents reads a single student directly, several students share one listing
+func (h *Handler) fetchStudents(ctx context.Context, rollNos []string) (map[string]*models.StudentModel, error) {
+	students := make(map[string]*models.StudentModel, len(rollNos))
+	if len(rollNos) == 1 {
+		student, err := h.students.GetOneStudent(ctx, rollNos[0], false)
+		if errors.IsKind(err, errors.NotFound) {
+			return students, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		students[rollNos[0]] = student
+		return students, nil
+	}
+
+	wanted := make(map[string]struct{}, len(rollNos))
+	for _, rollNo := range rollNos {
+		wanted[rollNo] = struct{}{}
+	}
+	all, err := h.students.GetAllStudents(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	for i, student := range *all {
+		if _, ok := wanted[student.RollNo]; ok {
+			students[student.RollNo] = &(*all)[i]
+		}
+	}
+	return students, nil
+}
@@ -0,0 +1,202 @@
+package graphql
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+func TestLoaderBatchesPendingKeys(t *testing.T) {
+	var batches [][]string
+	l := newLoader(func(_ context.Context, keys []string) (map[string]int, error) {
+		batches = append(batches, keys)
+		values := map[string]int{}
+		for _, key := range keys {
+			values[key] = len(key)
+		}
+		return values, nil
+	})
+
+	ctx := context.Background()
+	thunks := []func() (int, error){l.Load(ctx, "a"), l.Load(ctx, "bb"), l.Load(ctx, "a"), l.Load(ctx, "ccc")}
+	for i, want := range []int{1, 2, 1, 3} {
+		got, err := thunks[i]()
+		if err != nil || got != want {
+			t.Fatalf("thunk %d = %d, %v, want %d", i, got, err, want)
+		}
+	}
+	if want := [][]string{{"a", "bb", "ccc"}}; !reflect.DeepEqual(batches, want) {
+		t.Fatalf("batches = %v, want %v", batches, want)
+	}
+
+	// Keys loaded after a batch was fetched make a batch of their own
+	if got, _ := l.Load(ctx, "dddd")(); got != 4 {
+		t.Fatalf("thunk = %d, want 4", got)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("fetched %d batches, want 2", len(batches))
+	}
+}
+
+func TestLoaderReportsFetchErrorForEveryKey(t *testing.T) {
+	fetchErr := fmt.Errorf("store unavailable")
+	l := newLoader(func(context.Context, []string) (map[string]int, error) {
+		return nil, fetchErr
+	})
+
+	ctx := context.Background()
+	first, second := l.Load(ctx, "a"), l.Load(ctx, "b")
+	for _, thunk := range []func() (int, error){first, second} {
+		if _, err := thunk(); err != fetchErr {
+			t.Fatalf("thunk error = %v, want %v", err, fetchErr)
+		}
+	}
+}
+
+// fakeStudents and fakeOrders count the reads of the services
+type fakeStudents struct {
+	StudentsService
+	students []models.StudentModel
+
+	mu          sync.Mutex
+	getOneCalls int
+	getAllCalls int
+}
+
+func (f *fakeStudents) GetOneStudent(_ context.Context, rollNo string, _ bool) (*models.StudentModel, error) {
+	f.mu.Lock()
+	f.getOneCalls++
+	f.mu.Unlock()
+	for _, student := range f.students {
+		if student.RollNo == rollNo {
+			return &student, nil
+		}
+	}
+	return nil, errors.E(errors.NotFound, "student details not found")
+}
+
+func (f *fakeStudents) GetAllStudents(context.Context, bool) (*[]models.StudentModel, error) {
+	f.mu.Lock()
+	f.getAllCalls++
+	f.mu.Unlock()
+	students := append([]models.StudentModel{}, f.students...)
+	return &students, nil
+}
+
+type fakeOrders struct {
+	OrdersService
+	orders []models.Order
+
+	mu        sync.Mutex
+	listCalls []string
+}
+
+func (f *fakeOrders) List(_ context.Context, userID string, fn func(order models.Order) error) error {
+	f.mu.Lock()
+	f.listCalls = append(f.listCalls, userID)
+	f.mu.Unlock()
+	for _, order := range f.orders {
+		if userID != "" && order.UserID != userID {
+			continue
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestHandler(t *testing.T, students *fakeStudents, orders *fakeOrders) *Handler {
+	t.Helper()
+	h, err := NewHandler(students, orders, Limits{MaxDepth: 10, MaxComplexity: 100000}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewHandler() error = %v", err)
+	}
+	return h
+}
+
+func TestQueryLoadsNestedFieldsInOneBatch(t *testing.T) {
+	students := &fakeStudents{students: []models.StudentModel{
+		{RollNo: "r1", Name: "Ann"}, {RollNo: "r2", Name: "Bob"}, {RollNo: "r3", Name: "Cy"},
+	}}
+	orders := &fakeOrders{orders: []models.Order{
+		{ID: "o1", UserID: "r1", OrderStatus: "placed"},
+		{ID: "o2", UserID: "r2", OrderStatus: "shipped"},
+		{ID: "o3", UserID: "r1", OrderStatus: "shipped"},
+	}}
+	h := newTestHandler(t, students, orders)
+
+	result := h.execute(context.Background(), request{
+		Query: `{ students { roll_no orders { order_id student { name } } } }`,
+	}, false)
+	if result.HasErrors() {
+		t.Fatalf("execute() errors = %v", result.Errors)
+	}
+
+	data, _ := json.Marshal(result.Data)
+	var got struct {
+		Students []struct {
+			RollNo string `json:"roll_no"`
+			Orders []struct {
+				OrderID string `json:"order_id"`
+				Student struct {
+					Name string `json:"name"`
+				} `json:"student"`
+			} `json:"orders"`
+		} `json:"students"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	orderIDs := map[string][]string{}
+	for _, student := range got.Students {
+		for _, order := range student.Orders {
+			orderIDs[student.RollNo] = append(orderIDs[student.RollNo], order.OrderID+":"+order.Student.Name)
+		}
+		sort.Strings(orderIDs[student.RollNo])
+	}
+	want := map[string][]string{"r1": {"o1:Ann", "o3:Ann"}, "r2": {"o2:Bob"}}
+	if !reflect.DeepEqual(orderIDs, want) {
+		t.Fatalf("orders by student = %v, want %v", orderIDs, want)
+	}
+
+	// One listing of the orders for every student, one of the students for every order
+	// next to the listing of the students field
+	if len(orders.listCalls) != 1 {
+		t.Fatalf("orders listed %v, want a single listing", orders.listCalls)
+	}
+	if students.getAllCalls != 2 || students.getOneCalls != 0 {
+		t.Fatalf("students read with %d listings and %d lookups, want 2 listings", students.getAllCalls, students.getOneCalls)
+	}
+}
+
+func TestQueryLoadsSingleKeyDirectly(t *testing.T) {
+	students := &fakeStudents{students: []models.StudentModel{{RollNo: "r1", Name: "Ann"}}}
+	orders := &fakeOrders{orders: []models.Order{{ID: "o1", UserID: "r1", OrderStatus: "placed"}}}
+	h := newTestHandler(t, students, orders)
+
+	result := h.execute(context.Background(), request{
+		Query: `{ student(roll_no: "r1") { orders { order_id student { name } } } }`,
+	}, false)
+	if result.HasErrors() {
+		t.Fatalf("execute() errors = %v", result.Errors)
+	}
+	if !reflect.DeepEqual(orders.listCalls, []string{"r1"}) {
+		t.Fatalf("orders listed %v, want a listing filtered by r1", orders.listCalls)
+	}
+	if students.getAllCalls != 0 || students.getOneCalls != 2 {
+		t.Fatalf("students read with %d listings and %d lookups, want 2 lookups", students.getAllCalls, students.getOneCalls)
+	}
+}
@@ -0,0 +1,435 @@
+package graphql
+
+import (
+	// Go Internal Packages
+	"strings"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+
+	// External Packages
+	"github.com/graphql-go/graphql"
+)
+
+// Page sizes of the list fields, see Limits for how they weigh in the complexity
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// listFields are the fields returning paginated lists
+var listFields = map[string]struct{}{"students": {}, "orders": {}}
+
+// newSchema builds the schema, the object fields resolve from the json tags of the models
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	lineItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LineItem",
+		Fields: graphql.Fields{
+			"item_id":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"quantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"price":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	orderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"order_id":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"user_id":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"line_items":   &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(lineItemType)))},
+			"order_status": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"created_at":   &graphql.Field{Type: graphql.String},
+			"updated_at":   &graphql.Field{Type: graphql.String},
+			"shipped_at":   &graphql.Field{Type: graphql.String},
+			"delivered_at": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	studentType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Student",
+		Fields: graphql.Fields{
+			"roll_no": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"gender":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"mail_id": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	// Orders belong to the student whose roll number is their user id
+	studentType.AddFieldConfig("orders", &graphql.Field{
+		Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(orderType))),
+		Args: graphql.FieldConfigArgument{
+			"order_status": &graphql.ArgumentConfig{Type: graphql.String},
+			"limit":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+			"offset":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+		},
+		Resolve: h.resolveStudentOrders,
+	})
+	orderType.AddFieldConfig("student", &graphql.Field{
+		Type:    studentType,
+		Resolve: h.resolveOrderStudent,
+	})
+
+	studentInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "StudentInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"roll_no": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"name":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"gender":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"mail_id": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	lineItemInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "LineItemInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"item_id":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"quantity": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"price":    &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+	orderInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "OrderInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"user_id":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"line_items":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(lineItemInput)))},
+			"order_status": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"shipped_at":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"delivered_at": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"student": &graphql.Field{
+				Type:    studentType,
+				Args:    graphql.FieldConfigArgument{"roll_no": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: h.resolveStudent,
+			},
+			"students": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(studentType))),
+				Args: graphql.FieldConfigArgument{
+					"gender": &graphql.ArgumentConfig{Type: graphql.String},
+					"name":   &graphql.ArgumentConfig{Type: graphql.String, Description: "case-insensitive substring"},
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: h.resolveStudents,
+			},
+			"order": &graphql.Field{
+				Type:    orderType,
+				Args:    graphql.FieldConfigArgument{"order_id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: h.resolveOrder,
+			},
+			"orders": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(orderType))),
+				Args: graphql.FieldConfigArgument{
+					"user_id":      &graphql.ArgumentConfig{Type: graphql.String},
+					"order_status": &graphql.ArgumentConfig{Type: graphql.String},
+					"limit":        &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit},
+					"offset":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: h.resolveOrders,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createStudent": &graphql.Field{
+				Type:    studentType,
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(studentInput)}},
+				Resolve: h.createStudent,
+			},
+			"updateStudent": &graphql.Field{
+				Type: studentType,
+				Args: graphql.FieldConfigArgument{
+					"roll_no": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"input":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(studentInput)},
+				},
+				Resolve: h.updateStudent,
+			},
+			"deleteStudent": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Args:    graphql.FieldConfigArgument{"roll_no": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: h.deleteStudent,
+			},
+			"createOrder": &graphql.Field{
+				Type:    orderType,
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(orderInput)}},
+				Resolve: h.createOrder,
+			},
+			"updateOrder": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"order_id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"input":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(orderInput)},
+				},
+				Resolve: h.updateOrder,
+			},
+			"deleteOrder": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.Boolean),
+				Args:    graphql.FieldConfigArgument{"order_id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: h.deleteOrder,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// page returns the elements of items selected by the limit and offset arguments
+func page[T any](items []T, args map[string]interface{}) ([]T, error) {
+	limit, _ := args["limit"].(int)
+	offset, _ := args["offset"].(int)
+	if limit < 0 || limit > maxLimit {
+		return nil, errors.E(errors.Invalid, "limit must be between 0 and 100")
+	}
+	if offset < 0 {
+		return nil, errors.E(errors.Invalid, "offset cannot be negative")
+	}
+
+	if offset >= len(items) {
+		return []T{}, nil
+	}
+	return items[offset:min(offset+limit, len(items))], nil
+}
+
+func (h *Handler) resolveStudent(p graphql.ResolveParams) (interface{}, error) {
+	student, err := h.students.GetOneStudent(p.Context, p.Args["roll_no"].(string), false)
+	if errors.IsKind(err, errors.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return student, nil
+}
+
+func (h *Handler) resolveStudents(p graphql.ResolveParams) (interface{}, error) {
+	all, err := h.students.GetAllStudents(p.Context, false)
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+
+	gender, _ := p.Args["gender"].(string)
+	name, _ := p.Args["name"].(string)
+	students := []models.StudentModel{}
+	for _, student := range *all {
+		if gender != "" && !strings.EqualFold(student.Gender, gender) {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(student.Name), strings.ToLower(name)) {
+			continue
+		}
+		students = append(students, student)
+	}
+
+	students, err = page(students, p.Args)
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return students, nil
+}
+
+func (h *Handler) resolveOrder(p graphql.ResolveParams) (interface{}, error) {
+	order, err := h.orders.GetOne(p.Context, p.Args["order_id"].(string))
+	if errors.IsKind(err, errors.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return order, nil
+}
+
+func (h *Handler) resolveOrders(p graphql.ResolveParams) (interface{}, error) {
+	userID, _ := p.Args["user_id"].(string)
+	status, _ := p.Args["order_status"].(string)
+
+	orders := []models.Order{}
+	err := h.orders.List(p.Context, userID, func(order models.Order) error {
+		if status == "" || order.OrderStatus == status {
+			orders = append(orders, order)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+
+	orders, err = page(orders, p.Args)
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return orders, nil
+}
+
+// resolveStudentOrders defers to the orders loader, the orders of every student in
+// the result are fetched together
+func (h *Handler) resolveStudentOrders(p graphql.ResolveParams) (interface{}, error) {
+	student, err := sourceStudent(p.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	status, _ := p.Args["order_status"].(string)
+	load := loadersFrom(p.Context).orders.Load(p.Context, student.RollNo)
+	return func() (interface{}, error) {
+		all, err := load()
+		if err != nil {
+			return nil, h.toGraphQLError(err)
+		}
+
+		orders := []models.Order{}
+		for _, order := range all {
+			if status == "" || order.OrderStatus == status {
+				orders = append(orders, order)
+			}
+		}
+		orders, err = page(orders, p.Args)
+		if err != nil {
+			return nil, h.toGraphQLError(err)
+		}
+		return orders, nil
+	}, nil
+}
+
+// resolveOrderStudent defers to the students loader, it is null when the user is not a student
+func (h *Handler) resolveOrderStudent(p graphql.ResolveParams) (interface{}, error) {
+	order, ok := p.Source.(models.Order)
+	if !ok {
+		return nil, errors.NewError("order field resolved on a non order")
+	}
+
+	load := loadersFrom(p.Context).students.Load(p.Context, order.UserID)
+	return func() (interface{}, error) {
+		student, err := load()
+		if err != nil {
+			return nil, h.toGraphQLError(err)
+		}
+		if student == nil {
+			return nil, nil
+		}
+		return student, nil
+	}, nil
+}
+
+func (h *Handler) createStudent(p graphql.ResolveParams) (interface{}, error) {
+	student := studentFromInput(p.Args["input"])
+	if err := student.Validate(); err != nil {
+		return nil, h.toGraphQLError(errors.ValidationFailedErr(err))
+	}
+	if err := h.students.InsertStudent(p.Context, student); err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return student, nil
+}
+
+func (h *Handler) updateStudent(p graphql.ResolveParams) (interface{}, error) {
+	student := studentFromInput(p.Args["input"])
+	if err := student.Validate(); err != nil {
+		return nil, h.toGraphQLError(errors.ValidationFailedErr(err))
+	}
+	if err := h.students.UpdateStudent(p.Context, p.Args["roll_no"].(string), student); err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return student, nil
+}
+
+func (h *Handler) deleteStudent(p graphql.ResolveParams) (interface{}, error) {
+	if err := h.students.DeleteStudent(p.Context, p.Args["roll_no"].(string)); err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return true, nil
+}
+
+func (h *Handler) createOrder(p graphql.ResolveParams) (interface{}, error) {
+	order := orderFromInput(p.Args["input"])
+	if err := order.ValidateCreation(); err != nil {
+		return nil, h.toGraphQLError(errors.ValidationFailedErr(err))
+	}
+
+	orderID, err := h.orders.Insert(p.Context, order)
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return h.resolveOrder(graphql.ResolveParams{Context: p.Context, Args: map[string]interface{}{"order_id": orderID}})
+}
+
+func (h *Handler) updateOrder(p graphql.ResolveParams) (interface{}, error) {
+	orderID := p.Args["order_id"].(string)
+	current, err := h.orders.GetOne(p.Context, orderID)
+	if err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+
+	// The input carries the editable fields, the rest is kept from the current order
+	order := orderFromInput(p.Args["input"])
+	order.ID = orderID
+	order.CreatedAt = current.CreatedAt
+	if order.ShippedAt == "" {
+		order.ShippedAt = current.ShippedAt
+	}
+	if order.DeliveredAt == "" {
+		order.DeliveredAt = current.DeliveredAt
+	}
+	if err := order.ValidateUpdate(orderID); err != nil {
+		return nil, h.toGraphQLError(errors.ValidationFailedErr(err))
+	}
+
+	if err := h.orders.Update(p.Context, order); err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return h.resolveOrder(graphql.ResolveParams{Context: p.Context, Args: map[string]interface{}{"order_id": orderID}})
+}
+
+func (h *Handler) deleteOrder(p graphql.ResolveParams) (interface{}, error) {
+	if err := h.orders.Delete(p.Context, p.Args["order_id"].(string)); err != nil {
+		return nil, h.toGraphQLError(err)
+	}
+	return true, nil
+}
+
+func sourceStudent(source interface{}) (models.StudentModel, error) {
+	switch student := source.(type) {
+	case models.StudentModel:
+		return student, nil
+	case *models.StudentModel:
+		return *student, nil
+	default:
+		return models.StudentModel{}, errors.NewError("student field resolved on a non student")
+	}
+}
+
+func studentFromInput(input interface{}) models.StudentModel {
+	fields, _ := input.(map[string]interface{})
+	student := models.StudentModel{}
+	student.RollNo, _ = fields["roll_no"].(string)
+	student.Name, _ = fields["name"].(string)
+	student.Gender, _ = fields["gender"].(string)
+	student.MailID, _ = fields["mail_id"].(string)
+	return student
+}
+
+func orderFromInput(input interface{}) models.Order {
+	fields, _ := input.(map[string]interface{})
+	order := models.Order{LineItems: []models.LineItem{}}
+	order.UserID, _ = fields["user_id"].(string)
+	order.OrderStatus, _ = fields["order_status"].(string)
+	order.ShippedAt, _ = fields["shipped_at"].(string)
+	order.DeliveredAt, _ = fields["delivered_at"].(string)
+
+	items, _ := fields["line_items"].([]interface{})
+	for _, raw := range items {
+		itemFields, _ := raw.(map[string]interface{})
+		item := models.LineItem{}
+		item.ItemID, _ = itemFields["item_id"].(string)
+		item.Quantity, _ = itemFields["quantity"].(int)
+		item.Price, _ = itemFields["price"].(float64)
+		order.LineItems = append(order.LineItems, item)
+	}
+	return order
+}
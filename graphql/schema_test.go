@@ -0,0 +1,48 @@
+package graphql
+
+import (
+	// Go Internal Packages
+	"reflect"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+func TestPage(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4}
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		want    []int
+		wantErr bool
+	}{
+		{name: "first window", args: map[string]interface{}{"limit": 2, "offset": 0}, want: []int{0, 1}},
+		{name: "middle window", args: map[string]interface{}{"limit": 2, "offset": 2}, want: []int{2, 3}},
+		{name: "window past the end", args: map[string]interface{}{"limit": 10, "offset": 3}, want: []int{3, 4}},
+		{name: "offset past the end", args: map[string]interface{}{"limit": 2, "offset": 5}, want: []int{}},
+		{name: "zero limit", args: map[string]interface{}{"limit": 0, "offset": 0}, want: []int{}},
+		{name: "maximum limit", args: map[string]interface{}{"limit": maxLimit, "offset": 0}, want: items},
+		{name: "negative limit", args: map[string]interface{}{"limit": -1, "offset": 0}, wantErr: true},
+		{name: "limit above the maximum", args: map[string]interface{}{"limit": maxLimit + 1, "offset": 0}, wantErr: true},
+		{name: "negative offset", args: map[string]interface{}{"limit": 2, "offset": -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := page(items, tt.args)
+			if tt.wantErr {
+				if !errors.IsKind(err, errors.Invalid) {
+					t.Fatalf("page() error = %v, want an Invalid error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("page() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("page() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
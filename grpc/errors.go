@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	// Local Packages
+	errors "learn-go/errors"
+
+	// External Packages
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus converts an error returned by a service into a gRPC status, error kinds
+// map to the same classes as the HTTP status codes of response.RespondError
+func (s *Server) toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	// A call cancelled by its client or past its deadline is not an internal error
+	if st := status.FromContextError(err); st.Code() != codes.Unknown {
+		return st.Err()
+	}
+
+	e, ok := err.(*errors.Error)
+	if !ok {
+		s.logger.Error("internal error", zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	switch e.Kind {
+	case errors.NotFound:
+		return status.Error(codes.NotFound, e.Message)
+	case errors.Invalid:
+		var ve errors.ValidationErrors
+		if errors.As(e, &ve) {
+			return invalidStatus(e.Message, ve)
+		}
+		if e.WrappedErr != nil {
+			return status.Error(codes.InvalidArgument, e.WrappedErr.Error())
+		}
+		return status.Error(codes.InvalidArgument, e.Message)
+	case errors.Unsupported:
+		return status.Error(codes.InvalidArgument, e.Message)
+	case errors.TooLarge:
+		return status.Error(codes.ResourceExhausted, e.Message)
+	case errors.Conflict:
+		return status.Error(codes.Aborted, e.Message)
+	case errors.Unauthorized:
+		return status.Error(codes.Unauthenticated, e.Message)
+	case errors.Forbidden:
+		return status.Error(codes.PermissionDenied, e.Message)
+	default:
+		return status.Error(codes.Internal, e.Message)
+	}
+}
+
+// invalidStatus attaches the validation errors as BadRequest field violations
+func invalidStatus(message string, ve errors.ValidationErrors) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(ve))
+	for _, fe := range ve {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: fe.Field, Description: fe.Error})
+	}
+
+	st, err := status.New(codes.InvalidArgument, message).
+		WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return st.Err()
+}
@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	// Go Internal Packages
+	"context"
+
+	// Local Packages
+	errors "learn-go/errors"
+	pb "learn-go/grpc/pb"
+	models "learn-go/models"
+)
+
+type OrdersService interface {
+	Insert(ctx context.Context, order models.Order) (string, error)
+	GetOne(ctx context.Context, orderID string) (models.Order, error)
+	List(ctx context.Context, userID string, fn func(order models.Order) error) error
+	Update(ctx context.Context, order models.Order) error
+	Delete(ctx context.Context, orderID string) error
+}
+
+// ordersServer implements pb.OrdersServiceServer over the OrdersService
+type ordersServer struct {
+	pb.UnimplementedOrdersServiceServer
+	server *Server
+	svc    OrdersService
+}
+
+func (a *ordersServer) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.Order, error) {
+	if req.GetOrderId() == "" {
+		return nil, a.server.toStatus(errors.EmptyParamErr("order_id"))
+	}
+
+	order, err := a.svc.GetOne(ctx, req.GetOrderId())
+	if err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return toPBOrder(order), nil
+}
+
+func (a *ordersServer) ListOrders(req *pb.ListOrdersRequest, stream pb.OrdersService_ListOrdersServer) error {
+	var sendErr error
+	err := a.svc.List(stream.Context(), req.GetUserId(), func(order models.Order) error {
+		sendErr = stream.Send(toPBOrder(order))
+		return sendErr
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	return a.server.toStatus(err)
+}
+
+func (a *ordersServer) CreateOrder(ctx context.Context, req *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
+	order := fromPBOrder(req.GetOrder())
+	if err := order.ValidateCreation(); err != nil {
+		return nil, a.server.toStatus(errors.ValidationFailedErr(err))
+	}
+
+	orderID, err := a.svc.Insert(ctx, order)
+	if err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return &pb.CreateOrderResponse{OrderId: orderID}, nil
+}
+
+func (a *ordersServer) UpdateOrder(ctx context.Context, req *pb.UpdateOrderRequest) (*pb.UpdateOrderResponse, error) {
+	order := fromPBOrder(req.GetOrder())
+	if order.ID == "" {
+		return nil, a.server.toStatus(errors.EmptyParamErr("order_id"))
+	}
+	if err := order.ValidateUpdate(order.ID); err != nil {
+		return nil, a.server.toStatus(errors.ValidationFailedErr(err))
+	}
+
+	if err := a.svc.Update(ctx, order); err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return &pb.UpdateOrderResponse{}, nil
+}
+
+func (a *ordersServer) DeleteOrder(ctx context.Context, req *pb.DeleteOrderRequest) (*pb.DeleteOrderResponse, error) {
+	if req.GetOrderId() == "" {
+		return nil, a.server.toStatus(errors.EmptyParamErr("order_id"))
+	}
+
+	if err := a.svc.Delete(ctx, req.GetOrderId()); err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return &pb.DeleteOrderResponse{}, nil
+}
+
+func toPBOrder(order models.Order) *pb.Order {
+	items := make([]*pb.LineItem, 0, len(order.LineItems))
+	for _, item := range order.LineItems {
+		items = append(items, &pb.LineItem{ItemId: item.ItemID, Quantity: int64(item.Quantity), Price: item.Price})
+	}
+	return &pb.Order{
+		OrderId:     order.ID,
+		UserId:      order.UserID,
+		LineItems:   items,
+		OrderStatus: order.OrderStatus,
+		CreatedAt:   order.CreatedAt,
+		UpdatedAt:   order.UpdatedAt,
+		ShippedAt:   order.ShippedAt,
+		DeliveredAt: order.DeliveredAt,
+	}
+}
+
+func fromPBOrder(order *pb.Order) models.Order {
+	items := make([]models.LineItem, 0, len(order.GetLineItems()))
+	for _, item := range order.GetLineItems() {
+		items = append(items, models.LineItem{ItemID: item.GetItemId(), Quantity: int(item.GetQuantity()), Price: item.GetPrice()})
+	}
+	return models.Order{
+		ID:          order.GetOrderId(),
+		UserID:      order.GetUserId(),
+		LineItems:   items,
+		OrderStatus: order.GetOrderStatus(),
+		CreatedAt:   order.GetCreatedAt(),
+		UpdatedAt:   order.GetUpdatedAt(),
+		ShippedAt:   order.GetShippedAt(),
+		DeliveredAt: order.GetDeliveredAt(),
+	}
+}
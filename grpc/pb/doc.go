@@ -0,0 +1,5 @@
+// Package pb holds the protobuf messages and gRPC services of the learn-go API.
+// The generated files are committed, regenerate them after editing the .proto files.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative students.proto orders.proto
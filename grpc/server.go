@@ -0,0 +1,156 @@
+package grpc
+
+import (
+	// Go Internal Packages
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"time"
+
+	// Local Packages
+	audit "learn-go/audit"
+	pb "learn-go/grpc/pb"
+
+	// External Packages
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Server serves the students and orders services over gRPC
+type Server struct {
+	actorHeader     string
+	trustedClients  []string
+	logger          *zap.Logger
+	orders          OrdersService
+	shutdownTimeout time.Duration
+	students        StudentsService
+}
+
+func NewServer(
+	logger *zap.Logger,
+	studentsSvc StudentsService,
+	ordersSvc OrdersService,
+	shutdownTimeout time.Duration,
+	actorHeader string,
+	trustedClients []string,
+) *Server {
+	return &Server{
+		actorHeader:     strings.ToLower(actorHeader),
+		trustedClients:  trustedClients,
+		logger:          logger,
+		students:        studentsSvc,
+		orders:          ordersSvc,
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+// Listen starts the gRPC server and gracefully stops it when the context is done,
+// in-flight calls get the same shutdown timeout as the HTTP server before being cut off
+func (s *Server) Listen(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.serve(ctx, lis)
+}
+
+func (s *Server) serve(ctx context.Context, lis net.Listener) error {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryLogger, s.unaryAudit),
+		grpc.ChainStreamInterceptor(s.streamLogger),
+	)
+	pb.RegisterStudentsServiceServer(server, &studentsServer{server: s, svc: s.students})
+	pb.RegisterOrdersServiceServer(server, &ordersServer{server: s, svc: s.orders})
+	reflection.Register(server)
+
+	// Buffered so that Serve can return after the shutdown without a reader
+	errch := make(chan error, 1)
+	go func() {
+		s.logger.Info("Starting grpc server", zap.String("addr", lis.Addr().String()))
+		errch <- server.Serve(lis)
+	}()
+
+	select {
+	case err := <-errch:
+		return err
+	case <-ctx.Done():
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(s.shutdownTimeout):
+			server.Stop()
+		}
+		return nil
+	}
+}
+
+func (s *Server) unaryLogger(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.logger.Info("Served", zap.String("method", info.FullMethod),
+		zap.String("code", status.Code(err).String()), zap.Duration("duration", time.Since(start)))
+	return resp, err
+}
+
+func (s *Server) streamLogger(
+	srv any,
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	s.logger.Info("Served", zap.String("method", info.FullMethod),
+		zap.String("code", status.Code(err).String()), zap.Duration("duration", time.Since(start)))
+	return err
+}
+
+// unaryAudit stamps the call context with who makes the changes recorded in the audit
+// log, taken from the peer and the metadata like the HTTP server takes them from the
+// connection and the headers
+func (s *Server) unaryAudit(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var state *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			state = &info.State
+		}
+	}
+	actor := ""
+	if s.actorHeader != "" {
+		actor = first(md.Get(s.actorHeader))
+	}
+	auditReq := audit.Request{
+		Actor:     audit.Actor(state, actor, s.trustedClients),
+		RequestID: first(md.Get("x-request-id")),
+	}
+	return handler(audit.WithRequest(ctx, auditReq), req)
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
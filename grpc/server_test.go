@@ -0,0 +1,418 @@
+package grpc
+
+import (
+	// Go Internal Packages
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	// Local Packages
+	audit "learn-go/audit"
+	errors "learn-go/errors"
+	pb "learn-go/grpc/pb"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// memStudents is a StudentsService over a fixed list of students
+type memStudents struct {
+	students []models.StudentModel
+	err      error         // returned by every call when set
+	block    chan struct{} // GetOneStudent waits for it to be closed when set
+	started  chan struct{} // closed once a call waits on block
+
+	mu       sync.Mutex
+	requests []audit.Request
+}
+
+func (m *memStudents) GetOneStudent(ctx context.Context, rollNo string, _ bool) (*models.StudentModel, error) {
+	if m.block != nil {
+		close(m.started)
+		select {
+		case <-m.block:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, student := range m.students {
+		if student.RollNo == rollNo {
+			return &student, nil
+		}
+	}
+	return nil, errors.E(errors.NotFound, "student not found")
+}
+
+func (m *memStudents) GetAllStudents(context.Context, bool) (*[]models.StudentModel, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &m.students, nil
+}
+
+func (m *memStudents) InsertStudent(ctx context.Context, _ models.StudentModel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, audit.RequestFrom(ctx))
+	return m.err
+}
+
+func (m *memStudents) UpdateStudent(context.Context, string, models.StudentModel) error {
+	return m.err
+}
+
+func (m *memStudents) DeleteStudent(context.Context, string) error {
+	return m.err
+}
+
+// memOrders is an OrdersService over a fixed list of orders, List fails with err after
+// the orders when it is set
+type memOrders struct {
+	orders []models.Order
+	err    error
+}
+
+func (m *memOrders) Insert(context.Context, models.Order) (string, error) {
+	return "", m.err
+}
+
+func (m *memOrders) GetOne(context.Context, string) (models.Order, error) {
+	return models.Order{}, m.err
+}
+
+func (m *memOrders) List(_ context.Context, userID string, fn func(order models.Order) error) error {
+	for _, order := range m.orders {
+		if userID != "" && order.UserID != userID {
+			continue
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return m.err
+}
+
+func (m *memOrders) Update(context.Context, models.Order) error {
+	return m.err
+}
+
+func (m *memOrders) Delete(context.Context, string) error {
+	return m.err
+}
+
+// startServer serves the services over an in-memory listener and returns a client
+// connection to it, the server stops when stop is called or the test ends
+func startServer(
+	t *testing.T,
+	students StudentsService,
+	orders OrdersService,
+	shutdownTimeout time.Duration,
+) (conn *grpc.ClientConn, stop func() error) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(zap.NewNop(), students, orders, shutdownTimeout, "X-Actor", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.serve(ctx, lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var once sync.Once
+	var serveErr error
+	stop = func() error {
+		once.Do(func() {
+			cancel()
+			serveErr = <-done
+		})
+		return serveErr
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = stop()
+	})
+	return conn, stop
+}
+
+func TestErrorKindsToCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		want        codes.Code
+		wantMessage string
+	}{
+		{name: "not found", err: errors.E(errors.NotFound, "student not found"), want: codes.NotFound,
+			wantMessage: "student not found"},
+		{name: "invalid", err: errors.E(errors.Invalid, "bad roll no"), want: codes.InvalidArgument,
+			wantMessage: "bad roll no"},
+		{name: "unsupported", err: errors.E(errors.Unsupported, "unsupported"), want: codes.InvalidArgument},
+		{name: "too large", err: errors.E(errors.TooLarge, "too large"), want: codes.ResourceExhausted},
+		{name: "conflict", err: errors.E(errors.Conflict, "exists"), want: codes.Aborted},
+		{name: "unauthorized", err: errors.E(errors.Unauthorized, "who"), want: codes.Unauthenticated},
+		{name: "forbidden", err: errors.E(errors.Forbidden, "no"), want: codes.PermissionDenied},
+		{name: "internal kind", err: errors.E(errors.Internal, "broken"), want: codes.Internal},
+		{name: "unclassified", err: fmt.Errorf("connection refused"), want: codes.Internal,
+			wantMessage: "internal error"},
+		{name: "cancelled", err: fmt.Errorf("failed to get student: %w", context.Canceled), want: codes.Canceled},
+		{name: "deadline", err: errors.E(errors.Other, "failed to get student", context.DeadlineExceeded),
+			want: codes.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _ := startServer(t, &memStudents{err: tt.err}, &memOrders{}, time.Second)
+			_, err := pb.NewStudentsServiceClient(conn).GetStudent(context.Background(),
+				&pb.GetStudentRequest{RollNo: "21"})
+
+			st := status.Convert(err)
+			if st.Code() != tt.want {
+				t.Fatalf("code = %v, want %v: %v", st.Code(), tt.want, err)
+			}
+			if tt.wantMessage != "" && st.Message() != tt.wantMessage {
+				t.Fatalf("message = %q, want %q", st.Message(), tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestFieldViolations(t *testing.T) {
+	conn, _ := startServer(t, &memStudents{}, &memOrders{}, time.Second)
+	_, err := pb.NewStudentsServiceClient(conn).CreateStudent(context.Background(), &pb.CreateStudentRequest{
+		Student: &pb.Student{RollNo: "21", Name: "Jane", Gender: "robot", MailId: "not-a-mail"},
+	})
+
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("code = %v, want InvalidArgument: %v", st.Code(), err)
+	}
+	var fields []string
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range br.GetFieldViolations() {
+				if violation.GetDescription() == "" {
+					t.Fatalf("violation of %s has no description", violation.GetField())
+				}
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	if want := []string{"/gender", "/mail_id"}; !reflect.DeepEqual(fields, want) {
+		t.Fatalf("field violations = %v, want %v", fields, want)
+	}
+}
+
+func TestListStudentsStreams(t *testing.T) {
+	students := []models.StudentModel{
+		{RollNo: "1", Name: "Jane", Gender: "female", MailID: "jane@example.com"},
+		{RollNo: "2", Name: "John", Gender: "male", MailID: "john@example.com"},
+	}
+	conn, _ := startServer(t, &memStudents{students: students}, &memOrders{}, time.Second)
+	stream, err := pb.NewStudentsServiceClient(conn).ListStudents(context.Background(), &pb.ListStudentsRequest{})
+	if err != nil {
+		t.Fatalf("ListStudents() error = %v", err)
+	}
+
+	var got []string
+	for {
+		student, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		got = append(got, student.GetRollNo()+" "+student.GetMailId())
+	}
+	if want := []string{"1 jane@example.com", "2 john@example.com"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("streamed %v, want %v", got, want)
+	}
+}
+
+func TestListOrdersStreams(t *testing.T) {
+	orders := []models.Order{
+		{ID: "o1", UserID: "u1", LineItems: []models.LineItem{{ItemID: "i1", Quantity: 2, Price: 1.5}}},
+		{ID: "o2", UserID: "u2"},
+		{ID: "o3", UserID: "u1"},
+	}
+	tests := []struct {
+		name     string
+		err      error
+		want     []string
+		wantCode codes.Code
+	}{
+		{name: "orders of the user", want: []string{"o1", "o3"}, wantCode: codes.OK},
+		{name: "failing after some orders", err: errors.E(errors.Internal, "cursor lost"), want: []string{"o1", "o3"},
+			wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _ := startServer(t, &memStudents{}, &memOrders{orders: orders, err: tt.err}, time.Second)
+			stream, err := pb.NewOrdersServiceClient(conn).ListOrders(context.Background(),
+				&pb.ListOrdersRequest{UserId: "u1"})
+			if err != nil {
+				t.Fatalf("ListOrders() error = %v", err)
+			}
+
+			var got []string
+			var recvErr error
+			for {
+				order, err := stream.Recv()
+				if err != nil {
+					if err != io.EOF {
+						recvErr = err
+					}
+					break
+				}
+				if order.GetOrderId() == "o1" && order.GetLineItems()[0].GetQuantity() != 2 {
+					t.Fatalf("order o1 = %v, want its line items", order)
+				}
+				got = append(got, order.GetOrderId())
+			}
+			if !reflect.DeepEqual(got, tt.want) || status.Code(recvErr) != tt.wantCode {
+				t.Fatalf("streamed %v ending with %v, want %v ending with %v", got, recvErr, tt.want, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAuditRequestOfACall(t *testing.T) {
+	svc := &memStudents{}
+	conn, _ := startServer(t, svc, &memOrders{}, time.Second)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-1", "x-actor", "jane")
+	_, err := pb.NewStudentsServiceClient(conn).CreateStudent(ctx, &pb.CreateStudentRequest{
+		Student: &pb.Student{RollNo: "21", Name: "Jane", Gender: "female", MailId: "jane@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("CreateStudent() error = %v", err)
+	}
+
+	// The actor header of a client without a certificate is ignored
+	want := []audit.Request{{Actor: audit.Anonymous, RequestID: "req-1"}}
+	if !reflect.DeepEqual(svc.requests, want) {
+		t.Fatalf("audit requests = %+v, want %+v", svc.requests, want)
+	}
+}
+
+func TestUnaryAuditActor(t *testing.T) {
+	withCert := func(commonName string) *peer.Peer {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		state := tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}}
+	}
+	tests := []struct {
+		name  string
+		peer  *peer.Peer
+		actor string
+		want  string
+	}{
+		{name: "without a certificate", actor: "jane", want: audit.Anonymous},
+		{name: "client certificate", peer: withCert("billing"), want: "billing"},
+		{name: "header of a trusted client", peer: withCert("proxy"), actor: "jane", want: "jane"},
+		{name: "header of another client", peer: withCert("billing"), actor: "jane", want: "billing"},
+	}
+	s := NewServer(zap.NewNop(), nil, nil, time.Second, "X-Actor", []string{"proxy"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.peer != nil {
+				ctx = peer.NewContext(ctx, tt.peer)
+			}
+			if tt.actor != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-actor", tt.actor))
+			}
+
+			var got audit.Request
+			_, _ = s.unaryAudit(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+				got = audit.RequestFrom(ctx)
+				return nil, nil
+			})
+			if got.Actor != tt.want {
+				t.Fatalf("actor = %q, want %q", got.Actor, tt.want)
+			}
+		})
+	}
+}
+
+func TestShutdownCutsOffCallsAfterTheTimeout(t *testing.T) {
+	svc := &memStudents{block: make(chan struct{}), started: make(chan struct{})}
+	defer close(svc.block)
+	conn, stop := startServer(t, svc, &memOrders{}, 50*time.Millisecond)
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := pb.NewStudentsServiceClient(conn).GetStudent(context.Background(), &pb.GetStudentRequest{RollNo: "21"})
+		callErr <- err
+	}()
+	<-svc.started
+
+	start := time.Now()
+	if err := stop(); err != nil {
+		t.Fatalf("serve() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("serve() returned %v after the shutdown, want the call cut off after the timeout", elapsed)
+	}
+	select {
+	case err := <-callErr:
+		if err == nil {
+			t.Fatalf("the call in flight succeeded, want it cut off")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the call in flight did not end with the server")
+	}
+}
+
+func TestShutdownWaitsForCallsInFlight(t *testing.T) {
+	svc := &memStudents{
+		students: []models.StudentModel{{RollNo: "21", Name: "Jane"}},
+		block:    make(chan struct{}),
+		started:  make(chan struct{}),
+	}
+	conn, stop := startServer(t, svc, &memOrders{}, 5*time.Second)
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := pb.NewStudentsServiceClient(conn).GetStudent(context.Background(), &pb.GetStudentRequest{RollNo: "21"})
+		callErr <- err
+	}()
+	<-svc.started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- stop() }()
+	select {
+	case <-stopped:
+		t.Fatalf("serve() returned with a call in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(svc.block)
+	if err := <-callErr; err != nil {
+		t.Fatalf("the call in flight failed during the shutdown: %v", err)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("serve() error = %v", err)
+	}
+}
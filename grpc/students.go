@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	// Go Internal Packages
+	"context"
+
+	// Local Packages
+	errors "learn-go/errors"
+	pb "learn-go/grpc/pb"
+	models "learn-go/models"
+)
+
+type StudentsService interface {
+	GetOneStudent(context.Context, string, bool) (*models.StudentModel, error)
+	GetAllStudents(context.Context, bool) (*[]models.StudentModel, error)
+	InsertStudent(context.Context, models.StudentModel) error
+	UpdateStudent(context.Context, string, models.StudentModel) error
+	DeleteStudent(context.Context, string) error
+}
+
+// studentsServer implements pb.StudentsServiceServer over the StudentsService
+type studentsServer struct {
+	pb.UnimplementedStudentsServiceServer
+	server *Server
+	svc    StudentsService
+}
+
+func (a *studentsServer) GetStudent(ctx context.Context, req *pb.GetStudentRequest) (*pb.Student, error) {
+	if req.GetRollNo() == "" {
+		return nil, a.server.toStatus(errors.EmptyParamErr("roll_no"))
+	}
+
+	student, err := a.svc.GetOneStudent(ctx, req.GetRollNo(), false)
+	if err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return toPBStudent(*student), nil
+}
+
+func (a *studentsServer) ListStudents(req *pb.ListStudentsRequest, stream pb.StudentsService_ListStudentsServer) error {
+	students, err := a.svc.GetAllStudents(stream.Context(), false)
+	if err != nil {
+		return a.server.toStatus(err)
+	}
+
+	for _, student := range *students {
+		if err := stream.Send(toPBStudent(student)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *studentsServer) CreateStudent(ctx context.Context, req *pb.CreateStudentRequest) (*pb.Student, error) {
+	student := fromPBStudent(req.GetStudent())
+	if err := student.Validate(); err != nil {
+		return nil, a.server.toStatus(errors.ValidationFailedErr(err))
+	}
+
+	if err := a.svc.InsertStudent(ctx, student); err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return toPBStudent(student), nil
+}
+
+func (a *studentsServer) UpdateStudent(ctx context.Context, req *pb.UpdateStudentRequest) (*pb.Student, error) {
+	if req.GetRollNo() == "" {
+		return nil, a.server.toStatus(errors.EmptyParamErr("roll_no"))
+	}
+
+	student := fromPBStudent(req.GetStudent())
+	if err := student.Validate(); err != nil {
+		return nil, a.server.toStatus(errors.ValidationFailedErr(err))
+	}
+
+	if err := a.svc.UpdateStudent(ctx, req.GetRollNo(), student); err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return toPBStudent(student), nil
+}
+
+func (a *studentsServer) DeleteStudent(ctx context.Context, req *pb.DeleteStudentRequest) (*pb.DeleteStudentResponse, error) {
+	if req.GetRollNo() == "" {
+		return nil, a.server.toStatus(errors.EmptyParamErr("roll_no"))
+	}
+
+	if err := a.svc.DeleteStudent(ctx, req.GetRollNo()); err != nil {
+		return nil, a.server.toStatus(err)
+	}
+	return &pb.DeleteStudentResponse{}, nil
+}
+
+func toPBStudent(student models.StudentModel) *pb.Student {
+	return &pb.Student{
+		RollNo: student.RollNo,
+		Name:   student.Name,
+		Gender: student.Gender,
+		MailId: student.MailID,
+	}
+}
+
+func fromPBStudent(student *pb.Student) models.StudentModel {
+	return models.StudentModel{
+		RollNo: student.GetRollNo(),
+		Name:   student.GetName(),
+		Gender: student.GetGender(),
+		MailID: student.GetMailId(),
+	}
+}
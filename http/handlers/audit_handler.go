@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"net/http"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+)
+
+// defaultAuditLimit is the page size of List when no limit is given
+const defaultAuditLimit = 50
+
+type AuditService interface {
+	List(ctx context.Context, entity, entityID string, limit, offset int) ([]models.AuditEntry, error)
+}
+
+type AuditHandler struct {
+	svc AuditService
+}
+
+func NewAuditHandler(svc AuditService) *AuditHandler {
+	return &AuditHandler{svc: svc}
+}
+
+// List returns a page of the audit log of the entity query param, newest first, or of
+// one of them with the id query param
+func (a *AuditHandler) List(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	p, err := parsePage(r, defaultAuditLimit)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	entity := r.URL.Query().Get("entity")
+	if entity != models.AuditStudent && entity != models.AuditOrder {
+		ve := errors.ValidationErrs()
+		ve.Add("entity", "must be one of student, order")
+		return nil, http.StatusBadRequest, errors.InvalidParamsErr(ve.Err())
+	}
+
+	entries, err := a.svc.List(r.Context(), entity, r.URL.Query().Get("id"), p.Limit, p.Offset)
+	if err == nil {
+		return entries, http.StatusOK, nil
+	}
+	return
+}
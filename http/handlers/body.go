@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"net/http"
+
+	// Local Packages
+	errors "learn-go/errors"
+	resp "learn-go/http/response"
+)
+
+// decodeBody decodes the request body with the codec of its Content-Type
+func decodeBody(r *http.Request, v any) error {
+	codec, err := resp.Lookup(r.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if err := codec.Decode(r.Body, v); err != nil {
+		return errors.InvalidBodyErr(err)
+	}
+	return nil
+}
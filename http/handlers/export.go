@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+
+	// External Packages
+	"github.com/xuri/excelize/v2"
+)
+
+// exportFormat is a file format of the exports
+type exportFormat struct {
+	name        string
+	contentType string
+	newWriter   func(w io.Writer, sheet string, columns []string) (exportWriter, error)
+}
+
+// exportWriter writes the records of an export. Tabular formats write the rows of a
+// record, NDJSON writes the record itself.
+type exportWriter interface {
+	Write(record any, rows [][]any) error
+	Close() error
+}
+
+var exportFormats = []exportFormat{
+	{name: "csv", contentType: "text/csv", newWriter: newCSVExport},
+	{name: "ndjson", contentType: "application/x-ndjson", newWriter: newNDJSONExport},
+	{
+		name:        "xlsx",
+		contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		newWriter:   newXLSXExport,
+	},
+}
+
+// ExportMediaTypes returns the media types of the export formats
+func ExportMediaTypes() []string {
+	mediaTypes := make([]string, 0, len(exportFormats))
+	for _, format := range exportFormats {
+		mediaTypes = append(mediaTypes, format.contentType)
+	}
+	return mediaTypes
+}
+
+// exportFormatOf picks the format from the format query param, else from the Accept
+// header in the order of its media types. CSV is the default.
+func exportFormatOf(r *http.Request) (exportFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, format := range exportFormats {
+			if format.name == name {
+				return format, nil
+			}
+		}
+		ve := errors.ValidationErrs()
+		ve.Add("format", "must be one of csv, ndjson, xlsx")
+		return exportFormat{}, errors.InvalidParamsErr(ve.Err())
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return exportFormats[0], nil
+	}
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if mediaType == "*/*" || mediaType == "text/*" {
+			return exportFormats[0], nil
+		}
+		for _, format := range exportFormats {
+			if format.contentType == mediaType {
+				return format, nil
+			}
+		}
+	}
+	return exportFormat{}, errors.E(errors.Invalid,
+		"cannot export as "+accept+", accept text/csv, application/x-ndjson or the xlsx media type")
+}
+
+// export streams the records produced by iterate as a file attachment. Failures before
+// anything is sent are returned, the connection is aborted on later ones so that the
+// client does not take a truncated file for a complete one.
+func export(
+	w http.ResponseWriter,
+	format exportFormat,
+	name string,
+	columns []string,
+	iterate func(write func(record any, rows [][]any) error) error,
+) error {
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s-%s.%s"`, name, time.Now().Format("20060102"), format.name))
+
+	out := &sentWriter{w: w}
+	writer, err := format.newWriter(out, name, columns)
+	if err == nil {
+		err = iterate(writer.Write)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	if !out.sent {
+		w.Header().Del("Content-Disposition")
+		return err
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// sentWriter records whether anything was written to the response
+type sentWriter struct {
+	w    io.Writer
+	sent bool
+}
+
+func (s *sentWriter) Write(p []byte) (int, error) {
+	s.sent = s.sent || len(p) > 0
+	return s.w.Write(p)
+}
+
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w io.Writer, _ string, columns []string) (exportWriter, error) {
+	e := &csvExport{w: csv.NewWriter(w)}
+	return e, e.w.Write(columns)
+}
+
+func (e *csvExport) Write(_ any, rows [][]any) error {
+	for _, row := range rows {
+		fields := make([]string, len(row))
+		for i, cell := range row {
+			if text, ok := cell.(string); ok {
+				fields[i] = escapeFormula(text)
+			} else {
+				fields[i] = fmt.Sprint(cell)
+			}
+		}
+		if err := e.w.Write(fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *csvExport) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// escapeFormula prefixes text that a spreadsheet would evaluate as a formula with a
+// quote, so that a name like =HYPERLINK(...) opens as the text it is. Numbers are
+// written as they are, only text comes from the clients.
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func newNDJSONExport(w io.Writer, _ string, _ []string) (exportWriter, error) {
+	return &ndjsonExport{enc: json.NewEncoder(w)}, nil
+}
+
+func (e *ndjsonExport) Write(record any, _ [][]any) error {
+	return e.enc.Encode(record)
+}
+
+func (e *ndjsonExport) Close() error {
+	return nil
+}
+
+// xlsxExport writes the rows to a single sheet with a stream writer, which keeps them
+// in a temporary file once they outgrow memory. The workbook is sent on Close.
+type xlsxExport struct {
+	file   *excelize.File
+	out    io.Writer
+	row    int
+	stream *excelize.StreamWriter
+}
+
+func newXLSXExport(w io.Writer, sheet string, columns []string) (exportWriter, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+	stream, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &xlsxExport{file: file, out: w, stream: stream}
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	return e, e.Write(nil, [][]any{header})
+}
+
+// Write writes numbers as numbers and anything else as an inline string, which a
+// spreadsheet shows as text and never evaluates as a formula
+func (e *xlsxExport) Write(_ any, rows [][]any) error {
+	for _, row := range rows {
+		e.row++
+		cell, err := excelize.CoordinatesToCellName(1, e.row)
+		if err != nil {
+			return err
+		}
+		values := make([]any, len(row))
+		for i, value := range row {
+			switch value.(type) {
+			case int, int64, float64:
+				values[i] = value
+			default:
+				values[i] = fmt.Sprint(value)
+			}
+		}
+		if err := e.stream.SetRow(cell, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *xlsxExport) Close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.out)
+}
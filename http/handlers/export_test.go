@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	// External Packages
+	"github.com/xuri/excelize/v2"
+)
+
+// formulaRow is a row of client text a spreadsheet would evaluate, and numbers
+var formulaRow = []any{`=HYPERLINK("http://evil","x")`, "+1", "-2", "@SUM(A1)", "\tx", "Ann", -3, 2.5}
+
+func TestCSVExportEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newCSVExport(&buf, "students", []string{"a", "b", "c", "d", "e", "f", "g", "h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(nil, [][]any{formulaRow}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("export = %q, %v", buf.String(), err)
+	}
+	want := []string{`'=HYPERLINK("http://evil","x")`, "'+1", "'-2", "'@SUM(A1)", "'\tx", "Ann", "-3", "2.5"}
+	for i, field := range records[1] {
+		if field != want[i] {
+			t.Fatalf("field %d = %q, want %q", i, field, want[i])
+		}
+	}
+}
+
+func TestXLSXExportWritesTextAsStrings(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newXLSXExport(&buf, "students", []string{"a", "b", "c", "d", "e", "f", "g", "h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Write(nil, [][]any{formulaRow}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("OpenReader() error = %v", err)
+	}
+	defer file.Close()
+	for i, value := range formulaRow {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 2)
+		formula, _ := file.GetCellFormula("students", cell)
+		if formula != "" {
+			t.Fatalf("cell %s holds the formula %q", cell, formula)
+		}
+		cellType, _ := file.GetCellType("students", cell)
+		_, number := value.(int)
+		if _, ok := value.(float64); ok {
+			number = true
+		}
+		if !number && cellType != excelize.CellTypeInlineString {
+			t.Fatalf("cell %s has type %v, want an inline string", cell, cellType)
+		}
+		if number && cellType == excelize.CellTypeInlineString {
+			t.Fatalf("number of cell %s was written as text", cell)
+		}
+	}
+}
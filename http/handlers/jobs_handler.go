@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"net/http"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+
+	// External Packages
+	"github.com/go-chi/chi/v5"
+)
+
+type JobsService interface {
+	Enqueue(ctx context.Context, req models.JobRequest) (models.Job, error)
+	GetOne(ctx context.Context, jobID string) (models.Job, error)
+	Cancel(ctx context.Context, jobID string) (models.Job, error)
+}
+
+type JobsHandler struct {
+	svc JobsService
+}
+
+func NewJobsHandler(svc JobsService) *JobsHandler {
+	return &JobsHandler{svc: svc}
+}
+
+// Insert queues a job, its status is then polled with GetOne
+func (a *JobsHandler) Insert(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	var req models.JobRequest
+	if err := decodeBody(r, &req); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, http.StatusBadRequest, errors.ValidationFailedErr(err)
+	}
+
+	job, err := a.svc.Enqueue(r.Context(), req)
+	if err == nil {
+		return job, http.StatusAccepted, nil
+	}
+	return
+}
+
+func (a *JobsHandler) GetOne(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	jobID := chi.URLParam(r, "jobId")
+	if jobID == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("jobId")
+	}
+
+	job, err := a.svc.GetOne(r.Context(), jobID)
+	if err == nil {
+		return job, http.StatusOK, nil
+	}
+	return
+}
+
+// Cancel stops the job, a running job reports canceled once its worker interrupted it
+func (a *JobsHandler) Cancel(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	jobID := chi.URLParam(r, "jobId")
+	if jobID == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("jobId")
+	}
+
+	job, err := a.svc.Cancel(r.Context(), jobID)
+	if err == nil {
+		return job, http.StatusAccepted, nil
+	}
+	return
+}
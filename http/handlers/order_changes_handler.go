@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	resp "learn-go/http/response"
+	models "learn-go/models"
+
+	// External Packages
+	"github.com/go-chi/chi/v5"
+)
+
+type OrderChangesFeed interface {
+	Follow(ctx context.Context, lastID string, filter func(models.OrderChange) bool) (<-chan models.OrderChange, error)
+}
+
+// OrderChangesHandler streams order changes as Server-Sent Events and over websockets
+type OrderChangesHandler struct {
+	feed      OrderChangesFeed
+	heartbeat time.Duration
+	live      LiveOptions
+
+	// closing is closed on shutdown, conns tracks the open websockets
+	closing   chan struct{}
+	closeOnce sync.Once
+	conns     sync.WaitGroup
+}
+
+func NewOrderChangesHandler(feed OrderChangesFeed, heartbeat time.Duration, live LiveOptions) *OrderChangesHandler {
+	return &OrderChangesHandler{feed: feed, heartbeat: heartbeat, live: live, closing: make(chan struct{})}
+}
+
+// StreamAll streams the changes of every order
+func (a *OrderChangesHandler) StreamAll(w http.ResponseWriter, r *http.Request) {
+	a.stream(w, r, func(models.OrderChange) bool { return true })
+}
+
+// StreamOne streams the changes of the order in the path
+func (a *OrderChangesHandler) StreamOne(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "orderId")
+	a.stream(w, r, func(change models.OrderChange) bool { return change.OrderID == orderID })
+}
+
+// stream writes every change as an SSE event whose id is the resume token, a
+// reconnecting EventSource sends it back in Last-Event-ID and a malformed one is a
+// 400. Comment lines are written every heartbeat so that proxies do not close an
+// idle connection.
+func (a *OrderChangesHandler) stream(w http.ResponseWriter, r *http.Request, filter func(models.OrderChange) bool) {
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+
+	changes, err := a.feed.Follow(r.Context(), lastID, filter)
+	var e *errors.Error
+	if errors.As(err, &e) && e.Kind == errors.Invalid {
+		resp.RespondError(w, e)
+		return
+	}
+	if err != nil {
+		resp.RespondMessage(w, http.StatusInternalServerError, "cannot follow order changes")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(a.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				continue
+			}
+			_, err = fmt.Fprintf(w, "id: %s\nevent: order.%s\ndata: %s\n\n", change.ID, change.Kind, data)
+			if err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-a.closing:
+			// Clients reconnect with the id of the last change they received
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
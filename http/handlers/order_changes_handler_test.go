@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	// Local Packages
+	models "learn-go/models"
+	orders "learn-go/services/orders"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// memChanges is an OrderChangesRepository without any change
+type memChanges struct{}
+
+func (memChanges) Append(_ context.Context, change models.OrderChange) (models.OrderChange, error) {
+	return change, nil
+}
+
+func (memChanges) Retained(context.Context) (string, string, error) {
+	return "", "", nil
+}
+
+func (memChanges) Since(context.Context, string, int64) ([]models.OrderChange, error) {
+	return nil, nil
+}
+
+func (memChanges) Subscribe(ctx context.Context) <-chan models.OrderChange {
+	ch := make(chan models.OrderChange)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+func TestStreamLastEventID(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		query      string
+		wantStatus int
+	}{
+		{name: "malformed header", header: "yesterday", wantStatus: http.StatusBadRequest},
+		{name: "malformed query", query: "?last_event_id=1-x", wantStatus: http.StatusBadRequest},
+		{name: "valid header", header: "1700000000000-0", wantStatus: http.StatusOK},
+		{name: "valid query", query: "?last_event_id=1700000000000", wantStatus: http.StatusOK},
+		{name: "none", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewOrderChangesHandler(orders.NewChangeFeed(memChanges{}, zap.NewNop()), time.Minute, LiveOptions{})
+			ctx, cancel := context.WithCancel(context.Background())
+			req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/orders/stream"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			// A valid request streams until the client goes away
+			time.AfterFunc(50*time.Millisecond, cancel)
+			handler.StreamAll(rec, req)
+			cancel()
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
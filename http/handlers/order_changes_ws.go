@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	// Local Packages
+	models "learn-go/models"
+
+	// External Packages
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// LiveOptions configures the websocket endpoint of the OrderChangesHandler
+type LiveOptions struct {
+	MaxSubscriptions int
+	PingInterval     time.Duration
+	OriginPatterns   []string
+}
+
+// Messages sent by a live client
+const (
+	liveSubscribe   = "subscribe"
+	liveUnsubscribe = "unsubscribe"
+)
+
+// liveRequest subscribes to, or unsubscribes from, orders and users
+type liveRequest struct {
+	Action   string   `json:"action"`
+	OrderIDs []string `json:"order_ids"`
+	UserIDs  []string `json:"user_ids"`
+}
+
+// liveMessage is sent to the client, Type is one of "subscriptions", "change" or "error"
+type liveMessage struct {
+	Type          string              `json:"type"`
+	Subscriptions *liveSubscriptions  `json:"subscriptions,omitempty"`
+	Change        *models.OrderChange `json:"change,omitempty"`
+	Message       string              `json:"message,omitempty"`
+}
+
+type liveSubscriptions struct {
+	OrderIDs []string `json:"order_ids"`
+	UserIDs  []string `json:"user_ids"`
+}
+
+// subscriptionSet is read by the feed for every change while the client edits it
+type subscriptionSet struct {
+	mu     sync.RWMutex
+	orders map[string]struct{}
+	users  map[string]struct{}
+}
+
+func (s *subscriptionSet) matches(change models.OrderChange) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, order := s.orders[change.OrderID]
+	_, user := s.users[change.UserID]
+	return order || (change.UserID != "" && user)
+}
+
+// apply changes the set and returns an error when it would exceed max subscriptions
+func (s *subscriptionSet) apply(req liveRequest, max int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Action {
+	case liveSubscribe:
+		added := 0
+		for _, id := range req.OrderIDs {
+			if _, ok := s.orders[id]; !ok && id != "" {
+				added++
+			}
+		}
+		for _, id := range req.UserIDs {
+			if _, ok := s.users[id]; !ok && id != "" {
+				added++
+			}
+		}
+		if len(s.orders)+len(s.users)+added > max {
+			return fmt.Errorf("at most %d subscriptions are allowed per connection", max)
+		}
+		for _, id := range req.OrderIDs {
+			if id != "" {
+				s.orders[id] = struct{}{}
+			}
+		}
+		for _, id := range req.UserIDs {
+			if id != "" {
+				s.users[id] = struct{}{}
+			}
+		}
+	case liveUnsubscribe:
+		for _, id := range req.OrderIDs {
+			delete(s.orders, id)
+		}
+		for _, id := range req.UserIDs {
+			delete(s.users, id)
+		}
+	default:
+		return fmt.Errorf("unknown action %q, expected subscribe or unsubscribe", req.Action)
+	}
+	return nil
+}
+
+func (s *subscriptionSet) snapshot() *liveSubscriptions {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	subs := &liveSubscriptions{OrderIDs: []string{}, UserIDs: []string{}}
+	for id := range s.orders {
+		subs.OrderIDs = append(subs.OrderIDs, id)
+	}
+	for id := range s.users {
+		subs.UserIDs = append(subs.UserIDs, id)
+	}
+	return subs
+}
+
+// Live upgrades the request to a websocket over which the client subscribes to
+// order ids and user ids and receives their changes. The connection is pinged every
+// PingInterval and closed with "going away" when the change feed stops on shutdown.
+func (a *OrderChangesHandler) Live(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-a.closing:
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	default:
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: a.live.OriginPatterns})
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+
+	a.conns.Add(1)
+	defer a.conns.Done()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	subs := &subscriptionSet{orders: map[string]struct{}{}, users: map[string]struct{}{}}
+	changes, err := a.feed.Follow(ctx, "", subs.matches)
+	if err != nil {
+		conn.Close(websocket.StatusInternalError, "cannot follow order changes")
+		return
+	}
+
+	// Writes happen from the reader, the feed and the pinger, coder/websocket
+	// allows concurrent writers so no extra locking is required.
+	go a.readLive(ctx, cancel, conn, subs)
+	go a.pingLive(ctx, cancel, conn)
+
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				if ctx.Err() == nil {
+					conn.Close(websocket.StatusGoingAway, "server is shutting down")
+				}
+				return
+			}
+			if err := wsjson.Write(ctx, conn, liveMessage{Type: "change", Change: &change}); err != nil {
+				return
+			}
+		case <-a.closing:
+			conn.Close(websocket.StatusGoingAway, "server is shutting down")
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Shutdown ends the event streams and closes the open websockets with "going away",
+// then waits for the websockets to finish as hijacked connections are not tracked by
+// http.Server.Shutdown
+func (a *OrderChangesHandler) Shutdown(ctx context.Context) error {
+	a.closeOnce.Do(func() { close(a.closing) })
+
+	done := make(chan struct{})
+	go func() {
+		a.conns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readLive applies the client requests and acknowledges them with the resulting subscriptions
+func (a *OrderChangesHandler) readLive(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, subs *subscriptionSet) {
+	defer cancel()
+	for {
+		var req liveRequest
+		if err := wsjson.Read(ctx, conn, &req); err != nil {
+			return
+		}
+
+		msg := liveMessage{Type: "subscriptions"}
+		if err := subs.apply(req, a.live.MaxSubscriptions); err != nil {
+			msg = liveMessage{Type: "error", Message: err.Error()}
+		}
+		msg.Subscriptions = subs.snapshot()
+		if err := wsjson.Write(ctx, conn, msg); err != nil {
+			return
+		}
+	}
+}
+
+// pingLive closes the connection when a pong does not arrive within the ping interval
+func (a *OrderChangesHandler) pingLive(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn) {
+	defer cancel()
+	ticker := time.NewTicker(a.live.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, pingCancel := context.WithTimeout(ctx, a.live.PingInterval)
+			err := conn.Ping(pingCtx)
+			pingCancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
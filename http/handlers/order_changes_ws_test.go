@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	// Local Packages
+	models "learn-go/models"
+	orders "learn-go/services/orders"
+
+	// External Packages
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"go.uber.org/zap"
+)
+
+// liveChanges is an OrderChangesRepository whose live changes are sent by the test
+type liveChanges struct {
+	memChanges
+	changes chan models.OrderChange
+}
+
+func (l liveChanges) Subscribe(ctx context.Context) <-chan models.OrderChange {
+	return l.changes
+}
+
+// startLive serves the websocket endpoint over a running change feed, the test sends
+// the changes to the returned channel. handled is closed once Live returned.
+func startLive(t *testing.T, opts LiveOptions) (handler *OrderChangesHandler, url string,
+	changes chan<- models.OrderChange, handled <-chan struct{}) {
+	t.Helper()
+	ch := make(chan models.OrderChange)
+	feed := orders.NewChangeFeed(liveChanges{changes: ch}, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go feed.Run(ctx)
+
+	handler = NewOrderChangesHandler(feed, time.Minute, opts)
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		handler.Live(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return handler, ts.URL, ch, done
+}
+
+func dialLive(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+// request sends the request and returns the answer of the server
+func request(t *testing.T, conn *websocket.Conn, req liveRequest) liveMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wsjson.Write(ctx, conn, req); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return receiveLive(t, conn)
+}
+
+func receiveLive(t *testing.T, conn *websocket.Conn) liveMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var msg liveMessage
+	if err := wsjson.Read(ctx, conn, &msg); err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return msg
+}
+
+func TestLiveSubscriptions(t *testing.T) {
+	_, url, _, _ := startLive(t, LiveOptions{MaxSubscriptions: 3, PingInterval: time.Minute})
+	conn := dialLive(t, url)
+
+	tests := []struct {
+		name       string
+		req        liveRequest
+		wantType   string
+		wantError  string
+		wantOrders []string
+		wantUsers  []string
+	}{
+		{name: "subscribe", req: liveRequest{Action: liveSubscribe, OrderIDs: []string{"o1", ""}, UserIDs: []string{"u1"}},
+			wantType: "subscriptions", wantOrders: []string{"o1"}, wantUsers: []string{"u1"}},
+		{name: "over the limit", req: liveRequest{Action: liveSubscribe, OrderIDs: []string{"o2", "o3"}},
+			wantType: "error", wantError: "at most 3 subscriptions", wantOrders: []string{"o1"}, wantUsers: []string{"u1"}},
+		{name: "already subscribed ids do not count", req: liveRequest{Action: liveSubscribe, OrderIDs: []string{"o1", "o2"}},
+			wantType: "subscriptions", wantOrders: []string{"o1", "o2"}, wantUsers: []string{"u1"}},
+		{name: "unsubscribe", req: liveRequest{Action: liveUnsubscribe, OrderIDs: []string{"o1"}, UserIDs: []string{"u1"}},
+			wantType: "subscriptions", wantOrders: []string{"o2"}, wantUsers: []string{}},
+		{name: "unknown action", req: liveRequest{Action: "follow"}, wantType: "error", wantError: "unknown action",
+			wantOrders: []string{"o2"}, wantUsers: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := request(t, conn, tt.req)
+			if msg.Type != tt.wantType || !strings.Contains(msg.Message, tt.wantError) {
+				t.Fatalf("answer = %s %q, want %s %q", msg.Type, msg.Message, tt.wantType, tt.wantError)
+			}
+			slices.Sort(msg.Subscriptions.OrderIDs)
+			if !slices.Equal(msg.Subscriptions.OrderIDs, tt.wantOrders) || !slices.Equal(msg.Subscriptions.UserIDs, tt.wantUsers) {
+				t.Fatalf("subscriptions = %+v, want orders %v and users %v", msg.Subscriptions, tt.wantOrders, tt.wantUsers)
+			}
+		})
+	}
+}
+
+func TestLiveReceivesTheSubscribedChanges(t *testing.T) {
+	_, url, changes, _ := startLive(t, LiveOptions{MaxSubscriptions: 10, PingInterval: time.Minute})
+	conn := dialLive(t, url)
+	request(t, conn, liveRequest{Action: liveSubscribe, OrderIDs: []string{"o1"}, UserIDs: []string{"u1"}})
+
+	sent := []models.OrderChange{
+		{ID: "1-1", Kind: models.OrderUpdated, OrderID: "o2", UserID: "u2"},
+		{ID: "1-2", Kind: models.OrderUpdated, OrderID: "o1", UserID: "u2"},
+		{ID: "1-3", Kind: models.OrderDeleted, OrderID: "o3"},
+		{ID: "1-4", Kind: models.OrderInserted, OrderID: "o4", UserID: "u1"},
+	}
+	for _, change := range sent {
+		changes <- change
+	}
+
+	// The change of o1 and the one of an order of u1
+	for _, want := range []string{"1-2", "1-4"} {
+		msg := receiveLive(t, conn)
+		if msg.Type != "change" || msg.Change == nil || msg.Change.ID != want {
+			t.Fatalf("message = %+v, want the change %s", msg, want)
+		}
+	}
+}
+
+func TestLiveGoesAwayOnShutdown(t *testing.T) {
+	handler, url, _, handled := startLive(t, LiveOptions{MaxSubscriptions: 10, PingInterval: time.Minute})
+	conn := dialLive(t, url)
+	request(t, conn, liveRequest{Action: liveSubscribe, OrderIDs: []string{"o1"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- handler.Shutdown(ctx) }()
+
+	var msg liveMessage
+	err := wsjson.Read(ctx, conn, &msg)
+	if websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Fatalf("Read() error = %v, want the connection closed with going away", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Live() did not return after the shutdown")
+	}
+
+	// New connections are refused from then on
+	rec := httptest.NewRecorder()
+	handler.Live(rec, httptest.NewRequest(http.MethodGet, "/orders/live", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d after the shutdown, want 503", rec.Code)
+	}
+}
+
+func TestLiveClosesWithoutPongs(t *testing.T) {
+	_, url, _, handled := startLive(t, LiveOptions{MaxSubscriptions: 10, PingInterval: 20 * time.Millisecond})
+	// A client that never reads never answers the pings
+	dialLive(t, url)
+
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the websocket stayed open without pongs")
+	}
+}
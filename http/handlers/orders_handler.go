@@ -3,13 +3,13 @@ package handlers
 import (
 	// Go Internal Packages
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 
 	// Local Packages
 	errors "learn-go/errors"
 	models "learn-go/models"
+	patch "learn-go/patch"
 
 	// External Packages
 	"github.com/go-chi/chi/v5"
@@ -18,7 +18,9 @@ import (
 type OrdersService interface {
 	Insert(ctx context.Context, order models.Order) (string, error)
 	GetOne(ctx context.Context, orderID string) (models.Order, error)
+	List(ctx context.Context, userID string, fn func(order models.Order) error) error
 	Update(ctx context.Context, order models.Order) error
+	Patch(ctx context.Context, orderID string, modify func(current models.Order) (models.Order, error)) (models.Order, error)
 	Delete(ctx context.Context, orderID string) error
 }
 
@@ -43,10 +45,86 @@ func (a *OrdersHandler) GetOne(w http.ResponseWriter, r *http.Request) (response
 	return
 }
 
+// defaultOrdersLimit is the page size of List when no limit is given
+const defaultOrdersLimit = 100
+
+// List returns a page of orders in order id order, optionally only the orders of the
+// user_id query param. The after query param starts the page after the order with that
+// id, which pages consistently while orders are written, unlike offset.
+func (a *OrdersHandler) List(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	p, err := parsePage(r, defaultOrdersLimit)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	after := r.URL.Query().Get("after")
+
+	orders := []models.Order{}
+	collect := window(p, func(order models.Order) error {
+		orders = append(orders, order)
+		return nil
+	})
+	err = a.svc.List(r.Context(), r.URL.Query().Get("user_id"), func(order models.Order) error {
+		if after != "" && order.ID <= after {
+			return nil
+		}
+		return collect(order)
+	})
+	if err == nil || errors.Is(err, errPageFull) {
+		return orders, http.StatusOK, nil
+	}
+	return
+}
+
+// Export streams the orders as CSV, NDJSON or XLSX, see exportFormatOf, filtered like
+// List. Tabular formats have a row per line item, NDJSON a line per order.
+func (a *OrdersHandler) Export(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	format, err := exportFormatOf(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	p, err := parsePage(r, 0)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	columns := []string{
+		"order_id", "user_id", "order_status", "created_at", "updated_at", "shipped_at", "delivered_at",
+		"item_id", "quantity", "price",
+	}
+	err = export(w, format, "orders", columns, func(write func(any, [][]any) error) error {
+		err := a.svc.List(r.Context(), r.URL.Query().Get("user_id"), window(p, func(order models.Order) error {
+			return write(order, orderRows(order))
+		}))
+		if errors.Is(err, errPageFull) {
+			return nil
+		}
+		return err
+	})
+	// The body is written, status 0 keeps ToHTTPHandlerFunc from writing the header again
+	return nil, 0, err
+}
+
+// orderRows flattens the order into a row per line item, an order without line items
+// still gets a row
+func orderRows(order models.Order) [][]any {
+	head := []any{order.ID, order.UserID, order.OrderStatus, order.CreatedAt, order.UpdatedAt,
+		order.ShippedAt, order.DeliveredAt}
+	if len(order.LineItems) == 0 {
+		return [][]any{append(head, "", "", "")}
+	}
+
+	rows := make([][]any, 0, len(order.LineItems))
+	for _, item := range order.LineItems {
+		row := append(append([]any{}, head...), item.ItemID, item.Quantity, item.Price)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
 func (a *OrdersHandler) Insert(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
 	var order models.Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		return nil, http.StatusBadRequest, errors.InvalidBodyErr(err)
+	if err := decodeBody(r, &order); err != nil {
+		return nil, http.StatusBadRequest, err
 	}
 	if err := order.ValidateCreation(); err != nil {
 		return nil, http.StatusBadRequest, errors.ValidationFailedErr(err)
@@ -67,8 +145,8 @@ func (a *OrdersHandler) Update(w http.ResponseWriter, r *http.Request) (response
 	}
 
 	var updatedOrder models.Order
-	if err := json.NewDecoder(r.Body).Decode(&updatedOrder); err != nil {
-		return nil, http.StatusBadRequest, errors.InvalidBodyErr(err)
+	if err := decodeBody(r, &updatedOrder); err != nil {
+		return nil, http.StatusBadRequest, err
 	}
 
 	if err := updatedOrder.ValidateUpdate(orderID); err != nil {
@@ -83,6 +161,34 @@ func (a *OrdersHandler) Update(w http.ResponseWriter, r *http.Request) (response
 	return
 }
 
+// Patch applies a merge patch or a JSON Patch, chosen by the Content-Type, to the order
+func (a *OrdersHandler) Patch(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	orderID := chi.URLParam(r, "orderId")
+	if orderID == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("orderId")
+	}
+
+	p, err := readPatch(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	order, err := a.svc.Patch(r.Context(), orderID, func(current models.Order) (models.Order, error) {
+		updated, err := patch.To(p, current)
+		if err != nil {
+			return updated, err
+		}
+		if err := updated.ValidateUpdate(orderID); err != nil {
+			return updated, errors.ValidationFailedErr(err)
+		}
+		return updated, nil
+	})
+	if err == nil {
+		return order, http.StatusOK, nil
+	}
+	return
+}
+
 func (a *OrdersHandler) Delete(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
 	orderID := chi.URLParam(r, "orderId")
 	if orderID == "" {
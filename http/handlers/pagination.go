@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"net/http"
+	"strconv"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+// maxPageLimit bounds the limit query param of the list endpoints
+const maxPageLimit = 1000
+
+// page is the window of a list selected by the limit and offset query params
+type page struct {
+	Limit  int
+	Offset int
+}
+
+// parsePage reads limit and offset from the query, limit defaults to defaultLimit
+// and a defaultLimit of zero means no limit
+func parsePage(r *http.Request, defaultLimit int) (page, error) {
+	p := page{Limit: defaultLimit}
+	ve := errors.ValidationErrs()
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxPageLimit {
+			ve.Add("limit", "must be an integer between 1 and 1000")
+		}
+		p.Limit = limit
+	}
+	if raw := r.URL.Query().Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			ve.Add("offset", "must be a non negative integer")
+		}
+		p.Offset = offset
+	}
+	if err := ve.Err(); err != nil {
+		return page{}, errors.InvalidParamsErr(err)
+	}
+	return p, nil
+}
+
+// apply returns the items in the window
+func apply[T any](p page, items []T) []T {
+	if p.Offset >= len(items) {
+		return []T{}
+	}
+	items = items[p.Offset:]
+	if p.Limit > 0 && p.Limit < len(items) {
+		items = items[:p.Limit]
+	}
+	return items
+}
+
+// errPageFull stops an iteration once the window is filled
+var errPageFull = errors.NewError("page is full")
+
+// window wraps fn to be called only for the items of an iteration in the window, it
+// returns errPageFull once the window is filled
+func window[T any](p page, fn func(T) error) func(T) error {
+	skipped, taken := 0, 0
+	return func(item T) error {
+		if skipped < p.Offset {
+			skipped++
+			return nil
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+		taken++
+		if taken == p.Limit {
+			return errPageFull
+		}
+		return nil
+	}
+}
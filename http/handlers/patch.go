@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"io"
+	"net/http"
+
+	// Local Packages
+	errors "learn-go/errors"
+	resp "learn-go/http/response"
+	patch "learn-go/patch"
+)
+
+// readPatch parses the body as the patch format named by the Content-Type. A body of
+// another typed codec, e.g. MessagePack, is taken as a merge patch. XML carries no
+// value types to merge and is not supported.
+func readPatch(r *http.Request) (patch.Patch, error) {
+	contentType := r.Header.Get("Content-Type")
+	codec, err := resp.Lookup(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if !codec.Typed() {
+		return nil, errors.E(errors.Unsupported, "cannot patch with a body of type "+contentType)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.InvalidBodyErr(err)
+	}
+	if codec != resp.JSON {
+		if body, err = resp.Transcode(codec, body); err != nil {
+			return nil, errors.InvalidBodyErr(err)
+		}
+		contentType = "application/merge-patch+json"
+	}
+	return patch.Parse(contentType, body)
+}
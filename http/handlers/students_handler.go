@@ -3,59 +3,129 @@ package handlers
 import (
 	// Go Internal Packages
 	"context"
-	"encoding/json"
 	"fmt"
+	"iter"
 	"net/http"
+	"slices"
+	"strconv"
+	"time"
 
 	// Local Packages
+	audit "learn-go/audit"
 	errors "learn-go/errors"
 	models "learn-go/models"
+	patch "learn-go/patch"
 
 	// External Packages
 	"github.com/go-chi/chi/v5"
 )
 
 type StudentsService interface {
-	GetOneStudent(context.Context, string) (*models.StudentModel, error)
-	GetAllStudents(context.Context) (*[]models.StudentModel, error)
+	GetOneStudent(context.Context, string, bool) (*models.StudentModel, error)
+	GetAllStudents(context.Context, bool) (*[]models.StudentModel, error)
+	IterateStudents(context.Context, bool, func(models.StudentModel) error) error
 	InsertStudent(context.Context, models.StudentModel) error
 	UpdateStudent(context.Context, string, models.StudentModel) error
+	PatchStudent(context.Context, string, func(models.StudentModel) (models.StudentModel, error)) (models.StudentModel, error)
 	DeleteStudent(context.Context, string) error
+	RestoreStudent(context.Context, string) (models.StudentModel, error)
+	ImportStudents(context.Context, iter.Seq2[models.ImportRecord, error], bool) (models.ImportReport, error)
 }
 
 type StudentsHandler struct {
-	svc StudentsService
+	svc                  StudentsService
+	includeDeletedActors []string
 }
 
-func NewStudentsHandler(svc StudentsService) *StudentsHandler {
-	return &StudentsHandler{svc: svc}
+// NewStudentsHandler creates the handler, only the includeDeletedActors may read the
+// deleted students with the include_deleted query param
+func NewStudentsHandler(svc StudentsService, includeDeletedActors []string) *StudentsHandler {
+	return &StudentsHandler{svc: svc, includeDeletedActors: includeDeletedActors}
 }
 
+// GetAll returns every student, or the window selected by the limit and offset query params.
+// Deleted students are listed with the include_deleted query param.
 func (a *StudentsHandler) GetAll(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
-	students, err := a.svc.GetAllStudents(r.Context())
+	p, err := parsePage(r, 0)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	includeDeleted, err := a.includeDeleted(r)
+	if err != nil {
+		return
+	}
+
+	students, err := a.svc.GetAllStudents(r.Context(), includeDeleted)
 	if err == nil {
-		return students, http.StatusOK, nil
+		return apply(p, *students), http.StatusOK, nil
 	}
 	return
 }
 
+// GetOne returns the student, a deleted one only with the include_deleted query param
 func (a *StudentsHandler) GetOne(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
 	rollNo := chi.URLParam(r, "rollNo")
 	if rollNo == "" {
 		return nil, http.StatusBadRequest, errors.EmptyParamErr("rollNo")
 	}
+	includeDeleted, err := a.includeDeleted(r)
+	if err != nil {
+		return
+	}
 
-	student, err := a.svc.GetOneStudent(r.Context(), rollNo)
+	student, err := a.svc.GetOneStudent(r.Context(), rollNo, includeDeleted)
 	if err == nil {
 		return student, http.StatusOK, nil
 	}
 	return
 }
 
+// Export streams the students as CSV, NDJSON or XLSX, see exportFormatOf. The limit and
+// offset query params select a window like they do for GetAll, include_deleted adds the
+// deleted students and the columns of their deletion.
+func (a *StudentsHandler) Export(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	format, err := exportFormatOf(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	p, err := parsePage(r, 0)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	includeDeleted, err := a.includeDeleted(r)
+	if err != nil {
+		return
+	}
+
+	columns := []string{"roll_no", "name", "gender", "mail_id"}
+	if includeDeleted {
+		columns = append(columns, "deleted_at", "deleted_by")
+	}
+	err = export(w, format, "students", columns, func(write func(any, [][]any) error) error {
+		err := a.svc.IterateStudents(r.Context(), includeDeleted, window(p, func(student models.StudentModel) error {
+			row := []any{student.RollNo, student.Name, student.Gender, student.MailID}
+			if includeDeleted {
+				deletedAt := ""
+				if student.DeletedAt != nil {
+					deletedAt = student.DeletedAt.UTC().Format(time.RFC3339)
+				}
+				row = append(row, deletedAt, student.DeletedBy)
+			}
+			return write(student, [][]any{row})
+		}))
+		if errors.Is(err, errPageFull) {
+			return nil
+		}
+		return err
+	})
+	// The body is written, status 0 keeps ToHTTPHandlerFunc from writing the header again
+	return nil, 0, err
+}
+
 func (a *StudentsHandler) Insert(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
 	var student models.StudentModel
-	if err := json.NewDecoder(r.Body).Decode(&student); err != nil {
-		return nil, http.StatusBadRequest, errors.InvalidBodyErr(err)
+	if err := decodeBody(r, &student); err != nil {
+		return nil, http.StatusBadRequest, err
 	}
 	if err := student.Validate(); err != nil {
 		return nil, http.StatusBadRequest, errors.ValidationFailedErr(err)
@@ -75,8 +145,8 @@ func (a *StudentsHandler) Update(w http.ResponseWriter, r *http.Request) (respon
 	}
 
 	var updatedStudent models.StudentModel
-	if err := json.NewDecoder(r.Body).Decode(&updatedStudent); err != nil {
-		return nil, http.StatusBadRequest, errors.InvalidBodyErr(err)
+	if err := decodeBody(r, &updatedStudent); err != nil {
+		return nil, http.StatusBadRequest, err
 	}
 
 	if err := updatedStudent.Validate(); err != nil {
@@ -90,6 +160,34 @@ func (a *StudentsHandler) Update(w http.ResponseWriter, r *http.Request) (respon
 	return
 }
 
+// Patch applies a merge patch or a JSON Patch, chosen by the Content-Type, to the student
+func (a *StudentsHandler) Patch(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	rollNo := chi.URLParam(r, "rollNo")
+	if rollNo == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("rollNo")
+	}
+
+	p, err := readPatch(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	student, err := a.svc.PatchStudent(r.Context(), rollNo, func(current models.StudentModel) (models.StudentModel, error) {
+		updated, err := patch.To(p, current)
+		if err != nil {
+			return updated, err
+		}
+		if err := updated.Validate(); err != nil {
+			return updated, errors.ValidationFailedErr(err)
+		}
+		return updated, nil
+	})
+	if err == nil {
+		return student, http.StatusOK, nil
+	}
+	return
+}
+
 func (a *StudentsHandler) Delete(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
 	rollNo := chi.URLParam(r, "rollNo")
 	if rollNo == "" {
@@ -102,3 +200,37 @@ func (a *StudentsHandler) Delete(w http.ResponseWriter, r *http.Request) (respon
 	}
 	return
 }
+
+// Restore undoes the deletion of the student
+func (a *StudentsHandler) Restore(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	rollNo := chi.URLParam(r, "rollNo")
+	if rollNo == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("rollNo")
+	}
+
+	student, err := a.svc.RestoreStudent(r.Context(), rollNo)
+	if err == nil {
+		return student, http.StatusOK, nil
+	}
+	return
+}
+
+// includeDeleted reads the include_deleted query param, false when it is absent. Asking
+// for the deleted students is forbidden unless the actor of the request, see
+// audit.RequestFrom, is one of the includeDeletedActors.
+func (a *StudentsHandler) includeDeleted(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("include_deleted")
+	if raw == "" {
+		return false, nil
+	}
+	includeDeleted, err := strconv.ParseBool(raw)
+	if err != nil {
+		ve := errors.ValidationErrs()
+		ve.Add("include_deleted", "must be true or false")
+		return false, errors.InvalidParamsErr(ve.Err())
+	}
+	if includeDeleted && !slices.Contains(a.includeDeletedActors, audit.RequestFrom(r.Context()).Actor) {
+		return false, errors.E(errors.Forbidden, "not allowed to read deleted students")
+	}
+	return includeDeleted, nil
+}
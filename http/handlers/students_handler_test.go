@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	// Local Packages
+	audit "learn-go/audit"
+	errors "learn-go/errors"
+	models "learn-go/models"
+)
+
+// memStudents is the read side of a StudentsService over a fixed list of students
+type memStudents struct {
+	StudentsService
+	students []models.StudentModel
+}
+
+func (m memStudents) visible(includeDeleted bool) []models.StudentModel {
+	students := []models.StudentModel{}
+	for _, student := range m.students {
+		if includeDeleted || student.DeletedAt == nil {
+			students = append(students, student)
+		}
+	}
+	return students
+}
+
+func (m memStudents) GetAllStudents(_ context.Context, includeDeleted bool) (*[]models.StudentModel, error) {
+	students := m.visible(includeDeleted)
+	return &students, nil
+}
+
+func (m memStudents) IterateStudents(_ context.Context, includeDeleted bool, fn func(models.StudentModel) error) error {
+	for _, student := range m.visible(includeDeleted) {
+		if err := fn(student); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newDeletedStudents() memStudents {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return memStudents{students: []models.StudentModel{
+		{RollNo: "1", Name: "Ann", Gender: "female", MailID: "ann@example.com"},
+		{RollNo: "2", Name: "Bob", Gender: "male", MailID: "bob@example.com", DeletedAt: &deletedAt, DeletedBy: "admin"},
+	}}
+}
+
+func TestIncludeDeletedIsLimitedToAllowedActors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		actor    string
+		wantErr  errors.Kind
+		wantRows int
+	}{
+		{name: "without the param", query: "", wantRows: 1},
+		{name: "anonymous", query: "?include_deleted=true", wantErr: errors.Forbidden},
+		{name: "other actor", query: "?include_deleted=true", actor: "ann", wantErr: errors.Forbidden},
+		{name: "allowed actor", query: "?include_deleted=true", actor: "admin", wantRows: 2},
+		{name: "false needs no permission", query: "?include_deleted=false", wantRows: 1},
+		{name: "malformed", query: "?include_deleted=maybe", actor: "admin", wantErr: errors.Invalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewStudentsHandler(newDeletedStudents(), []string{"admin"})
+			r := httptest.NewRequest(http.MethodGet, "/students/"+tt.query, nil)
+			r = r.WithContext(audit.WithRequest(r.Context(), audit.Request{Actor: tt.actor}))
+
+			response, _, err := h.GetAll(httptest.NewRecorder(), r)
+			if tt.wantErr != errors.Other {
+				if !errors.IsKind(err, tt.wantErr) {
+					t.Fatalf("GetAll() error = %v, want kind %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetAll() error = %v", err)
+			}
+			if students := response.([]models.StudentModel); len(students) != tt.wantRows {
+				t.Fatalf("GetAll() = %d students, want %d", len(students), tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestExportIncludesDeletedStudents(t *testing.T) {
+	h := NewStudentsHandler(newDeletedStudents(), []string{"admin"})
+	export := func(query, actor string) (string, error) {
+		r := httptest.NewRequest(http.MethodGet, "/students/export?format=csv"+query, nil)
+		r = r.WithContext(audit.WithRequest(r.Context(), audit.Request{Actor: actor}))
+		w := httptest.NewRecorder()
+		_, _, err := h.Export(w, r)
+		return w.Body.String(), err
+	}
+
+	body, err := export("", "")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	want := "roll_no,name,gender,mail_id\n1,Ann,female,ann@example.com\n"
+	if body != want {
+		t.Fatalf("Export() = %q, want %q", body, want)
+	}
+
+	body, err = export("&include_deleted=true", "admin")
+	if err != nil {
+		t.Fatalf("Export() with include_deleted error = %v", err)
+	}
+	want = "roll_no,name,gender,mail_id,deleted_at,deleted_by\n" +
+		"1,Ann,female,ann@example.com,,\n" +
+		"2,Bob,male,bob@example.com,2024-01-02T03:04:05Z,admin\n"
+	if body != want {
+		t.Fatalf("Export() with include_deleted = %q, want %q", body, want)
+	}
+
+	if body, err := export("&include_deleted=true", "ann"); !errors.IsKind(err, errors.Forbidden) || strings.Contains(body, "Bob") {
+		t.Fatalf("Export() with include_deleted by another actor = %q, %v, want Forbidden", body, err)
+	}
+}
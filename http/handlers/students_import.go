@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	// Local Packages
+	errors "learn-go/errors"
+	resp "learn-go/http/response"
+	models "learn-go/models"
+)
+
+// Formats of a students import
+const (
+	importCSV    = "csv"
+	importNDJSON = "ndjson"
+)
+
+// maxImportLine bounds the length of an NDJSON line
+const maxImportLine = 1 << 20
+
+// Import inserts the students of a CSV or NDJSON upload and reports the outcome of every
+// row. The body is read as it streams in, either raw or as the file part of a multipart
+// form. The format comes from the format query param, else from the Content-Type or the
+// file name. With dry_run=true nothing is inserted. An import stopped by an unreadable
+// upload or a failed batch is answered with the report of the rows read so far.
+func (a *StudentsHandler) Import(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			ve := errors.ValidationErrs()
+			ve.Add("dry_run", "must be true or false")
+			return nil, http.StatusBadRequest, errors.InvalidParamsErr(ve.Err())
+		}
+	}
+
+	body, format, err := importBody(r)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	var records iter.Seq2[models.ImportRecord, error]
+	switch format {
+	case importCSV:
+		records = csvStudents(body)
+	case importNDJSON:
+		records = ndjsonStudents(body)
+	default:
+		return nil, http.StatusBadRequest, errors.E(errors.Invalid,
+			"unknown import format, send text/csv or application/x-ndjson or set the format param")
+	}
+
+	report, err := a.svc.ImportStudents(r.Context(), records, dryRun)
+	if err == nil {
+		return report, http.StatusOK, nil
+	}
+	if report.Total == 0 {
+		return nil, 0, err
+	}
+	// Batches may be inserted before the import stopped, the report tells which rows were
+	status, reason := abortedImport(err)
+	report.Error += ": " + reason
+	return report, status, err
+}
+
+// abortedImport returns the status and the reason of an import stopped by err, like
+// resp.RespondErrorWith would answer the error
+func abortedImport(err error) (int, string) {
+	e, ok := err.(*errors.Error)
+	if !ok {
+		return http.StatusInternalServerError, "internal error"
+	}
+	switch e.Kind {
+	case errors.Invalid:
+		if e.WrappedErr != nil {
+			return http.StatusBadRequest, e.WrappedErr.Error()
+		}
+		return http.StatusBadRequest, e.Message
+	case errors.TooLarge:
+		return http.StatusRequestEntityTooLarge, e.Message
+	default:
+		return http.StatusInternalServerError, e.Message
+	}
+}
+
+// importBody returns the uploaded file and its format, the file part of a multipart form
+// is read in place of the body
+func importBody(r *http.Request) (io.Reader, string, error) {
+	format := r.URL.Query().Get("format")
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		if format == "" {
+			format = formatOf(mediaType, "")
+		}
+		return r.Body, format, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", errors.InvalidBodyErr(err)
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, "", errors.EmptyParamErr("file")
+		}
+		if err != nil {
+			return nil, "", errors.InvalidBodyErr(err)
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		if format == "" {
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			format = formatOf(partType, part.FileName())
+		}
+		return part, format, nil
+	}
+}
+
+func formatOf(mediaType, fileName string) string {
+	switch mediaType {
+	case "text/csv":
+		return importCSV
+	case "application/x-ndjson", "application/jsonl", "application/json-lines":
+		return importNDJSON
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return importCSV
+	case ".ndjson", ".jsonl":
+		return importNDJSON
+	}
+	return ""
+}
+
+// csvStudents reads students from CSV, the header names the columns by their json
+// names in any order. Rows that cannot be parsed are yielded with Err set.
+func csvStudents(body io.Reader) iter.Seq2[models.ImportRecord, error] {
+	return func(yield func(models.ImportRecord, error) bool) {
+		reader := csv.NewReader(body)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		header, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			yield(models.ImportRecord{}, errors.InvalidBodyErr(err))
+			return
+		}
+
+		// Spreadsheets may start the file with a byte order mark
+		columns := map[string]int{}
+		for i, name := range header {
+			columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+		}
+		ve := errors.ValidationErrs()
+		for _, name := range []string{"roll_no", "name", "gender", "mail_id"} {
+			if _, ok := columns[name]; !ok {
+				ve.Add(name, "column is missing from the header")
+			}
+		}
+		if err := ve.Err(); err != nil {
+			yield(models.ImportRecord{}, errors.E(errors.Invalid, "invalid csv header", err))
+			return
+		}
+
+		for {
+			fields, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				if !yield(models.ImportRecord{Line: parseErr.StartLine, Err: parseErr.Err}, nil) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(models.ImportRecord{}, errors.InvalidBodyErr(err))
+				return
+			}
+
+			line, _ := reader.FieldPos(0)
+			record := models.ImportRecord{Line: line}
+			if len(fields) != len(header) {
+				record.Err = fmt.Errorf("expected %d fields, got %d", len(header), len(fields))
+			} else {
+				record.Student = models.StudentModel{
+					RollNo: fields[columns["roll_no"]],
+					Name:   fields[columns["name"]],
+					Gender: fields[columns["gender"]],
+					MailID: fields[columns["mail_id"]],
+				}
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ndjsonStudents reads a student per line, blank lines are skipped. Lines that are not
+// a student object are yielded with Err set.
+func ndjsonStudents(body io.Reader) iter.Seq2[models.ImportRecord, error] {
+	return func(yield func(models.ImportRecord, error) bool) {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+
+		for line := 1; scanner.Scan(); line++ {
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+
+			record := models.ImportRecord{Line: line}
+			if err := resp.JSON.Decode(bytes.NewReader(data), &record.Student); err != nil {
+				record.Err = err
+			}
+			if !yield(record, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(models.ImportRecord{}, errors.InvalidBodyErr(err))
+		}
+	}
+}
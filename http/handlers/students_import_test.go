@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"context"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+)
+
+// line is a decoded record without its Err, which tests only check for presence
+type line struct {
+	Line    int
+	Student models.StudentModel
+	Failed  bool
+}
+
+func collect(t *testing.T, records iter.Seq2[models.ImportRecord, error]) ([]line, error) {
+	t.Helper()
+	lines := []line{}
+	for record, err := range records {
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line{Line: record.Line, Student: record.Student, Failed: record.Err != nil})
+	}
+	return lines, nil
+}
+
+func TestCSVStudents(t *testing.T) {
+	ann := models.StudentModel{RollNo: "1", Name: "Ann", Gender: "female", MailID: "ann@example.com"}
+	tests := []struct {
+		name    string
+		body    string
+		want    []line
+		wantErr bool
+	}{
+		{name: "empty", body: "", want: []line{}},
+		{
+			name: "header only",
+			body: "roll_no,name,gender,mail_id\n",
+			want: []line{},
+		},
+		{
+			name: "columns in any order after a byte order mark",
+			body: "\ufeffname, mail_id,roll_no,gender\nAnn,ann@example.com,1,female\n",
+			want: []line{{Line: 2, Student: ann}},
+		},
+		{
+			name: "quoted field over two lines",
+			body: "roll_no,name,gender,mail_id\n1,\"Ann\nBea\",female,ann@example.com\n2,Bob,male,bob@example.com\n",
+			want: []line{
+				{Line: 2, Student: models.StudentModel{RollNo: "1", Name: "Ann\nBea", Gender: "female", MailID: "ann@example.com"}},
+				{Line: 4, Student: models.StudentModel{RollNo: "2", Name: "Bob", Gender: "male", MailID: "bob@example.com"}},
+			},
+		},
+		{
+			name: "rows that cannot be parsed are reported and skipped",
+			body: "roll_no,name,gender,mail_id\n1,Ann\n2,\"Bo\"b,male,bob@example.com\n1,Ann,female,ann@example.com\n",
+			want: []line{{Line: 2, Failed: true}, {Line: 3, Failed: true}, {Line: 4, Student: ann}},
+		},
+		{
+			name:    "missing column",
+			body:    "roll_no,name,gender\n1,Ann,female\n",
+			want:    []line{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := collect(t, csvStudents(strings.NewReader(tt.body)))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("csvStudents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.IsKind(err, errors.Invalid) {
+				t.Fatalf("csvStudents() error = %v, want Invalid", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("csvStudents() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNDJSONStudents(t *testing.T) {
+	ann := models.StudentModel{RollNo: "1", Name: "Ann", Gender: "female", MailID: "ann@example.com"}
+	annJSON := `{"roll_no":"1","name":"Ann","gender":"female","mail_id":"ann@example.com"}`
+	tests := []struct {
+		name    string
+		body    string
+		want    []line
+		wantErr bool
+	}{
+		{name: "empty", body: "", want: []line{}},
+		{name: "without a final newline", body: annJSON, want: []line{{Line: 1, Student: ann}}},
+		{
+			name: "blank lines are skipped but counted",
+			body: annJSON + "\n\n  \r\n" + annJSON + "\r\n",
+			want: []line{{Line: 1, Student: ann}, {Line: 4, Student: ann}},
+		},
+		{
+			name: "lines that are not a student are reported",
+			body: "[1]\n{\"roll_no\":\"1\",\"age\":3}\n" + annJSON + " {}\n{\n" + annJSON + "\n",
+			want: []line{{Line: 1, Failed: true}, {Line: 2, Failed: true}, {Line: 3, Failed: true},
+				{Line: 4, Failed: true}, {Line: 5, Student: ann}},
+		},
+		{
+			name:    "line too long",
+			body:    strings.Repeat(" ", maxImportLine+1),
+			want:    []line{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := collect(t, ndjsonStudents(strings.NewReader(tt.body)))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("ndjsonStudents() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// The decode errors of a failed line are not compared
+			for i := range got {
+				if got[i].Failed {
+					got[i].Student = models.StudentModel{}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ndjsonStudents() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportBody(t *testing.T) {
+	form := func(fields map[string]string, fileName, fileType, file string) (string, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for name, value := range fields {
+			_ = mw.WriteField(name, value)
+		}
+		if fileName != "" {
+			header := textproto.MIMEHeader{}
+			header.Set("Content-Disposition", `form-data; name="file"; filename="`+fileName+`"`)
+			if fileType != "" {
+				header.Set("Content-Type", fileType)
+			}
+			part, _ := mw.CreatePart(header)
+			_, _ = part.Write([]byte(file))
+		}
+		_ = mw.Close()
+		return mw.FormDataContentType(), buf.String()
+	}
+	byName, byNameBody := form(nil, "Students.CSV", "application/octet-stream", "data")
+	byType, byTypeBody := form(nil, "students", "application/x-ndjson", "data")
+	noFile, noFileBody := form(map[string]string{"note": "ignored"}, "", "", "")
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		wantFormat  string
+		wantErr     bool
+	}{
+		{name: "csv", contentType: "text/csv; charset=utf-8", body: "data", wantFormat: importCSV},
+		{name: "ndjson", contentType: "application/x-ndjson", body: "data", wantFormat: importNDJSON},
+		{name: "jsonl", contentType: "application/jsonl", body: "data", wantFormat: importNDJSON},
+		{name: "param wins", query: "?format=ndjson", contentType: "text/csv", body: "data", wantFormat: importNDJSON},
+		{name: "unknown", contentType: "application/octet-stream", body: "data", wantFormat: ""},
+		{name: "multipart by file name", contentType: byName, body: byNameBody, wantFormat: importCSV},
+		{name: "multipart by part type", contentType: byType, body: byTypeBody, wantFormat: importNDJSON},
+		{name: "multipart without a file", contentType: noFile, body: noFileBody, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/students/import"+tt.query, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			file, format, err := importBody(r)
+			if tt.wantErr {
+				if !errors.IsKind(err, errors.Invalid) {
+					t.Fatalf("importBody() error = %v, want Invalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("importBody() error = %v", err)
+			}
+			var data bytes.Buffer
+			_, _ = data.ReadFrom(file)
+			if format != tt.wantFormat || data.String() != "data" {
+				t.Fatalf("importBody() = %q, %q, want %q, %q", data.String(), format, "data", tt.wantFormat)
+			}
+		})
+	}
+}
+
+// importingStudents reports the rows of every import it is given
+type importingStudents struct {
+	StudentsService
+	dryRun bool
+}
+
+func (m *importingStudents) ImportStudents(
+	_ context.Context,
+	records iter.Seq2[models.ImportRecord, error],
+	dryRun bool,
+) (models.ImportReport, error) {
+	m.dryRun = dryRun
+	report := models.ImportReport{DryRun: dryRun}
+	for record, err := range records {
+		if err != nil {
+			report.Error = "import aborted"
+			return report, err
+		}
+		report.Rows = append(report.Rows, models.ImportRow{Line: record.Line, RollNo: record.Student.RollNo})
+		report.Total++
+	}
+	return report, nil
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		wantDryRun  bool
+		wantErr     bool
+	}{
+		{name: "import", contentType: "text/csv"},
+		{name: "dry run", query: "?dry_run=true", contentType: "text/csv", wantDryRun: true},
+		{name: "malformed dry run", query: "?dry_run=maybe", contentType: "text/csv", wantErr: true},
+		{name: "unknown format", contentType: "text/plain", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &importingStudents{}
+			h := NewStudentsHandler(svc, nil)
+			body := "roll_no,name,gender,mail_id\n1,Ann,female,ann@example.com\n"
+			r := httptest.NewRequest(http.MethodPost, "/students/import"+tt.query, strings.NewReader(body))
+			r.Header.Set("Content-Type", tt.contentType)
+
+			response, status, err := h.Import(httptest.NewRecorder(), r)
+			if tt.wantErr {
+				if !errors.IsKind(err, errors.Invalid) || status != http.StatusBadRequest {
+					t.Fatalf("Import() = %d, %v, want 400 Invalid", status, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			report := response.(models.ImportReport)
+			if svc.dryRun != tt.wantDryRun || len(report.Rows) != 1 || report.Rows[0].RollNo != "1" {
+				t.Fatalf("Import() = %+v with dry run %v, want the one row with dry run %v",
+					report, svc.dryRun, tt.wantDryRun)
+			}
+		})
+	}
+}
+
+func TestImportAnswersAStoppedImportWithItsReport(t *testing.T) {
+	body := "roll_no,name,gender,mail_id\n1,Ann,female,ann@example.com\n2,Bob,male,bob@example.com\n"
+	tests := []struct {
+		name       string
+		limit      int64
+		wantStatus int
+		wantRows   int
+		wantError  string
+	}{
+		{name: "cut off upload", limit: int64(len(body)) - 10, wantStatus: http.StatusRequestEntityTooLarge,
+			wantRows: 1, wantError: "import aborted: request body exceeds the limit of"},
+		{name: "nothing read", limit: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewStudentsHandler(&importingStudents{}, nil)
+			r := httptest.NewRequest(http.MethodPost, "/students/import", strings.NewReader(body))
+			r.Header.Set("Content-Type", "text/csv")
+			r.Body = http.MaxBytesReader(httptest.NewRecorder(), r.Body, tt.limit)
+
+			response, status, err := h.Import(httptest.NewRecorder(), r)
+			if !errors.IsKind(err, errors.TooLarge) {
+				t.Fatalf("Import() error = %v, want TooLarge", err)
+			}
+			if tt.wantRows == 0 {
+				if response != nil {
+					t.Fatalf("Import() = %+v, want only the error without rows", response)
+				}
+				return
+			}
+			report := response.(models.ImportReport)
+			if status != tt.wantStatus || len(report.Rows) != tt.wantRows || !strings.HasPrefix(report.Error, tt.wantError) {
+				t.Fatalf("Import() = %d, %+v, want %d with %d rows and the error %q",
+					status, report, tt.wantStatus, tt.wantRows, tt.wantError)
+			}
+		})
+	}
+}
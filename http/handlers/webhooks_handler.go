@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+
+	// External Packages
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultDeliveriesLimit = 50
+
+type WebhooksService interface {
+	Insert(ctx context.Context, webhook models.WebhookSubscription) (models.WebhookSubscription, error)
+	GetAll(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetOne(ctx context.Context, webhookID string) (models.WebhookSubscription, error)
+	Update(ctx context.Context, webhook models.WebhookSubscription) error
+	Delete(ctx context.Context, webhookID string) error
+	GetDeliveries(ctx context.Context, webhookID string, limit int64) ([]models.WebhookDelivery, error)
+}
+
+type WebhooksHandler struct {
+	svc WebhooksService
+}
+
+func NewWebhooksHandler(svc WebhooksService) *WebhooksHandler {
+	return &WebhooksHandler{svc: svc}
+}
+
+func (a *WebhooksHandler) GetAll(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	webhooks, err := a.svc.GetAll(r.Context())
+	if err == nil {
+		return webhooks, http.StatusOK, nil
+	}
+	return
+}
+
+func (a *WebhooksHandler) GetOne(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	webhookID := chi.URLParam(r, "webhookId")
+	if webhookID == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("webhookId")
+	}
+
+	webhook, err := a.svc.GetOne(r.Context(), webhookID)
+	if err == nil {
+		return webhook, http.StatusOK, nil
+	}
+	return
+}
+
+func (a *WebhooksHandler) Insert(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	var webhook models.WebhookSubscription
+	if err := decodeBody(r, &webhook); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, http.StatusBadRequest, errors.ValidationFailedErr(err)
+	}
+
+	created, err := a.svc.Insert(r.Context(), webhook)
+	if err == nil {
+		return created, http.StatusCreated, nil
+	}
+	return
+}
+
+func (a *WebhooksHandler) Update(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	webhookID := chi.URLParam(r, "webhookId")
+	if webhookID == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("webhookId")
+	}
+
+	var webhook models.WebhookSubscription
+	if err := decodeBody(r, &webhook); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, http.StatusBadRequest, errors.ValidationFailedErr(err)
+	}
+
+	webhook.ID = webhookID
+	err = a.svc.Update(r.Context(), webhook)
+	if err == nil {
+		return map[string]string{"message": fmt.Sprintf("sucessfully updated webhook : %s", webhookID)},
+			http.StatusOK, nil
+	}
+	return
+}
+
+func (a *WebhooksHandler) Delete(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	webhookID := chi.URLParam(r, "webhookId")
+	if webhookID == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("webhookId")
+	}
+
+	err = a.svc.Delete(r.Context(), webhookID)
+	if err == nil {
+		return map[string]string{"message": fmt.Sprintf("sucessfully deleted webhook : %s", webhookID)},
+			http.StatusOK, nil
+	}
+	return
+}
+
+func (a *WebhooksHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) (response any, status int, err error) {
+	webhookID := chi.URLParam(r, "webhookId")
+	if webhookID == "" {
+		return nil, http.StatusBadRequest, errors.EmptyParamErr("webhookId")
+	}
+
+	limit := int64(defaultDeliveriesLimit)
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || limit <= 0 {
+			ve := errors.ValidationErrs()
+			ve.Add("limit", "must be a positive integer")
+			return nil, http.StatusBadRequest, errors.InvalidParamsErr(ve.Err())
+		}
+	}
+
+	deliveries, err := a.svc.GetDeliveries(r.Context(), webhookID, limit)
+	if err == nil {
+		return deliveries, http.StatusOK, nil
+	}
+	return
+}
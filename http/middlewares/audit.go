@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"net/http"
+
+	// Local Packages
+	audit "learn-go/audit"
+
+	// External Packages
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// AuditRequest stamps the request context with who makes the changes recorded in the
+// audit log, see audit.Actor, and the request ID. The value of actorHeader is only
+// taken from the clients whose certificate is one of trustedClients.
+func AuditRequest(actorHeader string, trustedClients []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := audit.Request{
+				Actor:     audit.Actor(r.TLS, r.Header.Get(actorHeader), trustedClients),
+				RequestID: middleware.GetReqID(r.Context()),
+			}
+			next.ServeHTTP(w, r.WithContext(audit.WithRequest(r.Context(), req)))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	// Local Packages
+	audit "learn-go/audit"
+)
+
+func TestAuditRequestTrustsActorHeaderOnlyFromTrustedClients(t *testing.T) {
+	verified := func(commonName string) *tls.ConnectionState {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+	tests := []struct {
+		name  string
+		state *tls.ConnectionState
+		want  string
+	}{
+		{name: "plain http", want: audit.Anonymous},
+		{name: "untrusted client", state: verified("svc-a"), want: "svc-a"},
+		{name: "trusted proxy", state: verified("proxy"), want: "ann"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got audit.Request
+			handler := AuditRequest("X-Actor", []string{"proxy"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = audit.RequestFrom(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodDelete, "/students/1", nil)
+			req.Header.Set("X-Actor", "ann")
+			req.TLS = tt.state
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got.Actor != tt.want {
+				t.Fatalf("actor = %q, want %q", got.Actor, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	// External Packages
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is implemented by the writers of every supported content coding
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders pools the writers by content coding, a zstd writer keeps large windows
+// around and is costly to create per response
+var encoders = map[string]*sync.Pool{
+	"gzip": {New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+	"zstd": {New: func() any {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}},
+	"br": {New: func() any {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}},
+}
+
+// incompressible lists the media types that are compressed themselves, or streamed to
+// clients that must see every event as soon as it is flushed
+var incompressible = []string{
+	"application/gzip",
+	"application/zip",
+	"application/zstd",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"audio/",
+	"image/",
+	"text/event-stream",
+	"video/",
+}
+
+// Compress compresses the responses of at least minSize bytes with the first of the
+// encodings the client accepts, by the quality of the Accept-Encoding header and then
+// in the order of encodings. Responses are held back until they reach minSize, a
+// handler flushing earlier commits to compressing them. Bodies that are encoded
+// already, incompressible media types and upgraded connections are passed through.
+func Compress(minSize int, encodings []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"), encodings)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// acceptedEncoding returns the encoding to respond with, empty when the client accepts
+// none of them. The * coding stands for the encodings the header does not name.
+func acceptedEncoding(acceptEncoding string, encodings []string) string {
+	qs := map[string]float64{}
+	for _, value := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(value), ";")
+		q := 1.0
+		if raw, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		qs[strings.ToLower(strings.TrimSpace(coding))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := qs[encoding]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the response until it is known to be large enough, then
+// commits the header and writes the body either through the encoder or as it is
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status    int
+	buf       []byte
+	committed bool
+	enc       encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 || cw.committed {
+		return
+	}
+	cw.status = status
+	// Informational responses are sent as they come, the final one follows
+	if status < http.StatusOK {
+		cw.status = 0
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.commit(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.committed {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minSize {
+			return len(p), nil
+		}
+		if err := cw.commit(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far, compressed unless the response cannot be
+func (cw *compressWriter) Flush() {
+	if !cw.committed {
+		if cw.status == 0 {
+			cw.WriteHeader(http.StatusOK)
+		}
+		_ = cw.commit(true)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// commit writes the header and the buffered body, through an encoder when compress is
+// set and the response can be compressed
+func (cw *compressWriter) commit(compress bool) error {
+	cw.committed = true
+	if compress && cw.compressible() {
+		h := cw.Header()
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		cw.enc = encoders[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	for _, prefix := range incompressible {
+		if strings.HasPrefix(mediaType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// close ends the response, a response still buffered is below minSize and sent as it is
+func (cw *compressWriter) close() {
+	if !cw.committed {
+		if cw.status == 0 {
+			// Nothing was written, net/http sends the default response
+			return
+		}
+		_ = cw.commit(false)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Close()
+		cw.enc.Reset(nil)
+		encoders[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
@@ -0,0 +1,217 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	// External Packages
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+var testEncodings = []string{"zstd", "br", "gzip"}
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{acceptEncoding: "", want: ""},
+		{acceptEncoding: "identity", want: ""},
+		{acceptEncoding: "gzip", want: "gzip"},
+		{acceptEncoding: "GZIP, deflate", want: "gzip"},
+		{acceptEncoding: "gzip, br", want: "br"},
+		{acceptEncoding: "gzip, br;q=0.5", want: "gzip"},
+		{acceptEncoding: "gzip;q=0", want: ""},
+		{acceptEncoding: "*", want: "zstd"},
+		{acceptEncoding: "*;q=0.1, gzip;q=0.5", want: "gzip"},
+		{acceptEncoding: "*, zstd;q=0", want: "br"},
+		{acceptEncoding: "gzip;q=high, br", want: "br"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := acceptedEncoding(tt.acceptEncoding, testEncodings); got != tt.want {
+				t.Fatalf("acceptedEncoding() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// decode returns the body of the response, decompressed by its Content-Encoding
+func decode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding := rec.Header().Get("Content-Encoding"); encoding {
+	case "":
+		return rec.Body.String()
+	case "gzip":
+		r, err = gzip.NewReader(rec.Body)
+	case "zstd":
+		var dec *zstd.Decoder
+		dec, err = zstd.NewReader(rec.Body)
+		if err == nil {
+			defer dec.Close()
+		}
+		r = dec
+	case "br":
+		r = brotli.NewReader(rec.Body)
+	default:
+		t.Fatalf("unexpected Content-Encoding %q", encoding)
+	}
+	if err != nil {
+		t.Fatalf("undecodable %s body: %v", rec.Header().Get("Content-Encoding"), err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("undecodable %s body: %v", rec.Header().Get("Content-Encoding"), err)
+	}
+	return string(body)
+}
+
+func TestCompressThreshold(t *testing.T) {
+	const minSize = 100
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		size           int
+		writes         int // the body is written in this many parts
+		want           string
+	}{
+		{name: "below the threshold", acceptEncoding: "gzip", size: minSize - 1, writes: 1, want: ""},
+		{name: "at the threshold", acceptEncoding: "gzip", size: minSize, writes: 1, want: "gzip"},
+		{name: "reaching it over writes", acceptEncoding: "br", size: 3 * minSize, writes: 30, want: "br"},
+		{name: "zstd", acceptEncoding: "zstd", size: 2 * minSize, writes: 2, want: "zstd"},
+		{name: "not accepted", acceptEncoding: "", size: 2 * minSize, writes: 1, want: ""},
+		{name: "incompressible", acceptEncoding: "gzip", contentType: "image/png", size: 2 * minSize, writes: 1,
+			want: ""},
+		{name: "event stream", acceptEncoding: "gzip", contentType: "text/event-stream", size: 2 * minSize,
+			writes: 1, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.Repeat("a", tt.size)
+			handler := Compress(minSize, testEncodings)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				w.Header().Set("Content-Length", "1")
+				w.WriteHeader(http.StatusCreated)
+				part := len(body) / tt.writes
+				for i := 0; i < tt.writes; i++ {
+					end := (i + 1) * part
+					if i == tt.writes-1 {
+						end = len(body)
+					}
+					_, _ = w.Write([]byte(body[i*part : end]))
+				}
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+			if tt.want != "" && rec.Header().Get("Content-Length") != "" {
+				t.Fatalf("a compressed response kept the Content-Length of its handler")
+			}
+			if got := decode(t, rec); got != body {
+				t.Fatalf("body = %d bytes, want the %d written", len(got), len(body))
+			}
+			if rec.Header().Get("Vary") != "Accept-Encoding" {
+				t.Fatalf("Vary = %q, want Accept-Encoding", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCompressFlushCommitsToCompressing(t *testing.T) {
+	handler := Compress(1024, testEncodings)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(" second"))
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" || !rec.Flushed {
+		t.Fatalf("Content-Encoding = %q, flushed %v, want a flushed gzip response",
+			rec.Header().Get("Content-Encoding"), rec.Flushed)
+	}
+	if got := decode(t, rec); got != "first second" {
+		t.Fatalf("body = %q, want %q", got, "first second")
+	}
+}
+
+func TestCompressPassesThrough(t *testing.T) {
+	large := bytes.Repeat([]byte("a"), 1024)
+	tests := []struct {
+		name         string
+		method       string
+		handler      http.HandlerFunc
+		wantStatus   int
+		wantEncoding string
+		wantBody     []byte
+	}{
+		{
+			name:   "no content",
+			method: http.MethodDelete,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:   "encoded by the handler",
+			method: http.MethodGet,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "gzip")
+				_, _ = w.Write(large)
+			},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "gzip",
+			wantBody:     large,
+		},
+		{
+			name:   "head",
+			method: http.MethodHead,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(large)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   large,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			Compress(10, testEncodings)(tt.handler).ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus || rec.Header().Get("Content-Encoding") != tt.wantEncoding {
+				t.Fatalf("response = %d %q, want %d %q", rec.Code, rec.Header().Get("Content-Encoding"),
+					tt.wantStatus, tt.wantEncoding)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), tt.wantBody) {
+				t.Fatalf("body = %d bytes, want the %d written as they are", rec.Body.Len(), len(tt.wantBody))
+			}
+		})
+	}
+}
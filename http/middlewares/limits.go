@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"net/http"
+	"strings"
+
+	// Local Packages
+	xerrors "learn-go/errors"
+	resp "learn-go/http/response"
+
+	// External Packages
+	"github.com/go-chi/chi/v5"
+)
+
+// BodyLimit caps the request bodies at limit bytes, limits overrides the cap of single
+// routes by "METHOD /pattern" relative to basePath, e.g. "POST /students/import". A body
+// declaring a larger Content-Length is answered with a 413 before it is read, any other
+// is cut off by http.MaxBytesReader and whoever reads it reports the 413, see
+// errors.InvalidBodyErr. It must run before the OpenAPI validator, which reads bodies.
+func BodyLimit(basePath string, limit int64, limits map[string]int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			max := limit
+			if routeLimit, ok := limits[routeOf(r, basePath)]; ok {
+				max = routeLimit
+			}
+
+			if r.ContentLength > max {
+				codec, ok := resp.Negotiate(r.Header.Get("Accept"))
+				if !ok {
+					codec = resp.JSON
+				}
+				resp.RespondErrorWith(w, codec, xerrors.BodyTooLargeErr(max).(*xerrors.Error))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, max)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeOf returns the route of the request as "METHOD /pattern" relative to basePath,
+// the key of the per route settings. It is empty when no route matches.
+func routeOf(r *http.Request, basePath string) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil || rctx.Routes == nil {
+		return ""
+	}
+	pattern := rctx.Routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+	if pattern == "" {
+		return ""
+	}
+	return r.Method + " " + strings.TrimPrefix(pattern, basePath)
+}
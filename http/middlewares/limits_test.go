@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	// Local Packages
+	xerrors "learn-go/errors"
+	resp "learn-go/http/response"
+
+	// External Packages
+	"github.com/go-chi/chi/v5"
+)
+
+func TestBodyLimit(t *testing.T) {
+	// The handlers read the whole body and report a body cut off like the real ones do
+	read := func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			resp.RespondError(w, xerrors.InvalidBodyErr(err).(*xerrors.Error))
+			return
+		}
+		resp.RespondMessage(w, http.StatusOK, fmt.Sprintf("read %d bytes", len(body)))
+	}
+	router := chi.NewRouter()
+	router.Route("/learn-go/v1", func(r chi.Router) {
+		r.Use(BodyLimit("/learn-go/v1", 10, map[string]int64{"POST /students/import": 100}))
+		r.Post("/students/", read)
+		r.Post("/students/import", read)
+		r.Put("/students/{rollNo}", read)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		size       int
+		chunked    bool // sent without a Content-Length
+		wantStatus int
+	}{
+		{name: "within the limit", method: http.MethodPost, path: "/learn-go/v1/students/", size: 10,
+			wantStatus: http.StatusOK},
+		{name: "declared over the limit", method: http.MethodPost, path: "/learn-go/v1/students/", size: 11,
+			wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "streamed over the limit", method: http.MethodPost, path: "/learn-go/v1/students/", size: 11,
+			chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "route with a larger limit", method: http.MethodPost, path: "/learn-go/v1/students/import", size: 100,
+			wantStatus: http.StatusOK},
+		{name: "over the limit of its route", method: http.MethodPost, path: "/learn-go/v1/students/import",
+			size: 101, chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "route with a path param", method: http.MethodPut, path: "/learn-go/v1/students/1", size: 11,
+			wantStatus: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.NewReader(strings.Repeat("a", tt.size))
+			r := httptest.NewRequest(tt.method, tt.path, body)
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBodyLimitAnswersInTheNegotiatedCodec(t *testing.T) {
+	ran := false
+	handler := BodyLimit("", 10, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/students/", strings.NewReader(strings.Repeat("a", 11)))
+	r.Header.Set("Accept", "application/xml")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	if ran || rec.Code != http.StatusRequestEntityTooLarge || rec.Header().Get("Content-Type") != "application/xml" {
+		t.Fatalf("response = %d %s with the handler run %v, want a 413 in XML before the handler",
+			rec.Code, rec.Header().Get("Content-Type"), ran)
+	}
+	if !strings.Contains(rec.Body.String(), "request body exceeds the limit of 10 bytes") {
+		t.Fatalf("body = %q, want the limit", rec.Body.String())
+	}
+}
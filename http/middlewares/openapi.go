@@ -0,0 +1,217 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	// Local Packages
+	xerrors "learn-go/errors"
+	resp "learn-go/http/response"
+
+	// External Packages
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// OpenAPIValidator validates the path params, query params and body of every request
+// matching an operation of the spec before it reaches the handlers. Failures are
+// answered with a 400 listing the fields, bodies are reported by JSON pointer
+// (e.g. /line_items/2/quantity) and params by name. Requests matching no operation
+// are passed through so the router answers them.
+func OpenAPIValidator(spec *openapi3.T) (func(next http.Handler) http.Handler, error) {
+	router, err := gorillamux.NewRouter(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{MultiError: true, AuthenticationFunc: openapi3filter.NoopAuthenticationFunc}
+	streamOptions := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		ExcludeRequestBody: true,
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Validation errors are written in the negotiated media type, JSON when none is
+			// acceptable as the handlers answer that with a 406 themselves
+			codec, ok := resp.Negotiate(r.Header.Get("Accept"))
+			if !ok {
+				codec = resp.JSON
+			}
+
+			// Bodies are validated as JSON, see jsonBody. The validator reads the body and
+			// sets a replayable copy on the request it was given. Bodies of operations that
+			// do not take JSON, such as uploads, are streamed and checked by their handlers.
+			req, opts := r, options
+			var body []byte
+			switch {
+			case !takesJSON(route.Operation):
+				opts = streamOptions
+			case route.Operation.RequestBody != nil:
+				req, body, err = jsonBody(r)
+				if err != nil {
+					resp.RespondErrorWith(w, codec, err.(*xerrors.Error))
+					return
+				}
+				if req == nil {
+					req, opts = r, streamOptions
+				}
+			}
+			err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    opts,
+			})
+			if body != nil {
+				// The handlers decode the body in its own media type
+				r.Body, r.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+			} else {
+				r.Body, r.GetBody, r.ContentLength = req.Body, req.GetBody, req.ContentLength
+			}
+			if err != nil {
+				resp.RespondErrorWith(w, codec, toValidationErr(err).(*xerrors.Error))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// jsonBody returns the request whose body the validator checks. JSON bodies are checked
+// as they are, bodies of the other typed codecs are re-encoded as JSON and returned with
+// their original body to restore. XML bodies carry no value types to check against the
+// schemas, a nil request is returned for them and the handlers validate the models they
+// decode them into.
+func jsonBody(r *http.Request) (*http.Request, []byte, error) {
+	codec, err := resp.Lookup(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !codec.Typed() {
+		return nil, nil, nil
+	}
+	if codec == resp.JSON {
+		if strings.Contains(r.Header.Get("Content-Type"), "json") {
+			return r, nil, nil
+		}
+		req := r.Clone(r.Context())
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, nil, xerrors.InvalidBodyErr(err)
+	}
+	transcoded, err := resp.Transcode(codec, body)
+	if err != nil {
+		return nil, nil, xerrors.InvalidBodyErr(err)
+	}
+	req := r.Clone(r.Context())
+	req.Header.Set("Content-Type", "application/json")
+	req.Body, req.ContentLength = io.NopCloser(bytes.NewReader(transcoded)), int64(len(transcoded))
+	return req, body, nil
+}
+
+// takesJSON reports whether the operation has no body or accepts a JSON one
+func takesJSON(operation *openapi3.Operation) bool {
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return true
+	}
+	return operation.RequestBody.Value.Content.Get("application/json") != nil
+}
+
+// toValidationErr flattens the errors of openapi3filter into field errors, a body
+// that cannot be decoded is reported as an invalid body
+func toValidationErr(err error) error {
+	ve := xerrors.ValidationErrs()
+	for _, err := range flatten(err) {
+		var reqErr *openapi3filter.RequestError
+		if !errors.As(err, &reqErr) {
+			ve.Add("", err.Error())
+			continue
+		}
+
+		if reqErr.Parameter != nil {
+			for _, cause := range flatten(reqErr.Err) {
+				ve.Add(reqErr.Parameter.Name, reason(cause))
+			}
+			continue
+		}
+
+		if reqErr.RequestBody != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(reqErr.Err, &tooLarge) {
+				return xerrors.InvalidBodyErr(tooLarge)
+			}
+			var parseErr *openapi3filter.ParseError
+			if errors.As(reqErr.Err, &parseErr) {
+				return xerrors.InvalidBodyErr(parseErr)
+			}
+			if reqErr.Err == nil {
+				ve.Add("/", reqErr.Reason)
+				continue
+			}
+			// A value failing a keyword can be reported more than once, e.g. exclusiveMinimum
+			reported := map[string]bool{}
+			for _, cause := range flatten(reqErr.Err) {
+				if field := pointer(cause); !reported[field] {
+					reported[field] = true
+					ve.Add(field, reason(cause))
+				}
+			}
+			continue
+		}
+
+		ve.Add("", reqErr.Error())
+	}
+	return xerrors.ValidationFailedErr(ve.Err())
+}
+
+// flatten expands the nested multi errors produced by MultiError validation
+func flatten(err error) []error {
+	// Not errors.As, RequestError unwraps to the multi error of its causes
+	me, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, e := range me {
+		errs = append(errs, flatten(e)...)
+	}
+	return errs
+}
+
+// pointer returns the JSON pointer of the value a schema error is about
+func pointer(err error) string {
+	var schemaErr *openapi3.SchemaError
+	if !errors.As(err, &schemaErr) {
+		return "/"
+	}
+	tokens := schemaErr.JSONPointer()
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+	}
+	return "/" + strings.Join(tokens, "/")
+}
+
+func reason(err error) string {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) && schemaErr.Reason != "" {
+		return schemaErr.Reason
+	}
+	return err.Error()
+}
@@ -0,0 +1,181 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	// Local Packages
+	xerrors "learn-go/errors"
+	openapi "learn-go/http/openapi"
+	resp "learn-go/http/response"
+)
+
+// validated returns the validator over the spec of the server mounted at /learn-go/v1,
+// its handler echoes the body it receives with its Content-Length
+func validated(t *testing.T) http.Handler {
+	t.Helper()
+	spec, err := openapi.Load(context.Background(), "/learn-go/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := OpenAPIValidator(spec)
+	if err != nil {
+		t.Fatalf("OpenAPIValidator() error = %v", err)
+	}
+	return validator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			resp.RespondError(w, xerrors.InvalidBodyErr(err).(*xerrors.Error))
+			return
+		}
+		w.Header().Set("X-Content-Length", strconv.FormatInt(r.ContentLength, 10))
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write(body)
+	}))
+}
+
+// encode returns v in the media type of the codec
+func encode(t *testing.T, codec resp.Codec, v any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newOrder(lineItems ...map[string]any) map[string]any {
+	return map[string]any{"user_id": "u1", "order_status": "placed", "line_items": lineItems}
+}
+
+func lineItem(quantity int, price float64) map[string]any {
+	return map[string]any{"item_id": "i1", "quantity": quantity, "price": price}
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	valid := newOrder(lineItem(1, 2.5))
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        []byte
+		wantStatus  int
+		wantFields  []string // fields of the validation errors of a 400
+	}{
+		{name: "valid body", method: http.MethodPost, path: "/orders/", contentType: "application/json",
+			body: encode(t, resp.JSON, valid), wantStatus: http.StatusTeapot},
+		{name: "body field by pointer", method: http.MethodPost, path: "/orders/", contentType: "application/json",
+			body:       encode(t, resp.JSON, newOrder(lineItem(1, 1), lineItem(1, 1), lineItem(0, 1))),
+			wantStatus: http.StatusBadRequest, wantFields: []string{"/line_items/2/quantity"}},
+		{name: "keyword failing twice reported once", method: http.MethodPost, path: "/orders/",
+			contentType: "application/json", body: encode(t, resp.JSON, newOrder(lineItem(1, 0))),
+			wantStatus: http.StatusBadRequest, wantFields: []string{"/line_items/0/price"}},
+		{name: "every failing field", method: http.MethodPost, path: "/orders/", contentType: "application/json",
+			body:       []byte(`{"order_status": "placed", "line_items": []}`),
+			wantStatus: http.StatusBadRequest, wantFields: []string{"/line_items", "/user_id"}},
+		{name: "params by name", method: http.MethodGet, path: "/students/?limit=0&offset=-1",
+			wantStatus: http.StatusBadRequest, wantFields: []string{"limit", "offset"}},
+		{name: "malformed json", method: http.MethodPost, path: "/orders/", contentType: "application/json",
+			body: []byte(`{"user_id":`), wantStatus: http.StatusBadRequest},
+		{name: "unsupported media type", method: http.MethodPost, path: "/orders/", contentType: "text/plain",
+			body: []byte("order"), wantStatus: http.StatusUnsupportedMediaType},
+		{name: "messagepack body", method: http.MethodPost, path: "/orders/",
+			contentType: resp.MessagePack.MediaTypes()[0], body: encode(t, resp.MessagePack, valid),
+			wantStatus: http.StatusTeapot},
+		{name: "invalid messagepack body", method: http.MethodPost, path: "/orders/",
+			contentType: resp.MessagePack.MediaTypes()[0], body: encode(t, resp.MessagePack, newOrder(lineItem(0, 1))),
+			wantStatus: http.StatusBadRequest, wantFields: []string{"/line_items/0/quantity"}},
+		{name: "invalid cbor body", method: http.MethodPost, path: "/orders/", contentType: "application/cbor",
+			body:       encode(t, resp.CBOR, map[string]any{"order_status": "placed", "line_items": []any{lineItem(1, 1)}}),
+			wantStatus: http.StatusBadRequest, wantFields: []string{"/user_id"}},
+		{name: "undecodable cbor body", method: http.MethodPost, path: "/orders/", contentType: "application/cbor",
+			body: []byte{0xff, 0xff}, wantStatus: http.StatusBadRequest},
+		{name: "xml body left to the handler", method: http.MethodPost, path: "/orders/",
+			contentType: "application/xml", body: []byte("<order></order>"), wantStatus: http.StatusTeapot},
+		{name: "upload left to the handler", method: http.MethodPost, path: "/students/import?dry_run=true",
+			contentType: "text/csv", body: []byte("roll_no,name\n1,Jane\n"), wantStatus: http.StatusTeapot},
+		{name: "param of an upload", method: http.MethodPost, path: "/students/import?format=xlsx",
+			contentType: "text/csv", body: []byte("roll_no\n"), wantStatus: http.StatusBadRequest,
+			wantFields: []string{"format"}},
+		{name: "unknown route", method: http.MethodPost, path: "/unknown", contentType: "application/json",
+			body: []byte(`{`), wantStatus: http.StatusTeapot},
+	}
+	handler := validated(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/learn-go/v1"+tt.path, bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code == http.StatusTeapot {
+				// The handler gets the body as it was sent
+				length := rec.Header().Get("X-Content-Length")
+				if !bytes.Equal(rec.Body.Bytes(), tt.body) || length != strconv.Itoa(len(tt.body)) {
+					t.Fatalf("handler got %q with a Content-Length of %s, want the body sent", rec.Body.Bytes(), length)
+				}
+				return
+			}
+			if tt.wantFields == nil {
+				return
+			}
+			var got struct {
+				ValidationErrors []xerrors.FieldError `json:"validation_errors"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("undecodable response %s: %v", rec.Body.String(), err)
+			}
+			var fields []string
+			for _, fe := range got.ValidationErrors {
+				fields = append(fields, fe.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("fields = %v, want %v: %s", fields, tt.wantFields, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidatorPassesTooLargeBodies(t *testing.T) {
+	handler := BodyLimit("/learn-go/v1", 16, nil)(validated(t))
+	body := newOrder(lineItem(1, 1), lineItem(2, 2))
+	for _, codec := range []resp.Codec{resp.JSON, resp.MessagePack, resp.CBOR} {
+		t.Run(codec.MediaTypes()[0], func(t *testing.T) {
+			// Streamed, the body is cut off while the validator reads it
+			r := httptest.NewRequest(http.MethodPost, "/learn-go/v1/orders/", bytes.NewReader(encode(t, codec, body)))
+			r.ContentLength = -1
+			r.Header.Set("Content-Type", codec.MediaTypes()[0])
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOpenAPIValidatorAnswersInTheNegotiatedCodec(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/learn-go/v1/students/?limit=0", nil)
+	r.Header.Set("Accept", "application/cbor")
+	rec := httptest.NewRecorder()
+	validated(t).ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/cbor" {
+		t.Fatalf("response = %d %s, want a 400 in CBOR", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecurityOptions are the headers set on every response, an empty one is not sent,
+// see config.SecurityHeaders
+type SecurityOptions struct {
+	// HSTSMaxAge enables Strict-Transport-Security on HTTPS responses when greater than zero
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// TrustForwardedProto takes a request with X-Forwarded-Proto https as sent over HTTPS,
+	// clients can set the header themselves unless a proxy in front of the server does
+	TrustForwardedProto   bool
+	FrameOptions          string
+	ReferrerPolicy        string
+	ContentSecurityPolicy string
+}
+
+// SecurityHeaders sets the security headers on every response. HSTS is only sent on
+// requests that came over HTTPS, to the server or, when its X-Forwarded-Proto is
+// trusted, to the proxy in front of it. A plain HTTP response cannot pin the scheme. Routes serving pages override the policy with
+// ContentSecurityPolicy.
+func SecurityHeaders(opts SecurityOptions) func(next http.Handler) http.Handler {
+	hsts := ""
+	if opts.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(opts.HSTSMaxAge/time.Second), 10)
+		if opts.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if opts.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			if opts.FrameOptions != "" {
+				h.Set("X-Frame-Options", opts.FrameOptions)
+			}
+			if opts.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", opts.ReferrerPolicy)
+			}
+			if opts.ContentSecurityPolicy != "" {
+				h.Set("Content-Security-Policy", opts.ContentSecurityPolicy)
+			}
+			if hsts != "" && isHTTPS(r, opts.TrustForwardedProto) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ContentSecurityPolicy replaces the policy set by SecurityHeaders for the routes it wraps
+func ContentSecurityPolicy(policy string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Security-Policy", policy)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isHTTPS(r *http.Request, trustForwardedProto bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return trustForwardedProto && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
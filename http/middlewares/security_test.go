@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	all := SecurityOptions{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		ContentSecurityPolicy: "default-src 'none'",
+	}
+	tests := []struct {
+		name           string
+		opts           SecurityOptions
+		tls            bool
+		forwardedProto string
+		want           map[string]string
+	}{
+		{
+			name: "every header over https",
+			opts: all,
+			tls:  true,
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "no-referrer",
+				"Content-Security-Policy":   "default-src 'none'",
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains; preload",
+			},
+		},
+		{
+			name: "no hsts over plain http",
+			opts: all,
+			want: map[string]string{"Strict-Transport-Security": "", "X-Frame-Options": "DENY"},
+		},
+		{
+			name:           "hsts behind a trusted tls terminating proxy",
+			opts:           SecurityOptions{HSTSMaxAge: time.Hour, TrustForwardedProto: true},
+			forwardedProto: "HTTPS",
+			want:           map[string]string{"Strict-Transport-Security": "max-age=3600"},
+		},
+		{
+			name:           "plain http behind a trusted proxy",
+			opts:           SecurityOptions{HSTSMaxAge: time.Hour, TrustForwardedProto: true},
+			forwardedProto: "http",
+			want:           map[string]string{"Strict-Transport-Security": ""},
+		},
+		{
+			name:           "forwarded proto of an untrusted client",
+			opts:           SecurityOptions{HSTSMaxAge: time.Hour},
+			forwardedProto: "https",
+			want:           map[string]string{"Strict-Transport-Security": ""},
+		},
+		{
+			name: "empty options only set nosniff",
+			tls:  true,
+			want: map[string]string{
+				"X-Content-Type-Options":    "nosniff",
+				"X-Frame-Options":           "",
+				"Referrer-Policy":           "",
+				"Content-Security-Policy":   "",
+				"Strict-Transport-Security": "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.forwardedProto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+			}
+			rec := httptest.NewRecorder()
+			SecurityHeaders(tt.opts)(http.NotFoundHandler()).ServeHTTP(rec, r)
+
+			for name, want := range tt.want {
+				if got := rec.Header().Get(name); got != want {
+					t.Fatalf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestContentSecurityPolicyOverridesTheDefault(t *testing.T) {
+	handler := SecurityHeaders(SecurityOptions{ContentSecurityPolicy: "default-src 'none'"})(
+		ContentSecurityPolicy("default-src 'self'")(http.NotFoundHandler()))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	if got := rec.Header().Values("Content-Security-Policy"); len(got) != 1 || got[0] != "default-src 'self'" {
+		t.Fatalf("Content-Security-Policy = %q, want only the policy of the route", got)
+	}
+}
@@ -0,0 +1,50 @@
+package middlewares
+
+import (
+	// Go Internal Packages
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeouts runs the handlers with timeout on the request context, timeouts overrides it
+// for single routes by "METHOD /pattern" relative to basePath and a zero timeout
+// disables it. Handlers see the timeout passing through their context. The streaming
+// routes, e.g. uploads, exports and event streams, run without it and without the read
+// and write deadlines of the server so they are not cut off midway.
+func Timeouts(
+	basePath string,
+	timeout time.Duration,
+	timeouts map[string]time.Duration,
+	streaming []string,
+) func(next http.Handler) http.Handler {
+	streams := make(map[string]bool, len(streaming))
+	for _, route := range streaming {
+		streams[route] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeOf(r, basePath)
+			if streams[route] {
+				rc := http.NewResponseController(w)
+				_ = rc.SetReadDeadline(time.Time{})
+				_ = rc.SetWriteDeadline(time.Time{})
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			d := timeout
+			if routeTimeout, ok := timeouts[route]; ok {
+				d = routeTimeout
+			}
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
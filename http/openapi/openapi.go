@@ -0,0 +1,118 @@
+package openapi
+
+import (
+	// Go Internal Packages
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	// External Packages
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+//go:embed swagger.html
+var swaggerHTML []byte
+
+// swaggerUI holds the script and the stylesheet of swagger-ui-dist 5.18.2 (Apache-2.0),
+// the docs page loads them from this server
+//
+//go:embed swagger-ui
+var swaggerUI embed.FS
+
+// SwaggerUICSP is the content security policy of the docs page, it loads the UI from
+// this server, runs the inline script of the page by its hash and fetches openapi.json.
+// The UI sets inline styles.
+var SwaggerUICSP = swaggerUICSP()
+
+func swaggerUICSP() string {
+	script := regexp.MustCompile(`(?s)<script>(.*?)</script>`).FindSubmatch(swaggerHTML)
+	hash := sha256.Sum256(script[1])
+	return "default-src 'none'; " +
+		"script-src 'self' 'sha256-" + base64.StdEncoding.EncodeToString(hash[:]) + "'; " +
+		"style-src 'self' 'unsafe-inline'; " +
+		"img-src 'self' data:; " +
+		"connect-src 'self'; " +
+		"frame-ancestors 'none'; " +
+		"base-uri 'none'"
+}
+
+// Load parses and validates the embedded specification, basePath is where the
+// described paths are mounted, e.g. /learn-go/v1
+func Load(ctx context.Context, basePath string) (*openapi3.T, error) {
+	spec, err := openapi3.NewLoader().LoadFromData(specYAML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec: %w", err)
+	}
+	spec.Servers = openapi3.Servers{{URL: basePath}}
+	if err := spec.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	return spec, nil
+}
+
+// SpecHandler serves the specification as JSON
+func SpecHandler(spec *openapi3.T) (http.HandlerFunc, error) {
+	data, err := spec.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(data)
+	}, nil
+}
+
+// SwaggerUIHandler serves the Swagger UI page, it reads openapi.json next to itself
+func SwaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(swaggerHTML)
+}
+
+// SwaggerUIAssetsHandler serves the files of the Swagger UI, the route must end with
+// the /* wildcard naming the file
+func SwaggerUIAssetsHandler(w http.ResponseWriter, r *http.Request) {
+	name := "swagger-ui/" + chi.URLParam(r, "*")
+	if info, err := fs.Stat(swaggerUI, name); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	http.ServeFileFS(w, r, swaggerUI, name)
+}
+
+// CheckRoutes returns an error listing every route registered under basePath that
+// has no operation in the spec, so a route cannot be added without documenting it
+func CheckRoutes(routes chi.Routes, basePath string, spec *openapi3.T) error {
+	var missing []string
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path, ok := strings.CutPrefix(route, basePath)
+		if !ok {
+			return nil
+		}
+		item := spec.Paths.Find(path)
+		if item == nil || item.GetOperation(method) == nil {
+			missing = append(missing, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("routes missing from the openapi spec: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
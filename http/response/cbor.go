@@ -0,0 +1,29 @@
+package response
+
+import (
+	// Go Internal Packages
+	"io"
+
+	// External Packages
+	"github.com/ugorji/go/codec"
+)
+
+type cborCodec struct{}
+
+var cborHandle = &codec.CborHandle{}
+
+func (cborCodec) MediaTypes() []string { return []string{"application/cbor"} }
+
+func (cborCodec) Encode(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	return codec.NewEncoder(w, cborHandle).Encode(tree)
+}
+
+func (cborCodec) Decode(r io.Reader, v any) error {
+	return decodeAll(r, cborHandle, v)
+}
+
+func (cborCodec) Typed() bool { return true }
@@ -0,0 +1,246 @@
+package response
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	// Local Packages
+	"learn-go/errors"
+)
+
+// Codec encodes responses in, and decodes request bodies from, one family of media types
+type Codec interface {
+	// MediaTypes lists the media types of the codec, responses are written with the first
+	MediaTypes() []string
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+	// Typed reports whether the documents carry the types of their values, so that they
+	// can be decoded into an untyped value. XML does not, every value is text.
+	Typed() bool
+}
+
+// The codecs registered by default. Except for JSON they write the JSON representation of
+// the values, so field names, omitted fields and custom JSON marshalers are the same in
+// every media type.
+var (
+	JSON        Codec = jsonCodec{}
+	XML         Codec = xmlCodec{}
+	MessagePack Codec = msgpackCodec{}
+	CBOR        Codec = cborCodec{}
+)
+
+var errTrailingData = errors.NewError("body must hold a single value")
+
+// codecs in the order of preference of the server, JSON first
+var codecs = []Codec{JSON, XML, MessagePack, CBOR}
+
+// Register adds a codec, it must be called before the server starts
+func Register(codec Codec) {
+	codecs = append(codecs, codec)
+}
+
+// MediaTypes returns the media types of the registered codecs
+func MediaTypes() []string {
+	var mediaTypes []string
+	for _, codec := range codecs {
+		mediaTypes = append(mediaTypes, codec.MediaTypes()...)
+	}
+	return mediaTypes
+}
+
+// Negotiate picks the codec for the Accept header, in the order of preference of the
+// header. An empty header picks JSON. produces are the media types a handler writes
+// itself, e.g. exports, when one of them is picked the codec is JSON for the errors.
+// It reports false when neither a codec nor one of produces is acceptable.
+func Negotiate(accept string, produces ...string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return JSON, true
+	}
+
+	for _, mediaRange := range parseAccept(accept) {
+		for _, codec := range codecs {
+			for _, mediaType := range codec.MediaTypes() {
+				if matches(mediaRange, mediaType) {
+					return codec, true
+				}
+			}
+		}
+		for _, mediaType := range produces {
+			if matches(mediaRange, mediaType) {
+				return JSON, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Lookup returns the codec of a request body with the given Content-Type. A missing
+// Content-Type and the +json structured syntax suffix are taken as JSON, other media
+// types without a codec are reported as Unsupported.
+func Lookup(contentType string) (Codec, error) {
+	if strings.TrimSpace(contentType) == "" {
+		return JSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && strings.HasSuffix(mediaType, "+json") {
+		return JSON, nil
+	}
+	if err == nil {
+		for _, codec := range codecs {
+			for _, candidate := range codec.MediaTypes() {
+				if candidate == mediaType {
+					return codec, nil
+				}
+			}
+		}
+	}
+	return nil, errors.E(errors.Unsupported, fmt.Sprintf("cannot decode a %s body, send one of %s",
+		contentType, strings.Join(MediaTypes(), ", ")))
+}
+
+// Transcode re-encodes a body of a typed codec as JSON
+func Transcode(codec Codec, body []byte) ([]byte, error) {
+	if codec == JSON {
+		return body, nil
+	}
+	var v any
+	if err := codec.Decode(bytes.NewReader(body), &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept returns the acceptable media ranges of the header by decreasing quality,
+// ranges excluded with q=0 and malformed ones are dropped
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	return ranges
+}
+
+// matches reports whether the media type falls in the range, e.g. application/* or */*
+func matches(mediaRange acceptRange, mediaType string) bool {
+	if mediaRange.mediaType == "*/*" || mediaRange.mediaType == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mediaRange.mediaType, "*")
+	return ok && strings.HasSuffix(prefix, "/") && strings.HasPrefix(mediaType, prefix)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MediaTypes() []string { return []string{"application/json"} }
+
+func (jsonCodec) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+
+// Decode rejects fields the value does not have and anything following the value
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		var syntaxErr *json.SyntaxError
+		if err == nil || errors.As(err, &syntaxErr) {
+			return errTrailingData
+		}
+		return err
+	}
+	return nil
+}
+
+func (jsonCodec) Typed() bool { return true }
+
+// toTree returns the JSON representation of v as maps, slices and scalars, with the
+// integers as int64 so that binary codecs keep them apart from the other numbers
+func toTree(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return numbers(tree), nil
+}
+
+func numbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			v[key] = numbers(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = numbers(value)
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// fromTree decodes a value decoded by another codec into v through its JSON representation
+func fromTree(tree any, v any) error {
+	data, err := json.Marshal(stringKeys(tree))
+	if err != nil {
+		return err
+	}
+	return JSON.Decode(bytes.NewReader(data), v)
+}
+
+// stringKeys converts the maps with arbitrary keys some decoders produce into JSON objects
+func stringKeys(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case map[string]any:
+		for key, value := range v {
+			v[key] = stringKeys(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+	}
+	return v
+}
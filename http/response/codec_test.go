@@ -0,0 +1,211 @@
+package response
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	// Local Packages
+	"learn-go/errors"
+)
+
+type item struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+type document struct {
+	ID        string            `json:"id"`
+	Count     int64             `json:"count"`
+	Ratio     float64           `json:"ratio"`
+	Active    bool              `json:"active"`
+	Note      string            `json:"note,omitempty"`
+	Items     []item            `json:"items"`
+	Labels    map[string]string `json:"labels"`
+	CreatedAt time.Time         `json:"created_at"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"`
+	Extra     json.RawMessage   `json:"extra"`
+}
+
+func newDocument() document {
+	return document{
+		ID:        "o1",
+		Count:     9007199254740993, // not representable as a float64
+		Ratio:     0.5,
+		Active:    true,
+		Items:     []item{{Name: "pen", Price: 2}, {Name: "<ink & paper>", Price: 1.25}},
+		Labels:    map[string]string{"tier": "gold", "1st": "key that is not an XML name"},
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Extra:     json.RawMessage(`{"source":"web"}`),
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{JSON, XML, MessagePack, CBOR} {
+		t.Run(codec.MediaTypes()[0], func(t *testing.T) {
+			want := newDocument()
+			var body bytes.Buffer
+			if err := codec.Encode(&body, want); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			var got document
+			if err := codec.Decode(bytes.NewReader(body.Bytes()), &got); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			// The raw JSON is compared as a value, its spacing is the codec's
+			if !equalJSON(t, got.Extra, want.Extra) {
+				t.Fatalf("Decode() extra = %s, want %s", got.Extra, want.Extra)
+			}
+			got.Extra = want.Extra
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Decode() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestTypedCodecsDecodeUntypedValues(t *testing.T) {
+	for _, codec := range []Codec{MessagePack, CBOR} {
+		t.Run(codec.MediaTypes()[0], func(t *testing.T) {
+			var body bytes.Buffer
+			if err := codec.Encode(&body, map[string]any{"a": []any{1, "x", true, nil, 1.5}}); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			got, err := Transcode(codec, body.Bytes())
+			if err != nil {
+				t.Fatalf("Transcode() error = %v", err)
+			}
+			if want := `{"a":[1,"x",true,null,1.5]}`; !equalJSON(t, got, []byte(want)) {
+				t.Fatalf("Transcode() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCodecsRejectUnknownFieldsAndTrailingData(t *testing.T) {
+	type student struct {
+		Name string `json:"name"`
+	}
+	encode := func(codec Codec, v any) []byte {
+		var body bytes.Buffer
+		if err := codec.Encode(&body, v); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		return body.Bytes()
+	}
+	for _, codec := range []Codec{JSON, XML, MessagePack, CBOR} {
+		t.Run(codec.MediaTypes()[0], func(t *testing.T) {
+			var s student
+			unknown := encode(codec, map[string]string{"name": "Ann", "age": "3"})
+			if err := codec.Decode(bytes.NewReader(unknown), &s); err == nil {
+				t.Fatalf("Decode() of an unknown field succeeded")
+			}
+
+			one := encode(codec, student{Name: "Ann"})
+			if err := codec.Decode(bytes.NewReader(append(one, one...)), &s); err == nil {
+				t.Fatalf("Decode() of two values succeeded")
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept   string
+		produces []string
+		want     Codec
+		wantOK   bool
+	}{
+		{accept: "", want: JSON, wantOK: true},
+		{accept: "*/*", want: JSON, wantOK: true},
+		{accept: "application/*", want: JSON, wantOK: true},
+		{accept: "text/xml", want: XML, wantOK: true},
+		{accept: "application/json;q=0.5, application/cbor", want: CBOR, wantOK: true},
+		{accept: "application/vnd.msgpack;q=0.9, application/xml;q=0.1", want: MessagePack, wantOK: true},
+		{accept: "text/html, application/xml;q=0.8", want: XML, wantOK: true},
+		{accept: "application/json;q=0", wantOK: false},
+		{accept: "text/html", wantOK: false},
+		{accept: "text/*", want: XML, wantOK: true},
+		{accept: "not a media type, application/cbor", want: CBOR, wantOK: true},
+		// Errors of handlers writing the media type themselves are JSON
+		{accept: "text/csv", produces: []string{"text/csv"}, want: JSON, wantOK: true},
+		{accept: "text/csv", produces: []string{"application/pdf"}, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			got, ok := Negotiate(tt.accept, tt.produces...)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("Negotiate() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Codec
+		wantErr     bool
+	}{
+		{contentType: "", want: JSON},
+		{contentType: "application/json; charset=utf-8", want: JSON},
+		{contentType: "application/merge-patch+json", want: JSON},
+		{contentType: "text/xml", want: XML},
+		{contentType: "application/x-msgpack", want: MessagePack},
+		{contentType: "application/cbor", want: CBOR},
+		{contentType: "text/plain", wantErr: true},
+		{contentType: "application/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			got, err := Lookup(tt.contentType)
+			if tt.wantErr {
+				if !errors.IsKind(err, errors.Unsupported) {
+					t.Fatalf("Lookup() error = %v, want Unsupported", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Lookup() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRespondErrorWithCodec(t *testing.T) {
+	ve := errors.ValidationErrs()
+	ve.Add("/name", "cannot be empty")
+	rec := httptest.NewRecorder()
+	RespondErrorWith(rec, XML, errors.E(errors.Invalid, "invalid student", ve.Err()).(*errors.Error))
+
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/xml" {
+		t.Fatalf("RespondErrorWith() = %d %s, want 400 application/xml", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got struct {
+		Message          string                  `json:"message"`
+		ValidationErrors errors.ValidationErrors `json:"validation_errors"`
+	}
+	if err := XML.Decode(rec.Body, &got); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if got.Message != "invalid student" || len(got.ValidationErrors) != 1 || got.ValidationErrors[0].Field != "/name" {
+		t.Fatalf("RespondErrorWith() = %+v, want the validation errors", got)
+	}
+}
+
+func equalJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var av, bv any
+	if err := json.Unmarshal(a, &av); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(av, bv)
+}
@@ -0,0 +1,54 @@
+package response
+
+import (
+	// Go Internal Packages
+	"io"
+
+	// External Packages
+	"github.com/ugorji/go/codec"
+)
+
+type msgpackCodec struct{}
+
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true}
+
+func init() {
+	// Read both the raw and the str formats back as strings
+	msgpackHandle.RawToString = true
+}
+
+func (msgpackCodec) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	return codec.NewEncoder(w, msgpackHandle).Encode(tree)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	return decodeAll(r, msgpackHandle, v)
+}
+
+func (msgpackCodec) Typed() bool { return true }
+
+// decodeAll decodes the body with a binary codec, a body holding more than one value is
+// rejected like it is in JSON
+func decodeAll(r io.Reader, handle codec.Handle, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	dec := codec.NewDecoderBytes(data, handle)
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	if dec.NumBytesRead() != len(data) {
+		return errTrailingData
+	}
+	return fromTree(tree, v)
+}
@@ -2,51 +2,76 @@ package response
 
 import (
 	// Go Internal Packages
-	"encoding/json"
+	"bytes"
 	"net/http"
 
 	// Local Packages
 	"learn-go/errors"
 )
 
-// RespondJSON writes the data to the response writer as JSON
-func RespondJSON(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	if json.NewEncoder(w).Encode(data) != nil {
+// Respond writes the data encoded with the codec. The body is encoded before the header
+// is written, a value the codec cannot encode is answered with a 500.
+func Respond(w http.ResponseWriter, codec Codec, status int, data any) {
+	var body bytes.Buffer
+	if err := codec.Encode(&body, data); err != nil {
 		http.Error(w, `{"message": "Internal Error Encoding Response"}`, http.StatusInternalServerError)
+		return
 	}
+	w.Header().Set("Content-Type", codec.MediaTypes()[0])
+	w.WriteHeader(status)
+	_, _ = w.Write(body.Bytes())
+}
+
+// RespondJSON writes the data to the response writer as JSON
+func RespondJSON(w http.ResponseWriter, status int, data any) {
+	Respond(w, JSON, status, data)
 }
 
 // RespondMessage writes the message to the response writer
 func RespondMessage(w http.ResponseWriter, status int, message string) {
-	RespondJSON(w, status, map[string]string{"message": message})
+	RespondMessageWith(w, JSON, status, message)
+}
+
+// RespondMessageWith writes the message encoded with the codec
+func RespondMessageWith(w http.ResponseWriter, codec Codec, status int, message string) {
+	Respond(w, codec, status, map[string]string{"message": message})
 }
 
 // RespondError writes the error to the response writer
 func RespondError(w http.ResponseWriter, err *errors.Error) {
+	RespondErrorWith(w, JSON, err)
+}
+
+// RespondErrorWith writes the error encoded with the codec
+func RespondErrorWith(w http.ResponseWriter, codec Codec, err *errors.Error) {
 	switch err.Kind {
 	case errors.NotFound:
-		RespondMessage(w, http.StatusNotFound, err.Message)
+		RespondMessageWith(w, codec, http.StatusNotFound, err.Message)
 	case errors.Invalid:
 		var ve errors.ValidationErrors
 		if errors.As(err, &ve) {
-			RespondJSON(w, http.StatusBadRequest, map[string]any{
+			Respond(w, codec, http.StatusBadRequest, map[string]any{
 				"message":           err.Message,
 				"validation_errors": ve,
 			})
 			return
 		}
 		if err.WrappedErr != nil {
-			RespondMessage(w, http.StatusBadRequest, err.WrappedErr.Error())
+			RespondMessageWith(w, codec, http.StatusBadRequest, err.WrappedErr.Error())
 			return
 		}
-		RespondMessage(w, http.StatusBadRequest, err.Message)
+		RespondMessageWith(w, codec, http.StatusBadRequest, err.Message)
+	case errors.Conflict:
+		RespondMessageWith(w, codec, http.StatusConflict, err.Message)
 	case errors.Unauthorized:
-		RespondMessage(w, http.StatusUnauthorized, err.Message)
+		RespondMessageWith(w, codec, http.StatusUnauthorized, err.Message)
 	case errors.Forbidden:
-		RespondMessage(w, http.StatusForbidden, err.Message)
+		RespondMessageWith(w, codec, http.StatusForbidden, err.Message)
+	case errors.Unsupported:
+		RespondMessageWith(w, codec, http.StatusUnsupportedMediaType, err.Message)
+	case errors.TooLarge:
+		RespondMessageWith(w, codec, http.StatusRequestEntityTooLarge, err.Message)
 	default:
-		RespondMessage(w, http.StatusInternalServerError, err.Message)
+		RespondMessageWith(w, codec, http.StatusInternalServerError, err.Message)
 	}
 }
@@ -0,0 +1,286 @@
+package response
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// xmlCodec writes the JSON representation of a value as XML: objects become elements
+// named after their keys, array items are <item> elements and the document element is
+// <response>. Keys that are not valid element names are written as <entry key="...">.
+// Bodies are read the same way, guided by the Go type they are decoded into since XML
+// text carries no types.
+type xmlCodec struct{}
+
+const (
+	xmlRoot  = "response"
+	xmlItem  = "item"
+	xmlEntry = "entry"
+)
+
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+func (xmlCodec) MediaTypes() []string { return []string{"application/xml", "text/xml"} }
+
+func (xmlCodec) Typed() bool { return false }
+
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeXML(enc, dec, xmlRoot); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// writeXML writes the next JSON value of dec as the element name
+func writeXML(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	start := xmlStart(name)
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		for dec.More() {
+			child := xmlItem
+			if tok == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child = key.(string)
+			}
+			if err := writeXML(enc, dec, child); err != nil {
+				return err
+			}
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	case string:
+		err = enc.EncodeToken(xml.CharData(tok))
+	case json.Number:
+		err = enc.EncodeToken(xml.CharData(tok.String()))
+	case bool:
+		err = enc.EncodeToken(xml.CharData(strconv.FormatBool(tok)))
+	}
+	if err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func xmlStart(name string) xml.StartElement {
+	if xmlName.MatchString(name) && !strings.HasPrefix(strings.ToLower(name), "xml") {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: xmlEntry},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+	}
+}
+
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	root, err := parseXML(r)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(root.value(reflect.TypeOf(v)))
+	if err != nil {
+		return err
+	}
+	return JSON.Decode(bytes.NewReader(data), v)
+}
+
+// xmlNode is an element of a decoded document, name is the key of <entry> elements
+type xmlNode struct {
+	name     string
+	children []*xmlNode
+	text     strings.Builder
+}
+
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: tok.Name.Local}
+			for _, attr := range tok.Attr {
+				if tok.Name.Local == xmlEntry && attr.Name.Local == "key" {
+					node.name = attr.Value
+				}
+			}
+			switch {
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			case root == nil:
+				root = node
+			default:
+				return nil, errors.New("xml: more than one document element")
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(tok)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("xml: no document element")
+	}
+	return root, nil
+}
+
+var (
+	rawMessageType      = reflect.TypeOf(json.RawMessage{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// value returns the JSON representation of the node for a value of type t, a nil t
+// stands for any value
+func (n *xmlNode) value(t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t == rawMessageType || t.Kind() == reflect.Interface {
+		return n.untyped()
+	}
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return n.text.String()
+	}
+
+	text := strings.TrimSpace(n.text.String())
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		obj := make(map[string]any, len(n.children))
+		for _, child := range n.children {
+			obj[child.name] = child.value(fields[child.name])
+		}
+		return obj
+	case reflect.Map:
+		obj := make(map[string]any, len(n.children))
+		for _, child := range n.children {
+			obj[child.name] = child.value(t.Elem())
+		}
+		return obj
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Base64 text, as in JSON
+			return text
+		}
+		arr := make([]any, 0, len(n.children))
+		for _, child := range n.children {
+			arr = append(arr, child.value(t.Elem()))
+		}
+		return arr
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+		return text
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if json.Valid([]byte(text)) {
+			return json.Number(text)
+		}
+		return text
+	default:
+		return n.text.String()
+	}
+}
+
+// untyped returns the text of a leaf, the items of an element holding only <item>
+// elements and the children of any other element by name
+func (n *xmlNode) untyped() any {
+	if len(n.children) == 0 {
+		return n.text.String()
+	}
+
+	items := true
+	for _, child := range n.children {
+		items = items && child.name == xmlItem
+	}
+	if items {
+		arr := make([]any, 0, len(n.children))
+		for _, child := range n.children {
+			arr = append(arr, child.untyped())
+		}
+		return arr
+	}
+
+	obj := make(map[string]any, len(n.children))
+	for _, child := range n.children {
+		obj[child.name] = child.untyped()
+	}
+	return obj
+}
+
+// jsonFields returns the types of the fields of a struct by their JSON name
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for name, typ := range jsonFields(embedded) {
+					if _, ok := fields[name]; !ok {
+						fields[name] = typ
+					}
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
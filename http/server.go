@@ -4,28 +4,82 @@ import (
 	// Go Internal Packages
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	// Local Packages
 	errors "learn-go/errors"
 	handlers "learn-go/http/handlers"
 	smiddlewares "learn-go/http/middlewares"
+	openapi "learn-go/http/openapi"
 	resp "learn-go/http/response"
+	models "learn-go/models"
 	health "learn-go/services/health"
 
 	// External Packages
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+// CacheStatsProvider is implemented by caches that report their hit/miss counters
+type CacheStatsProvider interface {
+	Stats() models.CacheStats
+}
+
+// Options tunes the listener and the handling of requests, see config.HTTP
+type Options struct {
+	// BodyLimit caps request bodies in bytes, BodyLimits overrides it by "METHOD /pattern"
+	BodyLimit  int64
+	BodyLimits map[string]int64
+	// Responses of at least CompressMinSize bytes are compressed when Compress is set
+	Compress          bool
+	CompressMinSize   int
+	CompressEncodings []string
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	// HandlerTimeout bounds the request contexts, HandlerTimeouts overrides it by "METHOD /pattern"
+	HandlerTimeout  time.Duration
+	HandlerTimeouts map[string]time.Duration
+	// StreamingRoutes run without the read, write and handler timeouts
+	StreamingRoutes []string
+
+	// TLS serves HTTPS when set, H2C serves HTTP/2 over cleartext otherwise
+	TLS *TLSOptions
+	H2C bool
+
+	// CORS answers the cross origin requests of browsers when set
+	CORS *cors.Options
+	// SecurityHeaders are set on every response when set
+	SecurityHeaders *smiddlewares.SecurityOptions
+
+	// AuditActorHeader names the actor of the changes made through one of the
+	// AuditTrustedClients, the common names of their client certificates
+	AuditActorHeader    string
+	AuditTrustedClients []string
+}
+
 // Server struct follows the alphabet order
 type Server struct {
-	health   *health.HealthCheckerService
-	logger   *zap.Logger
-	orders   *handlers.OrdersHandler
-	prefix   string
-	students *handlers.StudentsHandler
+	audit         *handlers.AuditHandler
+	graphql       http.Handler
+	health        *health.HealthCheckerService
+	jobs          *handlers.JobsHandler
+	logger        *zap.Logger
+	options       Options
+	orderChanges  *handlers.OrderChangesHandler
+	orders        *handlers.OrdersHandler
+	prefix        string
+	students      *handlers.StudentsHandler
+	studentsCache CacheStatsProvider
+	webhooks      *handlers.WebhooksHandler
 }
 
 func NewServer(
@@ -34,49 +88,172 @@ func NewServer(
 	studentsHandlers *handlers.StudentsHandler,
 	ordersHandlers *handlers.OrdersHandler,
 	healthService *health.HealthCheckerService,
+	studentsCache CacheStatsProvider,
+	webhooksHandlers *handlers.WebhooksHandler,
+	orderChangesHandlers *handlers.OrderChangesHandler,
+	graphqlHandler http.Handler,
+	jobsHandlers *handlers.JobsHandler,
+	auditHandlers *handlers.AuditHandler,
+	options Options,
 ) *Server {
 	return &Server{
-		prefix:   prefix,
-		logger:   logger,
-		students: studentsHandlers,
-		orders:   ordersHandlers,
-		health:   healthService,
+		audit:         auditHandlers,
+		graphql:       graphqlHandler,
+		jobs:          jobsHandlers,
+		options:       options,
+		prefix:        prefix,
+		logger:        logger,
+		students:      studentsHandlers,
+		orders:        ordersHandlers,
+		health:        healthService,
+		studentsCache: studentsCache,
+		webhooks:      webhooksHandlers,
+		orderChanges:  orderChangesHandlers,
 	}
 }
 
-func (s *Server) Listen(ctx context.Context, addr string) error {
+// Router builds the routes of the server, it fails when a route under /v1 is missing
+// from the spec, see http/openapi/openapi.yaml
+func (s *Server) Router(ctx context.Context) (chi.Router, error) {
+	basePath := s.prefix + "/v1"
+	spec, err := openapi.Load(ctx, basePath)
+	if err != nil {
+		return nil, err
+	}
+	specHandler, err := openapi.SpecHandler(spec)
+	if err != nil {
+		return nil, err
+	}
+	validator, err := smiddlewares.OpenAPIValidator(spec)
+	if err != nil {
+		return nil, err
+	}
+
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(smiddlewares.HTTPMiddleware(s.logger))
 	r.Use(middleware.Recoverer)
+	// Preflights are answered here, before the routes could reject their OPTIONS method
+	if s.options.CORS != nil {
+		r.Use(cors.Handler(*s.options.CORS))
+	}
+	if s.options.SecurityHeaders != nil {
+		r.Use(smiddlewares.SecurityHeaders(*s.options.SecurityHeaders))
+	}
+	if s.options.Compress {
+		r.Use(smiddlewares.Compress(s.options.CompressMinSize, s.options.CompressEncodings))
+	}
 
 	r.Route(s.prefix, func(r chi.Router) {
+		r.Get("/openapi.json", specHandler)
+		r.With(smiddlewares.ContentSecurityPolicy(openapi.SwaggerUICSP)).Get("/docs", openapi.SwaggerUIHandler)
+		r.Get("/swagger-ui/*", openapi.SwaggerUIAssetsHandler)
+
 		r.Route("/v1", func(r chi.Router) {
+			r.Use(smiddlewares.Timeouts(basePath, s.options.HandlerTimeout, s.options.HandlerTimeouts,
+				s.options.StreamingRoutes))
+			r.Use(smiddlewares.BodyLimit(basePath, s.options.BodyLimit, s.options.BodyLimits))
+			r.Use(validator)
+			r.Use(smiddlewares.AuditRequest(s.options.AuditActorHeader, s.options.AuditTrustedClients))
 			r.Get("/health", s.HealthCheckHandler)
+			r.Get("/metrics", s.MetricsHandler)
+			if s.graphql != nil {
+				r.Get("/graphql", s.graphql.ServeHTTP)
+				r.Post("/graphql", s.graphql.ServeHTTP)
+			}
 
 			r.Group(func(r chi.Router) {
 				r.Route("/students", func(r chi.Router) {
 					r.Get("/", s.ToHTTPHandlerFunc(s.students.GetAll))
+					r.Get("/export", s.ToHTTPHandlerFunc(s.students.Export, handlers.ExportMediaTypes()...))
 					r.Get("/{rollNo}", s.ToHTTPHandlerFunc(s.students.GetOne))
 					r.Post("/", s.ToHTTPHandlerFunc(s.students.Insert))
+					r.Post("/import", s.ToHTTPHandlerFunc(s.students.Import))
 					r.Put("/{rollNo}", s.ToHTTPHandlerFunc(s.students.Update))
+					r.Patch("/{rollNo}", s.ToHTTPHandlerFunc(s.students.Patch))
 					r.Delete("/{rollNo}", s.ToHTTPHandlerFunc(s.students.Delete))
+					r.Post("/{rollNo}/restore", s.ToHTTPHandlerFunc(s.students.Restore))
 				})
 				r.Route("/orders", func(r chi.Router) {
+					r.Get("/", s.ToHTTPHandlerFunc(s.orders.List))
+					r.Get("/export", s.ToHTTPHandlerFunc(s.orders.Export, handlers.ExportMediaTypes()...))
 					r.Get("/{orderId}", s.ToHTTPHandlerFunc(s.orders.GetOne))
 					r.Post("/", s.ToHTTPHandlerFunc(s.orders.Insert))
 					r.Put("/{orderId}", s.ToHTTPHandlerFunc(s.orders.Update))
+					r.Patch("/{orderId}", s.ToHTTPHandlerFunc(s.orders.Patch))
 					r.Delete("/{orderId}", s.ToHTTPHandlerFunc(s.orders.Delete))
+					if s.orderChanges != nil {
+						r.Get("/stream", s.orderChanges.StreamAll)
+						r.Get("/live", s.orderChanges.Live)
+						r.Get("/{orderId}/stream", s.orderChanges.StreamOne)
+					}
 				})
+				if s.webhooks != nil {
+					r.Route("/webhooks", func(r chi.Router) {
+						r.Get("/", s.ToHTTPHandlerFunc(s.webhooks.GetAll))
+						r.Get("/{webhookId}", s.ToHTTPHandlerFunc(s.webhooks.GetOne))
+						r.Get("/{webhookId}/deliveries", s.ToHTTPHandlerFunc(s.webhooks.GetDeliveries))
+						r.Post("/", s.ToHTTPHandlerFunc(s.webhooks.Insert))
+						r.Put("/{webhookId}", s.ToHTTPHandlerFunc(s.webhooks.Update))
+						r.Delete("/{webhookId}", s.ToHTTPHandlerFunc(s.webhooks.Delete))
+					})
+				}
+				if s.audit != nil {
+					r.Get("/audit", s.ToHTTPHandlerFunc(s.audit.List))
+				}
+				if s.jobs != nil {
+					r.Route("/jobs", func(r chi.Router) {
+						r.Post("/", s.ToHTTPHandlerFunc(s.jobs.Insert))
+						r.Get("/{jobId}", s.ToHTTPHandlerFunc(s.jobs.GetOne))
+						r.Post("/{jobId}/cancel", s.ToHTTPHandlerFunc(s.jobs.Cancel))
+					})
+				}
 			})
 		})
 	})
 
+	// Every route under /v1 must be documented, see http/openapi/openapi.yaml
+	if err := openapi.CheckRoutes(r, basePath, spec); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *Server) Listen(ctx context.Context, addr string) error {
+	r, err := s.Router(ctx)
+	if err != nil {
+		return err
+	}
+
+	var handler http.Handler = r
+	if s.options.H2C {
+		handler = h2c.NewHandler(r, &http2.Server{IdleTimeout: s.options.IdleTimeout})
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: s.options.ReadHeaderTimeout,
+		ReadTimeout:       s.options.ReadTimeout,
+		WriteTimeout:      s.options.WriteTimeout,
+		IdleTimeout:       s.options.IdleTimeout,
+	}
+	if s.options.TLS != nil {
+		certs, err := newCertReloader(*s.options.TLS, s.logger)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = certs.TLSConfig()
+	}
+
 	errch := make(chan error)
-	server := &http.Server{Addr: addr, Handler: r}
 	go func() {
-		s.logger.Info("Starting server", zap.String("addr", addr))
+		s.logger.Info("Starting server", zap.String("addr", addr), zap.Bool("tls", server.TLSConfig != nil),
+			zap.Bool("h2c", s.options.H2C))
+		if server.TLSConfig != nil {
+			errch <- server.ListenAndServeTLS("", "")
+			return
+		}
 		errch <- server.ListenAndServe()
 	}()
 
@@ -84,29 +261,63 @@ func (s *Server) Listen(ctx context.Context, addr string) error {
 	case err := <-errch:
 		return err
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.options.ShutdownTimeout)
 		defer cancel()
+		// Streams never end on their own, they are closed first so that Shutdown only
+		// waits for the other requests
+		if s.orderChanges != nil {
+			if err := s.orderChanges.Shutdown(shutdownCtx); err != nil {
+				return err
+			}
+		}
 		return server.Shutdown(shutdownCtx)
 	}
 }
 
 // ToHTTPHandlerFunc converts a handler function to an http.HandlerFunc.
-// This wrapper function is used to handle errors and respond to the client
-func (s *Server) ToHTTPHandlerFunc(handler func(w http.ResponseWriter, r *http.Request) (any, int, error)) http.HandlerFunc {
+// This wrapper function is used to handle errors and respond to the client. Responses
+// and errors are encoded with the codec negotiated from the Accept header, requests
+// accepting none of the codecs nor of the media types the handler produces itself are
+// answered with a 406 before the handler runs. A response returned with an error is sent
+// with the status in place of the error.
+func (s *Server) ToHTTPHandlerFunc(
+	handler func(w http.ResponseWriter, r *http.Request) (any, int, error),
+	produces ...string,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		codec, ok := resp.Negotiate(r.Header.Get("Accept"), produces...)
+		if !ok {
+			available := append(resp.MediaTypes(), produces...)
+			resp.RespondMessage(w, http.StatusNotAcceptable,
+				"cannot respond with "+r.Header.Get("Accept")+", accept one of "+strings.Join(available, ", "))
+			return
+		}
+
 		response, status, err := handler(w, r)
+		if err != nil && r.Context().Err() == context.DeadlineExceeded {
+			// The handler timeout of the route passed, see smiddlewares.Timeouts
+			resp.RespondMessageWith(w, codec, http.StatusServiceUnavailable, "request timed out")
+			return
+		}
 		if err != nil {
-			switch err := err.(type) {
-			case *errors.Error:
-				resp.RespondError(w, err)
-			default:
+			e, ok := err.(*errors.Error)
+			if !ok {
 				s.logger.Error("internal error", zap.Error(err))
-				resp.RespondMessage(w, http.StatusInternalServerError, "internal error")
+			}
+			switch {
+			case response != nil:
+				// The response tells what was done before the error, like a partial import
+				resp.Respond(w, codec, status, response)
+			case ok:
+				resp.RespondErrorWith(w, codec, e)
+			default:
+				resp.RespondMessageWith(w, codec, http.StatusInternalServerError, "internal error")
 			}
 			return
 		}
 		if response != nil {
-			resp.RespondJSON(w, status, response)
+			resp.Respond(w, codec, status, response)
 		}
 		if status >= 100 && status < 600 {
 			w.WriteHeader(status)
@@ -114,11 +325,25 @@ func (s *Server) ToHTTPHandlerFunc(handler func(w http.ResponseWriter, r *http.R
 	}
 }
 
-// HealthCheckHandler returns the health status of the service
+// HealthCheckHandler returns the health status of the service, it fails from the start
+// of a shutdown so that no new requests are routed here
 func (s *Server) HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.health.Ready() {
+		resp.RespondMessage(w, http.StatusServiceUnavailable, "shutting down")
+		return
+	}
 	if ok := s.health.Health(r.Context()); !ok {
 		resp.RespondMessage(w, http.StatusServiceUnavailable, "health check failed")
 		return
 	}
 	resp.RespondMessage(w, http.StatusOK, "!!! We are RunninGoo !!!")
 }
+
+// MetricsHandler returns the runtime counters of the service, caches that are disabled are omitted
+func (s *Server) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	metrics := map[string]any{}
+	if s.studentsCache != nil {
+		metrics["students_cache"] = s.studentsCache.Stats()
+	}
+	resp.RespondJSON(w, http.StatusOK, metrics)
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	handlers "learn-go/http/handlers"
+	smiddlewares "learn-go/http/middlewares"
+	health "learn-go/services/health"
+
+	// External Packages
+	"github.com/go-chi/cors"
+	"go.uber.org/zap"
+)
+
+// newTestServer returns a server with every optional handler enabled, the services are
+// nil as the routes are built but never called
+func newTestServer() *Server {
+	return newTestServerWith(Options{BodyLimit: 1 << 20})
+}
+
+func newTestServerWith(opts Options) *Server {
+	logger := zap.NewNop()
+	return NewServer("/learn-go", logger,
+		handlers.NewStudentsHandler(nil, nil),
+		handlers.NewOrdersHandler(nil),
+		health.NewService(logger, nil, nil, nil),
+		nil,
+		handlers.NewWebhooksHandler(nil),
+		handlers.NewOrderChangesHandler(nil, time.Second, handlers.LiveOptions{}),
+		http.NotFoundHandler(),
+		handlers.NewJobsHandler(nil),
+		handlers.NewAuditHandler(nil),
+		opts,
+	)
+}
+
+func TestRouterDocumentsEveryRoute(t *testing.T) {
+	if _, err := newTestServer().Router(context.Background()); err != nil {
+		t.Fatalf("Router() error = %v", err)
+	}
+}
+
+func TestRouterServesSwaggerUI(t *testing.T) {
+	router, err := newTestServer().Router(context.Background())
+	if err != nil {
+		t.Fatalf("Router() error = %v", err)
+	}
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{path: "/learn-go/docs", status: http.StatusOK, contentType: "text/html"},
+		{path: "/learn-go/swagger-ui/swagger-ui-bundle.js", status: http.StatusOK, contentType: "text/javascript"},
+		{path: "/learn-go/swagger-ui/swagger-ui.css", status: http.StatusOK, contentType: "text/css"},
+		{path: "/learn-go/swagger-ui/", status: http.StatusNotFound},
+		{path: "/learn-go/swagger-ui/missing.js", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+				t.Fatalf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+		})
+	}
+}
+
+func TestToHTTPHandlerFuncNegotiatesTheResponse(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		produces        []string
+		wantStatus      int
+		wantContentType string
+		wantRun         bool
+	}{
+		{name: "no accept", wantStatus: http.StatusOK, wantContentType: "application/json", wantRun: true},
+		{name: "xml", accept: "application/xml", wantStatus: http.StatusOK, wantContentType: "application/xml",
+			wantRun: true},
+		{name: "cbor", accept: "text/html, application/cbor;q=0.5", wantStatus: http.StatusOK,
+			wantContentType: "application/cbor", wantRun: true},
+		{name: "nothing acceptable", accept: "text/html", wantStatus: http.StatusNotAcceptable,
+			wantContentType: "application/json"},
+		{name: "produced by the handler", accept: "text/csv", produces: []string{"text/csv"},
+			wantStatus: http.StatusOK, wantContentType: "application/json", wantRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := false
+			handler := newTestServer().ToHTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) (any, int, error) {
+				ran = true
+				return map[string]string{"name": "Ann"}, http.StatusOK, nil
+			}, tt.produces...)
+
+			r := httptest.NewRequest(http.MethodGet, "/learn-go/students/1", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, r)
+
+			if rec.Code != tt.wantStatus || rec.Header().Get("Content-Type") != tt.wantContentType || ran != tt.wantRun {
+				t.Fatalf("response = %d %s with the handler run %v, want %d %s and %v",
+					rec.Code, rec.Header().Get("Content-Type"), ran, tt.wantStatus, tt.wantContentType, tt.wantRun)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Fatalf("Vary = %q, want Accept", rec.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestToHTTPHandlerFuncEncodesErrorsWithTheNegotiatedCodec(t *testing.T) {
+	handler := newTestServer().ToHTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) (any, int, error) {
+		return nil, http.StatusNotFound, errors.E(errors.NotFound, "student not found")
+	})
+	r := httptest.NewRequest(http.MethodGet, "/learn-go/students/1", nil)
+	r.Header.Set("Accept", "application/xml")
+	rec := httptest.NewRecorder()
+	handler(rec, r)
+
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != "application/xml" ||
+		!strings.Contains(rec.Body.String(), "<message>student not found</message>") {
+		t.Fatalf("response = %d %s %q, want a 404 in XML", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+}
+
+func TestToHTTPHandlerFuncSendsTheResponseOfAnError(t *testing.T) {
+	handler := newTestServer().ToHTTPHandlerFunc(func(w http.ResponseWriter, r *http.Request) (any, int, error) {
+		return map[string]int{"inserted": 500}, http.StatusInternalServerError, fmt.Errorf("connection reset")
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/learn-go/students/import", nil))
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), `"inserted":500`) {
+		t.Fatalf("response = %d %q, want a 500 with the response of the handler", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRouterLimitsBodiesBeforeTheyAreValidated(t *testing.T) {
+	router, err := newTestServerWith(Options{BodyLimit: 64}).Router(context.Background())
+	if err != nil {
+		t.Fatalf("Router() error = %v", err)
+	}
+	student := `{"roll_no":"1","name":"` + strings.Repeat("a", 100) + `","gender":"female","mail_id":"a@example.com"}`
+	msgpackStudent := "\x81\xa4name\xd9\x64" + strings.Repeat("a", 100)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		chunked     bool
+	}{
+		{name: "declared", contentType: "application/json", body: student},
+		{name: "streamed json", contentType: "application/json", body: student, chunked: true},
+		{name: "streamed msgpack", contentType: "application/msgpack", body: msgpackStudent, chunked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/learn-go/v1/students/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouterAnswersCrossOriginRequests(t *testing.T) {
+	router, err := newTestServerWith(Options{
+		BodyLimit: 1 << 20,
+		CORS: &cors.Options{
+			AllowedOrigins: []string{"https://*.example.com"},
+			AllowedMethods: []string{http.MethodGet, http.MethodPost},
+			AllowedHeaders: []string{"Content-Type"},
+			ExposedHeaders: []string{"X-Request-Id"},
+			MaxAge:         600,
+		},
+		SecurityHeaders: &smiddlewares.SecurityOptions{ContentSecurityPolicy: "default-src 'none'"},
+	}).Router(context.Background())
+	if err != nil {
+		t.Fatalf("Router() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		origin     string
+		method     string // requested by the preflight
+		wantOrigin string
+		wantMaxAge string
+	}{
+		{name: "allowed origin", origin: "https://app.example.com", method: http.MethodPost,
+			wantOrigin: "https://app.example.com", wantMaxAge: "600"},
+		{name: "other origin", origin: "https://evil.test", method: http.MethodPost},
+		{name: "method not allowed", origin: "https://app.example.com", method: http.MethodDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The preflight is answered before the routes, which have no OPTIONS method
+			r := httptest.NewRequest(http.MethodOptions, "/learn-go/v1/students/", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", tt.method)
+			r.Header.Set("Access-Control-Request-Headers", "content-type")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, r)
+
+			if rec.Code >= http.StatusMultipleChoices {
+				t.Fatalf("preflight status = %d, want a success", rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Fatalf("Access-Control-Max-Age = %q, want %q", got, tt.wantMaxAge)
+			}
+		})
+	}
+
+	// Actual requests carry the CORS and security headers, the docs keep their own policy
+	r := httptest.NewRequest(http.MethodGet, "/learn-go/docs", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, r)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want the origin", got)
+	}
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-Id" {
+		t.Fatalf("Access-Control-Expose-Headers = %q, want X-Request-Id", got)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got == "default-src 'none'" || got == "" {
+		t.Fatalf("Content-Security-Policy of the docs = %q, want the Swagger UI policy", got)
+	}
+	if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Fatalf("X-Content-Type-Options = %q, want nosniff", got)
+	}
+}
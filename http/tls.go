@@ -0,0 +1,141 @@
+package http
+
+import (
+	// Go Internal Packages
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// TLSOptions serves TLS with the certificate files, see config.TLS
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mTLS, client certificates are verified against its CAs
+	ClientCAFile string
+	// RequireClientCert rejects clients without a certificate, else one is only verified when sent
+	RequireClientCert bool
+	// ReloadInterval is how often the files are checked for changes
+	ReloadInterval time.Duration
+}
+
+// certReloader serves the certificate and client CAs of the files and reloads them when
+// their modification times change. The files are checked on handshakes at most once per
+// interval, a renewed certificate is picked up without a restart and a file that fails
+// to load keeps the previous one in use.
+type certReloader struct {
+	logger *zap.Logger
+	opts   TLSOptions
+
+	mu       sync.RWMutex
+	checked  time.Time
+	config   *tls.Config
+	modTimes []time.Time
+}
+
+func newCertReloader(opts TLSOptions, logger *zap.Logger) (*certReloader, error) {
+	cr := &certReloader{logger: logger, opts: opts}
+	modTimes, err := cr.stat()
+	if err != nil {
+		return nil, err
+	}
+	config, err := cr.load()
+	if err != nil {
+		return nil, err
+	}
+	cr.config, cr.modTimes, cr.checked = config, modTimes, time.Now()
+	return cr, nil
+}
+
+// TLSConfig returns the config of the server, every handshake gets the current files
+func (cr *certReloader) TLSConfig() *tls.Config {
+	return &tls.Config{MinVersion: tls.VersionTLS12, GetConfigForClient: cr.configForClient}
+}
+
+func (cr *certReloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	cr.mu.RLock()
+	config, due := cr.config, time.Since(cr.checked) >= cr.opts.ReloadInterval
+	cr.mu.RUnlock()
+	if !due {
+		return config, nil
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if time.Since(cr.checked) < cr.opts.ReloadInterval {
+		return cr.config, nil
+	}
+	cr.checked = time.Now()
+
+	modTimes, err := cr.stat()
+	if err != nil {
+		cr.logger.Error("cannot check the TLS files", zap.Error(err))
+		return cr.config, nil
+	}
+	if slices.Equal(modTimes, cr.modTimes) {
+		return cr.config, nil
+	}
+	config, err = cr.load()
+	if err != nil {
+		cr.logger.Error("cannot reload the TLS files, keeping the previous ones", zap.Error(err))
+		return cr.config, nil
+	}
+	cr.config, cr.modTimes = config, modTimes
+	cr.logger.Info("Reloaded the TLS files", zap.String("cert", cr.opts.CertFile))
+	return config, nil
+}
+
+// load reads the files into the config of a handshake, NextProtos is repeated from the
+// server config it replaces so that HTTP/2 is still negotiated
+func (cr *certReloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cr.opts.CertFile, cr.opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if cr.opts.ClientCAFile == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(cr.opts.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", cr.opts.ClientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if cr.opts.RequireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+func (cr *certReloader) stat() ([]time.Time, error) {
+	files := []string{cr.opts.CertFile, cr.opts.KeyFile}
+	if cr.opts.ClientCAFile != "" {
+		files = append(files, cr.opts.ClientCAFile)
+	}
+	modTimes := make([]time.Time, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
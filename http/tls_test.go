@@ -0,0 +1,183 @@
+package http
+
+import (
+	// Go Internal Packages
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// writeCert writes a self-signed certificate with the serial number and its key to the
+// files, and sets their modification time to modTime
+func writeCert(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, certFile, "CERTIFICATE", der, modTime)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER, modTime)
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// servedSerial makes a handshake with the config and returns the serial number of the
+// certificate the server presented
+func servedSerial(t *testing.T, config *tls.Config) int64 {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	server := tls.Server(serverConn, config)
+	defer server.Close()
+	go func() { _ = server.Handshake() }()
+
+	client := tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true, ServerName: "localhost"})
+	if err := client.Handshake(); err != nil {
+		t.Fatalf("Handshake() error = %v", err)
+	}
+	return client.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Hour)
+	writeCert(t, certFile, keyFile, 1, start)
+
+	cr, err := newCertReloader(TLSOptions{CertFile: certFile, KeyFile: keyFile}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("newCertReloader() error = %v", err)
+	}
+	config := cr.TLSConfig()
+	if got := servedSerial(t, config); got != 1 {
+		t.Fatalf("served certificate %d, want 1", got)
+	}
+
+	// A renewed certificate is served by the next handshake
+	writeCert(t, certFile, keyFile, 2, start.Add(time.Minute))
+	if got := servedSerial(t, config); got != 2 {
+		t.Fatalf("served certificate %d after the renewal, want 2", got)
+	}
+
+	// A file that fails to load keeps the previous certificate in use
+	writePEM(t, keyFile, "EC PRIVATE KEY", []byte("broken"), start.Add(2*time.Minute))
+	if got := servedSerial(t, config); got != 2 {
+		t.Fatalf("served certificate %d after a broken renewal, want 2", got)
+	}
+	if err := os.Remove(certFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := servedSerial(t, config); got != 2 {
+		t.Fatalf("served certificate %d after the file was removed, want 2", got)
+	}
+	writeCert(t, certFile, keyFile, 3, start.Add(3*time.Minute))
+	if got := servedSerial(t, config); got != 3 {
+		t.Fatalf("served certificate %d once fixed, want 3", got)
+	}
+}
+
+func TestCertReloaderChecksOncePerInterval(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Hour)
+	writeCert(t, certFile, keyFile, 1, start)
+
+	cr, err := newCertReloader(TLSOptions{CertFile: certFile, KeyFile: keyFile, ReloadInterval: time.Hour},
+		zap.NewNop())
+	if err != nil {
+		t.Fatalf("newCertReloader() error = %v", err)
+	}
+	writeCert(t, certFile, keyFile, 2, start.Add(time.Minute))
+	if got := servedSerial(t, cr.TLSConfig()); got != 1 {
+		t.Fatalf("served certificate %d within the interval, want 1", got)
+	}
+
+	cr.mu.Lock()
+	cr.checked = cr.checked.Add(-time.Hour)
+	cr.mu.Unlock()
+	if got := servedSerial(t, cr.TLSConfig()); got != 2 {
+		t.Fatalf("served certificate %d once the interval passed, want 2", got)
+	}
+}
+
+func TestCertReloaderClientCAs(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+	writeCert(t, certFile, keyFile, 1, time.Now())
+	writeCert(t, caFile, filepath.Join(dir, "ca.key"), 2, time.Now())
+
+	tests := []struct {
+		name    string
+		opts    TLSOptions
+		want    tls.ClientAuthType
+		wantErr bool
+	}{
+		{name: "without client CAs", opts: TLSOptions{}, want: tls.NoClientCert},
+		{name: "optional", opts: TLSOptions{ClientCAFile: caFile}, want: tls.VerifyClientCertIfGiven},
+		{name: "required", opts: TLSOptions{ClientCAFile: caFile, RequireClientCert: true},
+			want: tls.RequireAndVerifyClientCert},
+		{name: "CA file without certificates", opts: TLSOptions{ClientCAFile: keyFile}, wantErr: true},
+		{name: "missing CA file", opts: TLSOptions{ClientCAFile: filepath.Join(dir, "missing.crt")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.CertFile, tt.opts.KeyFile = certFile, keyFile
+			cr, err := newCertReloader(tt.opts, zap.NewNop())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("newCertReloader() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newCertReloader() error = %v", err)
+			}
+			config, _ := cr.configForClient(nil)
+			if config.ClientAuth != tt.want || (tt.opts.ClientCAFile != "") != (config.ClientCAs != nil) {
+				t.Fatalf("ClientAuth = %v with CAs %v, want %v", config.ClientAuth, config.ClientCAs != nil, tt.want)
+			}
+		})
+	}
+}
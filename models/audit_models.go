@@ -0,0 +1,42 @@
+package models
+
+import (
+	// Go Internal Packages
+	"time"
+)
+
+// Entities of the audit log
+const (
+	AuditStudent = "student"
+	AuditOrder   = "order"
+)
+
+// Actions of the audit log
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// AuditEntry records who changed a student or an order and how, entries are only
+// ever appended to the audit log
+type AuditEntry struct {
+	ID        string        `json:"id" bson:"_id"`
+	Entity    string        `json:"entity" bson:"entity"`
+	EntityID  string        `json:"entity_id" bson:"entity_id"`
+	Action    string        `json:"action" bson:"action"`
+	Actor     string        `json:"actor" bson:"actor"`
+	RequestID string        `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	At        time.Time     `json:"at" bson:"at"`
+	Changes   []AuditChange `json:"changes" bson:"changes"`
+}
+
+// AuditChange is a field whose value changed, nested fields are named by their path,
+// e.g. address.city. Before is null on creation and After on deletion.
+type AuditChange struct {
+	Field  string `json:"field" bson:"field"`
+	Before any    `json:"before" bson:"before"`
+	After  any    `json:"after" bson:"after"`
+}
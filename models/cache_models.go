@@ -0,0 +1,7 @@
+package models
+
+// CacheStats holds the lookup counters of a read-through cache
+type CacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "learn-go/errors"
+
+// Statuses of an imported row
+const (
+	ImportInserted  = "inserted"
+	ImportValid     = "valid" // The row would be inserted, only reported by dry runs
+	ImportInvalid   = "invalid"
+	ImportDuplicate = "duplicate"
+	ImportAborted   = "aborted" // The row was read but the import stopped before its batch was written
+)
+
+// ImportRecord is a decoded row of an import, Err tells why the row could not be decoded
+type ImportRecord struct {
+	Line    int
+	Student StudentModel
+	Err     error
+}
+
+// ImportRow is the outcome of a row, Line is its line in the uploaded file
+type ImportRow struct {
+	Line   int                     `json:"line"`
+	RollNo string                  `json:"roll_no,omitempty"`
+	Status string                  `json:"status"`
+	Errors errors.ValidationErrors `json:"errors,omitempty"`
+}
+
+// ImportReport sums up an import, nothing is written by a dry run. Error tells why an
+// import stopped after its last row, the batches written before are kept.
+type ImportReport struct {
+	DryRun     bool        `json:"dry_run"`
+	Total      int         `json:"total"`
+	Inserted   int         `json:"inserted"`
+	Valid      int         `json:"valid"`
+	Invalid    int         `json:"invalid"`
+	Duplicates int         `json:"duplicates"`
+	Aborted    int         `json:"aborted"`
+	Error      string      `json:"error,omitempty"`
+	Rows       []ImportRow `json:"rows"`
+}
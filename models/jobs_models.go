@@ -0,0 +1,63 @@
+package models
+
+import (
+	// Go Internal Packages
+	"encoding/json"
+	"time"
+
+	// Local Packages
+	"learn-go/errors"
+	"learn-go/validate"
+)
+
+// Statuses of a background job, succeeded, failed and canceled are final
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Job is a unit of background work, Result holds what the job returned once it succeeded
+// and Error why its last attempt failed
+type Job struct {
+	ID          string          `json:"job_id"`
+	Type        string          `json:"type"`
+	Status      string          `json:"status"`
+	Params      json.RawMessage `json:"params,omitempty"`
+	Progress    JobProgress     `json:"progress"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	RetryAt     *time.Time      `json:"retry_at,omitempty"`
+	HeartbeatAt *time.Time      `json:"heartbeat_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+}
+
+// JobProgress counts the units of work done, Total is zero while it is unknown
+type JobProgress struct {
+	Done  int64 `json:"done"`
+	Total int64 `json:"total"`
+}
+
+// JobRequest is the body of POST /jobs, MaxAttempts defaults to the configured one
+type JobRequest struct {
+	Type        string          `json:"type" validate:"trim,required,max=64"`
+	Params      json.RawMessage `json:"params"`
+	MaxAttempts int             `json:"max_attempts" validate:"min=0,max=10"`
+}
+
+// Finished reports whether the job reached a final status
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+func (r *JobRequest) Validate() error {
+	ve := errors.ValidationErrs()
+	validate.Struct(r, ve)
+	return ve.Err()
+}
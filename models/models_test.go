@@ -0,0 +1,105 @@
+package models
+
+import (
+	// Go Internal Packages
+	"reflect"
+	"testing"
+	"time"
+
+	// Local Packages
+	"learn-go/errors"
+)
+
+func fieldErrors(err error) errors.ValidationErrors {
+	if err == nil {
+		return nil
+	}
+	return err.(errors.ValidationErrors)
+}
+
+func TestStudentValidate(t *testing.T) {
+	valid := func() StudentModel {
+		return StudentModel{RollNo: "21", Name: "Ann", Gender: "female", MailID: "ann@example.com"}
+	}
+	tests := []struct {
+		name   string
+		modify func(s *StudentModel)
+		want   errors.ValidationErrors
+	}{
+		{name: "valid", modify: func(s *StudentModel) {}},
+		{name: "roll number with dashes", modify: func(s *StudentModel) { s.RollNo = "cs-21_a" }},
+		{name: "roll number starting with a dash", modify: func(s *StudentModel) { s.RollNo = "-21" },
+			want: errors.ValidationErrors{{Field: "/roll_no",
+				Error: "must be up to 32 letters, digits, '-' or '_' starting with a letter or digit"}}},
+		{name: "gender", modify: func(s *StudentModel) { s.Gender = "unknown" },
+			want: errors.ValidationErrors{{Field: "/gender", Error: "must be one of male, female, other"}}},
+		{name: "email", modify: func(s *StudentModel) { s.MailID = "ann" },
+			want: errors.ValidationErrors{{Field: "/mail_id", Error: "must be a valid email address"}}},
+		{name: "everything missing", modify: func(s *StudentModel) { *s = StudentModel{} },
+			want: errors.ValidationErrors{
+				{Field: "/roll_no", Error: "cannot be empty"},
+				{Field: "/name", Error: "cannot be empty"},
+				{Field: "/gender", Error: "cannot be empty"},
+				{Field: "/mail_id", Error: "cannot be empty"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			if got := fieldErrors(s.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStudentValidateNormalises(t *testing.T) {
+	deletedAt := time.Now()
+	s := StudentModel{RollNo: " 21 ", Name: " Ann ", Gender: "Female", MailID: " Ann@Example.com",
+		DeletedAt: &deletedAt, DeletedBy: "admin"}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	want := StudentModel{RollNo: "21", Name: "Ann", Gender: "female", MailID: "ann@example.com"}
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("Validate() left %+v, want %+v", s, want)
+	}
+}
+
+func TestOrderValidate(t *testing.T) {
+	valid := func() Order {
+		return Order{UserID: "u1", OrderStatus: "placed", LineItems: []LineItem{{ItemID: "i1", Quantity: 1, Price: 2.5}}}
+	}
+
+	o := valid()
+	if err := o.ValidateCreation(); err != nil {
+		t.Fatalf("ValidateCreation() error = %v", err)
+	}
+	if o.ShippedAt == "" || o.DeliveredAt == "" {
+		t.Fatalf("ValidateCreation() did not default the shipping dates: %+v", o)
+	}
+
+	o = valid()
+	o.ID = "o1"
+	o.LineItems = []LineItem{{ItemID: " ", Quantity: 0, Price: 1}}
+	want := errors.ValidationErrors{
+		{Field: "/order_id", Error: "must be empty during creation"},
+		{Field: "/line_items/0/item_id", Error: "cannot be empty"},
+		{Field: "/line_items/0/quantity", Error: "must be greater than 0"},
+	}
+	if got := fieldErrors(o.ValidateCreation()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ValidateCreation() = %+v, want %+v", got, want)
+	}
+
+	o = valid()
+	o.ID = "o1"
+	o.LineItems = nil
+	want = errors.ValidationErrors{
+		{Field: "/order_id", Error: "does not match the existing order"},
+		{Field: "/line_items", Error: "cannot be empty"},
+	}
+	if got := fieldErrors(o.ValidateUpdate("o2")); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ValidateUpdate() = %+v, want %+v", got, want)
+	}
+}
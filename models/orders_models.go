@@ -1,32 +1,36 @@
 package models
 
 import (
+	// Go Internal Packages
+	"time"
+
 	// Local Packages
 	"learn-go/errors"
+	"learn-go/validate"
 )
 
 type Order struct {
-	ID          string     `json:"order_id"`
-	UserID      string     `json:"user_id"`
-	LineItems   []LineItem `json:"line_items"`
-	OrderStatus string     `json:"order_status"`
-	CreatedAt   string     `json:"created_at"`
-	UpdatedAt   string     `json:"updated_at"`
-	ShippedAt   string     `json:"shipped_at"`
-	DeliveredAt string     `json:"delivered_at"`
+	ID          string     `json:"order_id" bson:"_id"`
+	UserID      string     `json:"user_id" bson:"user_id" validate:"trim,required,max=64"`
+	LineItems   []LineItem `json:"line_items" bson:"line_items" validate:"required,max=100"`
+	OrderStatus string     `json:"order_status" bson:"order_status" validate:"trim,required,max=32"`
+	CreatedAt   string     `json:"created_at" bson:"created_at"`
+	UpdatedAt   string     `json:"updated_at" bson:"updated_at"`
+	ShippedAt   string     `json:"shipped_at" bson:"shipped_at"`
+	DeliveredAt string     `json:"delivered_at" bson:"delivered_at"`
 }
 
 type LineItem struct {
-	ItemID   string  `json:"item_id"`
-	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"`
+	ItemID   string  `json:"item_id" bson:"item_id" validate:"trim,required,max=64"`
+	Quantity int     `json:"quantity" bson:"quantity" validate:"gt=0"`
+	Price    float64 `json:"price" bson:"price" validate:"gt=0"`
 }
 
 func (o *Order) ValidateCreation() error {
 	ve := errors.ValidationErrs()
 
 	if o.ID != "" {
-		ve.Add("order_id", "must be empty during creation")
+		ve.Add("/order_id", "must be empty during creation")
 	}
 	validateOrderFields(o, ve)
 
@@ -44,7 +48,7 @@ func (o *Order) ValidateUpdate(orderID string) error {
 	ve := errors.ValidationErrs()
 
 	if o.ID != orderID {
-		ve.Add("order_id", "does not match the existing order")
+		ve.Add("/order_id", "does not match the existing order")
 	}
 	validateOrderFields(o, ve)
 
@@ -52,24 +56,26 @@ func (o *Order) ValidateUpdate(orderID string) error {
 }
 
 func validateOrderFields(o *Order, ve *errors.ValidationErrorBuilder) {
-	if o.UserID == "" {
-		ve.Add("user_id", "cannot be empty")
-	}
-	if len(o.LineItems) == 0 {
-		ve.Add("line_items", "cannot be empty")
-	}
-	if o.OrderStatus == "" {
-		ve.Add("order_status", "cannot be empty")
-	}
-	for _, item := range o.LineItems {
-		if item.ItemID == "" {
-			ve.Add("item_id", "cannot be empty")
-		}
-		if item.Quantity <= 0 {
-			ve.Add("quantity", "must be greater than zero")
-		}
-		if item.Price <= 0 {
-			ve.Add("price", "must be greater than zero")
-		}
-	}
+	validate.Struct(o, ve)
+}
+
+// Kinds of OrderChange
+const (
+	OrderInserted = "inserted"
+	OrderUpdated  = "updated"
+	OrderDeleted  = "deleted"
+	// OrderReset carries no order, the changes after the id the client resumed from are
+	// no longer retained and it should reload the orders
+	OrderReset = "reset"
+)
+
+// OrderChange is pushed to the clients following the orders. ID is its position in the
+// change history and is what clients send back to resume after a disconnect.
+type OrderChange struct {
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	OrderID    string    `json:"order_id"`
+	UserID     string    `json:"user_id,omitempty"`
+	Order      *Order    `json:"order,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
 }
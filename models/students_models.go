@@ -1,27 +1,35 @@
 package models
 
-import "learn-go/errors"
+import (
+	// Go Internal Packages
+	"time"
+
+	// Local Packages
+	"learn-go/errors"
+	"learn-go/validate"
+)
+
+func init() {
+	validate.RegisterPattern("roll_no", `^[A-Za-z0-9][A-Za-z0-9_-]{0,31}$`,
+		"must be up to 32 letters, digits, '-' or '_' starting with a letter or digit")
+}
 
 type StudentModel struct {
-	RollNo string `json:"roll_no" bson:"Roll_No"`
-	Name   string `json:"name" bson:"Student_Name"`
-	Gender string `json:"gender" bson:"Gender"`
-	MailID string `json:"mail_id" bson:"Mail_Id"`
+	RollNo string `json:"roll_no" bson:"Roll_No" validate:"trim,required,pattern=roll_no"`
+	Name   string `json:"name" bson:"Student_Name" validate:"trim,required,max=100"`
+	Gender string `json:"gender" bson:"Gender" validate:"trim,lower,required,oneof=male female other"`
+	MailID string `json:"mail_id" bson:"Mail_Id" validate:"trim,lower,required,max=254,email"`
+
+	// Set once the student is deleted, until it is restored or purged
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"Deleted_At,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty" bson:"Deleted_By,omitempty"`
 }
 
+// Validate normalises the fields, trimming them and lowercasing the gender and mail id,
+// and checks them against their rules. The deletion is kept by the server and dropped.
 func (s *StudentModel) Validate() error {
+	s.DeletedAt, s.DeletedBy = nil, ""
 	ve := errors.ValidationErrs()
-	if s.RollNo == "" {
-		ve.Add("rollNo", "cannot be empty")
-	}
-	if s.Name == "" {
-		ve.Add("name", "cannot be empty")
-	}
-	if s.Gender == "" {
-		ve.Add("gender", "cannot be empty")
-	}
-	if s.MailID == "" {
-		ve.Add("mail_id", "cannot be empty")
-	}
+	validate.Struct(s, ve)
 	return ve.Err()
 }
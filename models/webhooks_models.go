@@ -0,0 +1,77 @@
+package models
+
+import (
+	// Go Internal Packages
+	"fmt"
+	"net/url"
+	"time"
+
+	// Local Packages
+	"learn-go/errors"
+	"learn-go/events"
+)
+
+type WebhookSubscription struct {
+	ID         string        `json:"webhook_id" bson:"_id"`
+	URL        string        `json:"url" bson:"url"`
+	Secret     string        `json:"secret,omitempty" bson:"secret"`
+	EventTypes []events.Type `json:"event_types" bson:"event_types"`
+	CreatedAt  time.Time     `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at" bson:"updated_at"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a subscription
+type WebhookDelivery struct {
+	ID             string      `json:"delivery_id" bson:"_id"`
+	WebhookID      string      `json:"webhook_id" bson:"webhook_id"`
+	EventID        string      `json:"event_id" bson:"event_id"`
+	EventType      events.Type `json:"event_type" bson:"event_type"`
+	Attempt        int         `json:"attempt" bson:"attempt"`
+	StatusCode     int         `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error          string      `json:"error,omitempty" bson:"error,omitempty"`
+	Success        bool        `json:"success" bson:"success"`
+	DurationMillis int64       `json:"duration_ms" bson:"duration_ms"`
+	DeliveredAt    time.Time   `json:"delivered_at" bson:"delivered_at"`
+}
+
+// WebhookRetry is a failed delivery of an event to a subscription waiting for its next
+// attempt, there is at most one per subscription and event
+type WebhookRetry struct {
+	ID        string      `bson:"_id"` // "<webhook id>:<event id>"
+	WebhookID string      `bson:"webhook_id"`
+	EventID   string      `bson:"event_id"`
+	EventType events.Type `bson:"event_type"`
+	Body      []byte      `bson:"body"`
+	Attempt   int         `bson:"attempt"`
+	DueAt     time.Time   `bson:"due_at"`
+}
+
+func (w *WebhookSubscription) Validate() error {
+	ve := errors.ValidationErrs()
+
+	if w.URL == "" {
+		ve.Add("/url", "cannot be empty")
+	} else if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ve.Add("/url", "must be an absolute http or https url")
+	}
+	if len(w.EventTypes) == 0 {
+		ve.Add("/event_types", "cannot be empty")
+	}
+	for i, eventType := range w.EventTypes {
+		if !eventType.IsValid() {
+			ve.Add(fmt.Sprintf("/event_types/%d", i), "unknown event type "+string(eventType))
+		}
+	}
+
+	return ve.Err()
+}
+
+// Subscribes reports whether the subscription wants events of the given type
+func (w *WebhookSubscription) Subscribes(eventType events.Type) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+package patch
+
+import (
+	// Go Internal Packages
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+// Operation is one step of a JSON Patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is an RFC 6902 patch, its operations are applied in order and the
+// patch fails as a whole when one of them fails
+type JSONPatch []Operation
+
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	var root any
+	if err := decode(doc, &root); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	ve := errors.ValidationErrs()
+	for i, op := range p {
+		var err error
+		if root, err = op.apply(root); err != nil {
+			ve.Add(fmt.Sprintf("/%d", i), err.Error())
+			return nil, errors.E(errors.Invalid, "failed to apply patch", ve.Err())
+		}
+	}
+	return json.Marshal(root)
+}
+
+func (op Operation) apply(root any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%s needs a value", op.Op)
+		}
+		var value any
+		if err := decode(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			if root, _, err = remove(root, path); err != nil {
+				return nil, err
+			}
+			return add(root, path, value)
+		default:
+			current, err := get(root, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(normalize(current), normalize(value)) {
+				return nil, fmt.Errorf("test failed for %s", op.Path)
+			}
+			return root, nil
+		}
+	case "remove":
+		root, _, err = remove(root, path)
+		return root, err
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		var value any
+		if op.Op == "move" {
+			if isPrefix(from, path) && len(from) < len(path) {
+				return nil, fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			if root, value, err = remove(root, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = get(root, from); err != nil {
+			return nil, err
+		}
+		return add(root, path, deepCopy(value))
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func get(node any, path []string) (any, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("path /%s does not exist", strings.Join(path, "/"))
+			}
+			node = child
+		case []any:
+			i, err := index(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("path /%s does not exist", strings.Join(path, "/"))
+		}
+	}
+	return node, nil
+}
+
+// add sets the value at path, inserting into arrays, and returns the new root
+func add(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		p[token] = value
+		return root, nil
+	case []any:
+		i := len(p)
+		if token != "-" {
+			if i, err = index(token, len(p)); err != nil {
+				return nil, err
+			}
+		}
+		p = append(p[:i], append([]any{value}, p[i:]...)...)
+		return set(root, path[:len(path)-1], p)
+	default:
+		return nil, fmt.Errorf("cannot add to /%s", strings.Join(path[:len(path)-1], "/"))
+	}
+}
+
+// remove deletes the value at path and returns the new root and the removed value
+func remove(root any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, root, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		value, ok := p[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path /%s does not exist", strings.Join(path, "/"))
+		}
+		delete(p, token)
+		return root, value, nil
+	case []any:
+		i, err := index(token, len(p)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := p[i]
+		root, err = set(root, path[:len(path)-1], append(p[:i:i], p[i+1:]...))
+		return root, value, err
+	default:
+		return nil, nil, fmt.Errorf("path /%s does not exist", strings.Join(path, "/"))
+	}
+}
+
+// set replaces the array at path, slices change identity when they grow or shrink
+func set(root any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	token := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		p[token] = value
+	case []any:
+		i, err := index(token, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+		p[i] = value
+	}
+	return root, nil
+}
+
+func index(token string, maxIndex int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > maxIndex || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return i, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for key, child := range v {
+			c[key] = deepCopy(child)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, child := range v {
+			c[i] = deepCopy(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// normalize makes numbers comparable whatever their textual form, e.g. 1 and 1.0
+func normalize(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for key, child := range v {
+			c[key] = normalize(child)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, child := range v {
+			c[i] = normalize(child)
+		}
+		return c
+	default:
+		return v
+	}
+}
@@ -0,0 +1,41 @@
+package patch
+
+import (
+	// Go Internal Packages
+	"encoding/json"
+	"fmt"
+)
+
+// MergePatch is an RFC 7396 merge patch: objects are merged recursively, null removes
+// a member and any other value replaces the target
+type MergePatch struct {
+	patch any
+}
+
+func (m MergePatch) Apply(doc []byte) ([]byte, error) {
+	var target any
+	if err := decode(doc, &target); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	return json.Marshal(merge(target, m.patch))
+}
+
+func merge(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = merge(targetObj[key], value)
+	}
+	return targetObj
+}
@@ -0,0 +1,67 @@
+// Package patch applies RFC 7396 JSON Merge Patches and RFC 6902 JSON Patches to
+// the JSON representation of a document.
+package patch
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+// Media types of the patch formats
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// Patch changes a JSON document
+type Patch interface {
+	Apply(doc []byte) ([]byte, error)
+}
+
+// Parse reads a JSON Patch when the Content-Type names one and a merge patch otherwise,
+// the same way the other handlers decode JSON whatever the Content-Type says. A
+// malformed patch is an Invalid error.
+func Parse(contentType string, body []byte) (Patch, error) {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == JSONPatchType {
+		var ops []Operation
+		if err := decode(body, &ops); err != nil {
+			return nil, errors.InvalidBodyErr(err)
+		}
+		return JSONPatch(ops), nil
+	}
+
+	var p any
+	if err := decode(body, &p); err != nil {
+		return nil, errors.InvalidBodyErr(err)
+	}
+	return MergePatch{patch: p}, nil
+}
+
+// To applies the patch to the JSON representation of v and decodes the result into a new T
+func To[T any](p Patch, v T) (T, error) {
+	var patched T
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return patched, fmt.Errorf("failed to encode document: %w", err)
+	}
+	if doc, err = p.Apply(doc); err != nil {
+		return patched, err
+	}
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return patched, errors.InvalidBodyErr(err)
+	}
+	return patched, nil
+}
+
+// decode keeps numbers as json.Number so that they are copied unchanged
+func decode(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,216 @@
+package patch
+
+import (
+	// Go Internal Packages
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+// equalJSON reports whether a and b hold the same JSON value
+func equalJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var av, bv any
+	if err := json.Unmarshal(a, &av); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &bv); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// The examples of RFC 7396, Appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.doc+" "+tt.patch, func(t *testing.T) {
+			p, err := Parse(MergePatchType, []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := p.Apply([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !equalJSON(t, got, []byte(tt.want)) {
+				t.Fatalf("Apply() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// The examples of RFC 6902, Appendix A
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"A.1 adding an object member",
+			`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"A.2 adding an array element",
+			`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"A.3 removing an object member",
+			`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"A.4 removing an array element",
+			`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"A.5 replacing a value",
+			`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"A.6 moving a value",
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"A.7 moving an array element",
+			`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`},
+		{"A.8 testing a value: success",
+			`{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{"A.10 adding a nested member object",
+			`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			`{"foo":"bar","child":{"grandchild":{}}}`},
+		{"A.11 ignoring unrecognized elements",
+			`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+		{"A.14 ~ escape ordering",
+			`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"A.16 adding an array value",
+			`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"copy",
+			`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			`{"a":{"b":1},"c":{"b":2}}`},
+		{"replace the root",
+			`{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(JSONPatchType, []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := p.Apply([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !equalJSON(t, got, []byte(tt.want)) {
+				t.Fatalf("Apply() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+	}{
+		{"A.9 testing a value: error", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+		{"A.12 adding to a nonexistent target", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{"A.13 invalid JSON patch document", `{"foo":"bar"}`,
+			`[{"op":"add","path":"/baz","value":"qux","op":"remove"}]`},
+		{"A.15 comparing strings and numbers", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`},
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`},
+		{"add without a value", `{}`, `[{"op":"add","path":"/a"}]`},
+		{"pointer without a slash", `{"a":1}`, `[{"op":"remove","path":"a"}]`},
+		{"index out of range", `{"a":[1]}`, `[{"op":"add","path":"/a/5","value":2}]`},
+		{"move into its own child", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(JSONPatchType, []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if _, err := p.Apply([]byte(tt.doc)); !errors.IsKind(err, errors.Invalid) {
+				t.Fatalf("Apply() error = %v, want Invalid", err)
+			}
+		})
+	}
+}
+
+func TestJSONPatchIsAtomic(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+	p, _ := Parse(JSONPatchType, []byte(`[{"op":"replace","path":"/a","value":2},{"op":"remove","path":"/b"}]`))
+	if _, err := p.Apply(doc); err == nil {
+		t.Fatalf("Apply() of a failing patch succeeded")
+	}
+	if string(doc) != `{"a":1}` {
+		t.Fatalf("a failed Apply() changed the document to %s", doc)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantPatch   Patch
+		wantErr     bool
+	}{
+		{name: "json patch", contentType: JSONPatchType + "; charset=utf-8", body: `[]`, wantPatch: JSONPatch{}},
+		{name: "merge patch", contentType: MergePatchType, body: `{}`, wantPatch: MergePatch{}},
+		{name: "plain json is a merge patch", contentType: "application/json", body: `{}`, wantPatch: MergePatch{}},
+		{name: "malformed json patch", contentType: JSONPatchType, body: `{"op":"add"}`, wantErr: true},
+		{name: "malformed merge patch", contentType: MergePatchType, body: `{`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(tt.contentType, []byte(tt.body))
+			if tt.wantErr {
+				if !errors.IsKind(err, errors.Invalid) {
+					t.Fatalf("Parse() error = %v, want Invalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if reflect.TypeOf(p) != reflect.TypeOf(tt.wantPatch) {
+				t.Fatalf("Parse() = %T, want %T", p, tt.wantPatch)
+			}
+		})
+	}
+}
+
+func TestTo(t *testing.T) {
+	type student struct {
+		Name   string `json:"name"`
+		Gender string `json:"gender"`
+		Marks  int64  `json:"marks"`
+	}
+	current := student{Name: "Ann", Gender: "female", Marks: 9007199254740993}
+
+	p, _ := Parse(MergePatchType, []byte(`{"name":"Bea"}`))
+	got, err := To(p, current)
+	if err != nil {
+		t.Fatalf("To() error = %v", err)
+	}
+	// Numbers the patch does not touch are copied exactly
+	if want := (student{Name: "Bea", Gender: "female", Marks: 9007199254740993}); got != want {
+		t.Fatalf("To() = %+v, want %+v", got, want)
+	}
+
+	p, _ = Parse(MergePatchType, []byte(`{"marks":"many"}`))
+	if _, err := To(p, current); !errors.IsKind(err, errors.Invalid) {
+		t.Fatalf("To() of a patch with the wrong type error = %v, want Invalid", err)
+	}
+}
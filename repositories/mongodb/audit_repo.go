@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+
+	// External Packages
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository keeps the audit log, entries are inserted and read but never
+// updated or deleted
+type AuditRepository struct {
+	client     *mongo.Client
+	collection string
+}
+
+func NewAuditRepository(client *mongo.Client) *AuditRepository {
+	return &AuditRepository{client: client, collection: "audit_log"}
+}
+
+// EnsureIndexes creates the index the entries of an entity are listed by
+func (r *AuditRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "entity", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create audit log index: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) Append(ctx context.Context, entries ...models.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	docs := make([]any, len(entries))
+	for i, entry := range entries {
+		docs[i] = entry
+	}
+
+	// Entries redelivered by the events bus are in the log already, the others are still inserted
+	collection := r.client.Database("mybase").Collection(r.collection)
+	_, err := collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicateKeys(err) {
+		return fmt.Errorf("failed to append audit entries: %w", err)
+	}
+	return nil
+}
+
+// onlyDuplicateKeys reports whether every write of a bulk insert failed on a taken _id
+func onlyDuplicateKeys(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return mongo.IsDuplicateKeyError(err)
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			return false
+		}
+	}
+	return true
+}
+
+// List returns the window of the entries of an entity, or of one of them when entityID
+// is not empty, newest first
+func (r *AuditRepository) List(ctx context.Context, entity, entityID string, limit, offset int) ([]models.AuditEntry, error) {
+	filter := bson.M{"entity": entity}
+	if entityID != "" {
+		filter["entity_id"] = entityID
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit))
+
+	collection := r.client.Database("mybase").Collection(r.collection)
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entries: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,211 @@
+package mongodb
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+
+	// External Packages
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OrdersRepository keeps orders in MongoDB, it is the system of record when
+// order persistence is enabled and Redis only holds the hot copy.
+type OrdersRepository struct {
+	client     *mongo.Client
+	collection string
+}
+
+func NewOrdersRepository(client *mongo.Client) *OrdersRepository {
+	return &OrdersRepository{client: client, collection: "orders"}
+}
+
+func (r *OrdersRepository) GetOne(ctx context.Context, orderID string) (models.Order, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+
+	var order models.Order
+	err := collection.FindOne(ctx, bson.M{"_id": orderID}).Decode(&order)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.Order{}, errors.E(errors.NotFound, "order not found")
+	}
+	if err != nil {
+		return models.Order{}, fmt.Errorf("failed to get order: %w", err)
+	}
+	return order, nil
+}
+
+func (r *OrdersRepository) Insert(ctx context.Context, order models.Order, evts ...events.Event) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		if _, err := collection.InsertOne(ctx, order); err != nil {
+			return fmt.Errorf("failed to insert order: %w", err)
+		}
+		return nil
+	})
+}
+
+// Update replaces the order, creating it when missing so replayed writes converge
+func (r *OrdersRepository) Update(ctx context.Context, order models.Order, evts ...events.Event) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	opts := options.Replace().SetUpsert(true)
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		if _, err := collection.ReplaceOne(ctx, bson.M{"_id": order.ID}, order, opts); err != nil {
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+		return nil
+	})
+}
+
+// modifyAttempts bounds the optimistic retries of Modify under contention
+const modifyAttempts = 3
+
+// Modify replaces the order with the one fn derives from it, writing only the changed
+// fields with $set and $unset. The update matches only while the changed fields hold
+// the values fn saw, otherwise fn is called again with the fresh order.
+func (r *OrdersRepository) Modify(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) (models.Order, []events.Event, error),
+) (models.Order, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		current, err := r.GetOne(ctx, orderID)
+		if err != nil {
+			return models.Order{}, err
+		}
+		updated, evts, err := fn(current)
+		if err != nil {
+			return models.Order{}, err
+		}
+		changed, update, err := patchUpdate(current, updated)
+		if err != nil {
+			return models.Order{}, err
+		}
+		if len(update) == 0 {
+			return updated, nil
+		}
+
+		filter := append(bson.D{{Key: "_id", Value: orderID}}, changed...)
+		err = withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+			res, err := collection.UpdateOne(ctx, filter, update)
+			if err != nil {
+				return fmt.Errorf("failed to update order: %w", err)
+			}
+			if res.MatchedCount == 0 {
+				return errModified
+			}
+			return nil
+		})
+		if errors.Is(err, errModified) {
+			continue
+		}
+		if err != nil {
+			return models.Order{}, err
+		}
+		return updated, nil
+	}
+	return models.Order{}, errors.E(errors.Conflict, "order was changed concurrently, retry the request")
+}
+
+// errModified reports that the order changed between the read and the write of Modify
+var errModified = errors.NewError("order was modified")
+
+func (r *OrdersRepository) Delete(ctx context.Context, orderID string, evts ...events.Event) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": orderID}); err != nil {
+			return fmt.Errorf("failed to delete order: %w", err)
+		}
+		return nil
+	})
+}
+
+// Remove deletes the order together with the events fn derives from it. The delete
+// matches only while every field of the order holds the value fn saw, otherwise fn is
+// called again with the fresh order.
+func (r *OrdersRepository) Remove(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) ([]events.Event, error),
+) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		current, err := r.GetOne(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		evts, err := fn(current)
+		if err != nil {
+			return err
+		}
+
+		filter, err := asFilter(current)
+		if err != nil {
+			return err
+		}
+		err = withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+			res, err := collection.DeleteOne(ctx, filter)
+			if err != nil {
+				return fmt.Errorf("failed to delete order: %w", err)
+			}
+			if res.DeletedCount == 0 {
+				return errModified
+			}
+			return nil
+		})
+		if !errors.Is(err, errModified) {
+			return err
+		}
+	}
+	return errors.E(errors.Conflict, "order was changed concurrently, retry the request")
+}
+
+// asFilter matches the document that holds every field of the order as it is
+func asFilter(order models.Order) (bson.D, error) {
+	data, err := bson.Marshal(order)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode order: %w", err)
+	}
+	var filter bson.D
+	if err := bson.Unmarshal(data, &filter); err != nil {
+		return nil, fmt.Errorf("failed to encode order: %w", err)
+	}
+	return filter, nil
+}
+
+func (r *OrdersRepository) Exists(ctx context.Context, orderID string) (bool, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": orderID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("failed to check order: %w", err)
+	}
+	return count == 1, nil
+}
+
+// Iterate calls fn for every stored order in order id order, stopping at the first error
+func (r *OrdersRepository) Iterate(ctx context.Context, fn func(order models.Order) error) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("failed to list orders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var order models.Order
+		if err := cursor.Decode(&order); err != nil {
+			return fmt.Errorf("failed to decode order: %w", err)
+		}
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,113 @@
+package mongodb
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"time"
+
+	// Local Packages
+	events "learn-go/events"
+
+	// External Packages
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const outboxCollection = "outbox"
+
+// Outbox reads the events written to the outbox collection. Published events
+// are kept with a published_at timestamp.
+type Outbox struct {
+	client *mongo.Client
+}
+
+func NewOutbox(client *mongo.Client) *Outbox {
+	return &Outbox{client: client}
+}
+
+func (o *Outbox) Name() string {
+	return "mongo"
+}
+
+func (o *Outbox) Pending(ctx context.Context, limit int) ([]events.Event, error) {
+	collection := o.client.Database("mybase").Collection(outboxCollection)
+	filter := bson.M{"published_at": bson.M{"$exists": false}}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "occurred_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	evts := []events.Event{}
+	if err := cursor.All(ctx, &evts); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox: %w", err)
+	}
+	return evts, nil
+}
+
+func (o *Outbox) MarkPublished(ctx context.Context, evts []events.Event) error {
+	ids := make([]string, 0, len(evts))
+	for _, evt := range evts {
+		ids = append(ids, evt.ID)
+	}
+
+	collection := o.client.Database("mybase").Collection(outboxCollection)
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	update := bson.M{"$set": bson.M{"published_at": time.Now().UTC()}}
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to record outbox progress: %w", err)
+	}
+	return nil
+}
+
+// withOutbox runs fn and writes the events to the outbox in a single transaction.
+// Without events fn runs on its own, so deployments that do not emit events do
+// not need a replica set.
+func withOutbox(ctx context.Context, client *mongo.Client, evts []events.Event, fn func(ctx context.Context) error) error {
+	return withOutcomeOutbox(ctx, client, len(evts) > 0, func(ctx context.Context) ([]events.Event, error) {
+		return evts, fn(ctx)
+	})
+}
+
+// withOutcomeOutbox is withOutbox for writes whose events depend on what they changed,
+// fn returns the events to write. It runs in a transaction only when transactional.
+func withOutcomeOutbox(
+	ctx context.Context,
+	client *mongo.Client,
+	transactional bool,
+	fn func(ctx context.Context) ([]events.Event, error),
+) error {
+	if !transactional {
+		_, err := fn(ctx)
+		return err
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		evts, err := fn(sc)
+		if err != nil || len(evts) == 0 {
+			return nil, err
+		}
+
+		docs := make([]any, 0, len(evts))
+		for _, evt := range evts {
+			docs = append(docs, evt)
+		}
+		collection := client.Database("mybase").Collection(outboxCollection)
+		if _, err := collection.InsertMany(sc, docs); err != nil {
+			return nil, fmt.Errorf("failed to write outbox: %w", err)
+		}
+		return nil, nil
+	})
+	return err
+}
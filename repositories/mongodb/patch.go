@@ -0,0 +1,77 @@
+package mongodb
+
+import (
+	// Go Internal Packages
+	"fmt"
+
+	// External Packages
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// patchUpdate compares the documents field by field and returns the update that turns
+// current into updated, $set for the changed fields and $unset for the emptied ones.
+// The filter matches the document only while the changed fields still hold their
+// current values, so a concurrent change makes the update match nothing.
+func patchUpdate(current, updated any) (filter bson.D, update bson.D, err error) {
+	currentDoc, err := toDoc(current)
+	if err != nil {
+		return nil, nil, err
+	}
+	updatedDoc, err := toDoc(updated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	elems, err := updatedDoc.Elements()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	set, unset := bson.D{}, bson.D{}
+	for _, elem := range elems {
+		key, value := elem.Key(), elem.Value()
+		old, lookupErr := currentDoc.LookupErr(key)
+		if lookupErr == nil && old.Equal(value) {
+			continue
+		}
+		if lookupErr == nil {
+			filter = append(filter, bson.E{Key: key, Value: old})
+		}
+		if empty(value) {
+			unset = append(unset, bson.E{Key: key, Value: ""})
+		} else {
+			set = append(set, bson.E{Key: key, Value: value})
+		}
+	}
+
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	return filter, update, nil
+}
+
+func toDoc(v any) (bson.Raw, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	return bson.Raw(data), nil
+}
+
+// empty reports whether the value is null, an empty string or an empty array
+func empty(value bson.RawValue) bool {
+	switch value.Type {
+	case bson.TypeNull:
+		return true
+	case bson.TypeString:
+		return value.StringValue() == ""
+	case bson.TypeArray:
+		values, err := value.Array().Values()
+		return err == nil && len(values) == 0
+	default:
+		return false
+	}
+}
@@ -3,8 +3,13 @@ package mongodb
 import (
 	// Go Internal Packages
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
 	models "learn-go/models"
 
 	// External Packages
@@ -22,12 +27,99 @@ func NewStudentsRepository(client *mongo.Client) *StudentsRepository {
 	return &StudentsRepository{client: client, collection: "class"}
 }
 
-// GetAllStudents returns all students in collection
-func (r *StudentsRepository) GetAllStudents(ctx context.Context) (*[]models.StudentModel, error) {
+// rollNoIndex is the name mongo gives the unique index on the rollNo
+const rollNoIndex = "Roll_No_1"
+
+// maxReportedDuplicates bounds the rollNos listed when duplicates block the unique index
+const maxReportedDuplicates = 10
+
+// EnsureIndexes creates the unique index on the rollNo, a deleted student keeps its
+// rollNo until it is purged. Students stored before the index may repeat a rollNo, the
+// index is then not created and the error lists the duplicates to resolve first.
+func (r *StudentsRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list students indexes: %w", err)
+	}
+	for _, spec := range specs {
+		if spec.Name == rollNoIndex && spec.Unique != nil && *spec.Unique {
+			return nil
+		}
+	}
+
+	duplicates, err := r.duplicateRollNos(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check students for duplicates: %w", err)
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("cannot create the unique students index, resolve the students sharing "+
+			"a roll number first (see Upgrading in the README): %s", strings.Join(duplicates, ", "))
+	}
+
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "Roll_No", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create students index: %w", err)
+	}
+	return nil
+}
+
+// duplicateRollNos returns the first rollNos held by more than one student, deleted or
+// not, with their number of students
+func (r *StudentsRepository) duplicateRollNos(ctx context.Context) ([]string, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$Roll_No"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: maxReportedDuplicates}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		RollNo string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	duplicates := make([]string, 0, len(groups))
+	for _, group := range groups {
+		duplicates = append(duplicates, fmt.Sprintf("%q (%d students)", group.RollNo, group.Count))
+	}
+	return duplicates, nil
+}
+
+// notDeleted matches the students that are not soft deleted
+var notDeleted = bson.E{Key: "Deleted_At", Value: bson.M{"$exists": false}}
+
+// byRollNo matches the student with rollNo, unless it is deleted and includeDeleted is not set
+func byRollNo(rollNo string, includeDeleted bool) bson.D {
+	filter := bson.D{{Key: "Roll_No", Value: rollNo}}
+	if !includeDeleted {
+		filter = append(filter, notDeleted)
+	}
+	return filter
+}
+
+// GetAllStudents returns all students in collection, the deleted ones only with includeDeleted
+func (r *StudentsRepository) GetAllStudents(ctx context.Context, includeDeleted bool) (*[]models.StudentModel, error) {
 	collection := r.client.Database("mybase").Collection(r.collection)
 	findOptions := options.Find().SetSort(bson.D{{Key: "Roll_No", Value: 1}})
+	filter := bson.D{}
+	if !includeDeleted {
+		filter = append(filter, notDeleted)
+	}
 
-	cursor, err := collection.Find(ctx, bson.M{}, findOptions)
+	cursor, err := collection.Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -40,10 +132,43 @@ func (r *StudentsRepository) GetAllStudents(ctx context.Context) (*[]models.Stud
 	return &students, nil
 }
 
-// GetOneStudent returns a student with given rollNo
-func (r *StudentsRepository) GetOneStudent(ctx context.Context, rollNo string) (*models.StudentModel, error) {
+// IterateStudents calls fn for every student in rollNo order, the deleted ones only with
+// includeDeleted, reading them from a cursor instead of loading them all, and stops at
+// the first error
+func (r *StudentsRepository) IterateStudents(
+	ctx context.Context,
+	includeDeleted bool,
+	fn func(student models.StudentModel) error,
+) error {
 	collection := r.client.Database("mybase").Collection(r.collection)
-	filter := bson.M{"Roll_No": rollNo}
+	findOptions := options.Find().SetSort(bson.D{{Key: "Roll_No", Value: 1}})
+	filter := bson.D{}
+	if !includeDeleted {
+		filter = append(filter, notDeleted)
+	}
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var student models.StudentModel
+		if err := cursor.Decode(&student); err != nil {
+			return err
+		}
+		if err := fn(student); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// GetOneStudent returns a student with given rollNo, a deleted one only with includeDeleted
+func (r *StudentsRepository) GetOneStudent(ctx context.Context, rollNo string, includeDeleted bool) (*models.StudentModel, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	filter := byRollNo(rollNo, includeDeleted)
 
 	var student models.StudentModel
 	err := collection.FindOne(ctx, filter).Decode(&student)
@@ -53,40 +178,229 @@ func (r *StudentsRepository) GetOneStudent(ctx context.Context, rollNo string) (
 	return &student, nil
 }
 
-// InsertStudent inserts a students to the collection
-func (r *StudentsRepository) InsertStudent(ctx context.Context, student models.StudentModel) error {
+// InsertStudent inserts a students to the collection, a taken rollNo is a Conflict
+func (r *StudentsRepository) InsertStudent(ctx context.Context, student models.StudentModel, evts ...events.Event) error {
 	collection := r.client.Database("mybase").Collection(r.collection)
-	_, err := collection.InsertOne(ctx, student)
-	if err != nil {
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		_, err := collection.InsertOne(ctx, student)
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.E(errors.Conflict, "student already exists", err)
+		}
 		return err
+	})
+}
+
+// ExistingStudents returns which of the rollNos belong to a student, deleted or not
+func (r *StudentsRepository) ExistingStudents(ctx context.Context, rollNos []string) (map[string]bool, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	filter := bson.M{"Roll_No": bson.M{"$in": rollNos}}
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"Roll_No": 1}))
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	defer cursor.Close(ctx)
+
+	existing := make(map[string]bool, len(rollNos))
+	for cursor.Next(ctx) {
+		var student models.StudentModel
+		if err := cursor.Decode(&student); err != nil {
+			return nil, err
+		}
+		existing[student.RollNo] = true
+	}
+	return existing, cursor.Err()
+}
+
+// InsertStudents inserts the students with a single unordered bulk write, skipping the
+// ones whose rollNo exists. inserted reports per student whether it was written, the
+// events of a student are written to the outbox only when it was.
+func (r *StudentsRepository) InsertStudents(
+	ctx context.Context,
+	students []models.StudentModel,
+	evts [][]events.Event,
+) (inserted []bool, err error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	writes := make([]mongo.WriteModel, 0, len(students))
+	for _, student := range students {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"Roll_No": student.RollNo}).
+			SetUpdate(bson.M{"$setOnInsert": student}).
+			SetUpsert(true))
+	}
+
+	transactional := false
+	for _, studentEvts := range evts {
+		transactional = transactional || len(studentEvts) > 0
+	}
+
+	err = withOutcomeOutbox(ctx, r.client, transactional, func(ctx context.Context) ([]events.Event, error) {
+		res, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return nil, err
+		}
+
+		inserted = make([]bool, len(students))
+		var written []events.Event
+		for i := range students {
+			if _, ok := res.UpsertedIDs[int64(i)]; ok {
+				inserted[i] = true
+				written = append(written, evts[i]...)
+			}
+		}
+		return written, nil
+	})
+	return inserted, err
+}
+
+// UpdateStudent updates the student details with given rollNo, unless it is deleted
+func (r *StudentsRepository) UpdateStudent(
+	ctx context.Context,
+	rollNo string,
+	updatedStudent models.StudentModel,
+	evts ...events.Event,
+) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	filter := byRollNo(rollNo, false)
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		res, err := collection.ReplaceOne(ctx, filter, updatedStudent)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
 }
 
-// UpdateStudent updates the student details with given rollNo
-func (r *StudentsRepository) UpdateStudent(ctx context.Context, rollNo string, updatedStudent models.StudentModel) error {
+// PatchStudent writes the fields changed from current to updated with $set and $unset.
+// It returns mongo.ErrNoDocuments when the student is gone or its changed fields no
+// longer hold their current values, and for deleted students.
+func (r *StudentsRepository) PatchStudent(
+	ctx context.Context,
+	rollNo string,
+	current, updated models.StudentModel,
+	evts ...events.Event,
+) error {
 	collection := r.client.Database("mybase").Collection(r.collection)
-	filter := bson.M{"Roll_No": rollNo}
-	res, err := collection.ReplaceOne(ctx, filter, updatedStudent)
+	changed, update, err := patchUpdate(current, updated)
 	if err != nil {
 		return err
 	}
-	if res.MatchedCount == 0 {
+	if len(update) == 0 {
+		return nil
+	}
+
+	filter := append(byRollNo(rollNo, false), changed...)
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		res, err := collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
+}
+
+// DeleteStudent marks the student deleted at deletedAt by deletedBy while it still holds
+// the current details, mongo.ErrNoDocuments is returned when it is gone, deleted already
+// or was changed meanwhile
+func (r *StudentsRepository) DeleteStudent(
+	ctx context.Context,
+	current models.StudentModel,
+	deletedAt time.Time,
+	deletedBy string,
+	evts ...events.Event,
+) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	filter := append(byRollNo(current.RollNo, false), byDetails(current)...)
+	update := bson.M{"$set": bson.M{"Deleted_At": deletedAt, "Deleted_By": deletedBy}}
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		res, err := collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
+}
+
+// RestoreStudent clears the deletion of the student while it is still deleted as it was
+// read, mongo.ErrNoDocuments is returned when it is gone, not deleted or was changed meanwhile
+func (r *StudentsRepository) RestoreStudent(ctx context.Context, deleted models.StudentModel, evts ...events.Event) error {
+	if deleted.DeletedAt == nil {
 		return mongo.ErrNoDocuments
 	}
-	return nil
+	collection := r.client.Database("mybase").Collection(r.collection)
+	filter := append(bson.D{{Key: "Roll_No", Value: deleted.RollNo}, {Key: "Deleted_At", Value: *deleted.DeletedAt}},
+		byDetails(deleted)...)
+	update := bson.M{"$unset": bson.M{"Deleted_At": "", "Deleted_By": ""}}
+	return withOutbox(ctx, r.client, evts, func(ctx context.Context) error {
+		res, err := collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return nil
+	})
+}
+
+// byDetails matches a student that still holds the details of the given one
+func byDetails(student models.StudentModel) bson.D {
+	return bson.D{
+		{Key: "Student_Name", Value: student.Name},
+		{Key: "Gender", Value: student.Gender},
+		{Key: "Mail_Id", Value: student.MailID},
+	}
 }
 
-// DeleteStudent deletes a student with given rollNo
-func (r *StudentsRepository) DeleteStudent(ctx context.Context, rollNo string) error {
+// PurgeStudents permanently removes the students deleted before deletedBefore and
+// returns their rollNos. A student restored meanwhile is kept. The events purgeEvents
+// returns for a student are written together with its removal.
+func (r *StudentsRepository) PurgeStudents(
+	ctx context.Context,
+	deletedBefore time.Time,
+	purgeEvents func(rollNo string) ([]events.Event, error),
+) ([]string, error) {
 	collection := r.client.Database("mybase").Collection(r.collection)
-	filter := bson.M{"Roll_No": rollNo}
-	res, err := collection.DeleteOne(ctx, filter)
+	expired := bson.M{"$lt": deletedBefore}
+	cursor, err := collection.Find(ctx, bson.M{"Deleted_At": expired},
+		options.Find().SetProjection(bson.M{"Roll_No": 1}))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if res.DeletedCount == 0 {
-		return mongo.ErrNoDocuments
+	var students []models.StudentModel
+	if err := cursor.All(ctx, &students); err != nil {
+		return nil, err
 	}
-	return nil
+
+	purged := []string{}
+	for _, student := range students {
+		evts, err := purgeEvents(student.RollNo)
+		if err != nil {
+			return purged, err
+		}
+		deleted := false
+		err = withOutcomeOutbox(ctx, r.client, len(evts) > 0, func(ctx context.Context) ([]events.Event, error) {
+			res, err := collection.DeleteOne(ctx, bson.M{"Roll_No": student.RollNo, "Deleted_At": expired})
+			if err != nil || res.DeletedCount == 0 {
+				return nil, err
+			}
+			deleted = true
+			return evts, nil
+		})
+		if err != nil {
+			return purged, err
+		}
+		if deleted {
+			purged = append(purged, student.RollNo)
+		}
+	}
+	return purged, nil
 }
@@ -0,0 +1,223 @@
+package mongodb
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+
+	// External Packages
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhooksRepository struct {
+	client     *mongo.Client
+	collection string
+	deliveries string
+	retries    string
+}
+
+func NewWebhooksRepository(client *mongo.Client) *WebhooksRepository {
+	return &WebhooksRepository{
+		client:     client,
+		collection: "webhooks",
+		deliveries: "webhook_deliveries",
+		retries:    "webhook_retries",
+	}
+}
+
+// EnsureIndexes creates the indexes subscriptions are matched to events by, the
+// delivery log of a webhook is listed and searched by event by, and the due retries
+// are found by
+func (r *WebhooksRepository) EnsureIndexes(ctx context.Context) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "event_types", Value: 1}}})
+	if err != nil {
+		return fmt.Errorf("failed to create webhooks index: %w", err)
+	}
+
+	deliveries := r.client.Database("mybase").Collection(r.deliveries)
+	_, err = deliveries.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "delivered_at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook deliveries index: %w", err)
+	}
+	_, err = deliveries.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "event_id", Value: 1}}})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook deliveries index: %w", err)
+	}
+
+	retries := r.client.Database("mybase").Collection(r.retries)
+	_, err = retries.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "due_at", Value: 1}}})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook retries index: %w", err)
+	}
+	return nil
+}
+
+// GetAll returns every webhook subscription ordered by creation
+func (r *WebhooksRepository) GetAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return r.find(ctx, bson.M{})
+}
+
+// GetSubscribed returns the webhook subscriptions listening to the event type
+func (r *WebhooksRepository) GetSubscribed(ctx context.Context, eventType events.Type) ([]models.WebhookSubscription, error) {
+	return r.find(ctx, bson.M{"event_types": eventType})
+}
+
+func (r *WebhooksRepository) GetOne(ctx context.Context, webhookID string) (models.WebhookSubscription, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+
+	var webhook models.WebhookSubscription
+	err := collection.FindOne(ctx, bson.M{"_id": webhookID}).Decode(&webhook)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.WebhookSubscription{}, errors.E(errors.NotFound, "webhook not found")
+	}
+	if err != nil {
+		return models.WebhookSubscription{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+func (r *WebhooksRepository) Insert(ctx context.Context, webhook models.WebhookSubscription) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	if _, err := collection.InsertOne(ctx, webhook); err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhooksRepository) Update(ctx context.Context, webhook models.WebhookSubscription) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	res, err := collection.ReplaceOne(ctx, bson.M{"_id": webhook.ID}, webhook)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return errors.E(errors.NotFound, "webhook not found")
+	}
+	return nil
+}
+
+func (r *WebhooksRepository) Delete(ctx context.Context, webhookID string) error {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	res, err := collection.DeleteOne(ctx, bson.M{"_id": webhookID})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return errors.E(errors.NotFound, "webhook not found")
+	}
+	return nil
+}
+
+// InsertDelivery appends a delivery attempt to the delivery log
+func (r *WebhooksRepository) InsertDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	collection := r.client.Database("mybase").Collection(r.deliveries)
+	if _, err := collection.InsertOne(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to log webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDeliveries returns the latest delivery attempts of a webhook, newest first
+func (r *WebhooksRepository) GetDeliveries(ctx context.Context, webhookID string, limit int64) ([]models.WebhookDelivery, error) {
+	collection := r.client.Database("mybase").Collection(r.deliveries)
+	findOptions := options.Find().SetSort(bson.D{{Key: "delivered_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, bson.M{"webhook_id": webhookID}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetHandled returns the ids of the webhooks the event was delivered to or whose
+// retry of it is scheduled
+func (r *WebhooksRepository) GetHandled(ctx context.Context, eventID string) (map[string]bool, error) {
+	deliveries := r.client.Database("mybase").Collection(r.deliveries)
+	delivered, err := deliveries.Distinct(ctx, "webhook_id", bson.M{"event_id": eventID, "success": true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	retries := r.client.Database("mybase").Collection(r.retries)
+	scheduled, err := retries.Distinct(ctx, "webhook_id", bson.M{"event_id": eventID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook retries: %w", err)
+	}
+
+	handled := make(map[string]bool, len(delivered)+len(scheduled))
+	for _, id := range append(delivered, scheduled...) {
+		if webhookID, ok := id.(string); ok {
+			handled[webhookID] = true
+		}
+	}
+	return handled, nil
+}
+
+// ScheduleRetry saves the retry, replacing the previous one of its subscription and event
+func (r *WebhooksRepository) ScheduleRetry(ctx context.Context, retry models.WebhookRetry) error {
+	collection := r.client.Database("mybase").Collection(r.retries)
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": retry.ID}, retry, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to schedule webhook retry: %w", err)
+	}
+	return nil
+}
+
+// ClaimRetry returns the retry due the longest and moves it lease into the future so
+// that no other replica claims it meanwhile, NotFound when none is due
+func (r *WebhooksRepository) ClaimRetry(ctx context.Context, now time.Time, lease time.Duration) (models.WebhookRetry, error) {
+	collection := r.client.Database("mybase").Collection(r.retries)
+	claimOptions := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "due_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var retry models.WebhookRetry
+	err := collection.FindOneAndUpdate(ctx, bson.M{"due_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"due_at": now.Add(lease)}}, claimOptions).Decode(&retry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.WebhookRetry{}, errors.E(errors.NotFound, "no webhook retry is due")
+	}
+	if err != nil {
+		return models.WebhookRetry{}, fmt.Errorf("failed to claim webhook retry: %w", err)
+	}
+	return retry, nil
+}
+
+func (r *WebhooksRepository) DeleteRetry(ctx context.Context, retryID string) error {
+	collection := r.client.Database("mybase").Collection(r.retries)
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": retryID}); err != nil {
+		return fmt.Errorf("failed to delete webhook retry: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhooksRepository) find(ctx context.Context, filter bson.M) ([]models.WebhookSubscription, error) {
+	collection := r.client.Database("mybase").Collection(r.collection)
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+
+	webhooks := []models.WebhookSubscription{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to decode webhooks: %w", err)
+	}
+	return webhooks, nil
+}
@@ -0,0 +1,273 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+
+	// External Packages
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	busReadCount  = 50
+	busBlock      = 2 * time.Second
+	busMaxBackoff = 5 * time.Minute
+)
+
+// EventBusOptions tunes the delivery guarantees of the EventBus
+type EventBusOptions struct {
+	Stream        string        // stream the events are appended to, "<Stream>:DLQ" holds dead letters
+	MaxLen        int64         // approximate number of entries kept in the stream
+	MaxDeliveries int64         // attempts per event and handler before it is dead-lettered
+	RetryBackoff  time.Duration // delay before the first redelivery, doubled on every attempt
+}
+
+// EventBus publishes events to a Redis Stream and delivers them to the subscribed
+// handlers through one consumer group per handler. An event is acknowledged once
+// its handler succeeded, failed events stay pending and are claimed again with an
+// exponential backoff until MaxDeliveries is reached and they move to the dead-letter stream.
+// While a handler runs its entry is claimed again every half RetryBackoff, which keeps it
+// from looking idle, so that only the entries of a consumer that died are taken over.
+type EventBus struct {
+	client   *redis.Client
+	consumer string
+	handlers []events.EventHandler
+	logger   *zap.Logger
+	opts     EventBusOptions
+}
+
+func NewEventBus(client *redis.Client, opts EventBusOptions, logger *zap.Logger) *EventBus {
+	hostname, _ := os.Hostname()
+	return &EventBus{
+		client:   client,
+		consumer: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		logger:   logger,
+		opts:     opts,
+	}
+}
+
+// Publish appends the event to the stream, it implements events.Publisher
+func (b *EventBus) Publish(ctx context.Context, evt events.Event) error {
+	data, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	err = b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.opts.Stream,
+		MaxLen: b.opts.MaxLen,
+		Approx: true,
+		Values: map[string]any{"event": data},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe creates the consumer group of the handler, new groups only receive
+// events published from now on. It must be called before Run.
+func (b *EventBus) Subscribe(ctx context.Context, handler events.EventHandler) error {
+	err := b.client.XGroupCreateMkStream(ctx, b.opts.Stream, handler.Name(), "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s: %w", handler.Name(), err)
+	}
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+// Run consumes the stream for every subscribed handler until ctx is cancelled
+func (b *EventBus) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, handler := range b.handlers {
+		wg.Add(1)
+		go func(handler events.EventHandler) {
+			defer wg.Done()
+			b.consume(ctx, handler)
+		}(handler)
+	}
+	wg.Wait()
+}
+
+func (b *EventBus) consume(ctx context.Context, handler events.EventHandler) {
+	logger := b.logger.With(zap.String("group", handler.Name()))
+	for ctx.Err() == nil {
+		streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    handler.Name(),
+			Consumer: b.consumer,
+			Streams:  []string{b.opts.Stream, ">"},
+			Count:    busReadCount,
+			Block:    busBlock,
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() == nil {
+				logger.Error("failed to read event stream", zap.Error(err))
+				select {
+				case <-ctx.Done():
+				case <-time.After(b.opts.RetryBackoff):
+				}
+			}
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				b.deliver(ctx, handler, msg, 1)
+			}
+		}
+		b.retryPending(ctx, handler)
+	}
+}
+
+// retryPending claims the events whose backoff has elapsed and delivers them again
+func (b *EventBus) retryPending(ctx context.Context, handler events.EventHandler) {
+	pending, err := b.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: b.opts.Stream,
+		Group:  handler.Name(),
+		Idle:   b.opts.RetryBackoff,
+		Start:  "-",
+		End:    "+",
+		Count:  busReadCount,
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		if ctx.Err() == nil {
+			b.logger.Error("failed to list pending events", zap.String("group", handler.Name()), zap.Error(err))
+		}
+		return
+	}
+
+	for _, entry := range pending {
+		backoff := b.backoff(entry.RetryCount)
+		if entry.Idle < backoff {
+			continue
+		}
+
+		// MinIdle makes the claim fail if another consumer claimed the event meanwhile
+		msgs, err := b.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   b.opts.Stream,
+			Group:    handler.Name(),
+			Consumer: b.consumer,
+			MinIdle:  backoff,
+			Messages: []string{entry.ID},
+		}).Result()
+		if err != nil {
+			b.logger.Error("failed to claim event", zap.String("group", handler.Name()),
+				zap.String("entry", entry.ID), zap.Error(err))
+			continue
+		}
+		for _, msg := range msgs {
+			b.deliver(ctx, handler, msg, entry.RetryCount+1)
+		}
+	}
+}
+
+// deliver hands the entry to the handler and acknowledges or dead-letters it
+func (b *EventBus) deliver(ctx context.Context, handler events.EventHandler, msg redis.XMessage, attempt int64) {
+	logger := b.logger.With(zap.String("group", handler.Name()), zap.String("entry", msg.ID),
+		zap.Int64("attempt", attempt))
+
+	raw, _ := msg.Values["event"].(string)
+	var evt events.Event
+	err := json.Unmarshal([]byte(raw), &evt)
+	if err != nil {
+		// A malformed entry will never succeed, dead-letter it right away
+		attempt = b.opts.MaxDeliveries
+		err = fmt.Errorf("failed to decode event: %w", err)
+	} else {
+		stop := b.heartbeat(ctx, handler, msg.ID)
+		err = safeHandle(ctx, handler, evt)
+		stop()
+	}
+
+	if err == nil {
+		if ackErr := b.client.XAck(ctx, b.opts.Stream, handler.Name(), msg.ID).Err(); ackErr != nil {
+			logger.Error("failed to acknowledge event", zap.Error(ackErr))
+		}
+		return
+	}
+
+	if attempt < b.opts.MaxDeliveries {
+		logger.Warn("event handler failed, will retry", zap.String("id", evt.ID), zap.Error(err))
+		return
+	}
+
+	logger.Error("event handler failed, moving event to dead-letter stream", zap.String("id", evt.ID), zap.Error(err))
+	tx := b.client.TxPipeline()
+	tx.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.opts.Stream + ":DLQ",
+		Values: map[string]any{
+			"event":    raw,
+			"group":    handler.Name(),
+			"entry":    msg.ID,
+			"attempts": attempt,
+			"error":    err.Error(),
+		},
+	})
+	tx.XAck(ctx, b.opts.Stream, handler.Name(), msg.ID)
+	if _, err := tx.Exec(ctx); err != nil {
+		logger.Error("failed to dead-letter event", zap.Error(err))
+	}
+}
+
+// heartbeat claims the entry for this consumer every half RetryBackoff until stop is called.
+// A claim with JUSTID resets the idle time of the entry without counting a delivery, so
+// retryPending of other consumers leaves the entry alone while its handler runs.
+func (b *EventBus) heartbeat(ctx context.Context, handler events.EventHandler, entryID string) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(max(b.opts.RetryBackoff/2, time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := b.client.XClaimJustID(ctx, &redis.XClaimArgs{
+				Stream:   b.opts.Stream,
+				Group:    handler.Name(),
+				Consumer: b.consumer,
+				Messages: []string{entryID},
+			}).Err()
+			if err != nil && ctx.Err() == nil {
+				b.logger.Warn("failed to extend the claim of an event", zap.String("group", handler.Name()),
+					zap.String("entry", entryID), zap.Error(err))
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// backoff returns how long an event delivered the given number of times waits before its next attempt
+func (b *EventBus) backoff(deliveries int64) time.Duration {
+	backoff := b.opts.RetryBackoff
+	for i := int64(1); i < deliveries && backoff < busMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, busMaxBackoff)
+}
+
+// safeHandle turns a handler panic into an error so that the event is retried
+func safeHandle(ctx context.Context, handler events.EventHandler, evt events.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return handler.Handle(ctx, evt)
+}
@@ -0,0 +1,105 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// Local Packages
+	events "learn-go/events"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// slowHandler blocks in its first Handle until release is closed
+type slowHandler struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *slowHandler) Name() string {
+	return "slow"
+}
+
+func (h *slowHandler) Handle(context.Context, events.Event) error {
+	if h.calls.Add(1) == 1 {
+		close(h.started)
+		<-h.release
+	}
+	return nil
+}
+
+func TestEventBusDoesNotReclaimEventsStillHandled(t *testing.T) {
+	client, _ := newTestClient(t)
+	opts := EventBusOptions{Stream: "EVENTS", MaxLen: 100, MaxDeliveries: 5, RetryBackoff: 100 * time.Millisecond}
+	handler := &slowHandler{started: make(chan struct{}), release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Two replicas of the same handler, in the same consumer group
+	first, second := NewEventBus(client, opts, zap.NewNop()), NewEventBus(client, opts, zap.NewNop())
+	first.consumer, second.consumer = "first", "second"
+	for _, bus := range []*EventBus{first, second} {
+		if err := bus.Subscribe(ctx, handler); err != nil {
+			t.Fatalf("Subscribe() error = %v", err)
+		}
+	}
+
+	evt, err := events.New(events.OrderCreated, "o1", map[string]string{"id": "o1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Publish(ctx, evt); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		first.Run(ctx)
+	}()
+	select {
+	case <-handler.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event was not delivered")
+	}
+
+	// The handler runs for longer than the backoff, the entry must not look idle
+	time.Sleep(3 * opts.RetryBackoff)
+	second.retryPending(ctx, handler)
+	if calls := handler.calls.Load(); calls != 1 {
+		t.Fatalf("the event was delivered %d times while its handler ran, want 1", calls)
+	}
+
+	close(handler.release)
+	cancel()
+	<-done
+}
+
+func TestEventBusReadErrorBackoffHonoursContext(t *testing.T) {
+	client, server := newTestClient(t)
+	opts := EventBusOptions{Stream: "EVENTS", MaxLen: 100, MaxDeliveries: 5, RetryBackoff: time.Hour}
+	bus := NewEventBus(client, opts, zap.NewNop())
+	if err := bus.Subscribe(context.Background(), events.NewLogHandler(zap.NewNop())); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Run(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() kept sleeping after the context was cancelled")
+	}
+}
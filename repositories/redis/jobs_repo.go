@@ -0,0 +1,212 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+	utils "learn-go/utils"
+
+	// External Packages
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	jobsQueueKey      = "JOBS:QUEUE"
+	jobsProcessingKey = "JOBS:PROCESSING"
+	jobsDelayedKey    = "JOBS:DELAYED"
+	jobsCancelChannel = "JOBS:CANCEL"
+	jobsPromoteLimit  = 100
+)
+
+// promoteDue moves the delayed jobs whose retry time has come back to the queue
+var promoteDue = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('LPUSH', KEYS[2], id)
+end
+return #ids
+`)
+
+// requeueJob moves a job from the processing list back to the queue, only when it is
+// still there, so that a job is never queued twice
+var requeueJob = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call('RPUSH', KEYS[2], ARGV[1])
+return 1
+`)
+
+// JobsRepository stores every job under JOB:<id>. Queued ids wait in the JOBS:QUEUE
+// list and move to JOBS:PROCESSING while a worker runs them, failed jobs wait for
+// their retry in the JOBS:DELAYED sorted set scored by the retry time.
+type JobsRepository struct {
+	client *redis.Client
+}
+
+func NewJobsRepository(client *redis.Client) *JobsRepository {
+	return &JobsRepository{client: client}
+}
+
+// Enqueue stores the job and appends it to the queue
+func (r *JobsRepository) Enqueue(ctx context.Context, job models.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	tx := r.client.TxPipeline()
+	tx.Set(ctx, utils.GetJobKey(job.ID), data, 0)
+	tx.LPush(ctx, jobsQueueKey, job.ID)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return nil
+}
+
+func (r *JobsRepository) GetOne(ctx context.Context, jobID string) (models.Job, error) {
+	value, err := r.client.Get(ctx, utils.GetJobKey(jobID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return models.Job{}, errors.E(errors.NotFound, "job not found")
+	}
+	if err != nil {
+		return models.Job{}, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	var job models.Job
+	if err := json.Unmarshal([]byte(value), &job); err != nil {
+		return models.Job{}, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return job, nil
+}
+
+// Save overwrites the job record, it expires after ttl unless ttl is zero
+func (r *JobsRepository) Save(ctx context.Context, job models.Job, ttl time.Duration) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+	if err := r.client.Set(ctx, utils.GetJobKey(job.ID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// Dequeue moves the oldest queued job to the processing list and returns its id. It
+// waits up to timeout for a job and returns an empty id when none was queued.
+func (r *JobsRepository) Dequeue(ctx context.Context, timeout time.Duration) (string, error) {
+	jobID, err := r.client.BLMove(ctx, jobsQueueKey, jobsProcessingKey, "RIGHT", "LEFT", timeout).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to dequeue job: %w", err)
+	}
+	return jobID, nil
+}
+
+// Ack removes a job that is done with from the processing list
+func (r *JobsRepository) Ack(ctx context.Context, jobID string) error {
+	if err := r.client.LRem(ctx, jobsProcessingKey, 1, jobID).Err(); err != nil {
+		return fmt.Errorf("failed to acknowledge job: %w", err)
+	}
+	return nil
+}
+
+// Requeue hands a processing job back to the queue, it is the next one dequeued. It
+// reports false when the job was no longer processing.
+func (r *JobsRepository) Requeue(ctx context.Context, jobID string) (bool, error) {
+	keys := []string{jobsProcessingKey, jobsQueueKey}
+	requeued, err := requeueJob.Run(ctx, r.client, keys, jobID).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to requeue job: %w", err)
+	}
+	return requeued == 1, nil
+}
+
+// Schedule moves a processing job to the delayed set, PromoteDue queues it again at runAt
+func (r *JobsRepository) Schedule(ctx context.Context, jobID string, runAt time.Time) error {
+	tx := r.client.TxPipeline()
+	tx.LRem(ctx, jobsProcessingKey, 1, jobID)
+	tx.ZAdd(ctx, jobsDelayedKey, redis.Z{Score: float64(runAt.UnixMilli()), Member: jobID})
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to schedule job: %w", err)
+	}
+	return nil
+}
+
+// PromoteDue queues the delayed jobs due at now and returns how many were queued
+func (r *JobsRepository) PromoteDue(ctx context.Context, now time.Time) (int, error) {
+	keys := []string{jobsDelayedKey, jobsQueueKey}
+	promoted, err := promoteDue.Run(ctx, r.client, keys, strconv.FormatInt(now.UnixMilli(), 10), jobsPromoteLimit).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to promote delayed jobs: %w", err)
+	}
+	return promoted, nil
+}
+
+// Processing returns the ids of the jobs taken by the workers of every replica
+func (r *JobsRepository) Processing(ctx context.Context) ([]string, error) {
+	jobIDs, err := r.client.LRange(ctx, jobsProcessingKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processing jobs: %w", err)
+	}
+	return jobIDs, nil
+}
+
+// RequestCancel flags the job as cancelled for ttl and notifies the workers of every replica
+func (r *JobsRepository) RequestCancel(ctx context.Context, jobID string, ttl time.Duration) error {
+	tx := r.client.TxPipeline()
+	tx.Set(ctx, utils.GetJobCancelKey(jobID), 1, ttl)
+	tx.Publish(ctx, jobsCancelChannel, jobID)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cancel job: %w", err)
+	}
+	return nil
+}
+
+// CancelRequested reports whether the job was flagged by RequestCancel
+func (r *JobsRepository) CancelRequested(ctx context.Context, jobID string) (bool, error) {
+	n, err := r.client.Exists(ctx, utils.GetJobCancelKey(jobID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check job cancellation: %w", err)
+	}
+	return n == 1, nil
+}
+
+// SubscribeCancels streams the ids of the jobs cancelled on any replica until ctx is cancelled
+func (r *JobsRepository) SubscribeCancels(ctx context.Context) <-chan string {
+	pubsub := r.client.Subscribe(ctx, jobsCancelChannel)
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
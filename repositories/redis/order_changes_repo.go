@@ -0,0 +1,133 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	// Local Packages
+	models "learn-go/models"
+
+	// External Packages
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const orderChangesKey = "ORDERS:CHANGES"
+
+// appendChange adds the change to the history stream and publishes "<id> <change>"
+// on the channel in one step, so every published change can be resumed from.
+var appendChange = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[1], '*', 'change', ARGV[2])
+redis.call('PUBLISH', KEYS[1], id .. ' ' .. ARGV[2])
+return id
+`)
+
+// OrderChangesRepository keeps a capped history of order changes in the ORDERS:CHANGES
+// stream and fans new changes out to every replica over the ORDERS:CHANGES channel.
+type OrderChangesRepository struct {
+	client  *redis.Client
+	history int64
+	logger  *zap.Logger
+}
+
+func NewOrderChangesRepository(client *redis.Client, history int64, logger *zap.Logger) *OrderChangesRepository {
+	return &OrderChangesRepository{client: client, history: history, logger: logger}
+}
+
+// Append records the change and returns it with its id set
+func (r *OrderChangesRepository) Append(ctx context.Context, change models.OrderChange) (models.OrderChange, error) {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return change, fmt.Errorf("failed to encode order change: %w", err)
+	}
+
+	id, err := appendChange.Run(ctx, r.client, []string{orderChangesKey}, r.history, data).Text()
+	if err != nil {
+		return change, fmt.Errorf("failed to append order change: %w", err)
+	}
+	change.ID = id
+	return change, nil
+}
+
+// Retained returns the ids of the oldest and the newest retained changes, both are
+// empty while there is no history
+func (r *OrderChangesRepository) Retained(ctx context.Context) (oldest, newest string, err error) {
+	first, err := r.client.XRangeN(ctx, orderChangesKey, "-", "+", 1).Result()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read order changes: %w", err)
+	}
+	last, err := r.client.XRevRangeN(ctx, orderChangesKey, "+", "-", 1).Result()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read order changes: %w", err)
+	}
+	if len(first) == 0 || len(last) == 0 {
+		return "", "", nil
+	}
+	return first[0].ID, last[0].ID, nil
+}
+
+// Since returns at most limit of the retained changes recorded after lastID, oldest first
+func (r *OrderChangesRepository) Since(ctx context.Context, lastID string, limit int64) ([]models.OrderChange, error) {
+	msgs, err := r.client.XRangeN(ctx, orderChangesKey, "("+lastID, "+", limit).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order changes: %w", err)
+	}
+
+	changes := make([]models.OrderChange, 0, len(msgs))
+	for _, msg := range msgs {
+		raw, _ := msg.Values["change"].(string)
+		change, err := decodeChange(msg.ID, raw)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// Subscribe streams the changes published by any replica until ctx is cancelled
+func (r *OrderChangesRepository) Subscribe(ctx context.Context) <-chan models.OrderChange {
+	pubsub := r.client.Subscribe(ctx, orderChangesKey)
+	out := make(chan models.OrderChange)
+
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		msgs := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				id, raw, _ := strings.Cut(msg.Payload, " ")
+				change, err := decodeChange(id, raw)
+				if err != nil {
+					r.logger.Error("dropping malformed order change", zap.Error(err))
+					continue
+				}
+				select {
+				case out <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func decodeChange(id, raw string) (models.OrderChange, error) {
+	var change models.OrderChange
+	if err := json.Unmarshal([]byte(raw), &change); err != nil {
+		return change, fmt.Errorf("failed to decode order change %s: %w", id, err)
+	}
+	change.ID = id
+	return change, nil
+}
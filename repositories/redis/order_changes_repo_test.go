@@ -0,0 +1,44 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"testing"
+
+	// Local Packages
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+func TestOrderChangesRepositoryHistory(t *testing.T) {
+	client, _ := newTestClient(t)
+	repo := NewOrderChangesRepository(client, 3, zap.NewNop())
+	ctx := context.Background()
+
+	if oldest, newest, err := repo.Retained(ctx); err != nil || oldest != "" || newest != "" {
+		t.Fatalf("Retained() = %q, %q, %v without a history, want empty ids", oldest, newest, err)
+	}
+	var ids []string
+	for _, orderID := range []string{"o1", "o2", "o3", "o4", "o5"} {
+		change, err := repo.Append(ctx, models.OrderChange{Kind: models.OrderInserted, OrderID: orderID})
+		if err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+		ids = append(ids, change.ID)
+	}
+
+	// Only the last 3 changes are retained
+	oldest, newest, err := repo.Retained(ctx)
+	if err != nil || oldest != ids[2] || newest != ids[4] {
+		t.Fatalf("Retained() = %q, %q, %v, want %q, %q", oldest, newest, err, ids[2], ids[4])
+	}
+	changes, err := repo.Since(ctx, ids[2], 1)
+	if err != nil || len(changes) != 1 || changes[0].ID != ids[3] || changes[0].OrderID != "o4" {
+		t.Fatalf("Since(%q, 1) = %+v, %v, want the change of o4", ids[2], changes, err)
+	}
+	if changes, err = repo.Since(ctx, ids[3], 10); err != nil || len(changes) != 1 || changes[0].OrderID != "o5" {
+		t.Fatalf("Since(%q, 10) = %+v, %v, want the change of o5", ids[3], changes, err)
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	// Local Packages
 	errors "learn-go/errors"
+	events "learn-go/events"
 	models "learn-go/models"
 	utils "learn-go/utils"
 
@@ -41,7 +43,7 @@ func (r *OrdersRepository) GetOne(ctx context.Context, orderID string) (models.O
 	return order, nil
 }
 
-func (r *OrdersRepository) Insert(ctx context.Context, order models.Order) error {
+func (r *OrdersRepository) Insert(ctx context.Context, order models.Order, evts ...events.Event) error {
 	data, err := json.Marshal(order)
 	if err != nil {
 		return fmt.Errorf("failed to encode order: %w", err)
@@ -61,27 +63,113 @@ func (r *OrdersRepository) Insert(ctx context.Context, order models.Order) error
 		return fmt.Errorf("failed to add order to set: %w", err)
 	}
 
+	if err := addToOutbox(ctx, tx, evts); err != nil {
+		tx.Discard()
+		return err
+	}
+
 	if _, err := tx.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to insert order: %w", err)
 	}
 	return nil
 }
 
-func (r *OrdersRepository) Update(ctx context.Context, order models.Order) error {
+// Update replaces the order and writes the events with it, a missing order is not found
+// and gets no events. The key is watched from the existence check to the write, so a
+// concurrent delete drops the write and the check runs again.
+func (r *OrdersRepository) Update(ctx context.Context, order models.Order, evts ...events.Event) error {
 	data, err := json.Marshal(order)
 	if err != nil {
 		return fmt.Errorf("failed to encode order: %w", err)
 	}
 
 	key := utils.GetOrderID(order.ID)
-	err = r.client.SetXX(ctx, key, data, 0).Err()
-	if err != nil {
-		return fmt.Errorf("failed to update order: %w", err)
+	update := func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to check order: %w", err)
+		}
+		if exists == 0 {
+			return errors.E(errors.NotFound, "order not found")
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetXX(ctx, key, data, 0)
+			return addToOutbox(ctx, pipe, evts)
+		})
+		if err != nil && !errors.Is(err, redis.TxFailedErr) {
+			return fmt.Errorf("failed to update order: %w", err)
+		}
+		return err
 	}
-	return nil
+
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		err := r.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errors.E(errors.Conflict, "order was changed concurrently, retry the request")
 }
 
-func (r *OrdersRepository) Delete(ctx context.Context, orderID string) error {
+// modifyAttempts bounds the optimistic retries of Update, Modify and Remove under contention
+const modifyAttempts = 3
+
+// Modify atomically replaces the order with the one fn derives from it. The key is
+// watched while fn runs, so the write is dropped and fn called again with the fresh
+// order when another client changed it meanwhile.
+func (r *OrdersRepository) Modify(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) (models.Order, []events.Event, error),
+) (models.Order, error) {
+	key := utils.GetOrderID(orderID)
+
+	var updated models.Order
+	modify := func(tx *redis.Tx) error {
+		value, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return errors.E(errors.NotFound, "order not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		var current models.Order
+		if err := json.Unmarshal([]byte(value), &current); err != nil {
+			return fmt.Errorf("failed to decode order: %w", err)
+		}
+		order, evts, err := fn(current)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(order)
+		if err != nil {
+			return fmt.Errorf("failed to encode order: %w", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetXX(ctx, key, data, 0)
+			return addToOutbox(ctx, pipe, evts)
+		})
+		updated = order
+		return err
+	}
+
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		err := r.client.Watch(ctx, modify, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return models.Order{}, err
+		}
+		return updated, nil
+	}
+	return models.Order{}, errors.E(errors.Conflict, "order was changed concurrently, retry the request")
+}
+
+func (r *OrdersRepository) Delete(ctx context.Context, orderID string, evts ...events.Event) error {
 	key := utils.GetOrderID(orderID)
 	tx := r.client.TxPipeline()
 
@@ -94,6 +182,10 @@ func (r *OrdersRepository) Delete(ctx context.Context, orderID string) error {
 		tx.Discard()
 		return fmt.Errorf("failed to remove order from set: %w", err)
 	}
+	if err := addToOutbox(ctx, tx, evts); err != nil {
+		tx.Discard()
+		return err
+	}
 
 	if _, err := tx.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to delete order: %w", err)
@@ -101,6 +193,50 @@ func (r *OrdersRepository) Delete(ctx context.Context, orderID string) error {
 	return nil
 }
 
+// Remove deletes the order together with the events fn derives from it. The key is
+// watched while fn runs, so the delete is dropped and fn called again with the fresh
+// order when another client changed it meanwhile.
+func (r *OrdersRepository) Remove(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) ([]events.Event, error),
+) error {
+	key := utils.GetOrderID(orderID)
+	remove := func(tx *redis.Tx) error {
+		value, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return errors.E(errors.NotFound, "order not found")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		var current models.Order
+		if err := json.Unmarshal([]byte(value), &current); err != nil {
+			return fmt.Errorf("failed to decode order: %w", err)
+		}
+		evts, err := fn(current)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, key)
+			pipe.SRem(ctx, "ORDERS", key)
+			return addToOutbox(ctx, pipe, evts)
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < modifyAttempts; attempt++ {
+		err := r.client.Watch(ctx, remove, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return errors.E(errors.Conflict, "order was changed concurrently, retry the request")
+}
+
 func (r *OrdersRepository) Exists(ctx context.Context, orderID string) (bool, error) {
 	key := utils.GetOrderID(orderID)
 	res, err := r.client.Exists(ctx, key).Result()
@@ -109,3 +245,74 @@ func (r *OrdersRepository) Exists(ctx context.Context, orderID string) (bool, er
 	}
 	return res == 1, nil
 }
+
+// Put writes the given orders unconditionally, it is used to refill the cache
+// from the system of record
+func (r *OrdersRepository) Put(ctx context.Context, orders ...models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	tx := r.client.TxPipeline()
+	for _, order := range orders {
+		data, err := json.Marshal(order)
+		if err != nil {
+			tx.Discard()
+			return fmt.Errorf("failed to encode order: %w", err)
+		}
+		key := utils.GetOrderID(order.ID)
+		tx.Set(ctx, key, data, 0)
+		tx.SAdd(ctx, "ORDERS", key)
+	}
+
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to put orders: %w", err)
+	}
+	return nil
+}
+
+// iterateBatchSize is the number of orders Iterate reads with one MGET
+const iterateBatchSize = 100
+
+// Iterate calls fn for every cached order in order id order, stopping at the first error.
+// The keys are collected and sorted first, the orders are then read in batches.
+func (r *OrdersRepository) Iterate(ctx context.Context, fn func(order models.Order) error) error {
+	var keys []string
+	var cursor uint64
+	for {
+		batch, next, err := r.client.SScan(ctx, "ORDERS", cursor, "", 100).Result()
+		if err != nil {
+			return fmt.Errorf("failed to list orders: %w", err)
+		}
+		keys = append(keys, batch...)
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	// SSCAN may return a key more than once
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	for start := 0; start < len(keys); start += iterateBatchSize {
+		values, err := r.client.MGet(ctx, keys[start:min(start+iterateBatchSize, len(keys))]...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get orders: %w", err)
+		}
+		for _, value := range values {
+			// The order was deleted between SSCAN and MGET
+			data, ok := value.(string)
+			if !ok {
+				continue
+			}
+			var order models.Order
+			if err := json.Unmarshal([]byte(data), &order); err != nil {
+				return fmt.Errorf("failed to decode order: %w", err)
+			}
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+func newOrder(id string) models.Order {
+	return models.Order{
+		ID:          id,
+		UserID:      "u1",
+		OrderStatus: "placed",
+		LineItems:   []models.LineItem{{ItemID: "item-1", Quantity: 1, Price: 2.5}},
+	}
+}
+
+func TestOrdersRepositoryRemove(t *testing.T) {
+	client, _ := newTestClient(t)
+	repo := NewOrdersRepository(client)
+	outbox := NewOutbox(client, zap.NewNop())
+	ctx := context.Background()
+	if err := repo.Insert(ctx, newOrder("o1")); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	// An error of fn leaves the order and the outbox as they are
+	failure := errors.E(errors.Invalid, "stop")
+	err := repo.Remove(ctx, "o1", func(models.Order) ([]events.Event, error) { return nil, failure })
+	if err != failure {
+		t.Fatalf("Remove() error = %v, want the error of fn", err)
+	}
+	if exists, _ := repo.Exists(ctx, "o1"); !exists {
+		t.Fatalf("a failed Remove() deleted the order")
+	}
+
+	var removed models.Order
+	err = repo.Remove(ctx, "o1", func(current models.Order) ([]events.Event, error) {
+		removed = current
+		evt, err := events.New(events.OrderDeleted, current.ID, current)
+		return []events.Event{evt}, err
+	})
+	if err != nil || removed.ID != "o1" {
+		t.Fatalf("Remove() = %v, removed %+v", err, removed)
+	}
+	if exists, _ := repo.Exists(ctx, "o1"); exists {
+		t.Fatalf("Remove() kept the order")
+	}
+	if pending, _ := outbox.Pending(ctx, 10); len(pending) != 1 || pending[0].Type != events.OrderDeleted {
+		t.Fatalf("outbox = %+v, want the OrderDeleted event", pending)
+	}
+
+	err = repo.Remove(ctx, "o1", func(models.Order) ([]events.Event, error) {
+		t.Fatalf("fn called for a missing order")
+		return nil, nil
+	})
+	if !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("Remove() of a missing order error = %v, want NotFound", err)
+	}
+}
+
+func TestOrdersRepositoryUpdate(t *testing.T) {
+	client, _ := newTestClient(t)
+	repo := NewOrdersRepository(client)
+	outbox := NewOutbox(client, zap.NewNop())
+	ctx := context.Background()
+
+	// A missing order is not created and its events are not written
+	missing, _ := events.New(events.OrderUpdated, "o1", nil)
+	if err := repo.Update(ctx, newOrder("o1"), missing); !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("Update() of a missing order error = %v, want NotFound", err)
+	}
+	if exists, _ := repo.Exists(ctx, "o1"); exists {
+		t.Fatalf("Update() created the missing order")
+	}
+	if pending, _ := outbox.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("Update() of a missing order wrote %+v to the outbox", pending)
+	}
+
+	if err := repo.Insert(ctx, newOrder("o1")); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	order := newOrder("o1")
+	order.OrderStatus = "shipped"
+	evt, _ := events.New(events.OrderUpdated, "o1", order)
+	if err := repo.Update(ctx, order, evt); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := repo.GetOne(ctx, "o1"); got.OrderStatus != "shipped" {
+		t.Fatalf("GetOne() after Update() = %+v", got)
+	}
+	if pending, _ := outbox.Pending(ctx, 10); len(pending) != 1 || pending[0].ID != evt.ID {
+		t.Fatalf("outbox = %+v, want the update event", pending)
+	}
+}
@@ -0,0 +1,102 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+
+	// External Packages
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	outboxStream = "OUTBOX"
+	outboxCursor = "OUTBOX:CURSOR"
+)
+
+// Outbox reads the events written to the OUTBOX stream by the orders repository.
+// The id of the last published entry is kept in OUTBOX:CURSOR.
+type Outbox struct {
+	client *redis.Client
+	logger *zap.Logger
+}
+
+func NewOutbox(client *redis.Client, logger *zap.Logger) *Outbox {
+	return &Outbox{client: client, logger: logger}
+}
+
+func (o *Outbox) Name() string {
+	return "redis"
+}
+
+// Pending returns the entries after the cursor. An entry that cannot be decoded would
+// hold up the outbox forever, it is logged and the cursor moved past it once the
+// entries before it are published.
+func (o *Outbox) Pending(ctx context.Context, limit int) ([]events.Event, error) {
+	start := "-"
+	cursor, err := o.client.Get(ctx, outboxCursor).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read outbox cursor: %w", err)
+	}
+	if cursor != "" {
+		start = "(" + cursor
+	}
+
+	msgs, err := o.client.XRangeN(ctx, outboxStream, start, "+", int64(limit)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	evts := make([]events.Event, 0, len(msgs))
+	for _, msg := range msgs {
+		raw, _ := msg.Values["event"].(string)
+		var evt events.Event
+		if err := json.Unmarshal([]byte(raw), &evt); err != nil {
+			if len(evts) > 0 {
+				break
+			}
+			o.logger.Error("skipping malformed outbox entry", zap.String("entry", msg.ID), zap.Error(err))
+			if err := o.MarkPublished(ctx, []events.Event{{Position: msg.ID}}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		evt.Position = msg.ID
+		evts = append(evts, evt)
+	}
+	return evts, nil
+}
+
+// MarkPublished moves the cursor past the given events and trims the published entries
+func (o *Outbox) MarkPublished(ctx context.Context, evts []events.Event) error {
+	if len(evts) == 0 {
+		return nil
+	}
+	last := evts[len(evts)-1].Position
+
+	tx := o.client.TxPipeline()
+	tx.Set(ctx, outboxCursor, last, 0)
+	tx.XTrimMinID(ctx, outboxStream, last)
+	if _, err := tx.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record outbox progress: %w", err)
+	}
+	return nil
+}
+
+// addToOutbox queues the events on the transaction that carries the data change
+func addToOutbox(ctx context.Context, tx redis.Pipeliner, evts []events.Event) error {
+	for _, evt := range evts {
+		data, err := json.Marshal(evt)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		tx.XAdd(ctx, &redis.XAddArgs{Stream: outboxStream, Values: map[string]any{"event": data}})
+	}
+	return nil
+}
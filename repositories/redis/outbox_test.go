@@ -0,0 +1,94 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"testing"
+
+	// Local Packages
+	events "learn-go/events"
+
+	// External Packages
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// writeOutbox adds an entry per value, events are encoded and strings written as they are
+func writeOutbox(t *testing.T, client *redis.Client, values ...any) {
+	t.Helper()
+	ctx := context.Background()
+	for _, value := range values {
+		if evt, ok := value.(events.Event); ok {
+			tx := client.TxPipeline()
+			if err := addToOutbox(ctx, tx, []events.Event{evt}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tx.Exec(ctx); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := client.XAdd(ctx, &redis.XAddArgs{Stream: outboxStream, Values: map[string]any{"event": value}}).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func ids(evts []events.Event) []string {
+	ids := make([]string, 0, len(evts))
+	for _, evt := range evts {
+		ids = append(ids, evt.ID)
+	}
+	return ids
+}
+
+func TestOutboxMarkPublished(t *testing.T) {
+	client, _ := newTestClient(t)
+	outbox := NewOutbox(client, zap.NewNop())
+	ctx := context.Background()
+	writeOutbox(t, client, events.Event{ID: "e1"}, events.Event{ID: "e2"}, events.Event{ID: "e3"})
+
+	pending, err := outbox.Pending(ctx, 10)
+	if err != nil || len(pending) != 3 {
+		t.Fatalf("Pending() = %v, %v, want the 3 events", ids(pending), err)
+	}
+	if err := outbox.MarkPublished(ctx, pending[:2]); err != nil {
+		t.Fatalf("MarkPublished() error = %v", err)
+	}
+
+	if cursor := client.Get(ctx, outboxCursor).Val(); cursor != pending[1].Position {
+		t.Fatalf("cursor = %q, want the position of e2 %q", cursor, pending[1].Position)
+	}
+	// The entries before the cursor are trimmed
+	if length := client.XLen(ctx, outboxStream).Val(); length != 2 {
+		t.Fatalf("outbox holds %d entries, want e2 and e3", length)
+	}
+	if pending, err = outbox.Pending(ctx, 10); err != nil || len(pending) != 1 || pending[0].ID != "e3" {
+		t.Fatalf("Pending() = %v, %v after the progress, want e3", ids(pending), err)
+	}
+}
+
+func TestOutboxSkipsMalformedEntries(t *testing.T) {
+	client, _ := newTestClient(t)
+	outbox := NewOutbox(client, zap.NewNop())
+	ctx := context.Background()
+	writeOutbox(t, client, "{", events.Event{ID: "e1"}, "not an event", events.Event{ID: "e2"})
+
+	// The malformed head is skipped, the events before the next one are published first
+	pending, err := outbox.Pending(ctx, 10)
+	if err != nil || len(pending) != 1 || pending[0].ID != "e1" {
+		t.Fatalf("Pending() = %v, %v, want e1", ids(pending), err)
+	}
+	if err := outbox.MarkPublished(ctx, pending); err != nil {
+		t.Fatalf("MarkPublished() error = %v", err)
+	}
+	if pending, err = outbox.Pending(ctx, 10); err != nil || len(pending) != 1 || pending[0].ID != "e2" {
+		t.Fatalf("Pending() = %v, %v, want e2", ids(pending), err)
+	}
+	if err := outbox.MarkPublished(ctx, pending); err != nil {
+		t.Fatalf("MarkPublished() error = %v", err)
+	}
+	if pending, err = outbox.Pending(ctx, 10); err != nil || len(pending) != 0 {
+		t.Fatalf("Pending() = %v, %v, want an empty outbox", ids(pending), err)
+	}
+}
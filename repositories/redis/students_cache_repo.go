@@ -0,0 +1,336 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+	utils "learn-go/utils"
+
+	// External Packages
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// notFoundMarker is cached for roll numbers that do not exist
+const notFoundMarker = "null"
+
+// loadTimeout bounds a load shared by concurrent misses, it does not end with the
+// request that started it
+const loadTimeout = 10 * time.Second
+
+type studentsRepository interface {
+	GetOneStudent(ctx context.Context, rollNo string, includeDeleted bool) (*models.StudentModel, error)
+	GetAllStudents(ctx context.Context, includeDeleted bool) (*[]models.StudentModel, error)
+	IterateStudents(ctx context.Context, includeDeleted bool, fn func(student models.StudentModel) error) error
+	InsertStudent(ctx context.Context, student models.StudentModel, evts ...events.Event) error
+	UpdateStudent(ctx context.Context, rollNo string, updatedStudent models.StudentModel, evts ...events.Event) error
+	PatchStudent(ctx context.Context, rollNo string, current, updated models.StudentModel, evts ...events.Event) error
+	ExistingStudents(ctx context.Context, rollNos []string) (map[string]bool, error)
+	InsertStudents(ctx context.Context, students []models.StudentModel, evts [][]events.Event) ([]bool, error)
+	DeleteStudent(ctx context.Context, current models.StudentModel, deletedAt time.Time, deletedBy string, evts ...events.Event) error
+	RestoreStudent(ctx context.Context, deleted models.StudentModel, evts ...events.Event) error
+	PurgeStudents(
+		ctx context.Context,
+		deletedBefore time.Time,
+		purgeEvents func(rollNo string) ([]events.Event, error),
+	) ([]string, error)
+}
+
+// StudentsCacheRepository is a cache-aside decorator over another students repository.
+// GetOneStudent results are kept in Redis for ttl, missing students for negativeTTL,
+// and every write invalidates the affected keys once the underlying store accepted it.
+type StudentsCacheRepository struct {
+	client      *redis.Client
+	generations map[string]uint64 // of the rollNos being loaded, bumped by every invalidation
+	group       singleflight.Group
+	hits        atomic.Int64
+	logger      *zap.Logger
+	misses      atomic.Int64
+	mu          sync.Mutex
+	negativeTTL time.Duration
+	next        studentsRepository
+	ttl         time.Duration
+}
+
+func NewStudentsCacheRepository(
+	client *redis.Client,
+	next studentsRepository,
+	ttl, negativeTTL time.Duration,
+	logger *zap.Logger,
+) *StudentsCacheRepository {
+	return &StudentsCacheRepository{
+		client:      client,
+		generations: map[string]uint64{},
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		logger:      logger,
+	}
+}
+
+// Stats returns the hit and miss counters since startup
+func (r *StudentsCacheRepository) Stats() models.CacheStats {
+	return models.CacheStats{Hits: r.hits.Load(), Misses: r.misses.Load()}
+}
+
+// GetOneStudent serves the student from Redis, loading it from the underlying
+// repository on a miss. Concurrent misses for the same rollNo share one load, which
+// runs detached from the request that started it so that its cancellation does not
+// fail the others. A load is not cached when the rollNo is invalidated while it runs,
+// it may have read the student before the write. Deleted students are cached as missing,
+// reads including them are not cached.
+func (r *StudentsCacheRepository) GetOneStudent(ctx context.Context, rollNo string, includeDeleted bool) (*models.StudentModel, error) {
+	if includeDeleted {
+		return r.next.GetOneStudent(ctx, rollNo, true)
+	}
+	student, found, err := r.fromCache(ctx, rollNo)
+	if err == nil {
+		r.hits.Add(1)
+		if !found {
+			return nil, errors.E(errors.NotFound, "student details not found")
+		}
+		return student, nil
+	}
+	r.misses.Add(1)
+
+	loads := r.group.DoChan(rollNo, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		r.startLoad(rollNo)
+		defer r.endLoad(rollNo)
+
+		student, err := r.next.GetOneStudent(ctx, rollNo, false)
+		if err != nil {
+			if isStudentNotFound(err) {
+				r.storeLoaded(ctx, rollNo, notFoundMarker, r.negativeTTL)
+			}
+			return nil, err
+		}
+
+		data, err := json.Marshal(student)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode student: %w", err)
+		}
+		r.storeLoaded(ctx, rollNo, string(data), r.ttl)
+		return student, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-loads:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// Callers of a shared load must not share the same pointer
+		loaded := *res.Val.(*models.StudentModel)
+		return &loaded, nil
+	}
+}
+
+// GetAllStudents is not cached
+func (r *StudentsCacheRepository) GetAllStudents(ctx context.Context, includeDeleted bool) (*[]models.StudentModel, error) {
+	return r.next.GetAllStudents(ctx, includeDeleted)
+}
+
+// IterateStudents is not cached, like GetAllStudents
+func (r *StudentsCacheRepository) IterateStudents(
+	ctx context.Context,
+	includeDeleted bool,
+	fn func(student models.StudentModel) error,
+) error {
+	return r.next.IterateStudents(ctx, includeDeleted, fn)
+}
+
+// InsertStudent inserts through and drops a cached "not found" for the rollNo
+func (r *StudentsCacheRepository) InsertStudent(ctx context.Context, student models.StudentModel, evts ...events.Event) error {
+	if err := r.next.InsertStudent(ctx, student, evts...); err != nil {
+		return err
+	}
+	r.invalidate(ctx, student.RollNo)
+	return nil
+}
+
+// ExistingStudents is not cached, imports look up many roll numbers once
+func (r *StudentsCacheRepository) ExistingStudents(ctx context.Context, rollNos []string) (map[string]bool, error) {
+	return r.next.ExistingStudents(ctx, rollNos)
+}
+
+// InsertStudents inserts through and drops the cached "not found" of the inserted rollNos
+func (r *StudentsCacheRepository) InsertStudents(
+	ctx context.Context,
+	students []models.StudentModel,
+	evts [][]events.Event,
+) ([]bool, error) {
+	inserted, err := r.next.InsertStudents(ctx, students, evts)
+	if err != nil {
+		return nil, err
+	}
+
+	var rollNos []string
+	for i, student := range students {
+		if inserted[i] {
+			rollNos = append(rollNos, student.RollNo)
+		}
+	}
+	if len(rollNos) > 0 {
+		r.invalidate(ctx, rollNos...)
+	}
+	return inserted, nil
+}
+
+// UpdateStudent updates through and invalidates both the old and the new rollNo
+func (r *StudentsCacheRepository) UpdateStudent(
+	ctx context.Context,
+	rollNo string,
+	updatedStudent models.StudentModel,
+	evts ...events.Event,
+) error {
+	if err := r.next.UpdateStudent(ctx, rollNo, updatedStudent, evts...); err != nil {
+		return err
+	}
+	r.invalidate(ctx, rollNo, updatedStudent.RollNo)
+	return nil
+}
+
+// PatchStudent patches through and invalidates both the old and the new rollNo, also
+// when the patch did not match as the cached student is stale then
+func (r *StudentsCacheRepository) PatchStudent(
+	ctx context.Context,
+	rollNo string,
+	current, updated models.StudentModel,
+	evts ...events.Event,
+) error {
+	err := r.next.PatchStudent(ctx, rollNo, current, updated, evts...)
+	r.invalidate(ctx, rollNo, updated.RollNo)
+	return err
+}
+
+// DeleteStudent deletes through and invalidates the rollNo, also when the delete did
+// not match as the cached student is stale then
+func (r *StudentsCacheRepository) DeleteStudent(
+	ctx context.Context,
+	current models.StudentModel,
+	deletedAt time.Time,
+	deletedBy string,
+	evts ...events.Event,
+) error {
+	err := r.next.DeleteStudent(ctx, current, deletedAt, deletedBy, evts...)
+	r.invalidate(ctx, current.RollNo)
+	return err
+}
+
+// RestoreStudent restores through and drops the cached student for the rollNo
+func (r *StudentsCacheRepository) RestoreStudent(ctx context.Context, deleted models.StudentModel, evts ...events.Event) error {
+	err := r.next.RestoreStudent(ctx, deleted, evts...)
+	r.invalidate(ctx, deleted.RollNo)
+	return err
+}
+
+// PurgeStudents purges through and invalidates the purged rollNos
+func (r *StudentsCacheRepository) PurgeStudents(
+	ctx context.Context,
+	deletedBefore time.Time,
+	purgeEvents func(rollNo string) ([]events.Event, error),
+) ([]string, error) {
+	purged, err := r.next.PurgeStudents(ctx, deletedBefore, purgeEvents)
+	if len(purged) > 0 {
+		r.invalidate(ctx, purged...)
+	}
+	return purged, err
+}
+
+// fromCache returns redis.Nil when the key is absent. found is false for a cached negative lookup.
+func (r *StudentsCacheRepository) fromCache(ctx context.Context, rollNo string) (*models.StudentModel, bool, error) {
+	value, err := r.client.Get(ctx, utils.GetStudentCacheKey(rollNo)).Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			r.logger.Warn("students cache read failed", zap.String("rollNo", rollNo), zap.Error(err))
+		}
+		return nil, false, err
+	}
+	if value == notFoundMarker {
+		return nil, false, nil
+	}
+
+	var student models.StudentModel
+	if err := json.Unmarshal([]byte(value), &student); err != nil {
+		r.logger.Warn("students cache entry is corrupt", zap.String("rollNo", rollNo), zap.Error(err))
+		return nil, false, err
+	}
+	return &student, true, nil
+}
+
+func (r *StudentsCacheRepository) store(ctx context.Context, rollNo, value string, ttl time.Duration) {
+	if err := r.client.Set(ctx, utils.GetStudentCacheKey(rollNo), value, ttl).Err(); err != nil {
+		r.logger.Warn("students cache write failed", zap.String("rollNo", rollNo), zap.Error(err))
+	}
+}
+
+// startLoad tracks the generation of the rollNo from zero until endLoad. Loads of a
+// rollNo do not overlap as they are shared.
+func (r *StudentsCacheRepository) startLoad(rollNo string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generations[rollNo] = 0
+}
+
+func (r *StudentsCacheRepository) endLoad(rollNo string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.generations, rollNo)
+}
+
+// invalidatedLoad tells whether the rollNo was invalidated since its load started
+func (r *StudentsCacheRepository) invalidatedLoad(rollNo string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generations[rollNo] != 0
+}
+
+// storeLoaded caches the value of a load unless the rollNo was invalidated since the load
+// started. An invalidation may delete the key before the value is written, the generation
+// is checked again to delete the value then.
+func (r *StudentsCacheRepository) storeLoaded(ctx context.Context, rollNo, value string, ttl time.Duration) {
+	if r.invalidatedLoad(rollNo) {
+		return
+	}
+	r.store(ctx, rollNo, value, ttl)
+	if r.invalidatedLoad(rollNo) {
+		r.invalidate(ctx, rollNo)
+	}
+}
+
+// invalidate deletes the cached rollNos, the loads running for them are not cached
+func (r *StudentsCacheRepository) invalidate(ctx context.Context, rollNos ...string) {
+	keys := make([]string, 0, len(rollNos))
+	r.mu.Lock()
+	for _, rollNo := range rollNos {
+		if _, loading := r.generations[rollNo]; loading {
+			r.generations[rollNo]++
+		}
+		keys = append(keys, utils.GetStudentCacheKey(rollNo))
+	}
+	r.mu.Unlock()
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		r.logger.Warn("students cache invalidation failed", zap.Strings("keys", keys), zap.Error(err))
+	}
+}
+
+// isStudentNotFound recognises the not found errors of every students backend
+func isStudentNotFound(err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, sql.ErrNoRows) ||
+		errors.IsKind(err, errors.NotFound)
+}
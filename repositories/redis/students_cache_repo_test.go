@@ -0,0 +1,149 @@
+package redis
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+	utils "learn-go/utils"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// slowStudents is a students repository whose reads wait for release, they fail with
+// the error of their context when it is done first
+type slowStudents struct {
+	studentsRepository
+	started chan struct{}
+	release chan struct{}
+	loads   atomic.Int64
+}
+
+func (s *slowStudents) GetOneStudent(ctx context.Context, rollNo string, _ bool) (*models.StudentModel, error) {
+	s.loads.Add(1)
+	s.started <- struct{}{}
+	<-s.release
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if rollNo == "404" {
+		return nil, sql.ErrNoRows
+	}
+	return &models.StudentModel{RollNo: rollNo, Name: "Ann"}, nil
+}
+
+// UpdateStudent stands for a write committed while a read is in flight
+func (s *slowStudents) UpdateStudent(_ context.Context, _ string, _ models.StudentModel, _ ...events.Event) error {
+	return nil
+}
+
+func TestGetOneStudentSharedLoadOutlivesCanceledCaller(t *testing.T) {
+	client, _ := newTestClient(t)
+	next := &slowStudents{started: make(chan struct{}, 1), release: make(chan struct{})}
+	repo := NewStudentsCacheRepository(client, next, time.Minute, time.Minute, zap.NewNop())
+
+	// The first caller starts the load and gives up while it runs
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := repo.GetOneStudent(ctx, "1", false)
+		first <- err
+	}()
+	<-next.started
+
+	second := make(chan *models.StudentModel, 1)
+	go func() {
+		student, err := repo.GetOneStudent(context.Background(), "1", false)
+		if err != nil {
+			t.Errorf("GetOneStudent() of the second caller error = %v", err)
+		}
+		second <- student
+	}()
+
+	cancel()
+	select {
+	case err := <-first:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("GetOneStudent() of the canceled caller error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the canceled caller kept waiting for the load")
+	}
+
+	close(next.release)
+	if student := <-second; student == nil || student.Name != "Ann" {
+		t.Fatalf("GetOneStudent() of the second caller = %+v, want the loaded student", student)
+	}
+	// The load was cached although the caller that started it was gone
+	if student, err := repo.GetOneStudent(context.Background(), "1", false); err != nil || student.Name != "Ann" {
+		t.Fatalf("GetOneStudent() after the load = %+v, %v", student, err)
+	}
+	if loads := next.loads.Load(); loads != 1 {
+		t.Fatalf("the student was loaded %d times, want once", loads)
+	}
+}
+
+func TestGetOneStudentCachesMissingStudents(t *testing.T) {
+	client, _ := newTestClient(t)
+	next := &slowStudents{started: make(chan struct{}, 1), release: make(chan struct{})}
+	close(next.release)
+	repo := NewStudentsCacheRepository(client, next, time.Minute, time.Minute, zap.NewNop())
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := repo.GetOneStudent(ctx, "404", false); !isStudentNotFound(err) {
+			t.Fatalf("GetOneStudent() of a missing student error = %v, want not found", err)
+		}
+		select {
+		case <-next.started:
+		default:
+		}
+	}
+	if loads := next.loads.Load(); loads != 1 {
+		t.Fatalf("the missing student was loaded %d times, want once", loads)
+	}
+	if stats := repo.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("Stats() = %+v, want 1 hit and 1 miss", stats)
+	}
+}
+
+func TestGetOneStudentDoesNotCacheALoadOverlappingAWrite(t *testing.T) {
+	client, mr := newTestClient(t)
+	next := &slowStudents{started: make(chan struct{}, 1), release: make(chan struct{})}
+	repo := NewStudentsCacheRepository(client, next, time.Minute, time.Minute, zap.NewNop())
+	ctx := context.Background()
+
+	// The load reads the student, then the update commits and invalidates before the load is stored
+	loaded := make(chan error, 1)
+	go func() {
+		_, err := repo.GetOneStudent(ctx, "1", false)
+		loaded <- err
+	}()
+	<-next.started
+	if err := repo.UpdateStudent(ctx, "1", models.StudentModel{RollNo: "1", Name: "Bob"}); err != nil {
+		t.Fatalf("UpdateStudent() error = %v", err)
+	}
+	close(next.release)
+	if err := <-loaded; err != nil {
+		t.Fatalf("GetOneStudent() error = %v", err)
+	}
+
+	if mr.Exists(utils.GetStudentCacheKey("1")) {
+		t.Fatalf("the load started before the update was cached")
+	}
+	// The next load is cached again
+	if _, err := repo.GetOneStudent(ctx, "1", false); err != nil {
+		t.Fatalf("GetOneStudent() after the update error = %v", err)
+	}
+	if !mr.Exists(utils.GetStudentCacheKey("1")) {
+		t.Fatalf("the load after the update was not cached")
+	}
+}
@@ -0,0 +1,97 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"fmt"
+
+	// Local Packages
+	errors "learn-go/errors"
+
+	// External Packages
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Supported values for the sql.driver config
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// iterateBatchSize is the number of rows the Iterate methods read before calling back,
+// a callback never runs while a query holds the connection
+const iterateBatchSize = 100
+
+// Connect opens a connection pool for the given driver and dsn and pings the database.
+func Connect(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	driverName, err := driverName(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer at a time, sharing one connection avoids
+	// "database is locked" errors and keeps in-memory databases consistent. Rows
+	// must therefore be read in full before running code that queries again, see
+	// iterateBatchSize.
+	if driver == DriverSQLite {
+		db.SetMaxOpenConns(1)
+	}
+
+	if pingErr := db.PingContext(ctx); pingErr != nil {
+		_ = db.Close()
+		return nil, pingErr
+	}
+	return db, nil
+}
+
+// driverName maps the configured driver to the name registered with database/sql
+func driverName(driver string) (string, error) {
+	switch driver {
+	case DriverSQLite:
+		return "sqlite", nil
+	case DriverPostgres:
+		return "pgx", nil
+	default:
+		return "", fmt.Errorf("unsupported sql driver %q", driver)
+	}
+}
+
+// withTx runs fn inside a transaction, committing when fn succeeds and rolling back otherwise
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a primary key or unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || code == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+}
+
+// migrations are applied in order and must never be edited once released,
+// add a new version instead. Statements are kept portable between SQLite and Postgres.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create students",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS students (
+				roll_no TEXT PRIMARY KEY,
+				name    TEXT NOT NULL,
+				gender  TEXT NOT NULL,
+				mail_id TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "create orders and line items",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS orders (
+				id           TEXT PRIMARY KEY,
+				user_id      TEXT NOT NULL,
+				order_status TEXT NOT NULL,
+				created_at   TEXT NOT NULL,
+				updated_at   TEXT NOT NULL,
+				shipped_at   TEXT NOT NULL,
+				delivered_at TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id)`,
+			`CREATE TABLE IF NOT EXISTS line_items (
+				order_id TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				item_id  TEXT NOT NULL,
+				quantity INTEGER NOT NULL,
+				price    DOUBLE PRECISION NOT NULL,
+				PRIMARY KEY (order_id, position)
+			)`,
+		},
+	},
+	{
+		version: 3,
+		name:    "create outbox",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS outbox (
+				id           TEXT PRIMARY KEY,
+				type         TEXT NOT NULL,
+				aggregate_id TEXT NOT NULL,
+				occurred_at  TEXT NOT NULL,
+				payload      TEXT NOT NULL,
+				published_at TEXT
+			)`,
+			`CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (published_at, occurred_at)`,
+		},
+	},
+	{
+		version: 4,
+		name:    "soft delete students",
+		statements: []string{
+			`ALTER TABLE students ADD COLUMN deleted_at TEXT`,
+			`ALTER TABLE students ADD COLUMN deleted_by TEXT`,
+			`CREATE INDEX IF NOT EXISTS students_deleted_at_idx ON students (deleted_at)`,
+		},
+	},
+}
+
+// Migrate brings the schema up to date by applying every migration that is not
+// yet recorded in the schema_migrations table, each one in its own transaction.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error {
+		for _, stmt := range m.statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+			m.version, m.name, time.Now().UTC().Format(time.RFC3339))
+		return err
+	})
+}
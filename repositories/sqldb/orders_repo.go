@@ -0,0 +1,277 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"fmt"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+)
+
+type OrdersRepository struct {
+	db *sql.DB
+}
+
+func NewOrdersRepository(db *sql.DB) *OrdersRepository {
+	return &OrdersRepository{db: db}
+}
+
+func (r *OrdersRepository) GetOne(ctx context.Context, orderID string) (models.Order, error) {
+	return getOrder(ctx, r.db, orderID)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func getOrder(ctx context.Context, q querier, orderID string) (models.Order, error) {
+	var order models.Order
+	err := q.QueryRowContext(ctx,
+		`SELECT id, user_id, order_status, created_at, updated_at, shipped_at, delivered_at
+		FROM orders WHERE id = $1`, orderID).
+		Scan(&order.ID, &order.UserID, &order.OrderStatus, &order.CreatedAt,
+			&order.UpdatedAt, &order.ShippedAt, &order.DeliveredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Order{}, errors.E(errors.NotFound, "order not found")
+	}
+	if err != nil {
+		return models.Order{}, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT item_id, quantity, price FROM line_items WHERE order_id = $1 ORDER BY position`, orderID)
+	if err != nil {
+		return models.Order{}, fmt.Errorf("failed to get line items: %w", err)
+	}
+	defer rows.Close()
+
+	order.LineItems = []models.LineItem{}
+	for rows.Next() {
+		var item models.LineItem
+		if err := rows.Scan(&item.ItemID, &item.Quantity, &item.Price); err != nil {
+			return models.Order{}, fmt.Errorf("failed to decode line item: %w", err)
+		}
+		order.LineItems = append(order.LineItems, item)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Order{}, fmt.Errorf("failed to get line items: %w", err)
+	}
+	return order, nil
+}
+
+func (r *OrdersRepository) Insert(ctx context.Context, order models.Order, evts ...events.Event) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO orders (id, user_id, order_status, created_at, updated_at, shipped_at, delivered_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			order.ID, order.UserID, order.OrderStatus, order.CreatedAt,
+			order.UpdatedAt, order.ShippedAt, order.DeliveredAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert order: %w", err)
+		}
+		if err := insertLineItems(ctx, tx, order); err != nil {
+			return err
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+func (r *OrdersRepository) Update(ctx context.Context, order models.Order, evts ...events.Event) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := updateOrder(ctx, tx, order); err != nil {
+			return err
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+// Modify replaces the order with the one fn derives from it within a transaction.
+// The row is locked by a no-op update before it is read, so concurrent modifications
+// of the same order are applied one after the other.
+func (r *OrdersRepository) Modify(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) (models.Order, []events.Event, error),
+) (models.Order, error) {
+	var updated models.Order
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE orders SET id = id WHERE id = $1`, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+		if err := expectAffected(res); err != nil {
+			return errors.E(errors.NotFound, "order not found")
+		}
+
+		current, err := getOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		order, evts, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if err := updateOrder(ctx, tx, order); err != nil {
+			return err
+		}
+		updated = order
+		return addToOutbox(ctx, tx, evts)
+	})
+	if err != nil {
+		return models.Order{}, err
+	}
+	return updated, nil
+}
+
+func updateOrder(ctx context.Context, tx *sql.Tx, order models.Order) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE orders SET user_id = $1, order_status = $2, created_at = $3, updated_at = $4,
+		shipped_at = $5, delivered_at = $6 WHERE id = $7`,
+		order.UserID, order.OrderStatus, order.CreatedAt, order.UpdatedAt,
+		order.ShippedAt, order.DeliveredAt, order.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+
+	// Line items are replaced as a whole, the same way the order document is
+	if _, err := tx.ExecContext(ctx, `DELETE FROM line_items WHERE order_id = $1`, order.ID); err != nil {
+		return fmt.Errorf("failed to remove line items: %w", err)
+	}
+	return insertLineItems(ctx, tx, order)
+}
+
+func (r *OrdersRepository) Delete(ctx context.Context, orderID string, evts ...events.Event) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM line_items WHERE order_id = $1`, orderID); err != nil {
+			return fmt.Errorf("failed to remove line items: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM orders WHERE id = $1`, orderID); err != nil {
+			return fmt.Errorf("failed to delete order: %w", err)
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+// Remove deletes the order within a transaction together with the events fn derives from
+// it. The row is locked by a no-op update before it is read, so fn sees the order deleted.
+func (r *OrdersRepository) Remove(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) ([]events.Event, error),
+) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `UPDATE orders SET id = id WHERE id = $1`, orderID)
+		if err != nil {
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+		if err := expectAffected(res); err != nil {
+			return errors.E(errors.NotFound, "order not found")
+		}
+
+		current, err := getOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		evts, err := fn(current)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM line_items WHERE order_id = $1`, orderID); err != nil {
+			return fmt.Errorf("failed to remove line items: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM orders WHERE id = $1`, orderID); err != nil {
+			return fmt.Errorf("failed to delete order: %w", err)
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+func (r *OrdersRepository) Exists(ctx context.Context, orderID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, orderID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check order: %w", err)
+	}
+	return exists, nil
+}
+
+// Iterate calls fn for every stored order in order id order, stopping at the first
+// error. Orders are read in batches and fn runs only once a batch is read in full, so
+// no connection is held while it runs and fn may use the database itself.
+func (r *OrdersRepository) Iterate(ctx context.Context, fn func(order models.Order) error) error {
+	after := ""
+	for {
+		orders, err := r.nextOrders(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := fn(order); err != nil {
+				return err
+			}
+		}
+		if len(orders) < iterateBatchSize {
+			return nil
+		}
+		after = orders[len(orders)-1].ID
+	}
+}
+
+// nextOrders reads the batch of orders with ids after the given one together with their line items
+func (r *OrdersRepository) nextOrders(ctx context.Context, after string) ([]models.Order, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT o.id, o.user_id, o.order_status, o.created_at, o.updated_at, o.shipped_at, o.delivered_at,
+			li.item_id, li.quantity, li.price
+		FROM orders o LEFT JOIN line_items li ON li.order_id = o.id
+		WHERE o.id IN (SELECT id FROM orders WHERE id > $1 ORDER BY id LIMIT $2)
+		ORDER BY o.id, li.position`, after, iterateBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		var order models.Order
+		var itemID sql.NullString
+		var quantity sql.NullInt64
+		var price sql.NullFloat64
+		err := rows.Scan(&order.ID, &order.UserID, &order.OrderStatus, &order.CreatedAt, &order.UpdatedAt,
+			&order.ShippedAt, &order.DeliveredAt, &itemID, &quantity, &price)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode order: %w", err)
+		}
+
+		if len(orders) == 0 || orders[len(orders)-1].ID != order.ID {
+			order.LineItems = []models.LineItem{}
+			orders = append(orders, order)
+		}
+		if itemID.Valid {
+			current := &orders[len(orders)-1]
+			current.LineItems = append(current.LineItems,
+				models.LineItem{ItemID: itemID.String, Quantity: int(quantity.Int64), Price: price.Float64})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	return orders, nil
+}
+
+func insertLineItems(ctx context.Context, tx *sql.Tx, order models.Order) error {
+	for i, item := range order.LineItems {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO line_items (order_id, position, item_id, quantity, price) VALUES ($1, $2, $3, $4, $5)`,
+			order.ID, i, item.ItemID, item.Quantity, item.Price)
+		if err != nil {
+			return fmt.Errorf("failed to insert line item: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+)
+
+func newOrder(id string, items int) models.Order {
+	order := models.Order{
+		ID:          id,
+		UserID:      "u1",
+		OrderStatus: "placed",
+		CreatedAt:   "2024-01-01T00:00:00Z",
+		UpdatedAt:   "2024-01-01T00:00:00Z",
+		LineItems:   []models.LineItem{},
+	}
+	for i := range items {
+		order.LineItems = append(order.LineItems, models.LineItem{ItemID: fmt.Sprintf("item-%d", i), Quantity: i + 1, Price: 2.5})
+	}
+	return order
+}
+
+func TestOrdersRepository(t *testing.T) {
+	repo := NewOrdersRepository(newTestDB(t))
+	ctx := context.Background()
+
+	order := newOrder("o1", 2)
+	if err := repo.Insert(ctx, order); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	got, err := repo.GetOne(ctx, "o1")
+	if err != nil || !reflect.DeepEqual(got, order) {
+		t.Fatalf("GetOne() = %+v, %v, want %+v", got, err, order)
+	}
+	if _, err := repo.GetOne(ctx, "404"); !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("GetOne() of a missing order error = %v, want NotFound", err)
+	}
+
+	order.OrderStatus = "shipped"
+	order.LineItems = order.LineItems[:1]
+	if err := repo.Update(ctx, order); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := repo.GetOne(ctx, "o1"); !reflect.DeepEqual(got, order) {
+		t.Fatalf("GetOne() after Update() = %+v, want %+v", got, order)
+	}
+
+	if err := repo.Delete(ctx, "o1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if exists, err := repo.Exists(ctx, "o1"); err != nil || exists {
+		t.Fatalf("Exists() after Delete() = %v, %v", exists, err)
+	}
+}
+
+func TestOrdersRepositoryModify(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewOrdersRepository(db)
+	ctx := context.Background()
+	if err := repo.Insert(ctx, newOrder("o1", 1)); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	evt, _ := events.New(events.OrderStatusChanged, "o1", nil)
+	updated, err := repo.Modify(ctx, "o1", func(current models.Order) (models.Order, []events.Event, error) {
+		current.OrderStatus = "shipped"
+		return current, []events.Event{evt}, nil
+	})
+	if err != nil || updated.OrderStatus != "shipped" {
+		t.Fatalf("Modify() = %+v, %v", updated, err)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM outbox WHERE id = $1`, evt.ID); got != 1 {
+		t.Fatalf("Modify() did not write the event")
+	}
+
+	// An error of fn rolls the whole change back
+	_, err = repo.Modify(ctx, "o1", func(current models.Order) (models.Order, []events.Event, error) {
+		return models.Order{}, nil, errors.E(errors.Invalid, "invalid transition")
+	})
+	if !errors.IsKind(err, errors.Invalid) {
+		t.Fatalf("Modify() error = %v, want the error of fn", err)
+	}
+	if got, _ := repo.GetOne(ctx, "o1"); got.OrderStatus != "shipped" {
+		t.Fatalf("a failed Modify() changed the order to %+v", got)
+	}
+
+	_, err = repo.Modify(ctx, "404", func(current models.Order) (models.Order, []events.Event, error) {
+		return current, nil, nil
+	})
+	if !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("Modify() of a missing order error = %v, want NotFound", err)
+	}
+}
+
+func TestOrdersIterateCallbackMayQuery(t *testing.T) {
+	repo := NewOrdersRepository(newTestDB(t))
+	ctx := context.Background()
+	total := 2*iterateBatchSize + 5
+	want := map[string]models.Order{}
+	for i := range total {
+		order := newOrder(fmt.Sprintf("o%04d", i), i%3)
+		if err := repo.Insert(ctx, order); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+		want[order.ID] = order
+	}
+
+	// The callback reads the database again, which deadlocks while a query holds the only connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	last := ""
+	seen := 0
+	err := repo.Iterate(ctx, func(order models.Order) error {
+		if order.ID <= last {
+			return fmt.Errorf("order %s came after %s", order.ID, last)
+		}
+		last = order.ID
+		seen++
+
+		stored, err := repo.GetOne(ctx, order.ID)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(order, want[order.ID]) || !reflect.DeepEqual(stored, order) {
+			return fmt.Errorf("order %s = %+v, want %+v", order.ID, order, want[order.ID])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Iterate() error = %v", err)
+	}
+	if seen != total {
+		t.Fatalf("Iterate() yielded %d orders, want %d", seen, total)
+	}
+}
+
+func TestOrdersRepositoryRemove(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewOrdersRepository(db)
+	ctx := context.Background()
+	order := newOrder("o1", 2)
+	if err := repo.Insert(ctx, order); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	// An error of fn rolls the whole removal back
+	failure := errors.E(errors.Invalid, "stop")
+	err := repo.Remove(ctx, "o1", func(models.Order) ([]events.Event, error) { return nil, failure })
+	if err != failure {
+		t.Fatalf("Remove() error = %v, want the error of fn", err)
+	}
+	if got, _ := repo.GetOne(ctx, "o1"); !reflect.DeepEqual(got, order) {
+		t.Fatalf("a failed Remove() changed the order to %+v", got)
+	}
+
+	var removed models.Order
+	evt, _ := events.New(events.OrderDeleted, "o1", nil)
+	err = repo.Remove(ctx, "o1", func(current models.Order) ([]events.Event, error) {
+		removed = current
+		return []events.Event{evt}, nil
+	})
+	if err != nil || !reflect.DeepEqual(removed, order) {
+		t.Fatalf("Remove() = %v, removed %+v, want %+v", err, removed, order)
+	}
+	if exists, _ := repo.Exists(ctx, "o1"); exists {
+		t.Fatalf("Remove() kept the order")
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM line_items`); got != 0 {
+		t.Fatalf("Remove() kept %d line items", got)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM outbox WHERE id = $1`, evt.ID); got != 1 {
+		t.Fatalf("Remove() did not write the event")
+	}
+
+	err = repo.Remove(ctx, "o1", func(models.Order) ([]events.Event, error) { return nil, nil })
+	if !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("Remove() of a missing order error = %v, want NotFound", err)
+	}
+}
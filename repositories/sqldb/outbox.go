@@ -0,0 +1,85 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	// Local Packages
+	events "learn-go/events"
+)
+
+// timeLayout is fixed width so that timestamps sort as text
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Outbox reads the events written to the outbox table. Published events are
+// kept with a published_at timestamp.
+type Outbox struct {
+	db *sql.DB
+}
+
+func NewOutbox(db *sql.DB) *Outbox {
+	return &Outbox{db: db}
+}
+
+func (o *Outbox) Name() string {
+	return "sql"
+}
+
+func (o *Outbox) Pending(ctx context.Context, limit int) ([]events.Event, error) {
+	rows, err := o.db.QueryContext(ctx,
+		`SELECT id, type, aggregate_id, occurred_at, payload FROM outbox
+		WHERE published_at IS NULL ORDER BY occurred_at, id LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	defer rows.Close()
+
+	evts := []events.Event{}
+	for rows.Next() {
+		var (
+			evt        events.Event
+			occurredAt string
+			payload    string
+		)
+		if err := rows.Scan(&evt.ID, &evt.Type, &evt.AggregateID, &occurredAt, &payload); err != nil {
+			return nil, fmt.Errorf("failed to decode outbox: %w", err)
+		}
+		evt.OccurredAt, err = time.Parse(timeLayout, occurredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode outbox: %w", err)
+		}
+		evt.Payload = json.RawMessage(payload)
+		evts = append(evts, evt)
+	}
+	return evts, rows.Err()
+}
+
+func (o *Outbox) MarkPublished(ctx context.Context, evts []events.Event) error {
+	publishedAt := time.Now().UTC().Format(timeLayout)
+	return withTx(ctx, o.db, func(tx *sql.Tx) error {
+		for _, evt := range evts {
+			_, err := tx.ExecContext(ctx, `UPDATE outbox SET published_at = $1 WHERE id = $2`, publishedAt, evt.ID)
+			if err != nil {
+				return fmt.Errorf("failed to record outbox progress: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// addToOutbox writes the events in the transaction that carries the data change
+func addToOutbox(ctx context.Context, tx *sql.Tx, evts []events.Event) error {
+	for _, evt := range evts {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO outbox (id, type, aggregate_id, occurred_at, payload) VALUES ($1, $2, $3, $4, $5)`,
+			evt.ID, evt.Type, evt.AggregateID, evt.OccurredAt.UTC().Format(timeLayout), string(evt.Payload))
+		if err != nil {
+			return fmt.Errorf("failed to write outbox: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+)
+
+// newTestDB returns a migrated in-memory SQLite database of its own for the test
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := Connect(context.Background(), DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := Migrate(context.Background(), db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	return db
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s error = %v", query, err)
+	}
+	return n
+}
+
+func TestMigrate(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// A second run finds every migration applied and changes nothing
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate() again error = %v", err)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM schema_migrations`); got != len(migrations) {
+		t.Fatalf("schema_migrations has %d rows, want %d", got, len(migrations))
+	}
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("migration %d has version %d, versions must be sequential", i, m.version)
+		}
+	}
+
+	for _, table := range []string{"students", "orders", "line_items", "outbox"} {
+		if got := count(t, db, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1`, table); got != 1 {
+			t.Fatalf("table %s was not created", table)
+		}
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM pragma_table_info('students') WHERE name = 'deleted_at'`); got != 1 {
+		t.Fatalf("students.deleted_at was not added")
+	}
+}
+
+func TestMigrateAppliesOnlyMissingVersions(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+
+	// Forget the last migration and undo it, Migrate must apply just that one again
+	last := migrations[len(migrations)-1]
+	if _, err := db.Exec(`DELETE FROM schema_migrations WHERE version = $1`, last.version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`DROP INDEX students_deleted_at_idx`); err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"deleted_at", "deleted_by"} {
+		if _, err := db.Exec(`ALTER TABLE students DROP COLUMN ` + column); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM schema_migrations WHERE version = $1`, last.version); got != 1 {
+		t.Fatalf("migration %d was not recorded", last.version)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	insert := func(tx *sql.Tx, rollNo string) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO students (roll_no, name, gender, mail_id) VALUES ($1, 'Ann', 'female', 'a@example.com')`, rollNo)
+		return err
+	}
+
+	if err := withTx(ctx, db, func(tx *sql.Tx) error { return insert(tx, "1") }); err != nil {
+		t.Fatalf("withTx() error = %v", err)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM students WHERE roll_no = '1'`); got != 1 {
+		t.Fatalf("withTx() did not commit")
+	}
+
+	failure := errors.E(errors.Conflict, "stop")
+	err := withTx(ctx, db, func(tx *sql.Tx) error {
+		if err := insert(tx, "2"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("withTx() error = %v, want the error of fn", err)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM students WHERE roll_no = '2'`); got != 0 {
+		t.Fatalf("withTx() did not roll back")
+	}
+
+	err = withTx(ctx, db, func(tx *sql.Tx) error { return insert(tx, "1") })
+	if !isUniqueViolation(err) {
+		t.Fatalf("withTx() error = %v, want a unique violation", err)
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	repo := NewStudentsRepository(db)
+	outbox := NewOutbox(db)
+
+	evt, err := events.New(events.StudentEnrolled, "1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.InsertStudent(ctx, newStudent("1"), evt); err != nil {
+		t.Fatalf("InsertStudent() error = %v", err)
+	}
+	// A failed change leaves nothing in the outbox
+	dup, _ := events.New(events.StudentEnrolled, "1", nil)
+	if err := repo.InsertStudent(ctx, newStudent("1"), dup); err == nil {
+		t.Fatalf("InsertStudent() of a taken roll number succeeded")
+	}
+
+	pending, err := outbox.Pending(ctx, 10)
+	if err != nil || len(pending) != 1 || pending[0].ID != evt.ID {
+		t.Fatalf("Pending() = %+v, %v, want the one event", pending, err)
+	}
+	if err := outbox.MarkPublished(ctx, pending); err != nil {
+		t.Fatalf("MarkPublished() error = %v", err)
+	}
+	if pending, _ := outbox.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("Pending() after MarkPublished() = %+v, want none", pending)
+	}
+}
@@ -0,0 +1,372 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+)
+
+type StudentsRepository struct {
+	db *sql.DB
+}
+
+func NewStudentsRepository(db *sql.DB) *StudentsRepository {
+	return &StudentsRepository{db: db}
+}
+
+// studentColumns are the columns scanStudent reads
+const studentColumns = `roll_no, name, gender, mail_id, deleted_at, deleted_by`
+
+// scanStudent reads the studentColumns of a row
+func scanStudent(scan func(dest ...any) error) (models.StudentModel, error) {
+	var student models.StudentModel
+	var deletedAt, deletedBy sql.NullString
+	err := scan(&student.RollNo, &student.Name, &student.Gender, &student.MailID, &deletedAt, &deletedBy)
+	if err != nil {
+		return models.StudentModel{}, err
+	}
+	if deletedAt.Valid {
+		at, err := time.Parse(timeLayout, deletedAt.String)
+		if err != nil {
+			return models.StudentModel{}, fmt.Errorf("invalid deleted_at of student %s: %w", student.RollNo, err)
+		}
+		student.DeletedAt, student.DeletedBy = &at, deletedBy.String
+	}
+	return student, nil
+}
+
+// GetAllStudents returns all students ordered by roll number, the deleted ones only
+// with includeDeleted
+func (r *StudentsRepository) GetAllStudents(ctx context.Context, includeDeleted bool) (*[]models.StudentModel, error) {
+	query := `SELECT ` + studentColumns + ` FROM students WHERE deleted_at IS NULL ORDER BY roll_no`
+	if includeDeleted {
+		query = `SELECT ` + studentColumns + ` FROM students ORDER BY roll_no`
+	}
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := []models.StudentModel{}
+	for rows.Next() {
+		student, err := scanStudent(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &students, nil
+}
+
+// IterateStudents calls fn for every student in roll_no order, the deleted ones only with
+// includeDeleted, and stops at the first error. Students are read in batches and fn runs
+// only once a batch is read in full, so no connection is held while it runs.
+func (r *StudentsRepository) IterateStudents(
+	ctx context.Context,
+	includeDeleted bool,
+	fn func(student models.StudentModel) error,
+) error {
+	after := ""
+	for {
+		students, err := r.nextStudents(ctx, after, includeDeleted)
+		if err != nil {
+			return err
+		}
+		for _, student := range students {
+			if err := fn(student); err != nil {
+				return err
+			}
+		}
+		if len(students) < iterateBatchSize {
+			return nil
+		}
+		after = students[len(students)-1].RollNo
+	}
+}
+
+// nextStudents reads the batch of students with roll numbers after the given one, the
+// deleted ones only with includeDeleted
+func (r *StudentsRepository) nextStudents(ctx context.Context, after string, includeDeleted bool) ([]models.StudentModel, error) {
+	query := `SELECT ` + studentColumns + ` FROM students WHERE deleted_at IS NULL AND roll_no > $1
+		ORDER BY roll_no LIMIT $2`
+	if includeDeleted {
+		query = `SELECT ` + studentColumns + ` FROM students WHERE roll_no > $1 ORDER BY roll_no LIMIT $2`
+	}
+	rows, err := r.db.QueryContext(ctx, query, after, iterateBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	students := []models.StudentModel{}
+	for rows.Next() {
+		student, err := scanStudent(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+	return students, rows.Err()
+}
+
+// GetOneStudent returns a student with given rollNo, sql.ErrNoRows when it does not exist
+// or is deleted and includeDeleted is not set
+func (r *StudentsRepository) GetOneStudent(ctx context.Context, rollNo string, includeDeleted bool) (*models.StudentModel, error) {
+	query := `SELECT ` + studentColumns + ` FROM students WHERE roll_no = $1 AND deleted_at IS NULL`
+	if includeDeleted {
+		query = `SELECT ` + studentColumns + ` FROM students WHERE roll_no = $1`
+	}
+
+	student, err := scanStudent(r.db.QueryRowContext(ctx, query, rollNo).Scan)
+	if err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+// InsertStudent inserts a student into the students table, a taken rollNo is a Conflict
+func (r *StudentsRepository) InsertStudent(ctx context.Context, student models.StudentModel, evts ...events.Event) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO students (roll_no, name, gender, mail_id) VALUES ($1, $2, $3, $4)`,
+			student.RollNo, student.Name, student.Gender, student.MailID)
+		if isUniqueViolation(err) {
+			return errors.E(errors.Conflict, "student already exists", err)
+		}
+		if err != nil {
+			return err
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+// ExistingStudents returns which of the rollNos belong to a student, deleted or not
+func (r *StudentsRepository) ExistingStudents(ctx context.Context, rollNos []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(rollNos))
+	if len(rollNos) == 0 {
+		return existing, nil
+	}
+
+	placeholders := make([]string, len(rollNos))
+	args := make([]any, len(rollNos))
+	for i, rollNo := range rollNos {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = rollNo
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT roll_no FROM students WHERE roll_no IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rollNo string
+		if err := rows.Scan(&rollNo); err != nil {
+			return nil, err
+		}
+		existing[rollNo] = true
+	}
+	return existing, rows.Err()
+}
+
+// InsertStudents inserts the students in a single transaction, skipping the ones whose
+// rollNo exists. inserted reports per student whether it was written, the events of a
+// student are written to the outbox only when it was.
+func (r *StudentsRepository) InsertStudents(
+	ctx context.Context,
+	students []models.StudentModel,
+	evts [][]events.Event,
+) (inserted []bool, err error) {
+	err = withTx(ctx, r.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx,
+			`INSERT INTO students (roll_no, name, gender, mail_id) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (roll_no) DO NOTHING`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		inserted = make([]bool, len(students))
+		for i, student := range students {
+			res, err := stmt.ExecContext(ctx, student.RollNo, student.Name, student.Gender, student.MailID)
+			if err != nil {
+				return err
+			}
+			if err := expectAffected(res); err != nil {
+				continue
+			}
+			inserted[i] = true
+			if err := addToOutbox(ctx, tx, evts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return inserted, err
+}
+
+// UpdateStudent replaces the student details with given rollNo, unless it is deleted
+func (r *StudentsRepository) UpdateStudent(
+	ctx context.Context,
+	rollNo string,
+	updatedStudent models.StudentModel,
+	evts ...events.Event,
+) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE students SET roll_no = $1, name = $2, gender = $3, mail_id = $4
+			WHERE roll_no = $5 AND deleted_at IS NULL`,
+			updatedStudent.RollNo, updatedStudent.Name, updatedStudent.Gender, updatedStudent.MailID, rollNo)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(res); err != nil {
+			return err
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+// PatchStudent replaces the student only while it still holds the current details,
+// sql.ErrNoRows is returned when it is gone, deleted or was changed meanwhile
+func (r *StudentsRepository) PatchStudent(
+	ctx context.Context,
+	rollNo string,
+	current, updated models.StudentModel,
+	evts ...events.Event,
+) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE students SET roll_no = $1, name = $2, gender = $3, mail_id = $4
+			WHERE roll_no = $5 AND name = $6 AND gender = $7 AND mail_id = $8 AND deleted_at IS NULL`,
+			updated.RollNo, updated.Name, updated.Gender, updated.MailID,
+			rollNo, current.Name, current.Gender, current.MailID)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(res); err != nil {
+			return err
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+// DeleteStudent marks the student deleted at deletedAt by deletedBy while it still holds
+// the current details, sql.ErrNoRows is returned when it is gone, deleted already or was
+// changed meanwhile
+func (r *StudentsRepository) DeleteStudent(
+	ctx context.Context,
+	current models.StudentModel,
+	deletedAt time.Time,
+	deletedBy string,
+	evts ...events.Event,
+) error {
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE students SET deleted_at = $1, deleted_by = $2
+			WHERE roll_no = $3 AND name = $4 AND gender = $5 AND mail_id = $6 AND deleted_at IS NULL`,
+			deletedAt.UTC().Format(timeLayout), deletedBy,
+			current.RollNo, current.Name, current.Gender, current.MailID)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(res); err != nil {
+			return err
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+// RestoreStudent clears the deletion of the student while it is still deleted as it was
+// read, sql.ErrNoRows is returned when it is gone, not deleted or was changed meanwhile
+func (r *StudentsRepository) RestoreStudent(ctx context.Context, deleted models.StudentModel, evts ...events.Event) error {
+	if deleted.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	return withTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE students SET deleted_at = NULL, deleted_by = NULL
+			WHERE roll_no = $1 AND name = $2 AND gender = $3 AND mail_id = $4 AND deleted_at = $5`,
+			deleted.RollNo, deleted.Name, deleted.Gender, deleted.MailID, deleted.DeletedAt.UTC().Format(timeLayout))
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(res); err != nil {
+			return err
+		}
+		return addToOutbox(ctx, tx, evts)
+	})
+}
+
+// PurgeStudents permanently removes the students deleted before deletedBefore and
+// returns their rollNos, the events purgeEvents returns for them are written in the
+// same transaction
+func (r *StudentsRepository) PurgeStudents(
+	ctx context.Context,
+	deletedBefore time.Time,
+	purgeEvents func(rollNo string) ([]events.Event, error),
+) ([]string, error) {
+	purged := []string{}
+	err := withTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`DELETE FROM students WHERE deleted_at < $1 RETURNING roll_no`, deletedBefore.UTC().Format(timeLayout))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rollNo string
+			if err := rows.Scan(&rollNo); err != nil {
+				return err
+			}
+			purged = append(purged, rollNo)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		// The rows are read in full before the outbox is written on the same connection
+		rows.Close()
+
+		for _, rollNo := range purged {
+			evts, err := purgeEvents(rollNo)
+			if err != nil {
+				return err
+			}
+			if err := addToOutbox(ctx, tx, evts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return []string{}, err
+	}
+	return purged, nil
+}
+
+// expectAffected returns sql.ErrNoRows when the statement did not touch any row
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package sqldb
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+)
+
+func newStudent(rollNo string) models.StudentModel {
+	return models.StudentModel{RollNo: rollNo, Name: "Student " + rollNo, Gender: "female", MailID: rollNo + "@example.com"}
+}
+
+func TestStudentsRepository(t *testing.T) {
+	repo := NewStudentsRepository(newTestDB(t))
+	ctx := context.Background()
+
+	if err := repo.InsertStudent(ctx, newStudent("1")); err != nil {
+		t.Fatalf("InsertStudent() error = %v", err)
+	}
+	if err := repo.InsertStudent(ctx, newStudent("1")); !errors.IsKind(err, errors.Conflict) {
+		t.Fatalf("InsertStudent() of a taken roll number error = %v, want Conflict", err)
+	}
+
+	updated := newStudent("1")
+	updated.Name = "Ann"
+	if err := repo.UpdateStudent(ctx, "1", updated); err != nil {
+		t.Fatalf("UpdateStudent() error = %v", err)
+	}
+	if err := repo.UpdateStudent(ctx, "404", updated); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("UpdateStudent() of a missing student error = %v, want sql.ErrNoRows", err)
+	}
+
+	// PatchStudent only applies while the student still holds the details it was read with
+	patched := updated
+	patched.Gender = "other"
+	if err := repo.PatchStudent(ctx, "1", newStudent("1"), patched); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("PatchStudent() of stale details error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repo.PatchStudent(ctx, "1", updated, patched); err != nil {
+		t.Fatalf("PatchStudent() error = %v", err)
+	}
+	got, err := repo.GetOneStudent(ctx, "1", false)
+	if err != nil || *got != patched {
+		t.Fatalf("GetOneStudent() = %+v, %v, want %+v", got, err, patched)
+	}
+}
+
+func TestStudentsRepositorySoftDelete(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewStudentsRepository(db)
+	ctx := context.Background()
+	for _, rollNo := range []string{"1", "2"} {
+		if err := repo.InsertStudent(ctx, newStudent(rollNo)); err != nil {
+			t.Fatalf("InsertStudent() error = %v", err)
+		}
+	}
+
+	deletedAt := time.Now().Add(-time.Hour)
+	stale := newStudent("1")
+	stale.Name = "Ann"
+	if err := repo.DeleteStudent(ctx, stale, deletedAt, "admin"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("DeleteStudent() of stale details error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repo.DeleteStudent(ctx, newStudent("1"), deletedAt, "admin"); err != nil {
+		t.Fatalf("DeleteStudent() error = %v", err)
+	}
+	if err := repo.DeleteStudent(ctx, newStudent("1"), deletedAt, "admin"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("DeleteStudent() twice error = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, err := repo.GetOneStudent(ctx, "1", false); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetOneStudent() of a deleted student error = %v, want sql.ErrNoRows", err)
+	}
+	deleted, err := repo.GetOneStudent(ctx, "1", true)
+	if err != nil || deleted.DeletedAt == nil || deleted.DeletedBy != "admin" {
+		t.Fatalf("GetOneStudent() with includeDeleted = %+v, %v", deleted, err)
+	}
+	if all, _ := repo.GetAllStudents(ctx, false); len(*all) != 1 {
+		t.Fatalf("GetAllStudents() = %d students, want 1", len(*all))
+	}
+	if all, _ := repo.GetAllStudents(ctx, true); len(*all) != 2 {
+		t.Fatalf("GetAllStudents() with includeDeleted = %d students, want 2", len(*all))
+	}
+	if err := repo.UpdateStudent(ctx, "1", newStudent("1")); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("UpdateStudent() of a deleted student error = %v, want sql.ErrNoRows", err)
+	}
+
+	notDeleted, _ := repo.GetOneStudent(ctx, "2", false)
+	if err := repo.RestoreStudent(ctx, *notDeleted); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("RestoreStudent() of a student that is not deleted error = %v, want sql.ErrNoRows", err)
+	}
+	// A restore only applies to the deletion it was read with
+	other := *deleted
+	otherAt := deleted.DeletedAt.Add(time.Second)
+	other.DeletedAt = &otherAt
+	if err := repo.RestoreStudent(ctx, other); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("RestoreStudent() of another deletion error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repo.RestoreStudent(ctx, *deleted); err != nil {
+		t.Fatalf("RestoreStudent() error = %v", err)
+	}
+	if _, err := repo.GetOneStudent(ctx, "1", false); err != nil {
+		t.Fatalf("GetOneStudent() of a restored student error = %v", err)
+	}
+
+	if err := repo.DeleteStudent(ctx, newStudent("1"), deletedAt, "admin"); err != nil {
+		t.Fatalf("DeleteStudent() error = %v", err)
+	}
+	purged, err := repo.PurgeStudents(ctx, time.Now(), func(rollNo string) ([]events.Event, error) {
+		evt, err := events.New(events.StudentDeleted, rollNo, nil)
+		return []events.Event{evt}, err
+	})
+	if err != nil || len(purged) != 1 || purged[0] != "1" {
+		t.Fatalf("PurgeStudents() = %v, %v, want [1]", purged, err)
+	}
+	if existing, _ := repo.ExistingStudents(ctx, []string{"1", "2"}); existing["1"] || !existing["2"] {
+		t.Fatalf("ExistingStudents() = %v, want only 2", existing)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM outbox WHERE aggregate_id = '1'`); got != 1 {
+		t.Fatalf("outbox has %d events of the purged student, want 1", got)
+	}
+}
+
+func TestStudentsRepositoryInsertStudents(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewStudentsRepository(db)
+	ctx := context.Background()
+	if err := repo.InsertStudent(ctx, newStudent("2")); err != nil {
+		t.Fatalf("InsertStudent() error = %v", err)
+	}
+
+	students := []models.StudentModel{newStudent("1"), newStudent("2"), newStudent("3")}
+	evts := make([][]events.Event, len(students))
+	for i, student := range students {
+		evt, _ := events.New(events.StudentEnrolled, student.RollNo, nil)
+		evts[i] = []events.Event{evt}
+	}
+	inserted, err := repo.InsertStudents(ctx, students, evts)
+	if err != nil {
+		t.Fatalf("InsertStudents() error = %v", err)
+	}
+	if want := []bool{true, false, true}; fmt.Sprint(inserted) != fmt.Sprint(want) {
+		t.Fatalf("InsertStudents() = %v, want %v", inserted, want)
+	}
+	if got := count(t, db, `SELECT COUNT(*) FROM outbox`); got != 2 {
+		t.Fatalf("outbox has %d events, want the 2 of the inserted students", got)
+	}
+}
+
+func TestIterateStudentsCallbackMayQuery(t *testing.T) {
+	repo := NewStudentsRepository(newTestDB(t))
+	ctx := context.Background()
+	total := 2*iterateBatchSize + 5
+	for i := range total {
+		if err := repo.InsertStudent(ctx, newStudent(fmt.Sprintf("%04d", i))); err != nil {
+			t.Fatalf("InsertStudent() error = %v", err)
+		}
+	}
+	if err := repo.DeleteStudent(ctx, newStudent("0003"), time.Now(), "admin"); err != nil {
+		t.Fatalf("DeleteStudent() error = %v", err)
+	}
+
+	// The callback reads the database again, which deadlocks while a query holds the only connection
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var rollNos []string
+	err := repo.IterateStudents(ctx, false, func(student models.StudentModel) error {
+		if _, err := repo.GetOneStudent(ctx, student.RollNo, false); err != nil {
+			return err
+		}
+		rollNos = append(rollNos, student.RollNo)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("IterateStudents() error = %v", err)
+	}
+	if len(rollNos) != total-1 {
+		t.Fatalf("IterateStudents() yielded %d students, want %d", len(rollNos), total-1)
+	}
+	for i := 1; i < len(rollNos); i++ {
+		if rollNos[i] <= rollNos[i-1] || rollNos[i] == "0003" {
+			t.Fatalf("IterateStudents() yielded %s after %s", rollNos[i], rollNos[i-1])
+		}
+	}
+
+	stop := errors.E(errors.Internal, "stop")
+	calls := 0
+	err = repo.IterateStudents(ctx, false, func(models.StudentModel) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatalf("IterateStudents() = %v after %d calls, want to stop at the first error", err, calls)
+	}
+}
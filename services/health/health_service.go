@@ -3,6 +3,8 @@ package health
 import (
 	// Go Internal Packages
 	"context"
+	"database/sql"
+	"sync/atomic"
 
 	// External Packages
 	"github.com/redis/go-redis/v9"
@@ -10,33 +12,62 @@ import (
 	"go.uber.org/zap"
 )
 
+// HealthCheckerService pings every configured datastore, a nil client means the
+// store is not used by this deployment and is skipped.
 type HealthCheckerService struct {
 	logger      *zap.Logger
 	mongoClient *mongo.Client
 	redisClient *redis.Client
+	sqlDB       *sql.DB
+
+	// notReady is set on shutdown so that load balancers stop routing requests here
+	notReady atomic.Bool
 }
 
 // NewService creates a new HealthCheckerService instance and returns the instance.
-func NewService(logger *zap.Logger, mongoClient *mongo.Client, redisClient *redis.Client) *HealthCheckerService {
+func NewService(logger *zap.Logger, mongoClient *mongo.Client, redisClient *redis.Client, sqlDB *sql.DB) *HealthCheckerService {
 	return &HealthCheckerService{
 		logger:      logger,
 		mongoClient: mongoClient,
 		redisClient: redisClient,
+		sqlDB:       sqlDB,
 	}
 }
 
+// SetReady flips the readiness, the health endpoint fails while not ready
+func (h *HealthCheckerService) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
+// Ready reports whether the service accepts new requests
+func (h *HealthCheckerService) Ready() bool {
+	return !h.notReady.Load()
+}
+
 // Health checks the health of the database connections and returns true if all the connections are healthy.
 func (h *HealthCheckerService) Health(ctx context.Context) bool {
 	// check mongo ping
-	if mongoPingErr := h.mongoClient.Ping(ctx, nil); mongoPingErr != nil {
-		h.logger.Error("Mongo ping failed", zap.Error(mongoPingErr))
-		return false
+	if h.mongoClient != nil {
+		if mongoPingErr := h.mongoClient.Ping(ctx, nil); mongoPingErr != nil {
+			h.logger.Error("Mongo ping failed", zap.Error(mongoPingErr))
+			return false
+		}
 	}
 
 	// check redis ping
-	if redisPingErr := h.redisClient.Ping(ctx).Err(); redisPingErr != nil {
-		h.logger.Error("Redis ping failed", zap.Error(redisPingErr))
-		return false
+	if h.redisClient != nil {
+		if redisPingErr := h.redisClient.Ping(ctx).Err(); redisPingErr != nil {
+			h.logger.Error("Redis ping failed", zap.Error(redisPingErr))
+			return false
+		}
+	}
+
+	// check sql ping
+	if h.sqlDB != nil {
+		if sqlPingErr := h.sqlDB.PingContext(ctx); sqlPingErr != nil {
+			h.logger.Error("SQL ping failed", zap.Error(sqlPingErr))
+			return false
+		}
 	}
 
 	return true
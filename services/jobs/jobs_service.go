@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+	utils "learn-go/utils"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+type JobsRepository interface {
+	Enqueue(ctx context.Context, job models.Job) error
+	GetOne(ctx context.Context, jobID string) (models.Job, error)
+	Save(ctx context.Context, job models.Job, ttl time.Duration) error
+	Dequeue(ctx context.Context, timeout time.Duration) (string, error)
+	Ack(ctx context.Context, jobID string) error
+	Requeue(ctx context.Context, jobID string) (bool, error)
+	Schedule(ctx context.Context, jobID string, runAt time.Time) error
+	PromoteDue(ctx context.Context, now time.Time) (int, error)
+	Processing(ctx context.Context) ([]string, error)
+	RequestCancel(ctx context.Context, jobID string, ttl time.Duration) error
+	CancelRequested(ctx context.Context, jobID string) (bool, error)
+	SubscribeCancels(ctx context.Context) <-chan string
+}
+
+// Handler runs a job with its params and returns the result stored on the job. It must
+// return once ctx is cancelled and may report its progress as it goes. Errors of kind
+// Invalid fail the job right away, other errors are retried until the attempts run out.
+type Handler func(ctx context.Context, params json.RawMessage, progress ProgressFunc) (any, error)
+
+// ProgressFunc reports the units of work done so far out of total, zero when unknown
+type ProgressFunc func(done, total int64)
+
+// ParamsValidator rejects the params of a job type before the job is queued
+type ParamsValidator func(params json.RawMessage) error
+
+// Options tunes the worker pool started by Run
+type Options struct {
+	Workers      int           // jobs run concurrently by this replica
+	MaxAttempts  int           // attempts of a job unless its request sets them
+	RetryBackoff time.Duration // delay before the first retry, doubled on every attempt
+	DrainTimeout time.Duration // time given to the running jobs on shutdown before they are requeued
+	ResultTTL    time.Duration // time a finished job is kept
+}
+
+type jobType struct {
+	handler  Handler
+	validate ParamsValidator
+}
+
+// JobsService queues background jobs in Redis and runs them on a pool of workers, see Run.
+// Jobs are delivered at least once, a job whose replica stopped while running it is
+// picked up again by another one.
+type JobsService struct {
+	jobsRepository JobsRepository
+	logger         *zap.Logger
+	opts           Options
+	types          map[string]jobType
+
+	mu      sync.Mutex
+	running map[string]*runningJob
+}
+
+func NewService(jobsRepository JobsRepository, opts Options, logger *zap.Logger) *JobsService {
+	return &JobsService{
+		jobsRepository: jobsRepository,
+		logger:         logger,
+		opts:           opts,
+		types:          map[string]jobType{},
+		running:        map[string]*runningJob{},
+	}
+}
+
+// Register makes the job type available, validate may be nil when the type takes any
+// params. It must be called before Run.
+func (s *JobsService) Register(name string, handler Handler, validate ParamsValidator) {
+	s.types[name] = jobType{handler: handler, validate: validate}
+}
+
+// Enqueue validates the request against its job type and queues the job
+func (s *JobsService) Enqueue(ctx context.Context, req models.JobRequest) (models.Job, error) {
+	jt, ok := s.types[req.Type]
+	if !ok {
+		ve := errors.ValidationErrs()
+		ve.Add("/type", "must be one of "+strings.Join(s.typeNames(), ", "))
+		return models.Job{}, errors.ValidationFailedErr(ve.Err())
+	}
+	if jt.validate != nil {
+		if err := jt.validate(req.Params); err != nil {
+			return models.Job{}, err
+		}
+	}
+
+	job := models.Job{
+		ID:          utils.GenerateRandomID(),
+		Type:        req.Type,
+		Status:      models.JobQueued,
+		Params:      req.Params,
+		MaxAttempts: req.MaxAttempts,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = s.opts.MaxAttempts
+	}
+	if err := s.jobsRepository.Enqueue(ctx, job); err != nil {
+		return models.Job{}, err
+	}
+	return job, nil
+}
+
+func (s *JobsService) GetOne(ctx context.Context, jobID string) (models.Job, error) {
+	job, err := s.jobsRepository.GetOne(ctx, jobID)
+	if errors.IsKind(err, errors.NotFound) {
+		return models.Job{}, errors.E(errors.NotFound, fmt.Sprintf("job not found with id %s", jobID))
+	}
+	return job, err
+}
+
+// Cancel stops the job. A queued job is cancelled right away, a running job is
+// interrupted by its worker, which marks it cancelled once its handler returned.
+func (s *JobsService) Cancel(ctx context.Context, jobID string) (models.Job, error) {
+	job, err := s.GetOne(ctx, jobID)
+	if err != nil {
+		return models.Job{}, err
+	}
+	if job.Finished() {
+		return models.Job{}, errors.E(errors.Conflict, fmt.Sprintf("job %s already %s", jobID, job.Status))
+	}
+
+	// The flag is what the workers go by, it also covers a job dequeued meanwhile
+	if err := s.jobsRepository.RequestCancel(ctx, jobID, s.opts.ResultTTL); err != nil {
+		return models.Job{}, err
+	}
+	if job.Status == models.JobQueued {
+		now := time.Now().UTC()
+		job.Status = models.JobCanceled
+		job.RetryAt = nil
+		job.FinishedAt = &now
+		if err := s.jobsRepository.Save(ctx, job, s.opts.ResultTTL); err != nil {
+			return models.Job{}, err
+		}
+	}
+	return job, nil
+}
+
+func (s *JobsService) typeNames() []string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
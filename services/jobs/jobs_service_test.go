@@ -0,0 +1,341 @@
+package jobs
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+	redis "learn-go/repositories/redis"
+
+	// External Packages
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const testJob = "test"
+
+type fixture struct {
+	repo *redis.JobsRepository
+	svc  *JobsService
+	runs atomic.Int32
+}
+
+// newFixture returns a service over an in-memory Redis whose test jobs run handler
+func newFixture(t *testing.T, maxAttempts int, handler Handler) *fixture {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	f := &fixture{repo: redis.NewJobsRepository(client)}
+	opts := Options{Workers: 1, MaxAttempts: maxAttempts, RetryBackoff: time.Minute, DrainTimeout: time.Second,
+		ResultTTL: time.Hour}
+	f.svc = NewService(f.repo, opts, zap.NewNop())
+	f.svc.Register(testJob, func(ctx context.Context, params json.RawMessage, progress ProgressFunc) (any, error) {
+		f.runs.Add(1)
+		return handler(ctx, params, progress)
+	}, nil)
+	return f
+}
+
+// runNext takes the next queued job and runs one attempt of it, as a worker does
+func (f *fixture) runNext(t *testing.T, runCtx context.Context) models.Job {
+	t.Helper()
+	jobID, err := f.repo.Dequeue(context.Background(), time.Second)
+	if err != nil || jobID == "" {
+		t.Fatalf("Dequeue() = %q, %v, want a queued job", jobID, err)
+	}
+	f.svc.process(runCtx, jobID)
+	return f.job(t, jobID)
+}
+
+func (f *fixture) job(t *testing.T, jobID string) models.Job {
+	t.Helper()
+	job, err := f.repo.GetOne(context.Background(), jobID)
+	if err != nil {
+		t.Fatalf("GetOne() error = %v", err)
+	}
+	return job
+}
+
+func (f *fixture) processing(t *testing.T) []string {
+	t.Helper()
+	jobIDs, err := f.repo.Processing(context.Background())
+	if err != nil {
+		t.Fatalf("Processing() error = %v", err)
+	}
+	return jobIDs
+}
+
+func (f *fixture) enqueue(t *testing.T) models.Job {
+	t.Helper()
+	job, err := f.svc.Enqueue(context.Background(), models.JobRequest{Type: testJob})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	return job
+}
+
+// failing returns a handler that fails with err the first failures times and then succeeds
+func failing(failures int, err error) Handler {
+	var calls atomic.Int32
+	return func(ctx context.Context, params json.RawMessage, progress ProgressFunc) (any, error) {
+		if int(calls.Add(1)) <= failures {
+			return nil, err
+		}
+		return "done", nil
+	}
+}
+
+func TestRetries(t *testing.T) {
+	f := newFixture(t, 3, failing(2, fmt.Errorf("flaky")))
+	ctx := context.Background()
+	queued := f.enqueue(t)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+		job := f.runNext(t, ctx)
+		if job.Status != models.JobQueued || job.Attempts != attempt || job.Error != "flaky" || job.RetryAt == nil {
+			t.Fatalf("job after failed attempt %d = %+v, want it queued for a retry", attempt, job)
+		}
+		// The backoff doubles with every attempt
+		backoff := time.Minute << (attempt - 1)
+		if delay := job.RetryAt.Sub(before); delay < backoff || delay > backoff+time.Minute/2 {
+			t.Fatalf("retry of attempt %d in %v, want %v", attempt, delay, backoff)
+		}
+		if len(f.processing(t)) != 0 {
+			t.Fatalf("a job waiting for its retry is still processing")
+		}
+
+		// Nothing is queued until the retry is due
+		if promoted, _ := f.repo.PromoteDue(ctx, time.Now()); promoted != 0 {
+			t.Fatalf("PromoteDue() before the retry is due = %d, want 0", promoted)
+		}
+		if promoted, _ := f.repo.PromoteDue(ctx, job.RetryAt.Add(time.Millisecond)); promoted != 1 {
+			t.Fatalf("PromoteDue() once the retry is due = %d, want 1", promoted)
+		}
+	}
+
+	job := f.runNext(t, ctx)
+	if job.ID != queued.ID || job.Status != models.JobSucceeded || job.Attempts != 3 || string(job.Result) != `"done"` ||
+		job.RetryAt != nil || job.FinishedAt == nil {
+		t.Fatalf("job after the last attempt = %+v, want it succeeded", job)
+	}
+	if len(f.processing(t)) != 0 {
+		t.Fatalf("a finished job is still processing")
+	}
+}
+
+func TestFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		handler      Handler
+		wantAttempts int
+		wantError    string
+	}{
+		{
+			name:         "attempts run out",
+			maxAttempts:  2,
+			handler:      failing(5, fmt.Errorf("down")),
+			wantAttempts: 2,
+			wantError:    "down",
+		},
+		{
+			name:         "invalid params are not retried",
+			maxAttempts:  3,
+			handler:      failing(5, errors.E(errors.Invalid, "bad params")),
+			wantAttempts: 1,
+			wantError:    "bad params",
+		},
+		{
+			name:        "panics are retried",
+			maxAttempts: 2,
+			handler: func(ctx context.Context, params json.RawMessage, progress ProgressFunc) (any, error) {
+				panic("boom")
+			},
+			wantAttempts: 2,
+			wantError:    "job panicked: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, tt.maxAttempts, tt.handler)
+			ctx := context.Background()
+			f.enqueue(t)
+
+			job := f.runNext(t, ctx)
+			for job.Status == models.JobQueued {
+				_, _ = f.repo.PromoteDue(ctx, job.RetryAt.Add(time.Millisecond))
+				job = f.runNext(t, ctx)
+			}
+			if job.Status != models.JobFailed || job.Attempts != tt.wantAttempts || job.Error != tt.wantError {
+				t.Fatalf("job = %+v, want failed after %d attempts with %q", job, tt.wantAttempts, tt.wantError)
+			}
+			if len(f.processing(t)) != 0 {
+				t.Fatalf("a failed job is still processing")
+			}
+		})
+	}
+}
+
+// blocking is a handler that runs until its ctx is cancelled, started is closed once it runs
+func blocking(started chan struct{}) Handler {
+	return func(ctx context.Context, params json.RawMessage, progress ProgressFunc) (any, error) {
+		progress(1, 2)
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	f := newFixture(t, 1, failing(0, nil))
+	ctx := context.Background()
+	queued := f.enqueue(t)
+
+	job, err := f.svc.Cancel(ctx, queued.ID)
+	if err != nil || job.Status != models.JobCanceled {
+		t.Fatalf("Cancel() = %+v, %v, want it canceled", job, err)
+	}
+	if _, err := f.svc.Cancel(ctx, queued.ID); !errors.IsKind(err, errors.Conflict) {
+		t.Fatalf("Cancel() of a canceled job error = %v, want Conflict", err)
+	}
+	if _, err := f.svc.Cancel(ctx, "missing"); !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("Cancel() of a missing job error = %v, want NotFound", err)
+	}
+
+	// The worker dequeuing it drops it without running it
+	if job := f.runNext(t, ctx); job.Status != models.JobCanceled || f.runs.Load() != 0 {
+		t.Fatalf("job = %+v after %d runs, want it canceled without running", job, f.runs.Load())
+	}
+	if len(f.processing(t)) != 0 {
+		t.Fatalf("a canceled job is still processing")
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	started := make(chan struct{})
+	f := newFixture(t, 3, blocking(started))
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go f.svc.watchCancels(ctx)
+	queued := f.enqueue(t)
+
+	done := make(chan models.Job)
+	go func() { done <- f.runNext(t, context.Background()) }()
+	<-started
+
+	if job, err := f.svc.Cancel(ctx, queued.ID); err != nil || job.Status != models.JobRunning {
+		t.Fatalf("Cancel() = %+v, %v, want the running job", job, err)
+	}
+	select {
+	case job := <-done:
+		if job.Status != models.JobCanceled || job.Attempts != 1 || job.Progress.Done != 1 {
+			t.Fatalf("job = %+v, want it canceled with its progress", job)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the running job was not interrupted by Cancel()")
+	}
+}
+
+func TestShutdownRequeuesTheRunningJob(t *testing.T) {
+	started := make(chan struct{})
+	f := newFixture(t, 1, blocking(started))
+	queued := f.enqueue(t)
+
+	runCtx, abort := context.WithCancel(context.Background())
+	done := make(chan models.Job)
+	go func() { done <- f.runNext(t, runCtx) }()
+	<-started
+	abort()
+
+	// The interrupted attempt does not count and the job is the next one dequeued
+	job := <-done
+	if job.Status != models.JobQueued || job.Attempts != 0 || job.HeartbeatAt != nil {
+		t.Fatalf("job = %+v, want it queued again without an attempt", job)
+	}
+	if jobID, _ := f.repo.Dequeue(context.Background(), time.Second); jobID != queued.ID {
+		t.Fatalf("Dequeue() = %q, want the interrupted job %q", jobID, queued.ID)
+	}
+}
+
+func TestRecoverStale(t *testing.T) {
+	tests := []struct {
+		name      string
+		heartbeat time.Duration // age of the heartbeat of the job, none when zero
+		attempts  int
+		status    string
+		running   bool // run by this replica
+		want      string
+		requeued  bool
+	}{
+		{name: "stale job", heartbeat: time.Minute, attempts: 1, status: models.JobRunning,
+			want: models.JobQueued, requeued: true},
+		{name: "dequeued but never started", attempts: 0, status: models.JobQueued,
+			want: models.JobQueued, requeued: true},
+		{name: "stale job out of attempts", heartbeat: time.Minute, attempts: 2, status: models.JobRunning,
+			want: models.JobFailed},
+		{name: "recent heartbeat", heartbeat: time.Second, attempts: 1, status: models.JobRunning,
+			want: models.JobRunning},
+		{name: "run by this replica", heartbeat: time.Minute, attempts: 1, status: models.JobRunning, running: true,
+			want: models.JobRunning},
+		{name: "finished job", attempts: 1, status: models.JobSucceeded, want: models.JobSucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t, 2, failing(0, nil))
+			ctx := context.Background()
+
+			// The job was taken by a replica that stopped without handing it back
+			job := f.enqueue(t)
+			if jobID, _ := f.repo.Dequeue(ctx, time.Second); jobID != job.ID {
+				t.Fatalf("Dequeue() = %q, want %q", jobID, job.ID)
+			}
+			job.Status, job.Attempts = tt.status, tt.attempts
+			if tt.heartbeat != 0 {
+				heartbeat := time.Now().Add(-tt.heartbeat)
+				job.HeartbeatAt = &heartbeat
+			}
+			if err := f.repo.Save(ctx, job, 0); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+			if tt.running {
+				f.svc.track(job.ID, &runningJob{cancel: func() {}})
+			}
+
+			// A job is only recovered once it has been seen stale for staleAfter
+			suspects := map[string]time.Time{}
+			f.svc.recoverStale(ctx, suspects)
+			if got := f.job(t, job.ID); got.Status != tt.status {
+				t.Fatalf("job at first sight = %+v, want it left %s", got, tt.status)
+			}
+			if first, ok := suspects[job.ID]; ok {
+				suspects[job.ID] = first.Add(-staleAfter)
+			}
+			f.svc.recoverStale(ctx, suspects)
+
+			got := f.job(t, job.ID)
+			if got.Status != tt.want {
+				t.Fatalf("job = %+v, want it %s", got, tt.want)
+			}
+			// Only the job still running stays on the processing list
+			if processing := len(f.processing(t)) == 1; processing != (tt.want == models.JobRunning) {
+				t.Fatalf("job processing = %v, want it only while running", processing)
+			}
+			if tt.requeued {
+				if jobID, _ := f.repo.Dequeue(ctx, time.Second); jobID != job.ID {
+					t.Fatalf("Dequeue() = %q, want the recovered job %q", jobID, job.ID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package jobs
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	// Local Packages
+	audit "learn-go/audit"
+	errors "learn-go/errors"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+const (
+	dequeueTimeout   = time.Second
+	saveInterval     = time.Second // how often a running job saves its progress and heartbeat
+	maintainInterval = time.Second
+	staleAfter       = 30 * time.Second // processing jobs without a heartbeat for this long are requeued
+)
+
+// runningJob is a job being run by a worker of this replica
+type runningJob struct {
+	cancel   context.CancelFunc
+	canceled atomic.Bool // set when the job was cancelled through Cancel
+
+	mu       sync.Mutex
+	progress models.JobProgress
+}
+
+func (rj *runningJob) cancelByUser() {
+	rj.canceled.Store(true)
+	rj.cancel()
+}
+
+func (rj *runningJob) setProgress(done, total int64) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	rj.progress = models.JobProgress{Done: done, Total: total}
+}
+
+func (rj *runningJob) getProgress() models.JobProgress {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	return rj.progress
+}
+
+// Run starts the workers and runs the queued jobs until ctx is cancelled. The running
+// jobs are then given DrainTimeout to finish, the ones still running after it are
+// interrupted and handed back to the queue for another replica or the next start.
+func (s *JobsService) Run(ctx context.Context) {
+	runCtx, abort := context.WithCancel(context.WithoutCancel(ctx))
+	defer abort()
+
+	go s.watchCancels(ctx)
+	go s.maintain(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, runCtx)
+		}()
+	}
+	s.logger.Info("Started job workers", zap.Int("workers", s.opts.Workers))
+
+	<-ctx.Done()
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(s.opts.DrainTimeout):
+		s.logger.Warn("jobs still running after the drain timeout, handing them back to the queue")
+		abort()
+		<-drained
+	}
+	s.logger.Info("Stopped job workers")
+}
+
+// work takes jobs off the queue until ctx is cancelled, the jobs run with runCtx
+func (s *JobsService) work(ctx, runCtx context.Context) {
+	for ctx.Err() == nil {
+		jobID, err := s.jobsRepository.Dequeue(ctx, dequeueTimeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Error("failed to dequeue job", zap.Error(err))
+				sleep(ctx, s.opts.RetryBackoff)
+			}
+			continue
+		}
+		if jobID != "" {
+			s.process(runCtx, jobID)
+		}
+	}
+}
+
+// process runs one attempt of the job and records its outcome
+func (s *JobsService) process(runCtx context.Context, jobID string) {
+	// The records are written even once the drain timeout interrupted the job
+	ctx := context.WithoutCancel(runCtx)
+	logger := s.logger.With(zap.String("jobId", jobID))
+
+	job, err := s.jobsRepository.GetOne(ctx, jobID)
+	if errors.IsKind(err, errors.NotFound) {
+		// The record expired, there is nothing left to run
+		s.ack(ctx, jobID, logger)
+		return
+	}
+	if err != nil {
+		// Left in the processing list, it is requeued once found stale
+		logger.Error("failed to get job", zap.Error(err))
+		return
+	}
+	if job.Finished() {
+		s.ack(ctx, jobID, logger)
+		return
+	}
+	if canceled, err := s.jobsRepository.CancelRequested(ctx, jobID); err == nil && canceled {
+		s.finish(ctx, job, models.JobCanceled, "", logger)
+		return
+	}
+	jt, ok := s.types[job.Type]
+	if !ok {
+		s.finish(ctx, job, models.JobFailed, "unknown job type "+job.Type, logger)
+		return
+	}
+
+	now := time.Now().UTC()
+	job.Status = models.JobRunning
+	job.Attempts++
+	if job.StartedAt == nil {
+		job.StartedAt = &now
+	}
+	job.HeartbeatAt = &now
+	job.RetryAt = nil
+	if err := s.jobsRepository.Save(ctx, job, 0); err != nil {
+		logger.Error("failed to save running job", zap.Error(err))
+	}
+
+	// The changes of the job are audited as made by its type, within the job id
+	jobCtx, cancel := context.WithCancel(audit.WithRequest(runCtx, audit.Request{Actor: "job:" + job.Type, RequestID: job.ID}))
+	defer cancel()
+	rj := &runningJob{cancel: cancel, progress: job.Progress}
+	s.track(jobID, rj)
+	defer s.untrack(jobID)
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.heartbeat(ctx, job, rj, stop, logger)
+	}()
+	result, runErr := safeRun(jobCtx, jt.handler, job.Params, rj.setProgress)
+	close(stop)
+	<-stopped
+	job.Progress = rj.getProgress()
+
+	switch {
+	case runErr == nil:
+		job.Result, err = json.Marshal(result)
+		if err != nil {
+			s.finish(ctx, job, models.JobFailed, "failed to encode result: "+err.Error(), logger)
+			return
+		}
+		s.finish(ctx, job, models.JobSucceeded, "", logger)
+	case rj.canceled.Load():
+		s.finish(ctx, job, models.JobCanceled, "", logger)
+	case runCtx.Err() != nil:
+		// Interrupted by the shutdown, the attempt does not count
+		job.Attempts--
+		job.Status = models.JobQueued
+		job.HeartbeatAt = nil
+		s.requeue(ctx, job, logger)
+	case errors.IsKind(runErr, errors.Invalid) || job.Attempts >= job.MaxAttempts:
+		s.finish(ctx, job, models.JobFailed, errorMessage(runErr), logger)
+	default:
+		runAt := time.Now().UTC().Add(s.opts.RetryBackoff << (job.Attempts - 1))
+		job.Status = models.JobQueued
+		job.Error = errorMessage(runErr)
+		job.RetryAt = &runAt
+		job.HeartbeatAt = nil
+		logger.Warn("job failed, will retry", zap.Int("attempt", job.Attempts), zap.Time("retryAt", runAt),
+			zap.Error(runErr))
+		if err := s.jobsRepository.Save(ctx, job, 0); err != nil {
+			logger.Error("failed to save job", zap.Error(err))
+		}
+		if err := s.jobsRepository.Schedule(ctx, jobID, runAt); err != nil {
+			logger.Error("failed to schedule job retry", zap.Error(err))
+		}
+	}
+}
+
+// heartbeat saves the progress of the running job until stop is closed, and interrupts
+// the job when it was cancelled on a replica whose notification was missed
+func (s *JobsService) heartbeat(
+	ctx context.Context,
+	job models.Job,
+	rj *runningJob,
+	stop <-chan struct{},
+	logger *zap.Logger,
+) {
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UTC()
+		job.HeartbeatAt = &now
+		job.Progress = rj.getProgress()
+		if err := s.jobsRepository.Save(ctx, job, 0); err != nil {
+			logger.Error("failed to save job progress", zap.Error(err))
+		}
+		if canceled, err := s.jobsRepository.CancelRequested(ctx, job.ID); err == nil && canceled {
+			rj.cancelByUser()
+		}
+	}
+}
+
+// finish records the final status of the job and removes it from the processing list
+func (s *JobsService) finish(ctx context.Context, job models.Job, status, errMsg string, logger *zap.Logger) {
+	now := time.Now().UTC()
+	job.Status = status
+	job.Error = errMsg
+	job.RetryAt = nil
+	job.FinishedAt = &now
+	if status != models.JobSucceeded {
+		job.Result = nil
+	}
+
+	if err := s.jobsRepository.Save(ctx, job, s.opts.ResultTTL); err != nil {
+		// Left in the processing list, it is requeued once found stale
+		logger.Error("failed to save finished job", zap.Error(err))
+		return
+	}
+	s.ack(ctx, job.ID, logger)
+	logger.Info("job finished", zap.String("type", job.Type), zap.String("status", status),
+		zap.Int("attempts", job.Attempts))
+}
+
+// requeue saves the job as queued and hands it back to the queue
+func (s *JobsService) requeue(ctx context.Context, job models.Job, logger *zap.Logger) {
+	if err := s.jobsRepository.Save(ctx, job, 0); err != nil {
+		logger.Error("failed to save requeued job", zap.Error(err))
+		return
+	}
+	if _, err := s.jobsRepository.Requeue(ctx, job.ID); err != nil {
+		logger.Error("failed to requeue job", zap.Error(err))
+		return
+	}
+	logger.Info("job requeued", zap.String("type", job.Type))
+}
+
+func (s *JobsService) ack(ctx context.Context, jobID string, logger *zap.Logger) {
+	if err := s.jobsRepository.Ack(ctx, jobID); err != nil {
+		logger.Error("failed to acknowledge job", zap.Error(err))
+	}
+}
+
+// watchCancels interrupts the jobs of this replica as soon as they are cancelled
+func (s *JobsService) watchCancels(ctx context.Context) {
+	for jobID := range s.jobsRepository.SubscribeCancels(ctx) {
+		s.mu.Lock()
+		rj := s.running[jobID]
+		s.mu.Unlock()
+		if rj != nil {
+			rj.cancelByUser()
+		}
+	}
+}
+
+// maintain queues the jobs whose retry is due and recovers the stale ones until ctx is cancelled
+func (s *JobsService) maintain(ctx context.Context) {
+	ticker := time.NewTicker(maintainInterval)
+	defer ticker.Stop()
+
+	suspects := map[string]time.Time{}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := s.jobsRepository.PromoteDue(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.logger.Error("failed to queue due job retries", zap.Error(err))
+		}
+		s.recoverStale(ctx, suspects)
+	}
+}
+
+// recoverStale requeues the jobs left in the processing list by a replica that stopped
+// without handing them back. A job is stale once it has been seen there for staleAfter
+// without a heartbeat newer than staleAfter. suspects holds when each was first seen so.
+func (s *JobsService) recoverStale(ctx context.Context, suspects map[string]time.Time) {
+	jobIDs, err := s.jobsRepository.Processing(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("failed to list processing jobs", zap.Error(err))
+		}
+		return
+	}
+
+	now := time.Now()
+	processing := make(map[string]bool, len(jobIDs))
+	for _, jobID := range jobIDs {
+		processing[jobID] = true
+		if s.isRunning(jobID) {
+			delete(suspects, jobID)
+			continue
+		}
+
+		logger := s.logger.With(zap.String("jobId", jobID))
+		job, err := s.jobsRepository.GetOne(ctx, jobID)
+		if errors.IsKind(err, errors.NotFound) || (err == nil && job.Finished()) {
+			s.ack(ctx, jobID, logger)
+			continue
+		}
+		if err != nil {
+			logger.Error("failed to get processing job", zap.Error(err))
+			continue
+		}
+		if job.HeartbeatAt != nil && now.Sub(*job.HeartbeatAt) < staleAfter {
+			delete(suspects, jobID)
+			continue
+		}
+		if first, ok := suspects[jobID]; !ok {
+			suspects[jobID] = now
+			continue
+		} else if now.Sub(first) < staleAfter {
+			continue
+		}
+
+		delete(suspects, jobID)
+		if job.Status == models.JobRunning && job.Attempts >= job.MaxAttempts {
+			s.finish(ctx, job, models.JobFailed, "the worker running the job stopped responding", logger)
+			continue
+		}
+		logger.Warn("recovering stale job")
+		job.Status = models.JobQueued
+		job.HeartbeatAt = nil
+		s.requeue(ctx, job, logger)
+	}
+
+	for jobID := range suspects {
+		if !processing[jobID] {
+			delete(suspects, jobID)
+		}
+	}
+}
+
+func (s *JobsService) track(jobID string, rj *runningJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running[jobID] = rj
+}
+
+func (s *JobsService) untrack(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, jobID)
+}
+
+func (s *JobsService) isRunning(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.running[jobID]
+	return ok
+}
+
+// safeRun runs the handler, turning a panic into an error so the worker survives it
+func safeRun(ctx context.Context, handler Handler, params json.RawMessage, progress ProgressFunc) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, params, progress)
+}
+
+// errorMessage returns the message of an application error rather than its JSON form
+func errorMessage(err error) string {
+	e, ok := err.(*errors.Error)
+	if !ok {
+		return err.Error()
+	}
+	if e.WrappedErr != nil {
+		return e.Message + ": " + e.WrappedErr.Error()
+	}
+	return e.Message
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,260 @@
+package orders
+
+import (
+	// Go Internal Packages
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+const (
+	// subscriberBuffer is how far a follower may lag behind before it is disconnected
+	subscriberBuffer = 64
+	// replayPage is how many retained changes are read at a time for a resuming follower
+	replayPage = 1000
+)
+
+type OrderChangesRepository interface {
+	Append(ctx context.Context, change models.OrderChange) (models.OrderChange, error)
+	Retained(ctx context.Context) (oldest, newest string, err error)
+	Since(ctx context.Context, lastID string, limit int64) ([]models.OrderChange, error)
+	Subscribe(ctx context.Context) <-chan models.OrderChange
+}
+
+// ChangeFeed turns order events into OrderChanges and streams them to the clients
+// connected to this replica. As an events.EventHandler it records every change once
+// per deployment, Run then receives the changes recorded by any replica.
+type ChangeFeed struct {
+	changesRepository OrderChangesRepository
+	logger            *zap.Logger
+	mu                sync.Mutex
+	subscribers       map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	ch     chan models.OrderChange
+	filter func(models.OrderChange) bool
+}
+
+func NewChangeFeed(changesRepository OrderChangesRepository, logger *zap.Logger) *ChangeFeed {
+	return &ChangeFeed{
+		changesRepository: changesRepository,
+		logger:            logger,
+		subscribers:       make(map[*subscriber]struct{}),
+	}
+}
+
+func (f *ChangeFeed) Name() string {
+	return "order-changes"
+}
+
+// Handle records the change carried by an order event, other events are ignored
+func (f *ChangeFeed) Handle(ctx context.Context, evt events.Event) error {
+	change := models.OrderChange{OrderID: evt.AggregateID, OccurredAt: evt.OccurredAt}
+	switch evt.Type {
+	case events.OrderCreated:
+		change.Kind = models.OrderInserted
+	case events.OrderUpdated:
+		change.Kind = models.OrderUpdated
+	case events.OrderDeleted:
+		change.Kind = models.OrderDeleted
+	default:
+		return nil
+	}
+
+	// Every order event carries the order, the deleted one for OrderDeleted
+	var order models.Order
+	if err := json.Unmarshal(evt.Payload, &order); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", evt.Type, err)
+	}
+	change.UserID = order.UserID
+	if change.Kind != models.OrderDeleted {
+		change.Order = &order
+	}
+
+	_, err := f.changesRepository.Append(ctx, change)
+	return err
+}
+
+// Run fans the changes out to the local followers until ctx is cancelled
+func (f *ChangeFeed) Run(ctx context.Context) {
+	for change := range f.changesRepository.Subscribe(ctx) {
+		f.broadcast(change)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subscribers {
+		close(sub.ch)
+		delete(f.subscribers, sub)
+	}
+}
+
+// Follow streams the changes accepted by filter. With a lastID the retained
+// changes after it are replayed first, when it is older than the retained history
+// an OrderReset change is sent instead so that the follower reloads the orders.
+// The channel is closed when ctx is done, the feed stops, or the follower falls
+// too far behind, in which case it should reconnect with the id of the last change
+// it received. A malformed lastID is Invalid.
+func (f *ChangeFeed) Follow(
+	ctx context.Context,
+	lastID string,
+	filter func(models.OrderChange) bool,
+) (<-chan models.OrderChange, error) {
+	if lastID != "" {
+		if _, _, err := parseChangeID(lastID); err != nil {
+			return nil, errors.E(errors.Invalid, "invalid last event id")
+		}
+	}
+
+	// Subscribe before reading the history so that nothing falls in between
+	sub := &subscriber{ch: make(chan models.OrderChange, subscriberBuffer), filter: filter}
+	f.mu.Lock()
+	f.subscribers[sub] = struct{}{}
+	f.mu.Unlock()
+
+	var history []models.OrderChange
+	var reset *models.OrderChange
+	if lastID != "" {
+		oldest, newest, err := f.changesRepository.Retained(ctx)
+		if err == nil && oldest != "" && compareChangeIDs(lastID, oldest) < 0 {
+			// Changes after lastID may have been trimmed, a partial replay would hide the gap
+			reset = &models.OrderChange{ID: newest, Kind: models.OrderReset, OccurredAt: time.Now().UTC()}
+		} else if err == nil {
+			history, err = f.changesRepository.Since(ctx, lastID, replayPage)
+		}
+		if err != nil {
+			f.unsubscribe(sub)
+			return nil, err
+		}
+	}
+
+	out := make(chan models.OrderChange)
+	go func() {
+		defer close(out)
+		defer f.unsubscribe(sub)
+
+		last := lastID
+		send := func(change models.OrderChange) bool {
+			// Changes replayed from the history are also delivered live, skip those
+			if last != "" && compareChangeIDs(change.ID, last) <= 0 {
+				return true
+			}
+			last = change.ID
+			if !filter(change) {
+				return true
+			}
+			select {
+			case out <- change:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if reset != nil {
+			// The follower reloads everything up to the reset, whatever the filter
+			last = reset.ID
+			select {
+			case out <- *reset:
+			case <-ctx.Done():
+				return
+			}
+		}
+		// A full page means there may be more history after it
+		for len(history) > 0 {
+			for _, change := range history {
+				if !send(change) {
+					return
+				}
+			}
+			if len(history) < replayPage {
+				break
+			}
+			var err error
+			if history, err = f.changesRepository.Since(ctx, last, replayPage); err != nil {
+				f.logger.Error("failed to replay order changes", zap.Error(err))
+				return
+			}
+		}
+		for {
+			select {
+			case change, ok := <-sub.ch:
+				if !ok || !send(change) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (f *ChangeFeed) broadcast(change models.OrderChange) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subscribers {
+		if !sub.filter(change) {
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			f.logger.Warn("order change follower is too slow, disconnecting it")
+			close(sub.ch)
+			delete(f.subscribers, sub)
+		}
+	}
+}
+
+func (f *ChangeFeed) unsubscribe(sub *subscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.subscribers[sub]; ok {
+		close(sub.ch)
+		delete(f.subscribers, sub)
+	}
+}
+
+// compareChangeIDs orders two Redis stream ids of the form "<millis>-<seq>"
+func compareChangeIDs(a, b string) int {
+	aMillis, aSeq := splitChangeID(a)
+	bMillis, bSeq := splitChangeID(b)
+	if c := cmp.Compare(aMillis, bMillis); c != 0 {
+		return c
+	}
+	return cmp.Compare(aSeq, bSeq)
+}
+
+func splitChangeID(id string) (uint64, uint64) {
+	m, s, _ := parseChangeID(id)
+	return m, s
+}
+
+// parseChangeID splits a change id "<millis>-<seq>", the sequence may be left out
+func parseChangeID(id string) (millis, seq uint64, err error) {
+	rawMillis, rawSeq, hasSeq := strings.Cut(id, "-")
+	if millis, err = strconv.ParseUint(rawMillis, 10, 64); err != nil {
+		return 0, 0, err
+	}
+	if hasSeq {
+		if seq, err = strconv.ParseUint(rawSeq, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+	return millis, seq, nil
+}
@@ -0,0 +1,180 @@
+package orders
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+func TestParseChangeID(t *testing.T) {
+	tests := []struct {
+		id      string
+		millis  uint64
+		seq     uint64
+		wantErr bool
+	}{
+		{id: "1700000000000-3", millis: 1700000000000, seq: 3},
+		{id: "1700000000000", millis: 1700000000000},
+		{id: "", wantErr: true},
+		{id: "abc", wantErr: true},
+		{id: "1700000000000-", wantErr: true},
+		{id: "-1", wantErr: true},
+		{id: "1-2-3", wantErr: true},
+		{id: "1700000000000-x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			millis, seq, err := parseChangeID(tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseChangeID(%q) = %d, %d, want an error", tt.id, millis, seq)
+				}
+				return
+			}
+			if err != nil || millis != tt.millis || seq != tt.seq {
+				t.Fatalf("parseChangeID(%q) = %d, %d, %v, want %d, %d", tt.id, millis, seq, err, tt.millis, tt.seq)
+			}
+		})
+	}
+}
+
+func TestCompareChangeIDs(t *testing.T) {
+	if compareChangeIDs("9-1", "10-0") >= 0 || compareChangeIDs("10-2", "10-10") >= 0 || compareChangeIDs("10-0", "10") != 0 {
+		t.Fatalf("compareChangeIDs() does not compare ids numerically")
+	}
+}
+
+func TestFollowRejectsMalformedLastID(t *testing.T) {
+	feed := NewChangeFeed(nil, zap.NewNop())
+	_, err := feed.Follow(context.Background(), "not-an-id", func(models.OrderChange) bool { return true })
+	if !errors.IsKind(err, errors.Invalid) {
+		t.Fatalf("Follow() error = %v, want Invalid", err)
+	}
+	if len(feed.subscribers) != 0 {
+		t.Fatalf("Follow() kept a subscriber after rejecting the last id")
+	}
+}
+
+// memChanges is a retained history of changes whose ids are "1-<n>"
+type memChanges struct {
+	mu      sync.Mutex
+	history []models.OrderChange
+	reads   int
+}
+
+func newMemChanges(from, to int) *memChanges {
+	m := &memChanges{}
+	for n := from; n <= to; n++ {
+		m.history = append(m.history, models.OrderChange{ID: fmt.Sprintf("1-%d", n), Kind: models.OrderUpdated,
+			OrderID: fmt.Sprintf("o%d", n%3)})
+	}
+	return m
+}
+
+func (m *memChanges) Append(ctx context.Context, change models.OrderChange) (models.OrderChange, error) {
+	return change, nil
+}
+
+func (m *memChanges) Retained(ctx context.Context) (string, string, error) {
+	if len(m.history) == 0 {
+		return "", "", nil
+	}
+	return m.history[0].ID, m.history[len(m.history)-1].ID, nil
+}
+
+func (m *memChanges) Since(ctx context.Context, lastID string, limit int64) ([]models.OrderChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	var changes []models.OrderChange
+	for _, change := range m.history {
+		if compareChangeIDs(change.ID, lastID) > 0 && int64(len(changes)) < limit {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (m *memChanges) Subscribe(ctx context.Context) <-chan models.OrderChange {
+	return make(chan models.OrderChange)
+}
+
+// receive reads n changes from the channel
+func receive(t *testing.T, changes <-chan models.OrderChange, n int) []models.OrderChange {
+	t.Helper()
+	var got []models.OrderChange
+	for len(got) < n {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				t.Fatalf("the channel was closed after %d changes, want %d", len(got), n)
+			}
+			got = append(got, change)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d changes, want %d", len(got), n)
+		}
+	}
+	return got
+}
+
+func TestFollowReplaysTheWholeHistory(t *testing.T) {
+	repo := newMemChanges(1, 2500)
+	feed := NewChangeFeed(repo, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The follower is more than a page behind, the changes of o0 are filtered out
+	changes, err := feed.Follow(ctx, "1-1", func(change models.OrderChange) bool { return change.OrderID != "o0" })
+	if err != nil {
+		t.Fatalf("Follow() error = %v", err)
+	}
+	got := receive(t, changes, 1666)
+	for i, n := 0, 2; n <= 2500; n++ {
+		if n%3 == 0 {
+			continue
+		}
+		if want := fmt.Sprintf("1-%d", n); got[i].ID != want {
+			t.Fatalf("change %d = %s, want %s", i, got[i].ID, want)
+		}
+		i++
+	}
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	if repo.reads != 3 {
+		t.Fatalf("the history was read %d times, want 3 pages", repo.reads)
+	}
+}
+
+func TestFollowResetsBehindTheHistory(t *testing.T) {
+	repo := newMemChanges(100, 200)
+	feed := NewChangeFeed(repo, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := feed.Follow(ctx, "1-5", func(models.OrderChange) bool { return false })
+	if err != nil {
+		t.Fatalf("Follow() error = %v", err)
+	}
+	got := receive(t, changes, 1)
+	if got[0].Kind != models.OrderReset || got[0].ID != "1-200" {
+		t.Fatalf("first change = %+v, want a reset at the newest retained change", got[0])
+	}
+	select {
+	case change := <-changes:
+		t.Fatalf("received %+v after the reset, want no replay", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if repo.reads != 0 {
+		t.Fatalf("the history was read %d times after a reset, want none", repo.reads)
+	}
+}
@@ -4,27 +4,94 @@ import (
 	// Go Internal Packages
 	"context"
 	"fmt"
+	"sync"
 
 	// Local Packages
+	audit "learn-go/audit"
 	errors "learn-go/errors"
+	events "learn-go/events"
 	models "learn-go/models"
 	utils "learn-go/utils"
+
+	// External Packages
+	"go.uber.org/zap"
 )
 
 type OrdersRepository interface {
-	Insert(ctx context.Context, order models.Order) error
+	Insert(ctx context.Context, order models.Order, evts ...events.Event) error
 	GetOne(ctx context.Context, orderID string) (models.Order, error)
-	Update(ctx context.Context, order models.Order) error
-	Delete(ctx context.Context, orderID string) error
+	Update(ctx context.Context, order models.Order, evts ...events.Event) error
+	Delete(ctx context.Context, orderID string, evts ...events.Event) error
 	Exists(ctx context.Context, orderID string) (bool, error)
+	Iterate(ctx context.Context, fn func(order models.Order) error) error
+	Modify(
+		ctx context.Context,
+		orderID string,
+		fn func(current models.Order) (models.Order, []events.Event, error),
+	) (models.Order, error)
+	Remove(ctx context.Context, orderID string, fn func(current models.Order) ([]events.Event, error)) error
+}
+
+// OrdersCache is the hot copy of the orders kept in front of the system of record
+type OrdersCache interface {
+	OrdersRepository
+	Put(ctx context.Context, orders ...models.Order) error
+}
+
+// OrdersStore is the durable system of record for orders
+type OrdersStore interface {
+	OrdersRepository
 }
 
 type OrdersService struct {
+	emitter          *events.Emitter
 	ordersRepository OrdersRepository
+	recorder         *audit.Recorder
+
+	// Only set when orders are persisted to a system of record, see NewDurableService
+	cache       OrdersCache
+	logger      *zap.Logger
+	persistence Persistence
+	queue       chan writeOp
+	store       OrdersStore
+
+	// The deletes queued for the store by order id, see deletePending
+	pendingMu      sync.Mutex
+	pendingDeletes map[string]int
+}
+
+// NewService creates the service, the changes are recorded in the audit log unless
+// recorder is nil
+func NewService(ordersRepository OrdersRepository, emitter *events.Emitter, recorder *audit.Recorder) *OrdersService {
+	return &OrdersService{ordersRepository: ordersRepository, emitter: emitter, recorder: recorder, persistence: PersistenceNone}
 }
 
-func NewService(ordersRepository OrdersRepository) *OrdersService {
-	return &OrdersService{ordersRepository: ordersRepository}
+// NewDurableService creates an OrdersService that serves from the cache and keeps
+// the store as the system of record. With PersistenceWriteBehind, Run must be
+// started to flush the queued writes.
+func NewDurableService(
+	cache OrdersCache,
+	store OrdersStore,
+	persistence Persistence,
+	queueSize int,
+	emitter *events.Emitter,
+	recorder *audit.Recorder,
+	logger *zap.Logger,
+) *OrdersService {
+	s := &OrdersService{
+		ordersRepository: cache,
+		cache:            cache,
+		emitter:          emitter,
+		logger:           logger,
+		persistence:      persistence,
+		recorder:         recorder,
+		store:            store,
+	}
+	if persistence == PersistenceWriteBehind {
+		s.queue = make(chan writeOp, queueSize)
+		s.pendingDeletes = map[string]int{}
+	}
+	return s
 }
 
 func (s *OrdersService) Insert(ctx context.Context, order models.Order) (string, error) {
@@ -32,26 +99,223 @@ func (s *OrdersService) Insert(ctx context.Context, order models.Order) (string,
 	currTime := utils.GetCurrentTime()
 	order.CreatedAt = currTime
 	order.UpdatedAt = currTime
-	err := s.ordersRepository.Insert(ctx, order)
-	return order.ID, err
+
+	evts, err := s.emitter.Emit(events.OrderCreated, order.ID, order)
+	if err != nil {
+		return "", err
+	}
+	auditEvts, err := s.recorder.Events(ctx, audit.Change{Entity: models.AuditOrder, EntityID: order.ID, After: order})
+	if err != nil {
+		return "", err
+	}
+	evts = append(evts, auditEvts...)
+
+	if s.persistence == PersistenceWriteThrough {
+		if err := s.store.Insert(ctx, order, evts...); err != nil {
+			return "", err
+		}
+		if err := s.ordersRepository.Insert(ctx, order); err != nil {
+			s.logger.Warn("failed to cache inserted order", zap.String("orderId", order.ID), zap.Error(err))
+		}
+		return order.ID, nil
+	}
+
+	if err := s.ordersRepository.Insert(ctx, order, evts...); err != nil {
+		return "", err
+	}
+	return order.ID, s.enqueue(ctx, writeOp{kind: opInsert, order: order})
 }
 
 func (s *OrdersService) GetOne(ctx context.Context, orderID string) (models.Order, error) {
-	return s.ordersRepository.GetOne(ctx, orderID)
+	order, err := s.ordersRepository.GetOne(ctx, orderID)
+	if s.store == nil || !errors.IsKind(err, errors.NotFound) {
+		return order, err
+	}
+
+	// Cache miss, fall back to the system of record and refill the cache, unless the
+	// order is deleted and the store did not catch up yet
+	if s.deletePending(orderID) {
+		return models.Order{}, err
+	}
+	order, err = s.store.GetOne(ctx, orderID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	if err := s.cache.Put(ctx, order); err != nil {
+		s.logger.Warn("failed to refill order cache", zap.String("orderId", orderID), zap.Error(err))
+	}
+	return order, nil
 }
 
+// List calls fn for every order in order id order, or only for the orders of userID when
+// it is not empty.
+// With write-through the cache only holds the orders read or written since it was
+// rebuilt, so the orders are listed from the system of record.
+func (s *OrdersService) List(ctx context.Context, userID string, fn func(order models.Order) error) error {
+	repo := s.ordersRepository
+	if s.persistence == PersistenceWriteThrough {
+		repo = s.store
+	}
+
+	err := repo.Iterate(ctx, func(order models.Order) error {
+		if userID != "" && order.UserID != userID {
+			return nil
+		}
+		return fn(order)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list orders due to :: %w", err)
+	}
+	return nil
+}
+
+// Update replaces the order as a whole, see Patch
 func (s *OrdersService) Update(ctx context.Context, order models.Order) error {
-	exists, err := s.ordersRepository.Exists(ctx, order.ID)
+	_, err := s.Patch(ctx, order.ID, func(models.Order) (models.Order, error) {
+		return order, nil
+	})
+	return err
+}
+
+// Patch atomically replaces the order with the one modify derives from it, see the
+// Modify of the repositories. Errors of modify are returned as they are.
+func (s *OrdersService) Patch(
+	ctx context.Context,
+	orderID string,
+	modify func(current models.Order) (models.Order, error),
+) (models.Order, error) {
+	// The events and the audit entry are derived from the order the write replaces
+	change := func(current models.Order) (models.Order, []events.Event, error) {
+		updated, err := modify(current)
+		if err != nil {
+			return models.Order{}, nil, err
+		}
+		updated.UpdatedAt = utils.GetCurrentTime()
+		evts, err := s.updateEvents(ctx, current, updated)
+		return updated, evts, err
+	}
+
+	if s.persistence == PersistenceWriteThrough {
+		updated, err := s.store.Modify(ctx, orderID, change)
+		if err != nil {
+			return models.Order{}, s.patchErr(orderID, err)
+		}
+		if err := s.cache.Put(ctx, updated); err != nil {
+			s.logger.Warn("failed to cache patched order", zap.String("orderId", orderID), zap.Error(err))
+			s.evict(ctx, orderID)
+		}
+		return updated, nil
+	}
+
+	updated, err := s.ordersRepository.Modify(ctx, orderID, change)
+	if s.store != nil && errors.IsKind(err, errors.NotFound) {
+		// Cache miss, refill the cache from the system of record and try again
+		if _, err := s.GetOne(ctx, orderID); err != nil {
+			return models.Order{}, s.patchErr(orderID, err)
+		}
+		updated, err = s.ordersRepository.Modify(ctx, orderID, change)
+	}
 	if err != nil {
-		return err
+		return models.Order{}, s.patchErr(orderID, err)
 	}
-	if !exists {
-		return errors.E(errors.NotFound, fmt.Sprintf("order not found with id %s", order.ID))
+	return updated, s.enqueue(ctx, writeOp{kind: opUpdate, order: updated})
+}
+
+func (s *OrdersService) patchErr(orderID string, err error) error {
+	if errors.IsKind(err, errors.NotFound) {
+		return errors.E(errors.NotFound, fmt.Sprintf("order not found with id %s", orderID))
 	}
-	order.UpdatedAt = utils.GetCurrentTime()
-	return s.ordersRepository.Update(ctx, order)
+	return err
 }
 
+// Delete removes the order. When events are emitted or changes audited the order is
+// read and removed in one write, so OrderDeleted and the audit entry carry the order
+// that was deleted. Deleting an order that does not exist emits and records nothing.
 func (s *OrdersService) Delete(ctx context.Context, orderID string) error {
-	return s.ordersRepository.Delete(ctx, orderID)
+	if !s.emitter.Enabled() && !s.recorder.Enabled() {
+		return s.remove(ctx, orderID)
+	}
+	deleteEvents := func(current models.Order) ([]events.Event, error) {
+		evts, err := s.emitter.Emit(events.OrderDeleted, orderID, current)
+		if err != nil {
+			return nil, err
+		}
+		auditEvts, err := s.recorder.Events(ctx, audit.Change{Entity: models.AuditOrder, EntityID: orderID, Before: current})
+		if err != nil {
+			return nil, err
+		}
+		return append(evts, auditEvts...), nil
+	}
+
+	if s.persistence == PersistenceWriteThrough {
+		err := s.store.Remove(ctx, orderID, deleteEvents)
+		if err != nil && !errors.IsKind(err, errors.NotFound) {
+			return err
+		}
+		s.evict(ctx, orderID)
+		return nil
+	}
+
+	err := s.ordersRepository.Remove(ctx, orderID, deleteEvents)
+	if s.store != nil && errors.IsKind(err, errors.NotFound) {
+		// Cache miss, refill the cache from the system of record and try again
+		if _, err = s.GetOne(ctx, orderID); err == nil {
+			err = s.ordersRepository.Remove(ctx, orderID, deleteEvents)
+		}
+	}
+	if errors.IsKind(err, errors.NotFound) {
+		return s.remove(ctx, orderID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.enqueue(ctx, writeOp{kind: opDelete, order: models.Order{ID: orderID}})
+}
+
+// remove deletes the order without any events
+func (s *OrdersService) remove(ctx context.Context, orderID string) error {
+	if s.persistence == PersistenceWriteThrough {
+		if err := s.store.Delete(ctx, orderID); err != nil {
+			return err
+		}
+		s.evict(ctx, orderID)
+		return nil
+	}
+
+	if err := s.ordersRepository.Delete(ctx, orderID); err != nil {
+		return err
+	}
+	return s.enqueue(ctx, writeOp{kind: opDelete, order: models.Order{ID: orderID}})
+}
+
+// updateEvents returns OrderUpdated, preceded by OrderStatusChanged when the status moved
+// and followed by the audit entry of the update
+func (s *OrdersService) updateEvents(ctx context.Context, current, updated models.Order) ([]events.Event, error) {
+	var evts []events.Event
+	if current.OrderStatus != updated.OrderStatus {
+		change := events.StatusChange{OrderID: updated.ID, From: current.OrderStatus, To: updated.OrderStatus}
+		statusEvts, err := s.emitter.Emit(events.OrderStatusChanged, updated.ID, change)
+		if err != nil {
+			return nil, err
+		}
+		evts = append(evts, statusEvts...)
+	}
+
+	updatedEvts, err := s.emitter.Emit(events.OrderUpdated, updated.ID, updated)
+	if err != nil {
+		return nil, err
+	}
+	auditEvts, err := s.recorder.Events(ctx,
+		audit.Change{Entity: models.AuditOrder, EntityID: updated.ID, Before: current, After: updated})
+	if err != nil {
+		return nil, err
+	}
+	return append(append(evts, updatedEvts...), auditEvts...), nil
+}
+
+// evict drops the cached order so the next read goes to the system of record
+func (s *OrdersService) evict(ctx context.Context, orderID string) {
+	if err := s.cache.Delete(ctx, orderID); err != nil {
+		s.logger.Error("failed to evict cached order", zap.String("orderId", orderID), zap.Error(err))
+	}
 }
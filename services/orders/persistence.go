@@ -0,0 +1,184 @@
+package orders
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"time"
+
+	// Local Packages
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// Persistence defines how orders reach the system of record
+type Persistence string
+
+const (
+	PersistenceNone         Persistence = "none"          // Redis only
+	PersistenceWriteThrough Persistence = "write_through" // Store first, then cache, in the request
+	PersistenceWriteBehind  Persistence = "write_behind"  // Cache in the request, store asynchronously
+)
+
+const (
+	writeBehindMaxAttempts = 5
+	writeBehindBackoff     = 200 * time.Millisecond
+	// writeBehindDrainTimeout bounds the flush of the queued writes once Run is cancelled
+	writeBehindDrainTimeout = 10 * time.Second
+	rebuildBatchSize        = 500
+)
+
+type opKind uint8
+
+const (
+	opInsert opKind = iota
+	opUpdate
+	opDelete
+)
+
+type writeOp struct {
+	kind  opKind
+	order models.Order
+}
+
+// enqueue hands the write to the write-behind loop, it is a no-op for other modes
+func (s *OrdersService) enqueue(ctx context.Context, op writeOp) error {
+	if s.queue == nil {
+		return nil
+	}
+	if op.kind == opDelete {
+		s.trackDelete(op.order.ID, 1)
+	}
+	select {
+	case s.queue <- op:
+		return nil
+	case <-ctx.Done():
+		if op.kind == opDelete {
+			s.trackDelete(op.order.ID, -1)
+		}
+		return fmt.Errorf("failed to queue order %s for persistence: %w", op.order.ID, ctx.Err())
+	}
+}
+
+// trackDelete counts the deletes of the order that are queued and not flushed yet
+func (s *OrdersService) trackDelete(orderID string, delta int) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if s.pendingDeletes[orderID] += delta; s.pendingDeletes[orderID] <= 0 {
+		delete(s.pendingDeletes, orderID)
+	}
+}
+
+// deletePending reports whether a delete of the order is queued, the store still holds
+// the order until it is flushed and must not serve it
+func (s *OrdersService) deletePending(orderID string) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	return s.pendingDeletes[orderID] > 0
+}
+
+// Run flushes queued writes to the system of record until ctx is cancelled, then
+// drains whatever is still queued before returning. It returns immediately unless
+// the service runs in write-behind mode.
+//
+// The writes are flushed with a context that outlives ctx, so that the write in flight
+// at shutdown is not failed by the cancellation. Once ctx is cancelled the remaining
+// writes have writeBehindDrainTimeout to reach the store.
+func (s *OrdersService) Run(ctx context.Context) {
+	if s.queue == nil {
+		return
+	}
+
+	flushCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() { time.AfterFunc(writeBehindDrainTimeout, cancel) })
+	defer stop()
+
+	for {
+		select {
+		case op := <-s.queue:
+			s.flush(flushCtx, op)
+		case <-ctx.Done():
+			for {
+				select {
+				case op := <-s.queue:
+					s.flush(flushCtx, op)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush applies one queued write to the store, retrying with a linear backoff until
+// ctx is done
+func (s *OrdersService) flush(ctx context.Context, op writeOp) {
+	if op.kind == opDelete {
+		defer s.trackDelete(op.order.ID, -1)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		switch op.kind {
+		case opInsert, opUpdate:
+			// Update upserts, which keeps a retried insert idempotent
+			err = s.store.Update(ctx, op.order)
+		case opDelete:
+			err = s.store.Delete(ctx, op.order.ID)
+		}
+		if err == nil {
+			return
+		}
+		if attempt == writeBehindMaxAttempts || !sleep(ctx, time.Duration(attempt)*writeBehindBackoff) {
+			break
+		}
+	}
+	s.logger.Error("failed to persist order, the store is behind the cache",
+		zap.String("orderId", op.order.ID), zap.Error(err))
+}
+
+// sleep waits for d and reports whether it did, it returns false once ctx is done
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// RebuildCache repopulates the ORDER:* keys and the ORDERS set from the system of record
+// and returns the number of orders restored.
+func (s *OrdersService) RebuildCache(ctx context.Context) (int, error) {
+	if s.store == nil {
+		return 0, fmt.Errorf("order persistence is not enabled")
+	}
+
+	restored := 0
+	batch := make([]models.Order, 0, rebuildBatchSize)
+	putBatch := func() error {
+		if err := s.cache.Put(ctx, batch...); err != nil {
+			return err
+		}
+		restored += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err := s.store.Iterate(ctx, func(order models.Order) error {
+		batch = append(batch, order)
+		if len(batch) == rebuildBatchSize {
+			return putBatch()
+		}
+		return nil
+	})
+	if err != nil {
+		return restored, err
+	}
+	return restored, putBatch()
+}
@@ -0,0 +1,266 @@
+package orders
+
+import (
+	// Go Internal Packages
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	// Local Packages
+	audit "learn-go/audit"
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// memOrders is an in-memory OrdersCache, failures makes the next writes fail. The
+// events of Remove are kept in outbox.
+type memOrders struct {
+	mu       sync.Mutex
+	orders   map[string]models.Order
+	outbox   []events.Event
+	failures int
+	writes   chan struct{}
+}
+
+func newMemOrders(orders ...models.Order) *memOrders {
+	m := &memOrders{orders: map[string]models.Order{}, writes: make(chan struct{}, 16)}
+	for _, order := range orders {
+		m.orders[order.ID] = order
+	}
+	return m
+}
+
+func (m *memOrders) write(ctx context.Context, fn func()) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer func() {
+		select {
+		case m.writes <- struct{}{}:
+		default:
+		}
+	}()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.failures > 0 {
+		m.failures--
+		return fmt.Errorf("store unavailable")
+	}
+	fn()
+	return nil
+}
+
+func (m *memOrders) Insert(ctx context.Context, order models.Order, _ ...events.Event) error {
+	return m.write(ctx, func() { m.orders[order.ID] = order })
+}
+
+func (m *memOrders) Put(ctx context.Context, orders ...models.Order) error {
+	return m.write(ctx, func() {
+		for _, order := range orders {
+			m.orders[order.ID] = order
+		}
+	})
+}
+
+func (m *memOrders) Update(ctx context.Context, order models.Order, _ ...events.Event) error {
+	return m.write(ctx, func() { m.orders[order.ID] = order })
+}
+
+func (m *memOrders) Delete(ctx context.Context, orderID string, _ ...events.Event) error {
+	return m.write(ctx, func() { delete(m.orders, orderID) })
+}
+
+func (m *memOrders) GetOne(_ context.Context, orderID string) (models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[orderID]
+	if !ok {
+		return models.Order{}, errors.E(errors.NotFound, "order not found")
+	}
+	return order, nil
+}
+
+func (m *memOrders) Exists(ctx context.Context, orderID string) (bool, error) {
+	_, err := m.GetOne(ctx, orderID)
+	return err == nil, nil
+}
+
+func (m *memOrders) Iterate(_ context.Context, fn func(order models.Order) error) error {
+	m.mu.Lock()
+	orders := make([]models.Order, 0, len(m.orders))
+	for _, order := range m.orders {
+		orders = append(orders, order)
+	}
+	m.mu.Unlock()
+	for _, order := range orders {
+		if err := fn(order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memOrders) Modify(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) (models.Order, []events.Event, error),
+) (models.Order, error) {
+	current, err := m.GetOne(ctx, orderID)
+	if err != nil {
+		return models.Order{}, err
+	}
+	updated, _, err := fn(current)
+	if err != nil {
+		return models.Order{}, err
+	}
+	return updated, m.Update(ctx, updated)
+}
+
+func (m *memOrders) Remove(
+	ctx context.Context,
+	orderID string,
+	fn func(current models.Order) ([]events.Event, error),
+) error {
+	current, err := m.GetOne(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	evts, err := fn(current)
+	if err != nil {
+		return err
+	}
+	return m.write(ctx, func() {
+		delete(m.orders, orderID)
+		m.outbox = append(m.outbox, evts...)
+	})
+}
+
+func (m *memOrders) has(orderID string) bool {
+	ok, _ := m.Exists(context.Background(), orderID)
+	return ok
+}
+
+func TestWriteBehindFlushesInFlightWriteOnShutdown(t *testing.T) {
+	cache, store := newMemOrders(), newMemOrders()
+	store.failures = 1
+	svc := NewDurableService(cache, store, PersistenceWriteBehind, 8, nil, nil, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		svc.Run(ctx)
+	}()
+
+	orderID, err := svc.Insert(ctx, models.Order{UserID: "u1", OrderStatus: "placed"})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	// Cancel while the first, failed, attempt backs off
+	<-store.writes
+	cancel()
+	<-done
+
+	if !store.has(orderID) {
+		t.Fatalf("order %s was dropped on shutdown instead of persisted", orderID)
+	}
+}
+
+func TestGetOneSkipsStoreForQueuedDelete(t *testing.T) {
+	order := models.Order{ID: "o1", UserID: "u1", OrderStatus: "placed"}
+	cache, store := newMemOrders(order), newMemOrders(order)
+	svc := NewDurableService(cache, store, PersistenceWriteBehind, 8, nil, nil, zap.NewNop())
+	ctx := context.Background()
+
+	if err := svc.Delete(ctx, order.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := svc.GetOne(ctx, order.ID); !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("GetOne() with a queued delete error = %v, want NotFound", err)
+	}
+	if cache.has(order.ID) {
+		t.Fatalf("GetOne() refilled the cache with a deleted order")
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	svc.Run(runCtx)
+	if store.has(order.ID) {
+		t.Fatalf("the queued delete was not flushed")
+	}
+	if svc.deletePending(order.ID) {
+		t.Fatalf("the flushed delete is still pending")
+	}
+}
+
+func TestDeleteEmitsTheRemovedOrder(t *testing.T) {
+	order := models.Order{ID: "o1", UserID: "u1", OrderStatus: "placed"}
+	tests := []struct {
+		name        string
+		persistence Persistence
+		cache       *memOrders
+		store       *memOrders
+		outbox      func(cache, store *memOrders) []events.Event
+	}{
+		{
+			name:        "write-through",
+			persistence: PersistenceWriteThrough,
+			cache:       newMemOrders(order),
+			store:       newMemOrders(order),
+			outbox:      func(_, store *memOrders) []events.Event { return store.outbox },
+		},
+		{
+			name:        "write-behind cache miss",
+			persistence: PersistenceWriteBehind,
+			cache:       newMemOrders(),
+			store:       newMemOrders(order),
+			outbox:      func(cache, _ *memOrders) []events.Event { return cache.outbox },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := audit.NewRecorder(nil, zap.NewNop())
+			svc := NewDurableService(tt.cache, tt.store, tt.persistence, 8, events.NewEmitter(true), recorder, zap.NewNop())
+			ctx := context.Background()
+
+			if err := svc.Delete(ctx, order.ID); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			outbox := tt.outbox(tt.cache, tt.store)
+			if len(outbox) != 2 || outbox[0].Type != events.OrderDeleted || outbox[1].Type != events.AuditRecorded {
+				t.Fatalf("outbox = %+v, want OrderDeleted and its audit entry", outbox)
+			}
+			var deleted models.Order
+			if err := json.Unmarshal(outbox[0].Payload, &deleted); err != nil || deleted.UserID != order.UserID {
+				t.Fatalf("OrderDeleted payload = %s, want the removed order", outbox[0].Payload)
+			}
+
+			// Deleting it again finds nothing to emit or record
+			if err := svc.Delete(ctx, order.ID); err != nil {
+				t.Fatalf("Delete() again error = %v", err)
+			}
+			if got := tt.outbox(tt.cache, tt.store); len(got) != 2 {
+				t.Fatalf("Delete() of a missing order wrote %d events", len(got)-2)
+			}
+		})
+	}
+}
+
+func TestSleepReturnsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if sleep(ctx, time.Minute) {
+		t.Fatalf("sleep() = true on a cancelled context")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("sleep() ignored the cancelled context")
+	}
+}
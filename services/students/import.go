@@ -0,0 +1,188 @@
+package students
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"iter"
+
+	// Local Packages
+	audit "learn-go/audit"
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+)
+
+// importBatchSize is the number of students written per bulk insert
+const importBatchSize = 500
+
+// ImportStudents validates the records and inserts the valid ones in batches, reporting
+// the outcome of every row. Rows repeating a rollNo of the import or of an existing
+// student are reported as duplicates. A dry run reports what would happen without
+// inserting anything. A records error or a failed batch aborts the import, the batches
+// inserted before it are kept and the report of the rows read so far is returned with
+// the error.
+func (s *StudentsService) ImportStudents(
+	ctx context.Context,
+	records iter.Seq2[models.ImportRecord, error],
+	dryRun bool,
+) (models.ImportReport, error) {
+	imp := &studentsImport{svc: s, dryRun: dryRun, seen: map[string]int{}}
+	imp.report = models.ImportReport{DryRun: dryRun, Rows: []models.ImportRow{}}
+
+	for record, err := range records {
+		if err != nil {
+			return imp.abort(err)
+		}
+		imp.add(record)
+		if len(imp.pending) == importBatchSize {
+			if err := imp.flush(ctx); err != nil {
+				return imp.abort(fmt.Errorf("failed to import students due to :: %w", err))
+			}
+		}
+	}
+	if err := imp.flush(ctx); err != nil {
+		return imp.abort(fmt.Errorf("failed to import students due to :: %w", err))
+	}
+	imp.sum()
+	return imp.report, nil
+}
+
+type studentsImport struct {
+	dryRun  bool
+	pending []pendingStudent
+	report  models.ImportReport
+	seen    map[string]int // line of the first row of every rollNo
+	svc     *StudentsService
+}
+
+// abort ends the report of an import stopped by err, the valid rows that were not
+// written are reported as aborted
+func (imp *studentsImport) abort(err error) (models.ImportReport, error) {
+	line := 0
+	for i, row := range imp.report.Rows {
+		if row.Status == "" {
+			imp.report.Rows[i].Status = models.ImportAborted
+		}
+		line = row.Line
+	}
+	imp.report.Error = fmt.Sprintf("import aborted after line %d", line)
+	imp.sum()
+	return imp.report, err
+}
+
+// sum counts the rows of the report by status
+func (imp *studentsImport) sum() {
+	for _, row := range imp.report.Rows {
+		switch row.Status {
+		case models.ImportInserted:
+			imp.report.Inserted++
+		case models.ImportValid:
+			imp.report.Valid++
+		case models.ImportInvalid:
+			imp.report.Invalid++
+		case models.ImportDuplicate:
+			imp.report.Duplicates++
+		case models.ImportAborted:
+			imp.report.Aborted++
+		}
+	}
+	imp.report.Total = len(imp.report.Rows)
+}
+
+// pendingStudent is a valid student waiting for its batch, row indexes the report rows
+type pendingStudent struct {
+	row     int
+	student models.StudentModel
+}
+
+func (imp *studentsImport) add(record models.ImportRecord) {
+	err := record.Err
+	if err == nil {
+		err = record.Student.Validate()
+	}
+
+	row := models.ImportRow{Line: record.Line, RollNo: record.Student.RollNo}
+	switch first, seen := imp.seen[record.Student.RollNo]; {
+	case err != nil:
+		row.Status, row.Errors = models.ImportInvalid, fieldErrors(err)
+	case seen:
+		row.Status = models.ImportDuplicate
+		row.Errors = errors.ValidationErrors{{Field: "/roll_no", Error: fmt.Sprintf("repeats line %d", first)}}
+	default:
+		imp.seen[record.Student.RollNo] = record.Line
+		imp.pending = append(imp.pending, pendingStudent{row: len(imp.report.Rows), student: record.Student})
+	}
+	imp.report.Rows = append(imp.report.Rows, row)
+}
+
+// flush writes the pending students, or only checks them for duplicates on a dry run
+func (imp *studentsImport) flush(ctx context.Context) error {
+	if len(imp.pending) == 0 {
+		return nil
+	}
+	defer func() { imp.pending = imp.pending[:0] }()
+
+	rollNos := make([]string, len(imp.pending))
+	for i, p := range imp.pending {
+		rollNos[i] = p.student.RollNo
+	}
+	existing, err := imp.svc.studentsRepository.ExistingStudents(ctx, rollNos)
+	if err != nil {
+		return err
+	}
+
+	var students []models.StudentModel
+	var rows []int
+	var evts [][]events.Event
+	for _, p := range imp.pending {
+		if existing[p.student.RollNo] {
+			imp.duplicate(p.row)
+			continue
+		}
+		if imp.dryRun {
+			imp.report.Rows[p.row].Status = models.ImportValid
+			continue
+		}
+
+		studentEvts, err := imp.svc.events(ctx, events.StudentEnrolled, p.student.RollNo, p.student,
+			audit.Change{Entity: models.AuditStudent, EntityID: p.student.RollNo, After: p.student})
+		if err != nil {
+			return err
+		}
+		students = append(students, p.student)
+		rows = append(rows, p.row)
+		evts = append(evts, studentEvts)
+	}
+	if len(students) == 0 {
+		return nil
+	}
+
+	inserted, err := imp.svc.studentsRepository.InsertStudents(ctx, students, evts)
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		if inserted[i] {
+			imp.report.Rows[row].Status = models.ImportInserted
+		} else {
+			// Inserted by someone else since ExistingStudents
+			imp.duplicate(row)
+		}
+	}
+	return nil
+}
+
+func (imp *studentsImport) duplicate(row int) {
+	imp.report.Rows[row].Status = models.ImportDuplicate
+	imp.report.Rows[row].Errors = errors.ValidationErrors{{Field: "/roll_no", Error: "already exists"}}
+}
+
+// fieldErrors returns the validation errors of err, other errors are reported for the whole row
+func fieldErrors(err error) errors.ValidationErrors {
+	var ve errors.ValidationErrors
+	if errors.As(err, &ve) {
+		return ve
+	}
+	return errors.ValidationErrors{{Field: "", Error: err.Error()}}
+}
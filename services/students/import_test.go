@@ -0,0 +1,134 @@
+package students_test
+
+import (
+	// Go Internal Packages
+	"context"
+	"iter"
+	"reflect"
+	"strconv"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+)
+
+func records(students ...models.StudentModel) iter.Seq2[models.ImportRecord, error] {
+	return func(yield func(models.ImportRecord, error) bool) {
+		for i, student := range students {
+			if !yield(models.ImportRecord{Line: i + 2, Student: student}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestImportStudents(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	if err := f.svc.InsertStudent(ctx, newStudent("1")); err != nil {
+		t.Fatalf("InsertStudent() error = %v", err)
+	}
+	f.published(t)
+
+	invalid := newStudent("3")
+	invalid.Gender = "unknown"
+	upload := func() iter.Seq2[models.ImportRecord, error] {
+		return records(newStudent("1"), newStudent("2"), invalid, newStudent("2"), newStudent("4"))
+	}
+	rows := func(status string) []models.ImportRow {
+		return []models.ImportRow{
+			{Line: 2, RollNo: "1", Status: models.ImportDuplicate,
+				Errors: errors.ValidationErrors{{Field: "/roll_no", Error: "already exists"}}},
+			{Line: 3, RollNo: "2", Status: status},
+			{Line: 4, RollNo: "3", Status: models.ImportInvalid,
+				Errors: errors.ValidationErrors{{Field: "/gender", Error: "must be one of male, female, other"}}},
+			{Line: 5, RollNo: "2", Status: models.ImportDuplicate,
+				Errors: errors.ValidationErrors{{Field: "/roll_no", Error: "repeats line 3"}}},
+			{Line: 6, RollNo: "4", Status: status},
+		}
+	}
+
+	// A dry run reports the rows it would insert as valid and writes nothing
+	report, err := f.svc.ImportStudents(ctx, upload(), true)
+	if err != nil {
+		t.Fatalf("ImportStudents() dry run error = %v", err)
+	}
+	want := models.ImportReport{DryRun: true, Total: 5, Valid: 2, Invalid: 1, Duplicates: 2, Rows: rows(models.ImportValid)}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("ImportStudents() dry run = %+v, want %+v", report, want)
+	}
+	if _, err := f.svc.GetOneStudent(ctx, "2", true); !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("GetOneStudent() after a dry run error = %v, want NotFound", err)
+	}
+	if evts := f.published(t); len(evts) != 0 {
+		t.Fatalf("a dry run wrote %d events", len(evts))
+	}
+
+	// The import then inserts the rows the dry run found valid
+	report, err = f.svc.ImportStudents(ctx, upload(), false)
+	if err != nil {
+		t.Fatalf("ImportStudents() error = %v", err)
+	}
+	want = models.ImportReport{Total: 5, Inserted: 2, Invalid: 1, Duplicates: 2, Rows: rows(models.ImportInserted)}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("ImportStudents() = %+v, want %+v", report, want)
+	}
+	for _, rollNo := range []string{"2", "4"} {
+		if _, err := f.svc.GetOneStudent(ctx, rollNo, false); err != nil {
+			t.Fatalf("GetOneStudent(%s) after the import error = %v", rollNo, err)
+		}
+	}
+	enrolled := 0
+	for _, evt := range f.published(t) {
+		if evt.Type == events.StudentEnrolled {
+			enrolled++
+		}
+	}
+	if enrolled != 2 {
+		t.Fatalf("the import wrote %d enrolment events, want 2", enrolled)
+	}
+}
+
+func TestImportStudentsStopsOnARecordsError(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	invalid := newStudent("invalid")
+	invalid.Gender = "unknown"
+	// A batch and a row of the next one are read before the upload fails
+	failing := func(yield func(models.ImportRecord, error) bool) {
+		for i := range 500 {
+			if !yield(models.ImportRecord{Line: i + 2, Student: newStudent(strconv.Itoa(i))}, nil) {
+				return
+			}
+		}
+		if !yield(models.ImportRecord{Line: 502, Student: newStudent("500")}, nil) {
+			return
+		}
+		if !yield(models.ImportRecord{Line: 503, Student: invalid}, nil) {
+			return
+		}
+		yield(models.ImportRecord{}, errors.E(errors.Invalid, "unreadable upload"))
+	}
+
+	report, err := f.svc.ImportStudents(ctx, failing, false)
+	if !errors.IsKind(err, errors.Invalid) {
+		t.Fatalf("ImportStudents() error = %v, want Invalid", err)
+	}
+	// The report tells the inserted batch from the rows dropped with the import
+	if report.Total != 502 || report.Inserted != 500 || report.Invalid != 1 || report.Aborted != 1 ||
+		report.Error != "import aborted after line 503" {
+		t.Fatalf("ImportStudents() = %d rows, %d inserted, %d invalid, %d aborted, %q, want the inserted batch "+
+			"and the aborted row", report.Total, report.Inserted, report.Invalid, report.Aborted, report.Error)
+	}
+	if row := report.Rows[500]; row.Line != 502 || row.Status != models.ImportAborted {
+		t.Fatalf("row of line 502 = %+v, want it aborted", row)
+	}
+	if _, err := f.svc.GetOneStudent(ctx, "499", false); err != nil {
+		t.Fatalf("GetOneStudent() of the inserted batch error = %v", err)
+	}
+	if _, err := f.svc.GetOneStudent(ctx, "500", true); !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("GetOneStudent() of an aborted row error = %v, want NotFound", err)
+	}
+}
@@ -3,10 +3,14 @@ package students
 import (
 	// Go Internal Packages
 	"context"
+	"database/sql"
 	"fmt"
+	"time"
 
 	// Local Packages
+	audit "learn-go/audit"
 	errors "learn-go/errors"
+	events "learn-go/events"
 	models "learn-go/models"
 
 	// External Packages
@@ -14,35 +18,63 @@ import (
 )
 
 type StudentsRepository interface {
-	GetOneStudent(ctx context.Context, rollNo string) (*models.StudentModel, error)
-	GetAllStudents(ctx context.Context) (*[]models.StudentModel, error)
-	InsertStudent(ctx context.Context, student models.StudentModel) error
-	UpdateStudent(ctx context.Context, rollNo string, updatedStudent models.StudentModel) error
-	DeleteStudent(ctx context.Context, rollNo string) error
+	GetOneStudent(ctx context.Context, rollNo string, includeDeleted bool) (*models.StudentModel, error)
+	GetAllStudents(ctx context.Context, includeDeleted bool) (*[]models.StudentModel, error)
+	IterateStudents(ctx context.Context, includeDeleted bool, fn func(student models.StudentModel) error) error
+	InsertStudent(ctx context.Context, student models.StudentModel, evts ...events.Event) error
+	UpdateStudent(ctx context.Context, rollNo string, updatedStudent models.StudentModel, evts ...events.Event) error
+	PatchStudent(ctx context.Context, rollNo string, current, updated models.StudentModel, evts ...events.Event) error
+	ExistingStudents(ctx context.Context, rollNos []string) (map[string]bool, error)
+	InsertStudents(ctx context.Context, students []models.StudentModel, evts [][]events.Event) ([]bool, error)
+	DeleteStudent(ctx context.Context, current models.StudentModel, deletedAt time.Time, deletedBy string, evts ...events.Event) error
+	RestoreStudent(ctx context.Context, deleted models.StudentModel, evts ...events.Event) error
+	PurgeStudents(
+		ctx context.Context,
+		deletedBefore time.Time,
+		purgeEvents func(rollNo string) ([]events.Event, error),
+	) ([]string, error)
 }
 
 type StudentsService struct {
+	emitter            *events.Emitter
+	recorder           *audit.Recorder
 	studentsRepository StudentsRepository
 }
 
-func NewService(studentsRepository StudentsRepository) *StudentsService {
-	return &StudentsService{studentsRepository: studentsRepository}
+// NewService creates the service, the changes are recorded in the audit log unless
+// recorder is nil
+func NewService(studentsRepository StudentsRepository, emitter *events.Emitter, recorder *audit.Recorder) *StudentsService {
+	return &StudentsService{studentsRepository: studentsRepository, emitter: emitter, recorder: recorder}
 }
 
-// GetAllStudents returns all the students details
-func (s *StudentsService) GetAllStudents(ctx context.Context) (*[]models.StudentModel, error) {
-	students, err := s.studentsRepository.GetAllStudents(ctx)
+// GetAllStudents returns all the students details, the deleted ones only with includeDeleted
+func (s *StudentsService) GetAllStudents(ctx context.Context, includeDeleted bool) (*[]models.StudentModel, error) {
+	students, err := s.studentsRepository.GetAllStudents(ctx, includeDeleted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all students details due to :: %w", err)
 	}
 	return students, nil
 }
 
-// GetOneStudent returns the students details for the given rollNo
-func (s *StudentsService) GetOneStudent(ctx context.Context, rollNo string) (*models.StudentModel, error) {
-	student, err := s.studentsRepository.GetOneStudent(ctx, rollNo)
+// IterateStudents calls fn for every student in rollNo order, the deleted ones only with
+// includeDeleted, stopping at the first error
+func (s *StudentsService) IterateStudents(
+	ctx context.Context,
+	includeDeleted bool,
+	fn func(student models.StudentModel) error,
+) error {
+	if err := s.studentsRepository.IterateStudents(ctx, includeDeleted, fn); err != nil {
+		return fmt.Errorf("failed to iterate students due to :: %w", err)
+	}
+	return nil
+}
+
+// GetOneStudent returns the students details for the given rollNo, a deleted student is
+// not found unless includeDeleted is set
+func (s *StudentsService) GetOneStudent(ctx context.Context, rollNo string, includeDeleted bool) (*models.StudentModel, error) {
+	student, err := s.studentsRepository.GetOneStudent(ctx, rollNo, includeDeleted)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
+		if isNotFound(err) {
 			return nil, errors.E(errors.NotFound, "student details not found")
 		}
 		return nil, fmt.Errorf("failed to get student details for rollNo :: %s due to :: %w", rollNo, err)
@@ -50,35 +82,180 @@ func (s *StudentsService) GetOneStudent(ctx context.Context, rollNo string) (*mo
 	return student, nil
 }
 
-// InsertStudent inserts a new student into the database
+// InsertStudent inserts a new student into the database, a rollNo taken by a student,
+// deleted or not, is a Conflict
 func (s *StudentsService) InsertStudent(ctx context.Context, student models.StudentModel) error {
-	err := s.studentsRepository.InsertStudent(ctx, student)
+	existing, err := s.studentsRepository.GetOneStudent(ctx, student.RollNo, true)
+	switch {
+	case err == nil && existing.DeletedAt != nil:
+		return errors.E(errors.Conflict, "student is deleted, restore it")
+	case err == nil:
+		return errors.E(errors.Conflict, "student already exists")
+	case !isNotFound(err):
+		return fmt.Errorf("failed to get student details for rollNo :: %s due to :: %w", student.RollNo, err)
+	}
+
+	evts, err := s.events(ctx, events.StudentEnrolled, student.RollNo, student,
+		audit.Change{Entity: models.AuditStudent, EntityID: student.RollNo, After: student})
+	if err != nil {
+		return err
+	}
+
+	err = s.studentsRepository.InsertStudent(ctx, student, evts...)
+	if errors.IsKind(err, errors.Conflict) {
+		// Another request took the rollNo meanwhile
+		return errors.E(errors.Conflict, "student already exists")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to insert student due to :: %w", err)
 	}
 	return nil
 }
 
-// UpdateStudent updates the student details for the given rollNo
+// UpdateStudent replaces the student details for the given rollNo
 func (s *StudentsService) UpdateStudent(ctx context.Context, rollNo string, updatedStudent models.StudentModel) error {
-	err := s.studentsRepository.UpdateStudent(ctx, rollNo, updatedStudent)
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return errors.E(errors.NotFound, "student details not found")
+	_, err := s.PatchStudent(ctx, rollNo, func(models.StudentModel) (models.StudentModel, error) {
+		return updatedStudent, nil
+	})
+	return err
+}
+
+// writeAttempts bounds the read-modify-write retries of the conditional writes under contention
+const writeAttempts = 3
+
+// PatchStudent reads the student, lets modify derive the updated details from them and
+// writes only if the student was not changed meanwhile, retrying with a fresh read
+// otherwise. Errors of modify are returned as they are.
+func (s *StudentsService) PatchStudent(
+	ctx context.Context,
+	rollNo string,
+	modify func(current models.StudentModel) (models.StudentModel, error),
+) (models.StudentModel, error) {
+	var updated models.StudentModel
+	err := s.conditionally(ctx, rollNo, false, func(current models.StudentModel) error {
+		var err error
+		if updated, err = modify(current); err != nil {
+			return err
+		}
+
+		evts, err := s.events(ctx, events.StudentUpdated, rollNo, updated,
+			audit.Change{Entity: models.AuditStudent, EntityID: rollNo, Before: current, After: updated})
+		if err != nil {
+			return err
+		}
+		err = s.studentsRepository.PatchStudent(ctx, rollNo, current, updated, evts...)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to patch student details for rollNo :: %s due to :: %w", rollNo, err)
 		}
-		return fmt.Errorf("failed to update student details for rollNo :: %s due to :: %w", rollNo, err)
+		return err
+	})
+	if err != nil {
+		return models.StudentModel{}, err
 	}
-	return nil
+	return updated, nil
 }
 
-// DeleteStudent deletes the student details for the given rollNo
+// DeleteStudent marks the student with the given rollNo deleted by the actor of the
+// request, it is kept until RestoreStudent or PurgeStudents
 func (s *StudentsService) DeleteStudent(ctx context.Context, rollNo string) error {
-	err := s.studentsRepository.DeleteStudent(ctx, rollNo)
+	deletedBy := audit.RequestFrom(ctx).Actor
+	return s.conditionally(ctx, rollNo, false, func(current models.StudentModel) error {
+		evts, err := s.events(ctx, events.StudentDeleted, rollNo, events.Deletion{ID: rollNo},
+			audit.Change{Entity: models.AuditStudent, EntityID: rollNo, Before: current})
+		if err != nil {
+			return err
+		}
+		err = s.studentsRepository.DeleteStudent(ctx, current, time.Now().UTC(), deletedBy, evts...)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete student details for rollNo :: %s due to :: %w", rollNo, err)
+		}
+		return err
+	})
+}
+
+// RestoreStudent clears the deletion of the student with the given rollNo and returns it
+func (s *StudentsService) RestoreStudent(ctx context.Context, rollNo string) (models.StudentModel, error) {
+	var restored models.StudentModel
+	err := s.conditionally(ctx, rollNo, true, func(deleted models.StudentModel) error {
+		if deleted.DeletedAt == nil {
+			return errors.E(errors.Conflict, "student is not deleted")
+		}
+		restored = deleted
+		restored.DeletedAt, restored.DeletedBy = nil, ""
+
+		evts, err := s.events(ctx, events.StudentRestored, rollNo, restored,
+			audit.Change{Entity: models.AuditStudent, EntityID: rollNo, Action: models.AuditRestore,
+				Before: deleted, After: restored})
+		if err != nil {
+			return err
+		}
+		err = s.studentsRepository.RestoreStudent(ctx, deleted, evts...)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to restore student details for rollNo :: %s due to :: %w", rollNo, err)
+		}
+		return err
+	})
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return errors.E(errors.NotFound, "student details not found")
+		return models.StudentModel{}, err
+	}
+	return restored, nil
+}
+
+// conditionally calls write with the student as read until write finds it unchanged,
+// so that the events and the audit entry of a change describe the student it replaced.
+// write returns a not found error of the repository when the student changed meanwhile.
+func (s *StudentsService) conditionally(
+	ctx context.Context,
+	rollNo string,
+	includeDeleted bool,
+	write func(current models.StudentModel) error,
+) error {
+	for attempt := 0; attempt < writeAttempts; attempt++ {
+		current, err := s.GetOneStudent(ctx, rollNo, includeDeleted)
+		if err != nil {
+			return err
+		}
+		if err := write(*current); err == nil || !isNotFound(err) {
+			return err
 		}
-		return fmt.Errorf("failed to delete student details for rollNo :: %s due to :: %w", rollNo, err)
 	}
-	return nil
+	return errors.E(errors.Conflict, "student details were changed concurrently, retry the request")
+}
+
+// events returns the event of a change followed by its audit entry, each only when enabled
+func (s *StudentsService) events(
+	ctx context.Context,
+	eventType events.Type,
+	rollNo string,
+	payload any,
+	change audit.Change,
+) ([]events.Event, error) {
+	evts, err := s.emitter.Emit(eventType, rollNo, payload)
+	if err != nil {
+		return nil, err
+	}
+	auditEvts, err := s.recorder.Events(ctx, change)
+	if err != nil {
+		return nil, err
+	}
+	return append(evts, auditEvts...), nil
+}
+
+// PurgeStudents permanently removes the students deleted more than retention ago and
+// returns how many were purged
+func (s *StudentsService) PurgeStudents(ctx context.Context, retention time.Duration) (int, error) {
+	purgeEvents := func(rollNo string) ([]events.Event, error) {
+		return s.recorder.Events(ctx, audit.Change{Entity: models.AuditStudent, EntityID: rollNo, Action: models.AuditPurge})
+	}
+	purged, err := s.studentsRepository.PurgeStudents(ctx, time.Now().UTC().Add(-retention), purgeEvents)
+	if err != nil {
+		return len(purged), fmt.Errorf("failed to purge deleted students due to :: %w", err)
+	}
+	return len(purged), nil
+}
+
+// isNotFound reports whether the repository error means the student does not exist
+func isNotFound(err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, sql.ErrNoRows) ||
+		errors.IsKind(err, errors.NotFound)
 }
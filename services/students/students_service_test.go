@@ -0,0 +1,254 @@
+package students_test
+
+import (
+	// Go Internal Packages
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	// Local Packages
+	audit "learn-go/audit"
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+	sqldb "learn-go/repositories/sqldb"
+	students "learn-go/services/students"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// racingRepo changes the student through the inner repository right before each of the
+// first races conditional writes, as a concurrent request would
+type racingRepo struct {
+	students.StudentsRepository
+	races int
+}
+
+func (r *racingRepo) race(ctx context.Context, rollNo string) {
+	if r.races == 0 {
+		return
+	}
+	r.races--
+	current, err := r.StudentsRepository.GetOneStudent(ctx, rollNo, true)
+	if err != nil {
+		return
+	}
+	changed := *current
+	changed.Name = fmt.Sprintf("%s %d", changed.Name, r.races)
+	_ = r.StudentsRepository.PatchStudent(ctx, rollNo, *current, changed)
+}
+
+func (r *racingRepo) PatchStudent(
+	ctx context.Context,
+	rollNo string,
+	current, updated models.StudentModel,
+	evts ...events.Event,
+) error {
+	r.race(ctx, rollNo)
+	return r.StudentsRepository.PatchStudent(ctx, rollNo, current, updated, evts...)
+}
+
+func (r *racingRepo) DeleteStudent(
+	ctx context.Context,
+	current models.StudentModel,
+	deletedAt time.Time,
+	deletedBy string,
+	evts ...events.Event,
+) error {
+	r.race(ctx, current.RollNo)
+	return r.StudentsRepository.DeleteStudent(ctx, current, deletedAt, deletedBy, evts...)
+}
+
+type fixture struct {
+	db   *sql.DB
+	repo *racingRepo
+	svc  *students.StudentsService
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	ctx := context.Background()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := sqldb.Connect(ctx, sqldb.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := sqldb.Migrate(ctx, db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	repo := &racingRepo{StudentsRepository: sqldb.NewStudentsRepository(db)}
+	recorder := audit.NewRecorder(nil, zap.NewNop())
+	return fixture{db: db, repo: repo, svc: students.NewService(repo, events.NewEmitter(true), recorder)}
+}
+
+// published returns the events written to the outbox and marks them published
+func (f fixture) published(t *testing.T) []events.Event {
+	t.Helper()
+	outbox := sqldb.NewOutbox(f.db)
+	pending, err := outbox.Pending(context.Background(), 100)
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if err := outbox.MarkPublished(context.Background(), pending); err != nil {
+		t.Fatalf("MarkPublished() error = %v", err)
+	}
+	return pending
+}
+
+// auditEntry returns the one audit entry among evts
+func auditEntry(t *testing.T, evts []events.Event) models.AuditEntry {
+	t.Helper()
+	var entries []models.AuditEntry
+	for _, evt := range evts {
+		if evt.Type != events.AuditRecorded {
+			continue
+		}
+		var entry models.AuditEntry
+		if err := json.Unmarshal(evt.Payload, &entry); err != nil {
+			t.Fatalf("undecodable audit entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("outbox has %d audit entries, want 1", len(entries))
+	}
+	return entries[0]
+}
+
+func change(entry models.AuditEntry, field string) (models.AuditChange, bool) {
+	for _, c := range entry.Changes {
+		if c.Field == field {
+			return c, true
+		}
+	}
+	return models.AuditChange{}, false
+}
+
+func newStudent(rollNo string) models.StudentModel {
+	return models.StudentModel{RollNo: rollNo, Name: "Ann", Gender: "female", MailID: rollNo + "@example.com"}
+}
+
+func TestInsertStudentWritesAuditEntryWithTheChange(t *testing.T) {
+	f := newFixture(t)
+	ctx := audit.WithRequest(context.Background(), audit.Request{Actor: "admin", RequestID: "req-1"})
+
+	if err := f.svc.InsertStudent(ctx, newStudent("1")); err != nil {
+		t.Fatalf("InsertStudent() error = %v", err)
+	}
+	entry := auditEntry(t, f.published(t))
+	if entry.Action != models.AuditCreate || entry.Actor != "admin" || entry.RequestID != "req-1" {
+		t.Fatalf("audit entry = %+v, want a create by admin", entry)
+	}
+
+	// A failed change leaves no audit entry behind
+	if err := f.svc.InsertStudent(ctx, newStudent("1")); !errors.IsKind(err, errors.Conflict) {
+		t.Fatalf("InsertStudent() of a taken roll number error = %v, want Conflict", err)
+	}
+	if evts := f.published(t); len(evts) != 0 {
+		t.Fatalf("a failed insert wrote %d events", len(evts))
+	}
+}
+
+func TestConditionalWritesAuditTheStudentTheyReplaced(t *testing.T) {
+	tests := []struct {
+		name       string
+		write      func(ctx context.Context, svc *students.StudentsService) error
+		wantAction string
+	}{
+		{
+			name: "patch",
+			write: func(ctx context.Context, svc *students.StudentsService) error {
+				_, err := svc.PatchStudent(ctx, "1", func(current models.StudentModel) (models.StudentModel, error) {
+					current.Name = "Bea"
+					return current, nil
+				})
+				return err
+			},
+			wantAction: models.AuditUpdate,
+		},
+		{
+			name: "delete",
+			write: func(ctx context.Context, svc *students.StudentsService) error {
+				return svc.DeleteStudent(ctx, "1")
+			},
+			wantAction: models.AuditDelete,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			ctx := context.Background()
+			if err := f.svc.InsertStudent(ctx, newStudent("1")); err != nil {
+				t.Fatalf("InsertStudent() error = %v", err)
+			}
+			f.published(t)
+
+			// The first write finds the student changed to "Ann 0" and is retried
+			f.repo.races = 1
+			if err := tt.write(ctx, f.svc); err != nil {
+				t.Fatalf("write error = %v", err)
+			}
+			entry := auditEntry(t, f.published(t))
+			name, ok := change(entry, "name")
+			if entry.Action != tt.wantAction || !ok || name.Before != "Ann 0" {
+				t.Fatalf("audit entry = %+v, want a %s of the name Ann 0", entry, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestConditionalWritesGiveUpUnderContention(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	if err := f.svc.InsertStudent(ctx, newStudent("1")); err != nil {
+		t.Fatalf("InsertStudent() error = %v", err)
+	}
+	f.published(t)
+
+	f.repo.races = 10
+	if err := f.svc.DeleteStudent(ctx, "1"); !errors.IsKind(err, errors.Conflict) {
+		t.Fatalf("DeleteStudent() error = %v, want Conflict", err)
+	}
+	if evts := f.published(t); len(evts) != 0 {
+		t.Fatalf("a failed delete wrote %d events", len(evts))
+	}
+	if _, err := f.svc.GetOneStudent(ctx, "1", false); err != nil {
+		t.Fatalf("GetOneStudent() after a failed delete error = %v", err)
+	}
+}
+
+func TestRestoreStudent(t *testing.T) {
+	f := newFixture(t)
+	ctx := audit.WithRequest(context.Background(), audit.Request{Actor: "admin"})
+	if err := f.svc.InsertStudent(ctx, newStudent("1")); err != nil {
+		t.Fatalf("InsertStudent() error = %v", err)
+	}
+	if _, err := f.svc.RestoreStudent(ctx, "1"); !errors.IsKind(err, errors.Conflict) {
+		t.Fatalf("RestoreStudent() of a student that is not deleted error = %v, want Conflict", err)
+	}
+	if err := f.svc.DeleteStudent(ctx, "1"); err != nil {
+		t.Fatalf("DeleteStudent() error = %v", err)
+	}
+	f.published(t)
+
+	restored, err := f.svc.RestoreStudent(ctx, "1")
+	if err != nil || restored.DeletedAt != nil || restored.DeletedBy != "" {
+		t.Fatalf("RestoreStudent() = %+v, %v", restored, err)
+	}
+	entry := auditEntry(t, f.published(t))
+	deletedBy, ok := change(entry, "deleted_by")
+	if entry.Action != models.AuditRestore || !ok || deletedBy.Before != "admin" {
+		t.Fatalf("audit entry = %+v, want a restore of the deletion by admin", entry)
+	}
+
+	if err := f.svc.DeleteStudent(ctx, "404"); !errors.IsKind(err, errors.NotFound) {
+		t.Fatalf("DeleteStudent() of a missing student error = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,267 @@
+package webhooks
+
+import (
+	// Go Internal Packages
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+	utils "learn-go/utils"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// Headers sent with every webhook request
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the value of the X-Webhook-Signature header: the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret.
+// Receivers should recompute it and reject stale timestamps to prevent replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+const (
+	// retryPollInterval is how often Run looks for due retries
+	retryPollInterval = time.Second
+	// retryBatch caps the retries a replica makes at once
+	retryBatch = 50
+)
+
+// Deliverer is the event handler that posts events to the subscribed webhooks.
+// Handle makes one attempt per subscription, failed ones are retried by Run with an
+// exponential backoff, so a failing receiver neither holds up the events bus nor
+// duplicates deliveries to the others.
+type Deliverer struct {
+	backoff            time.Duration
+	client             *http.Client
+	logger             *zap.Logger
+	maxAttempts        int
+	webhooksRepository WebhooksRepository
+}
+
+func NewDeliverer(
+	webhooksRepository WebhooksRepository,
+	client *http.Client,
+	maxAttempts int,
+	backoff time.Duration,
+	logger *zap.Logger,
+) *Deliverer {
+	return &Deliverer{
+		backoff:            backoff,
+		client:             client,
+		logger:             logger,
+		maxAttempts:        maxAttempts,
+		webhooksRepository: webhooksRepository,
+	}
+}
+
+func (d *Deliverer) Name() string {
+	return "webhooks"
+}
+
+// Handle delivers the event to every subscription listening to its type and schedules
+// a retry for the failed ones. A redelivered event skips the subscriptions it was
+// delivered to or already scheduled for, so the error of a lookup or of scheduling a
+// retry is returned for the bus to redeliver it.
+func (d *Deliverer) Handle(ctx context.Context, evt events.Event) error {
+	webhooks, err := d.webhooksRepository.GetSubscribed(ctx, evt.Type)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	handled, err := d.webhooksRepository.GetHandled(ctx, evt.ID)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for _, webhook := range webhooks {
+		if handled[webhook.ID] {
+			continue
+		}
+		retry := models.WebhookRetry{
+			ID:        webhook.ID + ":" + evt.ID,
+			WebhookID: webhook.ID,
+			EventID:   evt.ID,
+			EventType: evt.Type,
+			Body:      body,
+			Attempt:   1,
+		}
+		wg.Add(1)
+		go func(webhook models.WebhookSubscription) {
+			defer wg.Done()
+			if err := d.deliver(ctx, webhook, retry); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}(webhook)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Run makes the scheduled retries as they fall due until ctx is cancelled
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+	for {
+		d.retryDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retryDue claims the due retries and makes their attempts concurrently. A claim
+// outlasts the request timeout, the retry of a replica that stopped meanwhile is
+// claimed again once it ran out.
+func (d *Deliverer) retryDue(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	lease := d.client.Timeout + time.Minute
+
+	for i := 0; i < retryBatch && ctx.Err() == nil; i++ {
+		retry, err := d.webhooksRepository.ClaimRetry(ctx, time.Now().UTC(), lease)
+		if errors.IsKind(err, errors.NotFound) {
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Error("failed to claim webhook retry", zap.Error(err))
+			}
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.retry(ctx, retry)
+		}()
+	}
+}
+
+func (d *Deliverer) retry(ctx context.Context, retry models.WebhookRetry) {
+	logger := d.logger.With(zap.String("webhookId", retry.WebhookID), zap.String("eventId", retry.EventID))
+
+	webhook, err := d.webhooksRepository.GetOne(ctx, retry.WebhookID)
+	if errors.IsKind(err, errors.NotFound) {
+		// The subscription was deleted since
+		if err := d.webhooksRepository.DeleteRetry(ctx, retry.ID); err != nil {
+			logger.Error("failed to delete webhook retry", zap.Error(err))
+		}
+		return
+	}
+	if err == nil {
+		err = d.deliver(ctx, webhook, retry)
+	}
+	if err != nil && ctx.Err() == nil {
+		logger.Error("failed to retry webhook delivery", zap.Error(err))
+	}
+}
+
+// deliver makes the attempt of the retry, a failed one is scheduled again with the
+// backoff doubled until the attempts run out
+func (d *Deliverer) deliver(ctx context.Context, webhook models.WebhookSubscription, retry models.WebhookRetry) error {
+	logger := d.logger.With(zap.String("webhookId", webhook.ID), zap.String("eventId", retry.EventID))
+
+	delivery := d.attempt(ctx, webhook, retry)
+	if err := d.webhooksRepository.InsertDelivery(ctx, delivery); err != nil {
+		logger.Error("failed to log webhook delivery", zap.Error(err))
+	}
+
+	if !delivery.Success && retry.Attempt < d.maxAttempts {
+		retry.DueAt = time.Now().UTC().Add(d.backoff << (retry.Attempt - 1))
+		retry.Attempt++
+		return d.webhooksRepository.ScheduleRetry(ctx, retry)
+	}
+	if !delivery.Success {
+		logger.Error("webhook delivery failed, giving up", zap.Int("attempts", retry.Attempt),
+			zap.Int("status", delivery.StatusCode), zap.String("error", delivery.Error))
+	}
+	// The first attempt has no retry to delete
+	if retry.Attempt > 1 {
+		return d.webhooksRepository.DeleteRetry(ctx, retry.ID)
+	}
+	return nil
+}
+
+func (d *Deliverer) attempt(
+	ctx context.Context,
+	webhook models.WebhookSubscription,
+	retry models.WebhookRetry,
+) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		ID:          utils.GenerateRandomID(),
+		WebhookID:   webhook.ID,
+		EventID:     retry.EventID,
+		EventType:   retry.EventType,
+		Attempt:     retry.Attempt,
+		DeliveredAt: time.Now().UTC(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(retry.Body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	timestamp := delivery.DeliveredAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, string(retry.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, retry.Body))
+
+	res, err := d.client.Do(req)
+	delivery.DurationMillis = time.Since(delivery.DeliveredAt).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	delivery.StatusCode = res.StatusCode
+	delivery.Success = res.StatusCode >= 200 && res.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = fmt.Sprintf("receiver responded with status %d", res.StatusCode)
+	}
+	return delivery
+}
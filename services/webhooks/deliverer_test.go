@@ -0,0 +1,444 @@
+package webhooks
+
+import (
+	// Go Internal Packages
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+
+	// External Packages
+	"go.uber.org/zap"
+)
+
+// memWebhooks is an in-memory WebhooksRepository
+type memWebhooks struct {
+	mu         sync.Mutex
+	webhooks   map[string]models.WebhookSubscription
+	deliveries []models.WebhookDelivery
+	retries    map[string]models.WebhookRetry
+}
+
+func newMemWebhooks(webhooks ...models.WebhookSubscription) *memWebhooks {
+	m := &memWebhooks{webhooks: map[string]models.WebhookSubscription{}, retries: map[string]models.WebhookRetry{}}
+	for _, webhook := range webhooks {
+		m.webhooks[webhook.ID] = webhook
+	}
+	return m
+}
+
+func (m *memWebhooks) GetAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	webhooks := []models.WebhookSubscription{}
+	for _, webhook := range m.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, nil
+}
+
+func (m *memWebhooks) GetSubscribed(ctx context.Context, eventType events.Type) ([]models.WebhookSubscription, error) {
+	all, _ := m.GetAll(ctx)
+	webhooks := []models.WebhookSubscription{}
+	for _, webhook := range all {
+		if webhook.Subscribes(eventType) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *memWebhooks) GetOne(_ context.Context, webhookID string) (models.WebhookSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	webhook, ok := m.webhooks[webhookID]
+	if !ok {
+		return models.WebhookSubscription{}, errors.E(errors.NotFound, "webhook not found")
+	}
+	return webhook, nil
+}
+
+func (m *memWebhooks) Insert(_ context.Context, webhook models.WebhookSubscription) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (m *memWebhooks) Update(ctx context.Context, webhook models.WebhookSubscription) error {
+	if _, err := m.GetOne(ctx, webhook.ID); err != nil {
+		return err
+	}
+	return m.Insert(ctx, webhook)
+}
+
+func (m *memWebhooks) Delete(_ context.Context, webhookID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.webhooks, webhookID)
+	return nil
+}
+
+func (m *memWebhooks) InsertDelivery(_ context.Context, delivery models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, delivery)
+	return nil
+}
+
+func (m *memWebhooks) GetDeliveries(_ context.Context, webhookID string, limit int64) ([]models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deliveries := []models.WebhookDelivery{}
+	for i := len(m.deliveries) - 1; i >= 0 && int64(len(deliveries)) < limit; i-- {
+		if m.deliveries[i].WebhookID == webhookID {
+			deliveries = append(deliveries, m.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *memWebhooks) GetHandled(_ context.Context, eventID string) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	handled := map[string]bool{}
+	for _, delivery := range m.deliveries {
+		if delivery.EventID == eventID && delivery.Success {
+			handled[delivery.WebhookID] = true
+		}
+	}
+	for _, retry := range m.retries {
+		if retry.EventID == eventID {
+			handled[retry.WebhookID] = true
+		}
+	}
+	return handled, nil
+}
+
+func (m *memWebhooks) ScheduleRetry(_ context.Context, retry models.WebhookRetry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[retry.ID] = retry
+	return nil
+}
+
+func (m *memWebhooks) ClaimRetry(_ context.Context, now time.Time, lease time.Duration) (models.WebhookRetry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due *models.WebhookRetry
+	for _, retry := range m.retries {
+		if !retry.DueAt.After(now) && (due == nil || retry.DueAt.Before(due.DueAt)) {
+			due = &retry
+		}
+	}
+	if due == nil {
+		return models.WebhookRetry{}, errors.E(errors.NotFound, "no webhook retry is due")
+	}
+	claimed := *due
+	claimed.DueAt = now.Add(lease)
+	m.retries[claimed.ID] = claimed
+	return *due, nil
+}
+
+func (m *memWebhooks) DeleteRetry(_ context.Context, retryID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.retries, retryID)
+	return nil
+}
+
+// scheduled returns the retries waiting for their next attempt
+func (m *memWebhooks) scheduled() []models.WebhookRetry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	retries := []models.WebhookRetry{}
+	for _, retry := range m.retries {
+		retries = append(retries, retry)
+	}
+	return retries
+}
+
+// receiver records the requests it gets and answers the first failures of them with a 503
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+	at     time.Time
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedRequest{}, rc.requests...)
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, receivedRequest{header: r.Header.Clone(), body: body, at: time.Now()})
+	if len(rc.requests) <= rc.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newEvent() events.Event {
+	return events.Event{ID: "evt-1", Type: events.StudentEnrolled, AggregateID: "21", OccurredAt: time.Now().UTC()}
+}
+
+func TestSign(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000.{\"id\":\"1\"}"))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := Sign("secret", 1700000000, []byte(`{"id":"1"}`)); got != want {
+		t.Fatalf("Sign() = %s, want %s", got, want)
+	}
+	if Sign("other", 1700000000, []byte(`{"id":"1"}`)) == want {
+		t.Fatalf("Sign() does not depend on the secret")
+	}
+	if Sign("secret", 1700000001, []byte(`{"id":"1"}`)) == want {
+		t.Fatalf("Sign() does not depend on the timestamp")
+	}
+}
+
+func TestDelivererSignsRequests(t *testing.T) {
+	rc := &receiver{}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+	webhook := models.WebhookSubscription{ID: "wh-1", URL: ts.URL, Secret: "s3cret", EventTypes: []events.Type{events.StudentEnrolled}}
+	repo := newMemWebhooks(webhook)
+	d := NewDeliverer(repo, ts.Client(), 3, time.Millisecond, zap.NewNop())
+
+	evt := newEvent()
+	if err := d.Handle(context.Background(), evt); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(rc.requests))
+	}
+	req := rc.requests[0]
+	timestamp, err := strconv.ParseInt(req.header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s header: %v", HeaderTimestamp, err)
+	}
+	if got, want := req.header.Get(HeaderSignature), Sign(webhook.Secret, timestamp, req.body); got != want {
+		t.Fatalf("%s = %s, want %s", HeaderSignature, got, want)
+	}
+	if got := req.header.Get(HeaderEvent); got != string(events.StudentEnrolled) {
+		t.Fatalf("%s = %s, want %s", HeaderEvent, got, events.StudentEnrolled)
+	}
+	var got events.Event
+	if err := json.Unmarshal(req.body, &got); err != nil || got.ID != evt.ID {
+		t.Fatalf("body = %s, want the event", req.body)
+	}
+
+	deliveries, _ := repo.GetDeliveries(context.Background(), webhook.ID, 10)
+	if len(deliveries) != 1 || !deliveries[0].Success || deliveries[0].StatusCode != http.StatusNoContent {
+		t.Fatalf("deliveries = %+v, want one successful delivery", deliveries)
+	}
+	if deliveries[0].ID != req.header.Get(HeaderDelivery) {
+		t.Fatalf("delivery id %s does not match the %s header", deliveries[0].ID, HeaderDelivery)
+	}
+}
+
+func TestDelivererRetriesInTheBackground(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		maxAttempts  int
+		wantAttempts int
+		wantSuccess  bool
+	}{
+		{name: "succeeds after failures", failures: 2, maxAttempts: 4, wantAttempts: 3, wantSuccess: true},
+		{name: "gives up after the attempts", failures: 10, maxAttempts: 3, wantAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &receiver{failures: tt.failures}
+			ts := httptest.NewServer(rc)
+			defer ts.Close()
+			webhook := models.WebhookSubscription{ID: "wh-1", URL: ts.URL, EventTypes: []events.Type{events.StudentEnrolled}}
+			repo := newMemWebhooks(webhook)
+			backoff := 20 * time.Millisecond
+			d := NewDeliverer(repo, ts.Client(), tt.maxAttempts, backoff, zap.NewNop())
+
+			// The handler makes the first attempt only
+			if err := d.Handle(context.Background(), newEvent()); err != nil {
+				t.Fatalf("Handle() error = %v", err)
+			}
+			if retries := repo.scheduled(); len(rc.received()) != 1 || len(retries) != 1 || retries[0].Attempt != 2 {
+				t.Fatalf("Handle() made %d requests and scheduled %+v, want 1 and the second attempt",
+					len(rc.received()), retries)
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for len(repo.scheduled()) > 0 && time.Now().Before(deadline) {
+				d.retryDue(context.Background())
+				time.Sleep(time.Millisecond)
+			}
+			requests := rc.received()
+			if len(requests) != tt.wantAttempts || len(repo.scheduled()) != 0 {
+				t.Fatalf("receiver got %d requests with %d retries left, want %d and none",
+					len(requests), len(repo.scheduled()), tt.wantAttempts)
+			}
+			// The backoff doubles after every failed attempt
+			for i := 1; i < len(requests); i++ {
+				want := backoff << (i - 1)
+				if gap := requests[i].at.Sub(requests[i-1].at); gap < want {
+					t.Fatalf("attempt %d came %s after the previous one, want at least %s", i+1, gap, want)
+				}
+			}
+
+			deliveries, _ := repo.GetDeliveries(context.Background(), webhook.ID, 10)
+			if len(deliveries) != tt.wantAttempts {
+				t.Fatalf("logged %d deliveries, want %d", len(deliveries), tt.wantAttempts)
+			}
+			for i, delivery := range deliveries {
+				attempt := tt.wantAttempts - i
+				success := tt.wantSuccess && attempt == tt.wantAttempts
+				if delivery.Attempt != attempt || delivery.Success != success {
+					t.Fatalf("delivery %d = attempt %d success %v, want attempt %d success %v",
+						i, delivery.Attempt, delivery.Success, attempt, success)
+				}
+				if !success && delivery.StatusCode != http.StatusServiceUnavailable {
+					t.Fatalf("failed delivery status = %d, want 503", delivery.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestDelivererDoesNotWaitForTheBackoff(t *testing.T) {
+	failing, healthy := &receiver{failures: 10}, &receiver{}
+	failingServer, healthyServer := httptest.NewServer(failing), httptest.NewServer(healthy)
+	defer failingServer.Close()
+	defer healthyServer.Close()
+	repo := newMemWebhooks(
+		models.WebhookSubscription{ID: "wh-1", URL: failingServer.URL, EventTypes: []events.Type{events.StudentEnrolled}},
+		models.WebhookSubscription{ID: "wh-2", URL: healthyServer.URL, EventTypes: []events.Type{events.StudentEnrolled}},
+	)
+	d := NewDeliverer(repo, http.DefaultClient, 5, time.Hour, zap.NewNop())
+
+	start := time.Now()
+	if err := d.Handle(context.Background(), newEvent()); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if time.Since(start) > 5*time.Second || len(failing.received()) != 1 || len(healthy.received()) != 1 {
+		t.Fatalf("Handle() waited for the retries of the failing receiver")
+	}
+
+	// The retry is not due before the backoff
+	d.retryDue(context.Background())
+	if retries := repo.scheduled(); len(failing.received()) != 1 || len(retries) != 1 || retries[0].WebhookID != "wh-1" {
+		t.Fatalf("retries = %+v after %d requests, want the one of wh-1 waiting", retries, len(failing.received()))
+	}
+}
+
+func TestDelivererSkipsHandledSubscriptionsOnRedelivery(t *testing.T) {
+	failing, healthy := &receiver{failures: 10}, &receiver{}
+	failingServer, healthyServer := httptest.NewServer(failing), httptest.NewServer(healthy)
+	defer failingServer.Close()
+	defer healthyServer.Close()
+	repo := newMemWebhooks(
+		models.WebhookSubscription{ID: "wh-1", URL: failingServer.URL, EventTypes: []events.Type{events.StudentEnrolled}},
+		models.WebhookSubscription{ID: "wh-2", URL: healthyServer.URL, EventTypes: []events.Type{events.StudentEnrolled}},
+	)
+	d := NewDeliverer(repo, http.DefaultClient, 5, time.Hour, zap.NewNop())
+
+	for i := 0; i < 2; i++ {
+		if err := d.Handle(context.Background(), newEvent()); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+	// The delivered subscription and the one waiting for its retry are both skipped
+	if len(failing.received()) != 1 || len(healthy.received()) != 1 {
+		t.Fatalf("receivers got %d and %d requests after a redelivery, want 1 each",
+			len(failing.received()), len(healthy.received()))
+	}
+
+	// Another event is delivered to both
+	evt := newEvent()
+	evt.ID = "evt-2"
+	if err := d.Handle(context.Background(), evt); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(failing.received()) != 2 || len(healthy.received()) != 2 {
+		t.Fatalf("receivers got %d and %d requests for another event, want 2 each",
+			len(failing.received()), len(healthy.received()))
+	}
+}
+
+func TestDelivererDropsTheRetriesOfDeletedSubscriptions(t *testing.T) {
+	rc := &receiver{failures: 10}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+	repo := newMemWebhooks(models.WebhookSubscription{ID: "wh-1", URL: ts.URL, EventTypes: []events.Type{events.StudentEnrolled}})
+	d := NewDeliverer(repo, ts.Client(), 5, 0, zap.NewNop())
+
+	if err := d.Handle(context.Background(), newEvent()); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	_ = repo.Delete(context.Background(), "wh-1")
+	d.retryDue(context.Background())
+
+	if len(rc.received()) != 1 || len(repo.scheduled()) != 0 {
+		t.Fatalf("receiver got %d requests with %d retries left, want the retry dropped",
+			len(rc.received()), len(repo.scheduled()))
+	}
+}
+
+func TestDelivererSkipsOtherEventTypes(t *testing.T) {
+	rc := &receiver{}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+	webhook := models.WebhookSubscription{ID: "wh-1", URL: ts.URL, EventTypes: []events.Type{events.OrderCreated}}
+	repo := newMemWebhooks(webhook)
+	d := NewDeliverer(repo, ts.Client(), 3, time.Millisecond, zap.NewNop())
+
+	if err := d.Handle(context.Background(), newEvent()); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(rc.requests) != 0 || len(repo.deliveries) != 0 {
+		t.Fatalf("an event the webhook is not subscribed to was delivered")
+	}
+}
+
+func TestHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	ts := httptest.NewServer(&receiver{})
+	defer ts.Close()
+
+	res, err := NewHTTPClient(time.Second, false).Get(ts.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Fatalf("Get() of a loopback url succeeded, want it refused")
+	}
+
+	res, err = NewHTTPClient(time.Second, true).Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Get() with private networks allowed error = %v", err)
+	}
+	res.Body.Close()
+}
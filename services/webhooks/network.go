@@ -0,0 +1,97 @@
+package webhooks
+
+import (
+	// Go Internal Packages
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+// nonPublicPrefixes are the special purpose ranges netip does not classify
+// as private, loopback or link-local but that must not be reached either
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// IsPublic reports whether a webhook may be sent to the address, loopback,
+// link-local, private and other special purpose ranges are refused
+func IsPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewHTTPClient returns the client webhooks are delivered with. Unless private
+// networks are allowed it refuses to connect to addresses that are not public,
+// the check runs on the address actually dialled so neither a redirect nor a
+// DNS answer that changed since the subscription was validated gets around it.
+func NewHTTPClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivateNetworks {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublicOnly}
+		transport.DialContext = dialer.DialContext
+		// A proxy would be dialled in place of the receiver and defeat the check
+		transport.Proxy = nil
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func dialPublicOnly(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublic(addrPort.Addr()) {
+		return fmt.Errorf("webhook address %s is not public", addrPort.Addr())
+	}
+	return nil
+}
+
+// checkURL rejects a webhook url whose host is, or resolves to, an address that is not public
+func (s *WebhooksService) checkURL(ctx context.Context, rawURL string) error {
+	if s.allowPrivateNetworks {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.E(errors.Invalid, "invalid webhook url", err)
+	}
+
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		addrs = []netip.Addr{addr}
+	} else if addrs, err = s.resolver.LookupNetIP(ctx, "ip", u.Hostname()); err != nil {
+		ve := errors.ValidationErrs()
+		ve.Add("/url", "host cannot be resolved")
+		return errors.ValidationFailedErr(ve.Err())
+	}
+
+	for _, addr := range addrs {
+		if !IsPublic(addr) {
+			ve := errors.ValidationErrs()
+			ve.Add("/url", "must not point to a loopback, link-local or private address")
+			return errors.ValidationFailedErr(ve.Err())
+		}
+	}
+	return nil
+}
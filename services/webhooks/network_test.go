@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	// Go Internal Packages
+	"context"
+	"net/netip"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+	events "learn-go/events"
+	models "learn-go/models"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.215.14", want: true},
+		{addr: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "0.0.0.0"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "100.64.0.1"},
+		{addr: "fe80::1"},
+		{addr: "fd00::1"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "::ffff:10.0.0.1"},
+		{addr: "224.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := IsPublic(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Fatalf("IsPublic(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceRejectsPrivateURLs(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://93.184.215.14/hook"},
+		{url: "http://127.0.0.1:8080/hook", wantErr: true},
+		{url: "http://localhost/hook", wantErr: true},
+		{url: "http://[::1]/hook", wantErr: true},
+		{url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{url: "http://10.0.0.5/hook", wantErr: true},
+		{url: "http://[::ffff:192.168.0.1]/hook", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			svc := NewService(newMemWebhooks(), false)
+			webhook := models.WebhookSubscription{URL: tt.url, EventTypes: []events.Type{events.StudentEnrolled}}
+
+			_, err := svc.Insert(context.Background(), webhook)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("Insert() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.IsKind(err, errors.Invalid) {
+				t.Fatalf("Insert() error = %v, want Invalid", err)
+			}
+		})
+	}
+}
+
+func TestServiceAllowsPrivateURLsWhenConfigured(t *testing.T) {
+	svc := NewService(newMemWebhooks(), true)
+	webhook := models.WebhookSubscription{URL: "http://127.0.0.1:8080/hook", EventTypes: []events.Type{events.StudentEnrolled}}
+
+	created, err := svc.Insert(context.Background(), webhook)
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	created.URL = "http://10.0.0.5/hook"
+	if err := svc.Update(context.Background(), created); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+}
+
+func TestServiceUpdateRejectsPrivateURLs(t *testing.T) {
+	svc := NewService(newMemWebhooks(), false)
+	created, err := svc.Insert(context.Background(), models.WebhookSubscription{
+		URL: "https://93.184.215.14/hook", EventTypes: []events.Type{events.StudentEnrolled},
+	})
+	if err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	created.URL = "http://169.254.169.254/"
+	if err := svc.Update(context.Background(), created); !errors.IsKind(err, errors.Invalid) {
+		t.Fatalf("Update() error = %v, want Invalid", err)
+	}
+}
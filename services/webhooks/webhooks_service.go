@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	// Go Internal Packages
+	"context"
+	"net"
+	"time"
+
+	// Local Packages
+	events "learn-go/events"
+	models "learn-go/models"
+	utils "learn-go/utils"
+)
+
+type WebhooksRepository interface {
+	GetAll(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetSubscribed(ctx context.Context, eventType events.Type) ([]models.WebhookSubscription, error)
+	GetOne(ctx context.Context, webhookID string) (models.WebhookSubscription, error)
+	Insert(ctx context.Context, webhook models.WebhookSubscription) error
+	Update(ctx context.Context, webhook models.WebhookSubscription) error
+	Delete(ctx context.Context, webhookID string) error
+	InsertDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookID string, limit int64) ([]models.WebhookDelivery, error)
+	GetHandled(ctx context.Context, eventID string) (map[string]bool, error)
+	ScheduleRetry(ctx context.Context, retry models.WebhookRetry) error
+	ClaimRetry(ctx context.Context, now time.Time, lease time.Duration) (models.WebhookRetry, error)
+	DeleteRetry(ctx context.Context, retryID string) error
+}
+
+type WebhooksService struct {
+	allowPrivateNetworks bool
+	resolver             *net.Resolver
+	webhooksRepository   WebhooksRepository
+}
+
+// NewService returns the subscriptions service, unless allowPrivateNetworks is
+// set subscriptions to urls that are not public are rejected
+func NewService(webhooksRepository WebhooksRepository, allowPrivateNetworks bool) *WebhooksService {
+	return &WebhooksService{
+		allowPrivateNetworks: allowPrivateNetworks,
+		resolver:             net.DefaultResolver,
+		webhooksRepository:   webhooksRepository,
+	}
+}
+
+// Insert creates the subscription and generates its secret when none was given.
+// The returned subscription is the only response that carries the secret.
+func (s *WebhooksService) Insert(ctx context.Context, webhook models.WebhookSubscription) (models.WebhookSubscription, error) {
+	if err := s.checkURL(ctx, webhook.URL); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	webhook.ID = utils.GenerateRandomID()
+	if webhook.Secret == "" {
+		webhook.Secret = utils.GenerateSecret()
+	}
+	now := time.Now().UTC()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+
+	if err := s.webhooksRepository.Insert(ctx, webhook); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	return webhook, nil
+}
+
+// GetAll returns every subscription without its secret
+func (s *WebhooksService) GetAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	webhooks, err := s.webhooksRepository.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// GetOne returns the subscription without its secret
+func (s *WebhooksService) GetOne(ctx context.Context, webhookID string) (models.WebhookSubscription, error) {
+	webhook, err := s.webhooksRepository.GetOne(ctx, webhookID)
+	webhook.Secret = ""
+	return webhook, err
+}
+
+// Update replaces the url and event types, the secret is rotated only when a new one is given
+func (s *WebhooksService) Update(ctx context.Context, webhook models.WebhookSubscription) error {
+	if err := s.checkURL(ctx, webhook.URL); err != nil {
+		return err
+	}
+	current, err := s.webhooksRepository.GetOne(ctx, webhook.ID)
+	if err != nil {
+		return err
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = current.Secret
+	}
+	webhook.CreatedAt = current.CreatedAt
+	webhook.UpdatedAt = time.Now().UTC()
+	return s.webhooksRepository.Update(ctx, webhook)
+}
+
+func (s *WebhooksService) Delete(ctx context.Context, webhookID string) error {
+	return s.webhooksRepository.Delete(ctx, webhookID)
+}
+
+// GetDeliveries returns the latest delivery attempts of an existing subscription
+func (s *WebhooksService) GetDeliveries(ctx context.Context, webhookID string, limit int64) ([]models.WebhookDelivery, error) {
+	if _, err := s.webhooksRepository.GetOne(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.webhooksRepository.GetDeliveries(ctx, webhookID, limit)
+}
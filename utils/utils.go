@@ -2,6 +2,8 @@ package utils
 
 import (
 	// Go Internal Packages
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -13,6 +15,13 @@ func GenerateRandomID() string {
 	return uuid.New().String()
 }
 
+// GenerateSecret returns 32 random bytes hex encoded
+func GenerateSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 func GetCurrentTime() string {
 	loc, _ := time.LoadLocation("Asia/Kolkata")
 	istTime := time.Now().In(loc).Format("Jan 02 2006 03:04:05 PM")
@@ -22,3 +31,15 @@ func GetCurrentTime() string {
 func GetOrderID(id string) string {
 	return fmt.Sprintf("ORDER:%s", id)
 }
+
+func GetStudentCacheKey(rollNo string) string {
+	return fmt.Sprintf("STUDENT:%s", rollNo)
+}
+
+func GetJobKey(id string) string {
+	return fmt.Sprintf("JOB:%s", id)
+}
+
+func GetJobCancelKey(id string) string {
+	return fmt.Sprintf("JOB:%s:CANCEL", id)
+}
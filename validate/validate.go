@@ -0,0 +1,272 @@
+// Package validate normalises and validates structs from the rules declared in their
+// `validate` tags, e.g.
+//
+//	MailID string `json:"mail_id" validate:"trim,lower,required,email"`
+//
+// Rules run left to right and a field reports only its first failure, under the
+// JSON pointer built from the json tags. Nested structs, pointers to structs and
+// slices of structs are validated too.
+//
+// Rules:
+//
+//	trim          removes leading and trailing white space
+//	lower         lowercases the value
+//	required      the value cannot be empty, later rules are skipped for empty values otherwise
+//	min=n, max=n  bounds the length of strings and slices, the value of numbers
+//	gt=n          the number must be greater than n
+//	email         the string must be an email address
+//	oneof=a b c   the string must be one of the space separated values
+//	pattern=name  the string must match the pattern registered as name
+package validate
+
+import (
+	// Go Internal Packages
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+type pattern struct {
+	re      *regexp.Regexp
+	message string
+}
+
+var (
+	patterns = map[string]pattern{}
+	types    sync.Map // reflect.Type -> []field
+)
+
+// RegisterPattern makes the pattern available to the `pattern=name` rule, message
+// is reported when a value does not match. It panics if expr does not compile.
+func RegisterPattern(name, expr, message string) {
+	patterns[name] = pattern{re: regexp.MustCompile(expr), message: message}
+}
+
+// Struct applies the rules of the struct pointed to by v, adding the failures to ve
+func Struct(v any, ve *errors.ValidationErrorBuilder) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: expected a pointer to a struct, got %T", v))
+	}
+	walk(rv.Elem(), "", ve)
+}
+
+type rule struct {
+	name  string
+	num   float64
+	set   []string
+	match pattern
+}
+
+type field struct {
+	index int
+	name  string
+	rules []rule
+}
+
+func walk(rv reflect.Value, path string, ve *errors.ValidationErrorBuilder) {
+	for _, f := range fieldsOf(rv.Type()) {
+		value := rv.Field(f.index)
+		fieldPath := path + "/" + f.name
+		if ok := check(value, f.rules, fieldPath, ve); !ok {
+			continue
+		}
+
+		switch value.Kind() {
+		case reflect.Struct:
+			walk(value, fieldPath, ve)
+		case reflect.Pointer:
+			if !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+				walk(value.Elem(), fieldPath, ve)
+			}
+		case reflect.Slice:
+			for i := 0; i < value.Len(); i++ {
+				if item := reflect.Indirect(value.Index(i)); item.Kind() == reflect.Struct {
+					walk(item, fieldPath+"/"+strconv.Itoa(i), ve)
+				}
+			}
+		}
+	}
+}
+
+// check applies the rules to the value and reports whether they all passed
+func check(value reflect.Value, rules []rule, path string, ve *errors.ValidationErrorBuilder) bool {
+	for _, r := range rules {
+		switch r.name {
+		case "trim":
+			value.SetString(strings.TrimSpace(value.String()))
+			continue
+		case "lower":
+			value.SetString(strings.ToLower(value.String()))
+			continue
+		}
+
+		if empty(value) {
+			if r.name == "required" {
+				ve.Add(path, "cannot be empty")
+				return false
+			}
+			return true
+		}
+		if msg := apply(r, value); msg != "" {
+			ve.Add(path, msg)
+			return false
+		}
+	}
+	return true
+}
+
+func apply(r rule, value reflect.Value) string {
+	switch r.name {
+	case "min":
+		if size, unit := measure(value); size < r.num {
+			return fmt.Sprintf("must be at least %s%s", format(r.num), unit)
+		}
+	case "max":
+		if size, unit := measure(value); size > r.num {
+			return fmt.Sprintf("must be at most %s%s", format(r.num), unit)
+		}
+	case "gt":
+		if size, _ := measure(value); size <= r.num {
+			return "must be greater than " + format(r.num)
+		}
+	case "email":
+		addr, err := mail.ParseAddress(value.String())
+		if err != nil || addr.Address != value.String() || !strings.Contains(addr.Address[strings.LastIndex(addr.Address, "@"):], ".") {
+			return "must be a valid email address"
+		}
+	case "oneof":
+		for _, allowed := range r.set {
+			if value.String() == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(r.set, ", ")
+	case "pattern":
+		if !r.match.re.MatchString(value.String()) {
+			return r.match.message
+		}
+	}
+	return ""
+}
+
+func empty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	default:
+		return false
+	}
+}
+
+// measure returns the length of strings and slices and the value of numbers
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Map:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	default:
+		return 0, ""
+	}
+}
+
+func format(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// fieldsOf returns the tagged and nested fields of the struct type, parsed once per type
+func fieldsOf(t reflect.Type) []field {
+	if cached, ok := types.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		tag := sf.Tag.Get("validate")
+		if tag == "" && !nested(sf.Type) {
+			continue
+		}
+		fields = append(fields, field{index: i, name: escape(name), rules: parse(t, sf, tag)})
+	}
+
+	types.Store(t, fields)
+	return fields
+}
+
+func nested(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+	return t.Kind() == reflect.Struct && t.PkgPath() != "time"
+}
+
+func parse(t reflect.Type, sf reflect.StructField, tag string) []rule {
+	if tag == "" {
+		return nil
+	}
+
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(part, "=")
+		r := rule{name: name}
+		switch name {
+		case "trim", "lower":
+			if sf.Type.Kind() != reflect.String {
+				panic(fmt.Sprintf("validate: %s.%s: %s applies to strings only", t.Name(), sf.Name, name))
+			}
+		case "required", "email":
+		case "min", "max", "gt":
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validate: %s.%s: %s needs a number, got %q", t.Name(), sf.Name, name, param))
+			}
+			r.num = n
+		case "oneof":
+			r.set = strings.Fields(param)
+		case "pattern":
+			p, ok := patterns[param]
+			if !ok {
+				panic(fmt.Sprintf("validate: %s.%s: unknown pattern %q", t.Name(), sf.Name, param))
+			}
+			r.match = p
+		default:
+			panic(fmt.Sprintf("validate: %s.%s: unknown rule %q", t.Name(), sf.Name, name))
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// escape encodes the name as a JSON pointer reference token
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,151 @@
+package validate
+
+import (
+	// Go Internal Packages
+	"reflect"
+	"testing"
+
+	// Local Packages
+	errors "learn-go/errors"
+)
+
+func init() {
+	RegisterPattern("code", `^[A-Z]{3}$`, "must be three capital letters")
+}
+
+type item struct {
+	Name  string  `json:"name" validate:"trim,required"`
+	Price float64 `json:"price" validate:"gt=0"`
+}
+
+type sample struct {
+	Name    string   `json:"name" validate:"trim,required,min=2,max=5"`
+	Mail    string   `json:"mail,omitempty" validate:"trim,lower,email"`
+	Kind    string   `json:"kind" validate:"oneof=a b"`
+	Code    string   `json:"code" validate:"pattern=code"`
+	Count   int      `json:"count" validate:"min=1,max=3"`
+	Tags    []string `json:"tags" validate:"max=2"`
+	Items   []item   `json:"items"`
+	Main    *item    `json:"main"`
+	Nested  item     `json:"a/b~c"`
+	private string   `validate:"required"`
+	Skipped string   `json:"-" validate:"required"`
+}
+
+// valid returns a sample that passes every rule
+func valid() sample {
+	return sample{Name: "Ann", Kind: "a", Code: "ABC", Count: 1, Nested: item{Name: "n", Price: 1}}
+}
+
+func validate(v *sample) errors.ValidationErrors {
+	ve := errors.ValidationErrs()
+	Struct(v, ve)
+	if err := ve.Err(); err != nil {
+		return err.(errors.ValidationErrors)
+	}
+	return nil
+}
+
+func TestStructRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *sample)
+		want   errors.ValidationErrors
+	}{
+		{name: "valid", modify: func(s *sample) {}},
+		{name: "required", modify: func(s *sample) { s.Name = "" },
+			want: errors.ValidationErrors{{Field: "/name", Error: "cannot be empty"}}},
+		{name: "required after trim", modify: func(s *sample) { s.Name = "   " },
+			want: errors.ValidationErrors{{Field: "/name", Error: "cannot be empty"}}},
+		{name: "min counts characters", modify: func(s *sample) { s.Name = "é" },
+			want: errors.ValidationErrors{{Field: "/name", Error: "must be at least 2 characters"}}},
+		{name: "max counts characters", modify: func(s *sample) { s.Name = "ééééé" }},
+		{name: "max", modify: func(s *sample) { s.Name = "Annabel" },
+			want: errors.ValidationErrors{{Field: "/name", Error: "must be at most 5 characters"}}},
+		{name: "optional empty skips the rules", modify: func(s *sample) { s.Mail, s.Kind, s.Code = "", "", "" }},
+		{name: "email", modify: func(s *sample) { s.Mail = "ann@example.com" }},
+		{name: "email without domain dot", modify: func(s *sample) { s.Mail = "ann@localhost" },
+			want: errors.ValidationErrors{{Field: "/mail", Error: "must be a valid email address"}}},
+		{name: "email with a display name", modify: func(s *sample) { s.Mail = "Ann <ann@example.com>" },
+			want: errors.ValidationErrors{{Field: "/mail", Error: "must be a valid email address"}}},
+		{name: "email without at", modify: func(s *sample) { s.Mail = "ann.example.com" },
+			want: errors.ValidationErrors{{Field: "/mail", Error: "must be a valid email address"}}},
+		{name: "oneof", modify: func(s *sample) { s.Kind = "c" },
+			want: errors.ValidationErrors{{Field: "/kind", Error: "must be one of a, b"}}},
+		{name: "pattern", modify: func(s *sample) { s.Code = "abc" },
+			want: errors.ValidationErrors{{Field: "/code", Error: "must be three capital letters"}}},
+		{name: "number min", modify: func(s *sample) { s.Count = 0 },
+			want: errors.ValidationErrors{{Field: "/count", Error: "must be at least 1"}}},
+		{name: "number max", modify: func(s *sample) { s.Count = 4 },
+			want: errors.ValidationErrors{{Field: "/count", Error: "must be at most 3"}}},
+		{name: "slice max", modify: func(s *sample) { s.Tags = []string{"a", "b", "c"} },
+			want: errors.ValidationErrors{{Field: "/tags", Error: "must be at most 2 items"}}},
+		{name: "gt", modify: func(s *sample) { s.Nested.Price = 0 },
+			want: errors.ValidationErrors{{Field: "/a~1b~0c/price", Error: "must be greater than 0"}}},
+		{name: "slice of structs", modify: func(s *sample) { s.Items = []item{{Name: "x", Price: 1}, {Price: -1}} },
+			want: errors.ValidationErrors{
+				{Field: "/items/1/name", Error: "cannot be empty"},
+				{Field: "/items/1/price", Error: "must be greater than 0"},
+			}},
+		{name: "pointer to struct", modify: func(s *sample) { s.Main = &item{Name: "x"} },
+			want: errors.ValidationErrors{{Field: "/main/price", Error: "must be greater than 0"}}},
+		{name: "first failure of a field only", modify: func(s *sample) { s.Name, s.Kind = "", "c" },
+			want: errors.ValidationErrors{
+				{Field: "/name", Error: "cannot be empty"},
+				{Field: "/kind", Error: "must be one of a, b"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+			if got := validate(&s); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Struct() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructNormalises(t *testing.T) {
+	s := valid()
+	s.Name = "  Ann "
+	s.Mail = " Ann@Example.COM "
+	s.Items = []item{{Name: " x ", Price: 1}}
+	if errs := validate(&s); errs != nil {
+		t.Fatalf("Struct() = %+v", errs)
+	}
+	if s.Name != "Ann" || s.Mail != "ann@example.com" || s.Items[0].Name != "x" {
+		t.Fatalf("Struct() left %q, %q, %q, want them trimmed and lowercased", s.Name, s.Mail, s.Items[0].Name)
+	}
+}
+
+func TestStructPanicsOnInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{name: "not a pointer", v: valid()},
+		{name: "unknown rule", v: &struct {
+			A string `validate:"shiny"`
+		}{}},
+		{name: "unknown pattern", v: &struct {
+			A string `validate:"pattern=nope"`
+		}{}},
+		{name: "bound without a number", v: &struct {
+			A string `validate:"max=ten"`
+		}{}},
+		{name: "trim of a number", v: &struct {
+			A int `validate:"trim"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Struct() did not panic")
+				}
+			}()
+			Struct(tt.v, errors.ValidationErrs())
+		})
+	}
+}